@@ -0,0 +1,89 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package logical
+
+import (
+	"context"
+
+	"github.com/cockroachdb/cockroach/pkg/repstream/streampb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/util/quotapool"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/cockroachdb/errors"
+)
+
+var applyConcurrencyLimit = settings.RegisterIntSetting(
+	settings.ApplicationLevel,
+	"logical_replication.consumer.apply_concurrency_limit",
+	"the maximum number of batches applied concurrently by the logical replication streams on a node; set to 0 for no limit",
+	0,
+	settings.NonNegativeInt,
+)
+
+// applyConcurrencyLimiter bounds the number of batches applied concurrently by
+// the processors on this node, per applyConcurrencyLimit.
+type applyConcurrencyLimiter struct {
+	syncutil.Mutex
+	// pool is created on first use, and has one unit of quota per slot.
+	pool *quotapool.IntPool
+}
+
+// get returns the pool, updated to the given limit.
+func (l *applyConcurrencyLimiter) get(limit uint64) *quotapool.IntPool {
+	l.Lock()
+	defer l.Unlock()
+	if l.pool == nil {
+		l.pool = quotapool.NewIntPool("logical replication apply", limit)
+	} else if l.pool.Capacity() != limit {
+		l.pool.UpdateCapacity(limit)
+	}
+	return l.pool
+}
+
+// acquireApplySlot waits for a slot to apply a batch in, if the number of
+// batches applied concurrently is limited, and returns a function releasing
+// it. Batches which have to wait are counted in ApplyConcurrencyLimited, so
+// that streams bound by the limit rather than by their source can be told
+// apart.
+func (lrw *logicalReplicationWriterProcessor) acquireApplySlot(
+	ctx context.Context,
+) (release func(), _ error) {
+	if lrw.FlowCtx == nil { // Some unit tests don't set this.
+		return func() {}, nil
+	}
+	limit := applyConcurrencyLimit.Get(&lrw.FlowCtx.Cfg.Settings.SV)
+	if limit == 0 {
+		return func() {}, nil
+	}
+	pool := lrw.metrics.applyConcurrency.get(uint64(limit))
+	alloc, err := pool.TryAcquire(ctx, 1)
+	if errors.Is(err, quotapool.ErrNotEnoughQuota) {
+		lrw.metrics.ApplyConcurrencyLimited.Inc(1)
+		start := timeutil.Now()
+		alloc, err = pool.Acquire(ctx, 1)
+		lrw.metrics.ApplyConcurrencyWaitNanos.RecordValue(timeutil.Since(start).Nanoseconds())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return alloc.Release, nil
+}
+
+// handleBatch applies batch using bh once a slot is available to do so in.
+func (lrw *logicalReplicationWriterProcessor) handleBatch(
+	ctx context.Context, bh BatchHandler, batch []streampb.StreamEvent_KV,
+) (batchStats, error) {
+	release, err := lrw.acquireApplySlot(ctx)
+	if err != nil {
+		return batchStats{}, err
+	}
+	defer release()
+	return bh.HandleBatch(ctx, batch)
+}
@@ -0,0 +1,67 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package logical
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/sql/execinfra"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcquireApplySlot(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	st := cluster.MakeTestingClusterSettings()
+	lrw := &logicalReplicationWriterProcessor{metrics: MakeMetrics(10 * time.Minute).(*Metrics)}
+	lrw.FlowCtx = &execinfra.FlowCtx{Cfg: &execinfra.ServerConfig{Settings: st}}
+	limited := lrw.metrics.ApplyConcurrencyLimited
+
+	// Without a limit, batches never wait.
+	for i := 0; i < 3; i++ {
+		_, err := lrw.acquireApplySlot(ctx)
+		require.NoError(t, err)
+	}
+	require.Zero(t, limited.Count())
+
+	applyConcurrencyLimit.Override(ctx, &st.SV, 1)
+	release, err := lrw.acquireApplySlot(ctx)
+	require.NoError(t, err)
+	require.Zero(t, limited.Count())
+
+	// A second batch has to wait for the first to release its slot.
+	acquired := make(chan error)
+	go func() {
+		release, err := lrw.acquireApplySlot(ctx)
+		if err == nil {
+			release()
+		}
+		acquired <- err
+	}()
+	require.Eventually(t, func() bool {
+		return limited.Count() == 1
+	}, 10*time.Second, time.Millisecond)
+	release()
+	require.NoError(t, <-acquired)
+
+	// A canceled batch gives up on waiting.
+	release, err = lrw.acquireApplySlot(ctx)
+	require.NoError(t, err)
+	defer release()
+	canceledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = lrw.acquireApplySlot(canceledCtx)
+	require.Error(t, err)
+	require.Equal(t, int64(2), limited.Count())
+}
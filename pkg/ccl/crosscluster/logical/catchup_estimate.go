@@ -0,0 +1,109 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package logical
+
+import (
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/jobs"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/errors"
+)
+
+var catchupTargetLag = settings.RegisterDurationSetting(
+	settings.ApplicationLevel,
+	"logical_replication.consumer.catchup_target_lag",
+	"the replicated time lag at or below which a logical replication job is considered caught up",
+	time.Minute,
+	settings.NonNegativeDuration,
+)
+
+var catchupDeadline = settings.RegisterDurationSetting(
+	settings.ApplicationLevel,
+	"logical_replication.consumer.catchup_deadline",
+	"if set, a logical replication job is paused with an error if its projected time to catch up "+
+		"exceeds this duration; if 0, disabled",
+	0,
+	settings.NonNegativeDuration,
+)
+
+// catchupEstimateWindow is how far back the samples used to estimate the rate
+// at which a job is catching up go.
+const catchupEstimateWindow = 5 * time.Minute
+
+type catchupSample struct {
+	at         time.Time
+	replicated hlc.Timestamp
+}
+
+// catchupEstimator estimates how long a job will take to catch up, i.e. for
+// its replicated time lag to drop to catchupTargetLag, from the rate at which
+// the lag went down over the last catchupEstimateWindow. Replicated time has
+// to advance faster than the clock for the lag to go down at all.
+type catchupEstimator struct {
+	// samples are ordered by time, and the first one is the most recent one
+	// taken at or before the start of the window, if any.
+	samples []catchupSample
+}
+
+// record adds a sample of the replicated time as of now.
+func (c *catchupEstimator) record(now time.Time, replicated hlc.Timestamp) {
+	c.samples = append(c.samples, catchupSample{at: now, replicated: replicated})
+	windowStart := now.Add(-catchupEstimateWindow)
+	for len(c.samples) > 2 && !c.samples[1].at.After(windowStart) {
+		c.samples = c.samples[1:]
+	}
+}
+
+// estimate returns the projected time for the lag to drop to targetLag, which
+// is zero if it is already there. It returns false if the lag isn't going down,
+// or if there are not enough samples to tell.
+func (c *catchupEstimator) estimate(now time.Time, targetLag time.Duration) (time.Duration, bool) {
+	if len(c.samples) == 0 {
+		return 0, false
+	}
+	first, last := c.samples[0], c.samples[len(c.samples)-1]
+	excessLag := now.Sub(last.replicated.GoTime()) - targetLag
+	if excessLag <= 0 {
+		return 0, true
+	}
+	elapsed := last.at.Sub(first.at)
+	reduction := last.replicated.GoTime().Sub(first.replicated.GoTime()) - elapsed
+	if elapsed <= 0 || reduction <= 0 {
+		return 0, false
+	}
+	return time.Duration(float64(excessLag) * (float64(elapsed) / float64(reduction))), true
+}
+
+// full returns whether the samples span the whole window, i.e. whether the
+// estimate takes the full window into account.
+func (c *catchupEstimator) full(now time.Time) bool {
+	return len(c.samples) > 0 && now.Sub(c.samples[0].at) >= catchupEstimateWindow
+}
+
+// checkDeadline returns a permanent job error if, per the samples of a full
+// window, the job is not going to catch up within deadline. A deadline of zero
+// is never exceeded.
+func (c *catchupEstimator) checkDeadline(now time.Time, targetLag, deadline time.Duration) error {
+	if deadline == 0 || !c.full(now) {
+		return nil
+	}
+	eta, ok := c.estimate(now, targetLag)
+	if !ok {
+		return jobs.MarkAsPermanentJobError(errors.Newf(
+			"replicated time lag exceeds %s and did not go down over the last %s, so catch-up cannot complete within %s",
+			targetLag, catchupEstimateWindow, deadline))
+	}
+	if eta > deadline {
+		return jobs.MarkAsPermanentJobError(errors.Newf(
+			"projected catch-up time of %s exceeds %s", eta.Round(time.Second), deadline))
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package logical
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/jobs"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCatchupEstimator(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	start := time.Unix(1000000, 0)
+	at := func(d time.Duration) time.Time { return start.Add(d) }
+	replicated := func(d time.Duration) hlc.Timestamp {
+		return hlc.Timestamp{WallTime: start.Add(d).UnixNano()}
+	}
+
+	var c catchupEstimator
+	_, ok := c.estimate(at(0), time.Minute)
+	require.False(t, ok)
+
+	// Lagging an hour behind, a single sample doesn't tell the rate.
+	c.record(at(0), replicated(-time.Hour))
+	_, ok = c.estimate(at(0), time.Minute)
+	require.False(t, ok)
+
+	// Replicated time advancing at twice the speed of the clock reduces the lag
+	// by a minute per minute, so from 59 minutes it takes 58 minutes to get down
+	// to a minute.
+	c.record(at(time.Minute), replicated(-time.Hour+2*time.Minute))
+	eta, ok := c.estimate(at(time.Minute), time.Minute)
+	require.True(t, ok)
+	require.Equal(t, 58*time.Minute, eta)
+
+	// The deadline is only enforced once the window is full.
+	require.NoError(t, c.checkDeadline(at(time.Minute), time.Minute, 10*time.Minute))
+	c.record(at(catchupEstimateWindow), replicated(-time.Hour+2*catchupEstimateWindow))
+	require.True(t, c.full(at(catchupEstimateWindow)))
+	require.NoError(t, c.checkDeadline(at(catchupEstimateWindow), time.Minute, 0))
+	require.NoError(t, c.checkDeadline(at(catchupEstimateWindow), time.Minute, time.Hour))
+	err := c.checkDeadline(at(catchupEstimateWindow), time.Minute, 10*time.Minute)
+	require.True(t, jobs.IsPermanentJobError(err))
+	require.ErrorContains(t, err, "projected catch-up time of 54m0s exceeds 10m0s")
+
+	// Samples older than the window are dropped as new ones come in, so once
+	// replicated time only advances with the clock, the lag isn't going down.
+	for i := 1; i <= 10; i++ {
+		d := catchupEstimateWindow + time.Duration(i)*time.Minute
+		c.record(at(d), replicated(d-time.Hour+catchupEstimateWindow))
+	}
+	now := at(catchupEstimateWindow + 10*time.Minute)
+	_, ok = c.estimate(now, time.Minute)
+	require.False(t, ok)
+	err = c.checkDeadline(now, time.Minute, 10*time.Minute)
+	require.True(t, jobs.IsPermanentJobError(err))
+	require.ErrorContains(t, err, "did not go down")
+
+	// Once the lag is within the target, the job has caught up.
+	eta, ok = c.estimate(now, time.Hour)
+	require.True(t, ok)
+	require.Zero(t, eta)
+	require.NoError(t, c.checkDeadline(now, time.Hour, 10*time.Minute))
+}
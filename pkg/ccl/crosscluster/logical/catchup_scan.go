@@ -0,0 +1,103 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package logical
+
+import (
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/metric"
+	"github.com/cockroachdb/cockroach/pkg/util/span"
+)
+
+// catchupScanTracker estimates how many bytes are left to be received before
+// a processor's subscription has caught up, i.e. until every span assigned to
+// the processor is resolved at or above the time at which the subscription was
+// started.
+//
+// The source does not tell us how much data it has yet to send, so the
+// estimate extrapolates from the bytes received so far and the fraction of the
+// assigned spans which have caught up: if half the spans have caught up after
+// receiving 1GiB, we assume roughly another 1GiB remains. The spans of a
+// partition generally correspond to source ranges, so weighting them equally
+// is a reasonable approximation of their relative sizes.
+type catchupScanTracker struct {
+	// target is the timestamp every span must be resolved at for the catch-up
+	// scan to be considered complete.
+	target hlc.Timestamp
+	spans  []roachpb.Span
+
+	receivedBytes int64
+	// reported is this tracker's current contribution to gauge.
+	reported int64
+	done     bool
+
+	gauge *metric.Gauge
+}
+
+// start begins tracking a catch-up scan of spans up to target.
+func (c *catchupScanTracker) start(
+	target hlc.Timestamp, spans []roachpb.Span, gauge *metric.Gauge, started *metric.Counter,
+) {
+	*c = catchupScanTracker{target: target, spans: spans, gauge: gauge}
+	started.Inc(1)
+}
+
+// recordReceived notes bytes received from the source while catching up.
+func (c *catchupScanTracker) recordReceived(bytes int64) {
+	if c.done || c.gauge == nil {
+		return
+	}
+	c.receivedBytes += bytes
+}
+
+// update recomputes the estimate from the progress recorded in frontier,
+// zeroing it once all spans have caught up.
+func (c *catchupScanTracker) update(frontier span.Frontier) {
+	if c.done || c.gauge == nil {
+		return
+	}
+	if !frontier.Frontier().Less(c.target) {
+		c.done = true
+		c.report(0)
+		return
+	}
+	var caughtUp int
+	for _, sp := range c.spans {
+		spanCaughtUp := true
+		frontier.SpanEntries(sp, func(_ roachpb.Span, ts hlc.Timestamp) span.OpResult {
+			if ts.Less(c.target) {
+				spanCaughtUp = false
+				return span.StopMatch
+			}
+			return span.ContinueMatch
+		})
+		if spanCaughtUp {
+			caughtUp++
+		}
+	}
+	// Until at least one span has caught up we have nothing to extrapolate
+	// from, so keep reporting the previous estimate.
+	if caughtUp == 0 {
+		return
+	}
+	c.report(c.receivedBytes * int64(len(c.spans)-caughtUp) / int64(caughtUp))
+}
+
+// close removes this tracker's contribution from the gauge.
+func (c *catchupScanTracker) close() {
+	if c.gauge == nil {
+		return
+	}
+	c.report(0)
+}
+
+func (c *catchupScanTracker) report(remaining int64) {
+	c.gauge.Inc(remaining - c.reported)
+	c.reported = remaining
+}
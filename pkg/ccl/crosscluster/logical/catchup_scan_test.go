@@ -0,0 +1,80 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package logical
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/metric"
+	"github.com/cockroachdb/cockroach/pkg/util/span"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCatchupScanTracker(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	sp := func(start, end string) roachpb.Span {
+		return roachpb.Span{Key: roachpb.Key(start), EndKey: roachpb.Key(end)}
+	}
+	spans := []roachpb.Span{sp("a", "b"), sp("b", "c"), sp("c", "d"), sp("d", "e")}
+	frontier, err := span.MakeFrontier(spans...)
+	require.NoError(t, err)
+	defer frontier.Release()
+
+	forward := func(s roachpb.Span, ts hlc.Timestamp) {
+		_, err := frontier.Forward(s, ts)
+		require.NoError(t, err)
+	}
+
+	gauge := metric.NewGauge(metric.Metadata{})
+	started := metric.NewCounter(metric.Metadata{})
+	target := hlc.Timestamp{WallTime: 10}
+
+	var c catchupScanTracker
+	c.start(target, spans, gauge, started)
+	require.Equal(t, int64(1), started.Count())
+
+	// Nothing has caught up yet, so there is nothing to extrapolate from.
+	c.recordReceived(100)
+	c.update(frontier)
+	require.Equal(t, int64(0), gauge.Value())
+
+	// One of four spans caught up after 100 bytes.
+	forward(spans[0], target)
+	c.update(frontier)
+	require.Equal(t, int64(300), gauge.Value())
+
+	// Progress below the target does not count.
+	forward(spans[1], hlc.Timestamp{WallTime: 5})
+	c.update(frontier)
+	require.Equal(t, int64(300), gauge.Value())
+
+	// Two of four spans caught up after 200 bytes.
+	c.recordReceived(100)
+	forward(spans[1], target)
+	c.update(frontier)
+	require.Equal(t, int64(200), gauge.Value())
+
+	// The gauge is shared between processors, and each only accounts for its
+	// own estimate.
+	gauge.Inc(1000)
+	forward(spans[2], target)
+	forward(spans[3], target)
+	c.update(frontier)
+	require.Equal(t, int64(1000), gauge.Value())
+
+	// Once caught up, further bytes are not part of the catch-up scan.
+	c.recordReceived(100)
+	c.update(frontier)
+	c.close()
+	require.Equal(t, int64(1000), gauge.Value())
+}
@@ -0,0 +1,69 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package logical
+
+import (
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+)
+
+// ingestRateWindow is the sliding window over which the ingest throughput is
+// computed, and ingestRateBuckets the number of buckets it is tracked in, so
+// the window slides by ingestRateWindow/ingestRateBuckets at a time.
+const (
+	ingestRateWindow  = 30 * time.Second
+	ingestRateBuckets = 6
+)
+
+type ingestRateBucket struct {
+	// start is the start of the bucket, a multiple of the bucket width.
+	start        time.Time
+	events, size int64
+}
+
+// ingestRate tracks the events, and their logical bytes, applied by all the
+// writer processors on a node over the last ingestRateWindow, from which
+// IngestRowsPerSec and IngestBytesPerSec are computed whenever they are read.
+type ingestRate struct {
+	syncutil.Mutex
+	buckets [ingestRateBuckets]ingestRateBucket
+}
+
+// record records events of size bytes applied at now.
+func (r *ingestRate) record(now time.Time, events, size int64) {
+	width := ingestRateWindow / ingestRateBuckets
+	start := now.Truncate(width)
+	r.Lock()
+	defer r.Unlock()
+	b := &r.buckets[(start.UnixNano()/int64(width))%ingestRateBuckets]
+	if !b.start.Equal(start) {
+		*b = ingestRateBucket{start: start}
+	}
+	b.events += events
+	b.size += size
+}
+
+// rates returns the events and bytes applied per second over the window
+// ending at now. The current bucket is only partially through, so the rates
+// dip by up to one bucket's share right after the window slides.
+func (r *ingestRate) rates(now time.Time) (eventsPerSec, bytesPerSec int64) {
+	windowStart := now.Add(-ingestRateWindow)
+	r.Lock()
+	defer r.Unlock()
+	var events, size int64
+	for _, b := range r.buckets {
+		if b.start.After(windowStart) && !b.start.After(now) {
+			events += b.events
+			size += b.size
+		}
+	}
+	secs := int64(ingestRateWindow / time.Second)
+	return events / secs, size / secs
+}
@@ -0,0 +1,48 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package logical
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIngestRate(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	start := time.Unix(1000000, 0)
+	at := func(d time.Duration) time.Time { return start.Add(d) }
+
+	var r ingestRate
+	requireRates := func(now time.Time, expEvents, expBytes int64) {
+		t.Helper()
+		events, size := r.rates(now)
+		require.Equal(t, expEvents, events)
+		require.Equal(t, expBytes, size)
+	}
+	requireRates(at(0), 0, 0)
+
+	// The rates are averaged over the whole window.
+	r.record(at(0), 300, 3000)
+	requireRates(at(0), 10, 100)
+	r.record(at(5*time.Second), 300, 3000)
+	requireRates(at(5*time.Second), 20, 200)
+
+	// Buckets drop out of the window as it slides, down to zero once nothing
+	// is recorded anymore.
+	requireRates(at(ingestRateWindow), 10, 100)
+	requireRates(at(ingestRateWindow+5*time.Second), 0, 0)
+
+	// A bucket reused for a later slot of the window starts from scratch.
+	r.record(at(ingestRateWindow), 60, 600)
+	requireRates(at(ingestRateWindow), 12, 120)
+}
@@ -69,6 +69,27 @@ var (
 	)
 )
 
+// heartbeatAgeSampleInterval is how often the age of each job's last heartbeat
+// is sampled for the last_heartbeat_age_seconds gauge.
+const heartbeatAgeSampleInterval = 10 * time.Second
+
+// heartbeatRecordingClient is a streamclient.Client which calls onHeartbeat
+// whenever a heartbeat is acknowledged by the source.
+type heartbeatRecordingClient struct {
+	streamclient.Client
+	onHeartbeat func()
+}
+
+func (c heartbeatRecordingClient) Heartbeat(
+	ctx context.Context, streamID streampb.StreamID, consumed hlc.Timestamp,
+) (streampb.StreamReplicationStatus, error) {
+	status, err := c.Client.Heartbeat(ctx, streamID, consumed)
+	if err == nil {
+		c.onHeartbeat()
+	}
+	return status, err
+}
+
 type logicalReplicationResumer struct {
 	job *jobs.Job
 }
@@ -121,6 +142,7 @@ func (r *logicalReplicationResumer) ingest(
 		streamID              = payload.StreamID
 		jobID                 = r.job.ID()
 		replicatedTimeAtStart = progress.ReplicatedTime
+		statsAtStart          = progress.Stats
 	)
 
 	client, err := streamclient.GetFirstActiveClient(ctx,
@@ -191,6 +213,9 @@ func (r *logicalReplicationResumer) ingest(
 		func() time.Duration { return crosscluster.LogicalReplanFrequency.Get(execCfg.SV()) },
 	)
 	metrics := execCfg.JobRegistry.MetricsStruct().JobSpecificMetrics[jobspb.TypeLogicalReplication].(*Metrics)
+	defer metrics.updateFrontierLagSpread(jobID, 0)
+	defer metrics.updateCatchupEstimate(jobID, 0, false)
+	defer metrics.updateTablesReplicating(jobID, 0)
 
 	// Store only the original plan diagram
 	jobsprofiler.StorePlanDiagram(ctx,
@@ -199,7 +224,15 @@ func (r *logicalReplicationResumer) ingest(
 		execCfg.InternalDB,
 		jobID)
 
-	heartbeatSender := streamclient.NewHeartbeatSender(ctx, client, streampb.StreamID(streamID),
+	// The job counts as having heartbeated when it starts, so that a job which
+	// never manages to heartbeat shows up in the heartbeat age.
+	metrics.recordHeartbeat(jobID, timeutil.Now())
+	defer metrics.recordHeartbeat(jobID, time.Time{})
+	heartbeatClient := heartbeatRecordingClient{
+		Client:      client,
+		onHeartbeat: func() { metrics.recordHeartbeat(jobID, timeutil.Now()) },
+	}
+	heartbeatSender := streamclient.NewHeartbeatSender(ctx, heartbeatClient, streampb.StreamID(streamID),
 		func() time.Duration {
 			return heartbeatFrequency.Get(&execCfg.Settings.SV)
 		})
@@ -216,12 +249,15 @@ func (r *logicalReplicationResumer) ingest(
 		}
 		rh := rowHandler{
 			replicatedTimeAtStart: replicatedTimeAtStart,
+			stats:                 statsAtStart,
 			frontier:              frontier,
 			metrics:               metrics,
 			settings:              &execCfg.Settings.SV,
 			job:                   r.job,
 			frontierUpdates:       heartbeatSender.FrontierUpdates,
+			stuckPartitions:       stuckPartitionTracker{gauge: metrics.StuckPartitions},
 		}
+		defer rh.stuckPartitions.close()
 		rowResultWriter := sql.NewCallbackResultWriter(rh.handleRow)
 		distSQLReceiver := sql.MakeDistSQLReceiver(
 			ctx,
@@ -254,13 +290,54 @@ func (r *logicalReplicationResumer) ingest(
 		return err
 	}
 
-	err = ctxgroup.GoAndWait(ctx, execPlan, replanner, startHeartbeat)
+	// The heartbeat age keeps growing between heartbeats, so sample it
+	// periodically rather than only when a heartbeat is received.
+	sampleHeartbeatAge := func(ctx context.Context) error {
+		timer := timeutil.NewTimer()
+		defer timer.Stop()
+		for {
+			timer.Reset(heartbeatAgeSampleInterval)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-timer.C:
+				timer.Read = true
+				metrics.sampleHeartbeatAge(timeutil.Now())
+			}
+		}
+	}
+
+	err = ctxgroup.GoAndWait(ctx, execPlan, replanner, startHeartbeat, sampleHeartbeatAge)
 	if errors.Is(err, sql.ErrPlanChanged) {
 		metrics.ReplanCount.Inc(1)
 	}
 	return err
 }
 
+// observeSourceDescriptors records the versions of the source table
+// descriptors a plan is being generated with, returning the number of tables
+// whose descriptor changed since the previous plan was generated.
+//
+// The source does not notify the stream of schema changes, but every plan,
+// including those periodically generated by the replanner, fetches the
+// source descriptors as of the replicated time, so a new version shows up
+// once the stream has replicated up to the schema change. Every new version
+// is counted, whether or not it changes the table's columns.
+func (p *logicalReplicationPlanner) observeSourceDescriptors(
+	descs map[int32]descpb.TableDescriptor,
+) int64 {
+	var changed int64
+	versions := make(map[int32]descpb.DescriptorVersion, len(descs))
+	for id, desc := range descs {
+		versions[id] = desc.Version
+		if prev, ok := p.srcDescVersions[id]; ok && prev != desc.Version {
+			changed++
+		}
+	}
+	p.srcDescVersions = versions
+	return changed
+}
+
 func getNodes(plan *sql.PhysicalPlan) (src, dst map[string]struct{}, nodeCount int) {
 	dst = make(map[string]struct{})
 	src = make(map[string]struct{})
@@ -291,6 +368,10 @@ type logicalReplicationPlanner struct {
 	job        *jobs.Job
 	jobExecCtx sql.JobExecContext
 	client     streamclient.Client
+
+	// srcDescVersions are the versions of the source table descriptors the
+	// last plan was generated with; see observeSourceDescriptors.
+	srcDescVersions map[int32]descpb.DescriptorVersion
 }
 
 type logicalReplicationPlanInfo struct {
@@ -353,6 +434,8 @@ func (p *logicalReplicationPlanner) generatePlanImpl(
 	}
 	info.sourceSpans = plan.SourceSpans
 	info.streamAddress = plan.Topology.StreamAddresses()
+	metrics := execCfg.JobRegistry.MetricsStruct().JobSpecificMetrics[jobspb.TypeLogicalReplication].(*Metrics)
+	metrics.SourceSchemaChanges.Inc(p.observeSourceDescriptors(plan.DescriptorMap))
 
 	var defaultFnOID oid.Oid
 	if defaultFnID := payload.DefaultConflictResolution.FunctionId; defaultFnID != 0 {
@@ -403,6 +486,9 @@ func (p *logicalReplicationPlanner) generatePlanImpl(
 	}); err != nil {
 		return nil, nil, info, err
 	}
+	// The set of tables is re-read from the job whenever it is replanned, so
+	// this picks up tables added to or removed from the stream.
+	metrics.updateTablesReplicating(p.job.ID(), len(tableMetadataByDestID))
 
 	planCtx, nodes, err := dsp.SetupAllNodesPlanning(ctx, evalCtx, execCfg)
 	if err != nil {
@@ -471,13 +557,18 @@ func (p *logicalReplicationPlanner) generatePlanImpl(
 // replication writer processors.
 type rowHandler struct {
 	replicatedTimeAtStart hlc.Timestamp
-	frontier              span.Frontier
-	metrics               *Metrics
-	settings              *settings.Values
-	job                   *jobs.Job
-	frontierUpdates       chan hlc.Timestamp
+	// stats are the job-wide totals of the events processed by all writer
+	// processors, including those recorded in the job's progress at start.
+	stats           jobspb.LogicalReplicationStats
+	frontier        span.Frontier
+	metrics         *Metrics
+	settings        *settings.Values
+	job             *jobs.Job
+	frontierUpdates chan hlc.Timestamp
 
 	lastPartitionUpdate time.Time
+	catchup             catchupEstimator
+	stuckPartitions     stuckPartitionTracker
 }
 
 func (rh *rowHandler) handleRow(ctx context.Context, row tree.Datums) error {
@@ -496,6 +587,10 @@ func (rh *rowHandler) handleRow(ctx context.Context, row tree.Datums) error {
 			return err
 		}
 	}
+	processorStats := resolvedSpans.Stats.LogicalReplication
+	rh.stats.EventsIngested += processorStats.EventsIngested
+	rh.stats.EventsDLQed += processorStats.EventsDLQed
+	rh.stats.ReceivedBytes += processorStats.ReceivedBytes
 
 	updateFreq := jobCheckpointFrequency.Get(rh.settings)
 	if updateFreq == 0 || timeutil.Since(rh.lastPartitionUpdate) < updateFreq {
@@ -503,13 +598,35 @@ func (rh *rowHandler) handleRow(ctx context.Context, row tree.Datums) error {
 	}
 
 	frontierResolvedSpans := make([]jobspb.ResolvedSpan, 0)
+	var mostAdvanced hlc.Timestamp
 	rh.frontier.Entries(func(sp roachpb.Span, ts hlc.Timestamp) (done span.OpResult) {
 		frontierResolvedSpans = append(frontierResolvedSpans, jobspb.ResolvedSpan{Span: sp, Timestamp: ts})
+		mostAdvanced.Forward(ts)
 		return span.ContinueMatch
 	})
 	replicatedTime := rh.frontier.Frontier()
+	// The spans of the frontier are those of the partitions assigned to the
+	// writer processors, so the spread between the most and least advanced
+	// span shows whether a single lagging partition holds back replicated
+	// time, while the overall lag may be caused by all of them.
+	if !replicatedTime.IsEmpty() {
+		rh.metrics.updateFrontierLagSpread(rh.job.ID(), mostAdvanced.GoTime().Sub(replicatedTime.GoTime()))
+	}
 
 	rh.lastPartitionUpdate = timeutil.Now()
+	rh.stuckPartitions.update(ctx, rh.lastPartitionUpdate, stuckPartitionThreshold.Get(rh.settings),
+		frontierResolvedSpans)
+	if !replicatedTime.IsEmpty() {
+		targetLag := catchupTargetLag.Get(rh.settings)
+		rh.catchup.record(rh.lastPartitionUpdate, replicatedTime)
+		eta, ok := rh.catchup.estimate(rh.lastPartitionUpdate, targetLag)
+		rh.metrics.updateCatchupEstimate(rh.job.ID(), eta, ok)
+		if err := rh.catchup.checkDeadline(
+			rh.lastPartitionUpdate, targetLag, catchupDeadline.Get(rh.settings),
+		); err != nil {
+			return err
+		}
+	}
 	log.VInfof(ctx, 2, "persisting replicated time of %s", replicatedTime.GoTime())
 	if err := rh.job.NoTxn().Update(ctx,
 		func(txn isql.Txn, md jobs.JobMetadata, ju *jobs.JobUpdater) error {
@@ -519,6 +636,7 @@ func (rh *rowHandler) handleRow(ctx context.Context, row tree.Datums) error {
 			progress := md.Progress
 			prog := progress.Details.(*jobspb.Progress_LogicalReplication).LogicalReplication
 			prog.Checkpoint.ResolvedSpans = frontierResolvedSpans
+			prog.Stats = rh.stats
 			if rh.replicatedTimeAtStart.Less(replicatedTime) {
 				prog.ReplicatedTime = replicatedTime
 				// The HighWater is for informational purposes
@@ -527,7 +645,8 @@ func (rh *rowHandler) handleRow(ctx context.Context, row tree.Datums) error {
 					HighWater: &replicatedTime,
 				}
 			}
-			progress.RunningStatus = fmt.Sprintf("logical replication running: %s", replicatedTime.GoTime())
+			progress.RunningStatus = fmt.Sprintf("logical replication running: %s (%d events ingested, %d events sent to the DLQ)",
+				replicatedTime.GoTime(), rh.stats.EventsIngested, rh.stats.EventsDLQed)
 			ju.UpdateProgress(progress)
 			if md.RunStats != nil && md.RunStats.NumRuns > 1 {
 				ju.UpdateRunStats(1, md.RunStats.LastRun)
@@ -699,6 +818,7 @@ func init() {
 		},
 		jobs.WithJobMetrics(m),
 		jobs.WithResolvedMetric(m.(*Metrics).ReplicatedTimeSeconds),
+		jobs.WithResolvedLagMetric(m.(*Metrics).ReplicatedTimeLagSeconds),
 		jobs.UsesTenantCostControl,
 	)
 }
@@ -51,6 +51,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/mon"
 	"github.com/cockroachdb/cockroach/pkg/util/randutil"
 	"github.com/cockroachdb/cockroach/pkg/util/retry"
 	"github.com/cockroachdb/cockroach/pkg/util/span"
@@ -311,6 +312,14 @@ func testLogicalStreamIngestionJobBasic(t *testing.T, mode string) {
 	dbA.CheckQueryResults(t, "SELECT * from a.tab", expectedRows)
 	dbB.CheckQueryResults(t, "SELECT * from b.tab", expectedRows)
 
+	// The job-wide stats are persisted along with the replicated time.
+	for _, jobID := range []jobspb.JobID{jobAID, jobBID} {
+		stats := jobutils.GetJobProgress(t, dbA, jobID).GetLogicalReplication().Stats
+		require.Positive(t, stats.EventsIngested)
+		require.Zero(t, stats.EventsDLQed)
+		require.Positive(t, stats.ReceivedBytes)
+	}
+
 	// Verify that we didn't have the data looping problem. These
 	// expecations are for how many operations happend on the
 	// a-side.
@@ -1494,6 +1503,7 @@ func TestFlushErrorHandling(t *testing.T) {
 		metrics:      MakeMetrics(0).(*Metrics),
 		getBatchSize: func() int { return 1 },
 		dlqClient:    &dlq,
+		memAcc:       *mon.NewStandaloneUnlimitedAccount(),
 	}
 	lrw.purgatory.flush = lrw.flushBuffer
 	lrw.purgatory.bytesGauge = lrw.metrics.RetryQueueBytes
@@ -1692,6 +1702,27 @@ func TestLogicalReplicationPlanner(t *testing.T) {
 	})
 }
 
+func TestObserveSourceDescriptors(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	descs := func(versions ...descpb.DescriptorVersion) map[int32]descpb.TableDescriptor {
+		m := make(map[int32]descpb.TableDescriptor)
+		for i, v := range versions {
+			m[int32(100+i)] = descpb.TableDescriptor{ID: descpb.ID(100 + i), Version: v}
+		}
+		return m
+	}
+
+	var p logicalReplicationPlanner
+	// The initial plan has nothing to compare against.
+	require.Zero(t, p.observeSourceDescriptors(descs(1, 1)))
+	require.Zero(t, p.observeSourceDescriptors(descs(1, 1)))
+	require.Equal(t, int64(1), p.observeSourceDescriptors(descs(1, 2)))
+	require.Equal(t, int64(2), p.observeSourceDescriptors(descs(3, 4)))
+	// A table added to the stream is not a schema change.
+	require.Zero(t, p.observeSourceDescriptors(descs(3, 4, 1)))
+}
+
 func TestShowLogicalReplicationJobs(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	skip.UnderDeadlock(t)
@@ -1751,6 +1782,8 @@ func TestShowLogicalReplicationJobs(t *testing.T) {
 		replicationStartTime   time.Time
 		conflictResolutionType string
 		description            string
+		eventsIngested         int64
+		eventsDLQed            int64
 	)
 
 	showRows := dbA.Query(t, "SELECT * FROM [SHOW LOGICAL REPLICATION JOBS] ORDER BY job_id")
@@ -1793,7 +1826,9 @@ func TestShowLogicalReplicationJobs(t *testing.T) {
 			&replicatedTime,
 			&replicationStartTime,
 			&conflictResolutionType,
-			&description)
+			&description,
+			&eventsIngested,
+			&eventsDLQed)
 		require.NoError(t, err)
 
 		expectedJobID := jobIDs[rowIdx]
@@ -1815,6 +1850,11 @@ func TestShowLogicalReplicationJobs(t *testing.T) {
 
 		require.Equal(t, expectedJobDescription, description)
 
+		// The job-wide counts can only have grown since the progress was read.
+		stats := jobutils.GetJobProgress(t, dbA, expectedJobID).GetLogicalReplication().Stats
+		require.LessOrEqual(t, eventsIngested, stats.EventsIngested)
+		require.LessOrEqual(t, eventsDLQed, stats.EventsDLQed)
+
 		rowIdx++
 	}
 	require.Equal(t, 2, rowIdx)
@@ -12,6 +12,8 @@ import (
 	"context"
 	"fmt"
 	"slices"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/cockroachdb/cockroach/pkg/ccl/changefeedccl/cdcevent"
@@ -19,6 +21,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/ccl/crosscluster/streamclient"
 	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
 	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/kvserverbase"
 	"github.com/cockroachdb/cockroach/pkg/repstream/streampb"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/settings"
@@ -35,16 +38,23 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/sessiondata"
 	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/util/admission/admissionpb"
 	"github.com/cockroachdb/cockroach/pkg/util/ctxgroup"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/cockroach/pkg/util/log/logcrash"
 	"github.com/cockroachdb/cockroach/pkg/util/metamorphic"
+	"github.com/cockroachdb/cockroach/pkg/util/metric"
+	"github.com/cockroachdb/cockroach/pkg/util/mon"
 	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
 	"github.com/cockroachdb/cockroach/pkg/util/span"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
 	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
 	"github.com/cockroachdb/cockroach/pkg/util/tracing"
+	"github.com/cockroachdb/cockroach/pkg/util/tracing/tracingpb"
 	"github.com/cockroachdb/errors"
 	"github.com/cockroachdb/logtags"
+	pbtypes "github.com/gogo/protobuf/types"
 )
 
 var logicalReplicationWriterResultType = []*types.T{
@@ -59,6 +69,27 @@ var flushBatchSize = settings.RegisterIntSetting(
 	settings.NonNegativeInt,
 )
 
+// applyProposalQuotaMaxWaiters is left at zero by default: the writes failing
+// are retried through the retry queue, which sends them to the DLQ once they
+// reach its age limit, so capping the waiters trades an apply which is slowed
+// down by a destination range's slow follower for one which may DLQ events.
+var applyProposalQuotaMaxWaiters = settings.RegisterIntSetting(
+	settings.ApplicationLevel,
+	"logical_replication.consumer.apply_proposal_quota_max_waiters",
+	"if non-zero, the maximum number of proposals the writes of an applied batch wait behind "+
+		"for the proposal quota of a destination range, beyond which they fail and their events "+
+		"are retried later; the SQL writer only picks up changes when its processors restart",
+	0,
+	settings.NonNegativeInt,
+)
+
+var coalesceEventsEnabled = settings.RegisterBoolSetting(
+	settings.ApplicationLevel,
+	"logical_replication.consumer.coalesce_events.enabled",
+	"if enabled, row update events which are overwritten by a later event to the same key in the same batch are not applied",
+	false,
+)
+
 // logicalReplicationWriterProcessor consumes a cross-cluster replication stream
 // by decoding kvs in it to logical changes and applying them by executing DMLs.
 type logicalReplicationWriterProcessor struct {
@@ -100,6 +131,32 @@ type logicalReplicationWriterProcessor struct {
 	dlqClient DeadLetterQueueClient
 
 	purgatory purgatory
+
+	// memAcc accounts for the events held by the apply path, i.e. the buffers
+	// received from the stream until they have been flushed, and the batches
+	// retried from the retry queue while they are being flushed. Its monitor
+	// reports to ApplyMemoryBytes.
+	memAcc mon.BoundAccount
+
+	// catchup estimates the data remaining to be received before the
+	// subscription has caught up.
+	catchup catchupScanTracker
+
+	// tableNames are the qualified names of the destination tables of the
+	// processor's source tables, by source table ID, as listed in pausedTables.
+	tableNames map[descpb.ID]string
+	// pausedTables are the source tables which were paused when last checked.
+	// See splitPaused.
+	pausedTables map[descpb.ID]bool
+	// pausedDecoder decodes the events of paused tables sent to the DLQ.
+	pausedDecoder cdcevent.Decoder
+
+	// stats accumulate the events processed since the last checkpoint was
+	// emitted, for the job coordinator to aggregate across all processors.
+	stats struct {
+		syncutil.Mutex
+		jobspb.LogicalReplicationStats
+	}
 }
 
 var (
@@ -140,12 +197,13 @@ func newLogicalReplicationWriterProcessor(
 			tableID:  descpb.ID(dstTableID),
 		}
 	}
+	metrics := flowCtx.Cfg.JobRegistry.MetricsStruct().JobSpecificMetrics[jobspb.TypeLogicalReplication].(*Metrics)
 	bhPool := make([]BatchHandler, maxWriterWorkers)
 	for i := range bhPool {
 		var rp RowProcessor
 		var err error
 		if spec.Mode == jobspb.LogicalReplicationDetails_Immediate {
-			rp, err = newKVRowProcessor(ctx, flowCtx.Cfg, flowCtx.EvalCtx, procConfigByDestTableID)
+			rp, err = newKVRowProcessor(ctx, flowCtx.Cfg, flowCtx.EvalCtx, procConfigByDestTableID, metrics)
 			if err != nil {
 				return nil, err
 			}
@@ -156,6 +214,7 @@ func newLogicalReplicationWriterProcessor(
 				// Initialize the executor with a fresh session data - this will
 				// avoid creating a new copy on each executor usage.
 				flowCtx.Cfg.DB.Executor(isql.WithSessionData(sql.NewInternalSessionData(ctx, flowCtx.Cfg.Settings, "" /* opName */))),
+				metrics,
 			)
 			if err != nil {
 				return nil, err
@@ -173,9 +232,22 @@ func newLogicalReplicationWriterProcessor(
 			rp:       rp,
 			settings: flowCtx.Cfg.Settings,
 			sd:       sql.NewInternalSessionData(ctx, flowCtx.Cfg.Settings, "" /* opName */),
+			metrics:  metrics,
+
+			metricsLabel: spec.MetricsLabel,
 		}
 	}
 
+	tableNames := make(map[descpb.ID]string, len(destTableBySrcID))
+	for srcID, md := range destTableBySrcID {
+		tableNames[srcID] = qualifiedTableName(md)
+	}
+	metrics.pausedTables.watch(&flowCtx.Cfg.Settings.SV)
+	pausedDecoder, err := newPausedEventDecoder(ctx, flowCtx.Cfg.Settings, procConfigByDestTableID)
+	if err != nil {
+		return nil, err
+	}
+
 	dlqDbExec := flowCtx.Cfg.DB.Executor(isql.WithSessionData(sql.NewInternalSessionData(ctx, flowCtx.Cfg.Settings, "" /* opName */)))
 
 	var numTablesWithSecondaryIndexes int
@@ -228,11 +300,19 @@ func newLogicalReplicationWriterProcessor(
 			StreamID:    streampb.StreamID(spec.StreamID),
 			ProcessorID: processorID,
 		},
-		dlqClient: InitDeadLetterQueueClient(dlqDbExec, destTableBySrcID),
-		metrics:   flowCtx.Cfg.JobRegistry.MetricsStruct().JobSpecificMetrics[jobspb.TypeLogicalReplication].(*Metrics),
+		dlqClient:     InitDeadLetterQueueClient(dlqDbExec, destTableBySrcID),
+		metrics:       metrics,
+		tableNames:    tableNames,
+		pausedDecoder: pausedDecoder,
+	}
+	retryQueueMaxAge := func() time.Duration {
+		maxAge := retryQueueAgeLimit.Get(&flowCtx.Cfg.Settings.SV)
+		lrw.metrics.RetryQueueMaxAgeSeconds.Update(int64(maxAge.Seconds()))
+		return maxAge
 	}
+	retryQueueMaxAge()
 	lrw.purgatory = purgatory{
-		deadline:    func() time.Duration { return retryQueueAgeLimit.Get(&flowCtx.Cfg.Settings.SV) },
+		deadline:    retryQueueMaxAge,
 		delay:       func() time.Duration { return retryQueueBackoff.Get(&flowCtx.Cfg.Settings.SV) },
 		byteLimit:   func() int64 { return retryQueueSizeLimit.Get(&flowCtx.Cfg.Settings.SV) },
 		flush:       lrw.flushBuffer,
@@ -242,7 +322,11 @@ func newLogicalReplicationWriterProcessor(
 		debug:       &lrw.debug,
 	}
 
-	if err := lrw.Init(ctx, lrw, post, logicalReplicationWriterResultType, flowCtx, processorID, nil, /* memMonitor */
+	memMonitor := execinfra.NewMonitor(ctx, flowCtx.Mon, "logical-replication-writer-mem")
+	memMonitor.SetMetrics(lrw.metrics.ApplyMemoryBytes, nil /* maxHist */)
+	lrw.memAcc = memMonitor.MakeBoundAccount()
+
+	if err := lrw.Init(ctx, lrw, post, logicalReplicationWriterResultType, flowCtx, processorID, memMonitor,
 		execinfra.ProcStateOpts{
 			InputsToDrain: []execinfra.RowSource{},
 			TrailingMetaCallback: func() []execinfrapb.ProducerMetadata {
@@ -320,6 +404,15 @@ func (lrw *logicalReplicationWriterProcessor) Start(ctx context.Context) {
 		return
 	}
 
+	// The subscription has to catch up from the frontier to the present before
+	// we are applying changes as they happen on the source.
+	lrw.catchup.start(
+		hlc.Timestamp{WallTime: timeutil.Now().UnixNano()},
+		lrw.spec.PartitionSpec.Spans,
+		lrw.metrics.CatchupScanRemainingBytes,
+		lrw.metrics.CatchupScansStarted,
+	)
+
 	// We use a different context for the subscription here so
 	// that we can explicitly cancel it.
 	var subscriptionCtx context.Context
@@ -327,10 +420,7 @@ func (lrw *logicalReplicationWriterProcessor) Start(ctx context.Context) {
 	lrw.workerGroup = ctxgroup.WithContext(lrw.Ctx())
 	lrw.subscription = sub
 	lrw.workerGroup.GoCtx(func(_ context.Context) error {
-		if err := sub.Subscribe(subscriptionCtx); err != nil {
-			log.Infof(lrw.Ctx(), "subscription completed. Error: %s", err)
-			lrw.sendError(errors.Wrap(err, "subscription"))
-		}
+		lrw.runSubscription(subscriptionCtx, sub)
 		return nil
 	})
 	lrw.workerGroup.GoCtx(func(ctx context.Context) error {
@@ -343,6 +433,25 @@ func (lrw *logicalReplicationWriterProcessor) Start(ctx context.Context) {
 	})
 }
 
+// runSubscription runs the subscription to the source until it completes,
+// sending any error it fails with to the processor.
+func (lrw *logicalReplicationWriterProcessor) runSubscription(
+	ctx context.Context, sub streamclient.Subscription,
+) {
+	lrw.metrics.ActivePartitions.Inc(1)
+	defer lrw.metrics.ActivePartitions.Dec(1)
+	if err := sub.Subscribe(ctx); err != nil {
+		// Unless it was cancelled by the processor closing, the failed
+		// subscription is restarted from the replicated progress by the job's
+		// retry loop.
+		if ctx.Err() == nil {
+			lrw.metrics.SourceRangefeedRestarts.Inc(1)
+		}
+		log.Infof(ctx, "subscription completed. Error: %s", err)
+		lrw.sendError(errors.Wrap(err, "subscription"))
+	}
+}
+
 // Next is part of the RowSource interface.
 func (lrw *logicalReplicationWriterProcessor) Next() (
 	rowenc.EncDatumRow,
@@ -356,6 +465,10 @@ func (lrw *logicalReplicationWriterProcessor) Next() (
 	case resolved, ok := <-lrw.checkpointCh:
 		if ok {
 			progressUpdate := &jobspb.ResolvedSpans{ResolvedSpans: resolved}
+			lrw.stats.Lock()
+			progressUpdate.Stats.LogicalReplication = lrw.stats.LogicalReplicationStats
+			lrw.stats.LogicalReplicationStats = jobspb.LogicalReplicationStats{}
+			lrw.stats.Unlock()
 			progressBytes, err := protoutil.Marshal(progressUpdate)
 			if err != nil {
 				lrw.MoveToDrainingAndLogError(err)
@@ -436,6 +549,16 @@ func (lrw *logicalReplicationWriterProcessor) close() {
 		lrw.purgatory.eventsGauge.Dec(int64(len(i.events)))
 		lrw.purgatory.debug.RecordPurgatory(-int64(len(i.events)))
 	}
+	lrw.catchup.close()
+	for id, paused := range lrw.pausedTables {
+		if paused {
+			lrw.metrics.pausedTables.update(lrw.metrics, lrw.tableNames[id], false)
+		}
+	}
+
+	lrw.memAcc.Close(lrw.Ctx())
+	lrw.MemMonitor.Stop(lrw.Ctx())
+	lrw.metrics.recordApplyMemory(timeutil.Now())
 
 	lrw.InternalClose()
 }
@@ -553,6 +676,7 @@ func (lrw *logicalReplicationWriterProcessor) checkpoint(
 		return nil
 	}
 	lrw.metrics.CheckpointEvents.Inc(1)
+	lrw.catchup.update(lrw.frontier)
 	lrw.debug.RecordCheckpoint(lrw.frontier.Frontier().GoTime())
 	return nil
 }
@@ -561,19 +685,49 @@ func (lrw *logicalReplicationWriterProcessor) checkpoint(
 func (lrw *logicalReplicationWriterProcessor) handleStreamBuffer(
 	ctx context.Context, kvs []streampb.StreamEvent_KV,
 ) error {
+	// The buffer is accounted for until it has been flushed; events which fail
+	// to apply are accounted for by the retry queue from then on.
+	var bufferedBytes int64
+	for i := range kvs {
+		bufferedBytes += int64(kvs[i].Size())
+	}
+	lrw.metrics.BufferedBytes.Inc(bufferedBytes)
+	defer lrw.metrics.BufferedBytes.Dec(bufferedBytes)
+	if err := lrw.growMemory(ctx, bufferedBytes); err != nil {
+		return err
+	}
+	defer lrw.shrinkMemory(ctx, bufferedBytes)
+
 	const notRetry = false
-	unapplied, unappliedBytes, err := lrw.flushBuffer(ctx, kvs, notRetry, lrw.purgatory.Enabled())
+	firstAttempt := timeutil.Now()
+	unapplied, unappliedBytes, err := lrw.flushBuffer(ctx, kvs, notRetry, lrw.purgatory.Enabled(), firstAttempt, time.Time{})
 	if err != nil {
 		return err
 	}
 	// Put any events that failed to apply into purgatory (flushing if needed).
-	if err := lrw.purgatory.Store(ctx, unapplied, unappliedBytes); err != nil {
+	if err := lrw.purgatory.Store(ctx, unapplied, unappliedBytes, firstAttempt); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// growMemory accounts for bytes of events held by the apply path, failing if
+// they exceed the memory budget.
+func (lrw *logicalReplicationWriterProcessor) growMemory(ctx context.Context, bytes int64) error {
+	if err := lrw.memAcc.Grow(ctx, bytes); err != nil {
+		return err
+	}
+	lrw.metrics.recordApplyMemory(timeutil.Now())
+	return nil
+}
+
+// shrinkMemory releases bytes accounted for by growMemory.
+func (lrw *logicalReplicationWriterProcessor) shrinkMemory(ctx context.Context, bytes int64) {
+	lrw.memAcc.Shrink(ctx, bytes)
+	lrw.metrics.recordApplyMemory(timeutil.Now())
+}
+
 func filterRemaining(kvs []streampb.StreamEvent_KV) []streampb.StreamEvent_KV {
 	remaining := kvs
 	var j int
@@ -592,6 +746,67 @@ func filterRemaining(kvs []streampb.StreamEvent_KV) []streampb.StreamEvent_KV {
 
 const maxWriterWorkers = 32
 
+// rowKey returns the row key for some KV event, truncated if needed to the row
+// key prefix.
+func rowKey(kv streampb.StreamEvent_KV) roachpb.Key {
+	if p, err := keys.EnsureSafeSplitKey(kv.KeyValue.Key); err == nil {
+		return p
+	}
+	return kv.KeyValue.Key
+}
+
+// distinctRowKeys returns the number of distinct rows in a batch sorted by
+// row key.
+func distinctRowKeys(batch []streampb.StreamEvent_KV) int64 {
+	var n int64
+	for i := range batch {
+		if i == 0 || !rowKey(batch[i-1]).Equal(rowKey(batch[i])) {
+			n++
+		}
+	}
+	return n
+}
+
+// coalesceBatch moves the events in a batch sorted by row key and timestamp
+// which are overwritten by a later event to the same key in the batch to the
+// front of the batch, preserving the order of the remaining events, and returns
+// the number of events moved. The previous value of each overwriting event is
+// replaced with that of the earliest event it overwrites, so that the remaining
+// events describe the same change to the destination as the whole batch.
+func coalesceBatch(batch []streampb.StreamEvent_KV) int {
+	superseded := make([]bool, len(batch))
+	var n int
+	for i := range batch {
+		// The events of a row are adjacent, so only the rest of the row needs to
+		// be searched for a later event to the same key.
+		row := rowKey(batch[i])
+		for j := i + 1; j < len(batch) && rowKey(batch[j]).Equal(row); j++ {
+			if batch[j].KeyValue.Key.Equal(batch[i].KeyValue.Key) {
+				batch[j].PrevValue = batch[i].PrevValue
+				superseded[i] = true
+				n++
+				break
+			}
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	reordered := make([]streampb.StreamEvent_KV, 0, len(batch))
+	for i := range batch {
+		if superseded[i] {
+			reordered = append(reordered, batch[i])
+		}
+	}
+	for i := range batch {
+		if !superseded[i] {
+			reordered = append(reordered, batch[i])
+		}
+	}
+	copy(batch, reordered)
+	return n
+}
+
 // flushBuffer processes some or all of the events in the passed buffer, and
 // zeros out each event in the passed buffer for which it successfully completed
 // processing either by applying it or by sending it to a DLQ. If mustProcess is
@@ -599,9 +814,15 @@ const maxWriterWorkers = 32
 // it is false it may elect to leave an event in the buffer to indicate that
 // processing of that event did not complete, for example if application failed
 // but it was not sent to the DLQ, and thus should remain buffered for a later
-// retry.
+// retry. firstAttempt is when application of the events was first attempted,
+// which is now unless they are being retried, in which case enqueuedAt is when
+// they were stored in the retry queue.
 func (lrw *logicalReplicationWriterProcessor) flushBuffer(
-	ctx context.Context, kvs []streampb.StreamEvent_KV, isRetry bool, canRetry retryEligibility,
+	ctx context.Context,
+	kvs []streampb.StreamEvent_KV,
+	isRetry bool,
+	canRetry retryEligibility,
+	firstAttempt, enqueuedAt time.Time,
 ) (notProcessed []streampb.StreamEvent_KV, notProcessedByteSize int64, _ error) {
 	ctx, sp := tracing.ChildSpan(ctx, "logical-replication-writer-flush")
 	defer sp.Finish()
@@ -610,6 +831,33 @@ func (lrw *logicalReplicationWriterProcessor) flushBuffer(
 		return nil, 0, nil
 	}
 
+	kvs, paused := lrw.splitPaused(kvs)
+	var pausedNotProcessed []streampb.StreamEvent_KV
+	var pausedNotProcessedBytes int64
+	if len(paused) > 0 {
+		var err error
+		pausedNotProcessed, pausedNotProcessedBytes, err = lrw.flushPaused(ctx, paused, isRetry, canRetry, firstAttempt)
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(kvs) == 0 {
+			return pausedNotProcessed, pausedNotProcessedBytes, nil
+		}
+	}
+
+	if isRetry {
+		// Events handled from the stream are accounted for by
+		// handleStreamBuffer.
+		var retriedBytes int64
+		for i := range kvs {
+			retriedBytes += int64(kvs[i].Size())
+		}
+		if err := lrw.growMemory(ctx, retriedBytes); err != nil {
+			return nil, 0, err
+		}
+		defer lrw.shrinkMemory(ctx, retriedBytes)
+	}
+
 	preFlushTime := timeutil.Now()
 
 	// Inform the debugging helper that a flush is starting and configure failure
@@ -626,26 +874,24 @@ func (lrw *logicalReplicationWriterProcessor) flushBuffer(
 		}()
 	}
 
-	// k returns the row key for some KV event, truncated if needed to the row key
-	// prefix.
-	k := func(kv streampb.StreamEvent_KV) roachpb.Key {
-		if p, err := keys.EnsureSafeSplitKey(kv.KeyValue.Key); err == nil {
-			return p
-		}
-		return kv.KeyValue.Key
-	}
-
 	firstKeyTS := kvs[0].KeyValue.Value.Timestamp.GoTime()
 
 	slices.SortFunc(kvs, func(a, b streampb.StreamEvent_KV) int {
-		if c := k(a).Compare(k(b)); c != 0 {
+		if c := rowKey(a).Compare(rowKey(b)); c != 0 {
 			return c
 		}
 		return a.KeyValue.Value.Timestamp.Compare(b.KeyValue.Value.Timestamp)
 	})
+	if !isRetry {
+		lrw.metrics.PKChangingUpdates.Inc(countPKChangingUpdates(kvs))
+	}
 
 	const minChunkSize = 64
 	chunkSize := max((len(kvs)/len(lrw.bh))+1, minChunkSize)
+	// The events must be read before they are cleared by the workers applying
+	// them, so each chunk is added before it is handed to its worker.
+	txns := makeSourceTxns()
+	batchSize := lrw.getBatchSize()
 
 	perChunkStats := make([]flushStats, len(lrw.bh))
 
@@ -657,12 +903,13 @@ func (lrw *logicalReplicationWriterProcessor) flushBuffer(
 		}
 		// The chunk should end after the first new key after chunk size.
 		chunkEnd := min(chunkSize, len(todo))
-		for chunkEnd < len(todo) && k(todo[chunkEnd-1]).Equal(k(todo[chunkEnd])) {
+		for chunkEnd < len(todo) && rowKey(todo[chunkEnd-1]).Equal(rowKey(todo[chunkEnd])) {
 			chunkEnd++
 		}
 		chunk := todo[0:chunkEnd]
 		todo = todo[len(chunk):]
 		bh := lrw.bh[worker]
+		txns.addChunk(chunk, batchSize)
 
 		if err := ctx.Err(); err != nil {
 			// Bail early if ctx is canceled. NB: we break rather than return the err
@@ -673,7 +920,7 @@ func (lrw *logicalReplicationWriterProcessor) flushBuffer(
 		}
 
 		g.GoCtx(func(ctx context.Context) error {
-			s, err := lrw.flushChunk(ctx, bh, chunk, canRetry)
+			s, err := lrw.flushChunk(ctx, bh, chunk, canRetry, firstAttempt)
 			if err != nil {
 				return err
 			}
@@ -698,28 +945,63 @@ func (lrw *logicalReplicationWriterProcessor) flushBuffer(
 	if stats.notProcessed.count > 0 {
 		notProcessed = filterRemaining(kvs)
 	}
+	lrw.metrics.SourceTxnsApplied.Inc(txns.applied(notProcessed))
+	lrw.metrics.SourceTxnSplits.Inc(txns.splits())
 
 	flushTime := timeutil.Since(preFlushTime).Nanoseconds()
 	lrw.debug.RecordFlushComplete(flushTime, int64(len(kvs)), stats.processed.bytes)
 
 	lrw.metrics.AppliedRowUpdates.Inc(stats.processed.success)
+	lrw.metrics.ingestRate.record(timeutil.Now(), stats.processed.success, stats.processed.bytes)
 	lrw.metrics.DLQedRowUpdates.Inc(stats.processed.dlq)
+	lrw.metrics.EventsCoalesced.Inc(stats.processed.coalesced)
+	lrw.metrics.EventsDroppedStale.Inc(stats.droppedStale)
+	lrw.metrics.EventsDeduplicated.Inc(stats.deduplicated)
+	lrw.metrics.EventsNoChange.Inc(stats.noChange)
+	lrw.metrics.EventsTypeCoerced.Inc(stats.typeCoerced)
+	lrw.metrics.ProjectedOutBytes.Inc(stats.projectedOutBytes)
+	lrw.metrics.recordDestinationWrites(stats.writeBytes, stats.writeLogicalBytes)
+	lrw.stats.Lock()
+	lrw.stats.EventsIngested += stats.processed.success
+	lrw.stats.EventsDLQed += stats.processed.dlq
+	if !isRetry {
+		lrw.stats.ReceivedBytes += stats.processed.bytes + stats.notProcessed.bytes
+	}
+	lrw.stats.Unlock()
 	if l := lrw.spec.MetricsLabel; l != "" {
 		lrw.metrics.LabeledEventsIngested.Inc(map[string]string{"label": l}, stats.processed.success)
 		lrw.metrics.LabeledEventsDLQed.Inc(map[string]string{"label": l}, stats.processed.dlq)
 	}
 
-	lrw.metrics.CommitToCommitLatency.RecordValue(timeutil.Since(firstKeyTS).Nanoseconds())
+	lrw.recordLatency(ctx, lrw.metrics.CommitToCommitLatency, timeutil.Since(firstKeyTS).Nanoseconds())
 
 	if isRetry {
 		lrw.metrics.RetriedApplySuccesses.Inc(stats.processed.success)
+		if !enqueuedAt.IsZero() {
+			residence := timeutil.Since(enqueuedAt).Nanoseconds()
+			for i := int64(0); i < stats.processed.success; i++ {
+				lrw.metrics.RetryQueueResidenceNanos.RecordValue(residence)
+			}
+		}
 		lrw.metrics.RetriedApplyFailures.Inc(stats.notProcessed.count + stats.processed.dlq)
+		lrw.metrics.recordRetryOutcomes(timeutil.Now(), 0, stats.processed.dlq)
 	} else {
 		lrw.metrics.InitialApplySuccesses.Inc(stats.processed.success)
 		lrw.metrics.InitialApplyFailures.Inc(stats.notProcessed.count + stats.processed.dlq)
-		lrw.metrics.ReceivedLogicalBytes.Inc(stats.processed.bytes + stats.notProcessed.bytes)
+		lrw.metrics.recordRetryOutcomes(timeutil.Now(), stats.notProcessed.count, 0)
+		lrw.recordReceived(stats.processed.bytes + stats.notProcessed.bytes)
 	}
-	return notProcessed, stats.notProcessed.bytes, nil
+	return append(notProcessed, pausedNotProcessed...), stats.notProcessed.bytes + pausedNotProcessedBytes, nil
+}
+
+// recordReceived records bytes of events received from the source, the first
+// time they are flushed.
+func (lrw *logicalReplicationWriterProcessor) recordReceived(bytes int64) {
+	lrw.metrics.ReceivedLogicalBytes.Inc(bytes)
+	if l := lrw.spec.MetricsLabel; l != "" {
+		lrw.metrics.LabeledReceivedBytes.Inc(map[string]string{"label": l}, bytes)
+	}
+	lrw.catchup.recordReceived(bytes)
 }
 
 type retryEligibility int
@@ -751,8 +1033,141 @@ const (
 	insertMutation replicationMutationType = iota
 	deleteMutation
 	updateMutation
+	numReplicationMutationTypes
 )
 
+// mutationTypeOf returns the type of mutation a row update event applies,
+// based on whether the row existed before and after the event. Note that an
+// update arriving without its previous value is indistinguishable from an
+// insert.
+func mutationTypeOf(kv streampb.StreamEvent_KV) replicationMutationType {
+	if !kv.KeyValue.Value.IsPresent() {
+		return deleteMutation
+	}
+	if kv.PrevValue.IsPresent() {
+		return updateMutation
+	}
+	return insertMutation
+}
+
+// countPKChangingUpdates returns the number of updates changing the primary
+// key of a row among kvs, which must be sorted by row key and timestamp.
+//
+// Such an update is not marked as such in the stream: it is received as the
+// deletion of the row under its old key and the insertion of a row under its
+// new key, with the same origin timestamp. The count is an estimate, which
+// pairs the rows deleted and inserted at each timestamp, so a transaction
+// deleting one row and inserting an unrelated one is also counted, and an
+// update whose two halves are flushed separately is not.
+func countPKChangingUpdates(kvs []streampb.StreamEvent_KV) int64 {
+	type rowCounts struct{ deleted, inserted int64 }
+	var byTimestamp map[hlc.Timestamp]*rowCounts
+	for i := range kvs {
+		ts := kvs[i].KeyValue.Value.Timestamp
+		// Count each row once, rather than each of its column families.
+		if i > 0 && ts == kvs[i-1].KeyValue.Value.Timestamp && rowKey(kvs[i]).Equal(rowKey(kvs[i-1])) {
+			continue
+		}
+		typ := mutationTypeOf(kvs[i])
+		if typ == updateMutation {
+			continue
+		}
+		if byTimestamp == nil {
+			byTimestamp = make(map[hlc.Timestamp]*rowCounts)
+		}
+		c, ok := byTimestamp[ts]
+		if !ok {
+			c = &rowCounts{}
+			byTimestamp[ts] = c
+		}
+		if typ == deleteMutation {
+			c.deleted++
+		} else {
+			c.inserted++
+		}
+	}
+	var n int64
+	for _, c := range byTimestamp {
+		n += min(c.deleted, c.inserted)
+	}
+	return n
+}
+
+// sourceTxns tracks the source transactions of the events of a flush, and how
+// many of the batches they are applied in each one's events are part of.
+//
+// The stream does not identify the transactions events were written by, so
+// they are identified by their commit timestamp, which all the events of a
+// transaction share; transactions committed at the same timestamp are counted
+// as one. The events of a transaction are not applied atomically: they are
+// spread across the workers' chunks by row key, and chunks are applied in
+// batches of their own, so a transaction whose events are applied in more than
+// one batch is split.
+type sourceTxns struct {
+	byTimestamp map[hlc.Timestamp]*sourceTxn
+	// batches is the number of batches added so far.
+	batches int
+}
+
+type sourceTxn struct {
+	batches int
+	// lastBatch is the index of the last batch the transaction's events were
+	// seen in.
+	lastBatch int
+	// notProcessed is set if some of the transaction's events were not
+	// processed.
+	notProcessed bool
+}
+
+func makeSourceTxns() sourceTxns {
+	return sourceTxns{byTimestamp: make(map[hlc.Timestamp]*sourceTxn)}
+}
+
+// addChunk adds the events of a chunk, which are applied in batches of up to
+// batchSize events.
+func (s *sourceTxns) addChunk(chunk []streampb.StreamEvent_KV, batchSize int) {
+	for i := range chunk {
+		if i%batchSize == 0 {
+			s.batches++
+		}
+		ts := chunk[i].KeyValue.Value.Timestamp
+		t, ok := s.byTimestamp[ts]
+		if !ok {
+			t = &sourceTxn{}
+			s.byTimestamp[ts] = t
+		}
+		if t.lastBatch != s.batches {
+			t.batches++
+			t.lastBatch = s.batches
+		}
+	}
+}
+
+// applied returns the number of transactions none of whose events are among
+// notProcessed, i.e. whose events were all applied or sent to the DLQ.
+func (s *sourceTxns) applied(notProcessed []streampb.StreamEvent_KV) int64 {
+	n := int64(len(s.byTimestamp))
+	for i := range notProcessed {
+		if t, ok := s.byTimestamp[notProcessed[i].KeyValue.Value.Timestamp]; ok && !t.notProcessed {
+			t.notProcessed = true
+			n--
+		}
+	}
+	return n
+}
+
+// splits returns the number of transactions whose events were applied in more
+// than one batch.
+func (s *sourceTxns) splits() int64 {
+	var n int64
+	for _, t := range s.byTimestamp {
+		if t.batches > 1 {
+			n++
+		}
+	}
+	return n
+}
+
 func (t replicationMutationType) String() string {
 	switch t {
 	case insertMutation:
@@ -768,11 +1183,20 @@ func (t replicationMutationType) String() string {
 
 // flushChunk is the per-thread body of flushBuffer; see flushBuffer's contract.
 func (lrw *logicalReplicationWriterProcessor) flushChunk(
-	ctx context.Context, bh BatchHandler, chunk []streampb.StreamEvent_KV, canRetry retryEligibility,
+	ctx context.Context,
+	bh BatchHandler,
+	chunk []streampb.StreamEvent_KV,
+	canRetry retryEligibility,
+	firstAttempt time.Time,
 ) (flushStats, error) {
 	batchSize := lrw.getBatchSize()
+	coalesce := lrw.FlowCtx != nil && coalesceEventsEnabled.Get(&lrw.FlowCtx.Cfg.Settings.SV)
 
 	var stats flushStats
+	// The row key of the last event of the previous batch, and how long that
+	// batch took to apply.
+	var prevBatchLastKey roachpb.Key
+	var prevBatchTime time.Duration
 	// TODO: The batching here in production would need to be much
 	// smarter. Namely, we don't want to include updates to the
 	// same key in the same batch. Also, it's possible batching
@@ -798,9 +1222,41 @@ func (lrw *logicalReplicationWriterProcessor) flushChunk(
 			}
 		}
 
-		preBatchTime := timeutil.Now()
+		preAssemblyTime := timeutil.Now()
+		// The batch is cleared as it is applied, so count its rows up front.
+		batchRows := distinctRowKeys(batch)
 
-		if s, err := bh.HandleBatch(ctx, batch); err != nil {
+		// Overwritten events are processed by applying the events which
+		// overwrite them, so clear them without applying them.
+		if coalesce && len(batch) > 1 {
+			coalesced := coalesceBatch(batch)
+			for i := range batch[:coalesced] {
+				stats.processed.bytes += int64(batch[i].Size())
+				batch[i] = streampb.StreamEvent_KV{}
+			}
+			stats.processed.coalesced += int64(coalesced)
+			batch = batch[coalesced:]
+		}
+		// The events of a row are applied in order, so those continuing a row
+		// from the previous batch could only be applied once it had been.
+		for i := 0; i < len(batch) && rowKey(batch[i]).Equal(prevBatchLastKey); i++ {
+			lrw.metrics.OrderingWaitNanos.RecordValue(prevBatchTime.Nanoseconds())
+		}
+		prevBatchLastKey = rowKey(batch[len(batch)-1])
+
+		preBatchTime := timeutil.Now()
+		lrw.metrics.BatchAssemblyNanos.RecordValue(preBatchTime.Sub(preAssemblyTime).Nanoseconds())
+		preBatchConflicts := stats.conflicted
+
+		batchCtx := lrw.trackDestinationQuotaWaits(ctx)
+		batchCtx, finishAdmissionWaitRecording := lrw.startAdmissionWaitRecording(batchCtx)
+		s, err := lrw.handleBatch(batchCtx, bh, batch)
+		var storeWait time.Duration
+		if wait, storeQueueWait, ok := finishAdmissionWaitRecording(); ok {
+			lrw.metrics.AdmissionWaitNanos.RecordValue(wait.Nanoseconds())
+			storeWait = storeQueueWait
+		}
+		if err != nil {
 			if ctxErr := ctx.Err(); ctxErr != nil {
 				return flushStats{}, ctxErr
 			}
@@ -808,7 +1264,7 @@ func (lrw *logicalReplicationWriterProcessor) flushChunk(
 			// If it already failed while applying on its own, handle the failure.
 			if len(batch) == 1 {
 				if eligibility := lrw.shouldRetryLater(err, canRetry); eligibility != retryAllowed {
-					if err := lrw.dlq(ctx, batch[0], bh.GetLastRow(), err, eligibility); err != nil {
+					if err := lrw.dlq(ctx, batch[0], bh.GetLastRow(), err, eligibility, firstAttempt); err != nil {
 						return flushStats{}, err
 					}
 					stats.processed.dlq++
@@ -820,12 +1276,12 @@ func (lrw *logicalReplicationWriterProcessor) flushChunk(
 				// If there were multiple events in the batch, give each its own chance
 				// to apply on its own before switching to handle its failure.
 				for i := range batch {
-					if singleStats, err := bh.HandleBatch(ctx, batch[i:i+1]); err != nil {
+					if singleStats, err := lrw.handleBatch(lrw.trackDestinationQuotaWaits(ctx), bh, batch[i:i+1]); err != nil {
 						if ctxErr := ctx.Err(); ctxErr != nil {
 							return flushStats{}, ctxErr
 						}
 						if eligibility := lrw.shouldRetryLater(err, canRetry); eligibility != retryAllowed {
-							if err := lrw.dlq(ctx, batch[i], bh.GetLastRow(), err, eligibility); err != nil {
+							if err := lrw.dlq(ctx, batch[i], bh.GetLastRow(), err, eligibility, firstAttempt); err != nil {
 								return flushStats{}, err
 							}
 							stats.processed.dlq++
@@ -836,6 +1292,14 @@ func (lrw *logicalReplicationWriterProcessor) flushChunk(
 					} else {
 						stats.optimisticInsertConflicts += singleStats.optimisticInsertConflicts
 						stats.kvWriteFallbacks += singleStats.kvWriteFallbacks
+						stats.conflicted += singleStats.conflicted
+						stats.writeBytes += singleStats.writeBytes
+						stats.writeLogicalBytes += singleStats.writeLogicalBytes
+						stats.droppedStale += singleStats.droppedStale
+						stats.deduplicated += singleStats.deduplicated
+						stats.noChange += singleStats.noChange
+						stats.typeCoerced += singleStats.typeCoerced
+						stats.projectedOutBytes += singleStats.projectedOutBytes
 						batch[i] = streampb.StreamEvent_KV{}
 						stats.processed.success++
 						stats.processed.bytes += int64(batch[i].Size())
@@ -845,6 +1309,14 @@ func (lrw *logicalReplicationWriterProcessor) flushChunk(
 		} else {
 			stats.optimisticInsertConflicts += s.optimisticInsertConflicts
 			stats.kvWriteFallbacks += s.kvWriteFallbacks
+			stats.conflicted += s.conflicted
+			stats.writeBytes += s.writeBytes
+			stats.writeLogicalBytes += s.writeLogicalBytes
+			stats.droppedStale += s.droppedStale
+			stats.deduplicated += s.deduplicated
+			stats.noChange += s.noChange
+			stats.typeCoerced += s.typeCoerced
+			stats.projectedOutBytes += s.projectedOutBytes
 			stats.processed.success += int64(len(batch))
 			// Clear the event to indicate successful application.
 			for i := range batch {
@@ -854,12 +1326,112 @@ func (lrw *logicalReplicationWriterProcessor) flushChunk(
 		}
 
 		batchTime := timeutil.Since(preBatchTime)
+		prevBatchTime = batchTime
+		if lrw.appliedStalledOnDisk(batchTime, storeWait) {
+			lrw.metrics.ApplyStallsDisk.Inc(1)
+		}
 		lrw.debug.RecordBatchApplied(batchTime, int64(len(batch)))
-		lrw.metrics.ApplyBatchNanosHist.RecordValue(batchTime.Nanoseconds())
+		lrw.recordLatency(ctx, lrw.metrics.ApplyBatchNanosHist, batchTime.Nanoseconds())
+		batchConflicts := stats.conflicted - preBatchConflicts
+		lrw.metrics.BatchConflictPercent.RecordValue(100 * batchConflicts / int64(len(batch)))
+		lrw.metrics.DistinctKeysPerBatch.RecordValue(batchRows)
 	}
 	return stats, nil
 }
 
+// recordLatency records a latency in one of the apply latency histograms, see
+// recordWithTraceExemplar.
+func (lrw *logicalReplicationWriterProcessor) recordLatency(
+	ctx context.Context, h metric.IHistogram, nanos int64,
+) {
+	var sv *settings.Values
+	if lrw.FlowCtx != nil { // Some unit tests don't set this.
+		sv = &lrw.FlowCtx.Cfg.Settings.SV
+	}
+	recordWithTraceExemplar(ctx, sv, h, nanos)
+}
+
+// appliedStalledOnDisk returns true if a batch which took batchTime to apply
+// was slow mostly because its writes waited storeWait to be admitted by the
+// destination's stores. Stores only hold up writes for admission when they
+// report IO overload, i.e. when their disks can't keep up, rather than because
+// of anything to do with the stream.
+func (lrw *logicalReplicationWriterProcessor) appliedStalledOnDisk(
+	batchTime, storeWait time.Duration,
+) bool {
+	if storeWait == 0 || lrw.FlowCtx == nil {
+		return false
+	}
+	threshold := applyStallThreshold.Get(&lrw.FlowCtx.Cfg.Settings.SV)
+	return threshold > 0 && batchTime >= threshold && storeWait >= batchTime/2
+}
+
+// startAdmissionWaitRecording returns a context in which to apply a batch and a
+// function to call once the batch has been applied, which returns the time
+// spent waiting in KV admission queues while applying it, and the part of it
+// spent waiting in store write queues. The wait time is collected from the
+// structured events that admission control records in the trace, so it is only
+// returned if admission wait recording is enabled, as recording adds some
+// overhead.
+func (lrw *logicalReplicationWriterProcessor) startAdmissionWaitRecording(
+	ctx context.Context,
+) (context.Context, func() (wait, storeWait time.Duration, ok bool)) {
+	if lrw.FlowCtx == nil || !admissionWaitRecordingEnabled.Get(&lrw.FlowCtx.Cfg.Settings.SV) {
+		return ctx, func() (time.Duration, time.Duration, bool) { return 0, 0, false }
+	}
+	ctx, sp := tracing.EnsureChildSpan(ctx, lrw.FlowCtx.Cfg.Tracer, "logical-replication-apply-batch",
+		tracing.WithRecording(tracingpb.RecordingStructured))
+	return ctx, func() (time.Duration, time.Duration, bool) {
+		wait, storeWait := admissionWaitTime(sp.FinishAndGetConfiguredRecording())
+		return wait, storeWait, true
+	}
+}
+
+// trackDestinationQuotaWaits returns a context in which to apply a batch under
+// which the worker applying it is counted in WorkersBlockedOnDestinationQuota
+// while any of the batch's requests are waiting for the proposal quota of a
+// destination range. Only the waits of ranges whose leaseholder is on this node
+// can be observed, as the context is not sent with requests to other nodes.
+func (lrw *logicalReplicationWriterProcessor) trackDestinationQuotaWaits(
+	ctx context.Context,
+) context.Context {
+	gauge := lrw.metrics.WorkersBlockedOnDestinationQuota
+	// A batch's requests to different ranges may wait concurrently, but the
+	// worker is only counted once.
+	var waiting atomic.Int32
+	return kvserverbase.ContextWithProposalQuotaWaitFunc(ctx, func(w bool) {
+		if w {
+			if waiting.Add(1) == 1 {
+				gauge.Inc(1)
+			}
+		} else if waiting.Add(-1) == 0 {
+			gauge.Dec(1)
+		}
+	})
+}
+
+// admissionWaitTime returns the total time spent waiting in admission queues
+// recorded in rec, and the part of it spent in store write queues, such as
+// "kv-regular-store-queue".
+func admissionWaitTime(rec tracingpb.Recording) (wait, storeWait time.Duration) {
+	var ev admissionpb.AdmissionWorkQueueStats
+	for i := range rec {
+		rec[i].Structured(func(any *pbtypes.Any, _ time.Time) {
+			if !pbtypes.Is(any, &ev) {
+				return
+			}
+			if err := pbtypes.UnmarshalAny(any, &ev); err != nil {
+				return
+			}
+			wait += ev.WaitDurationNanos
+			if strings.HasSuffix(ev.QueueKind, "-store-queue") {
+				storeWait += ev.WaitDurationNanos
+			}
+		})
+	}
+	return wait, storeWait
+}
+
 // shouldRetryLater returns true if a given error encountered by an attempt to
 // process an event may be resolved if processing of that event is reattempted
 // again at a later time. This could be the case, for example, if that time is
@@ -875,6 +1447,19 @@ func (lrw *logicalReplicationWriterProcessor) shouldRetryLater(
 		return tooOld
 	}
 
+	// A value which cannot be coerced to its destination column's type will not
+	// become coercible later.
+	if errors.Is(err, errTypeCoercionFailed) {
+		return errType
+	}
+
+	// Proposal quota backpressure clears once the destination range's followers
+	// catch up, but is counted separately so that retries can be attributed to
+	// it.
+	if errors.Is(err, kvserverbase.ErrProposalQuotaQueueTooDeep) {
+		lrw.metrics.ApplyRetriesQuota.Inc(1)
+	}
+
 	// TODO(dt): maybe this should only be constraint violation errors?
 	return retryAllowed
 }
@@ -886,6 +1471,12 @@ const logAllDLQs = true
 // it is available, and dlq may persist it in addition to the event if
 // row.IsInitialized() is true.
 //
+// If the event cannot be written to the DLQ, the returned error fails the
+// processor, and with it the flow. The event is not lost: the frontier is not
+// advanced past unapplied events, so once the job restarts the flow from its
+// last checkpoint the event is received and applied (or DLQ'ed) again. If the
+// DLQ remains unavailable, the job eventually runs out of retries and pauses.
+//
 // TODO(dt): implement something here.
 // TODO(dt): plumb the cdcevent.Row to this.
 func (lrw *logicalReplicationWriterProcessor) dlq(
@@ -894,6 +1485,7 @@ func (lrw *logicalReplicationWriterProcessor) dlq(
 	row cdcevent.Row,
 	applyErr error,
 	eligibility retryEligibility,
+	firstAttempt time.Time,
 ) error {
 	if log.V(1) || logAllDLQs {
 		if row.IsInitialized() {
@@ -914,31 +1506,90 @@ func (lrw *logicalReplicationWriterProcessor) dlq(
 	case errType:
 		lrw.metrics.DLQedDueToErrType.Inc(1)
 	}
-	return lrw.dlqClient.Log(ctx, lrw.spec.JobID, event, row, applyErr, eligibility)
+	if errors.Is(applyErr, errTypeCoercionFailed) {
+		lrw.metrics.EventsTypeCoercionFailed.Inc(1)
+	}
+	lrw.metrics.recordDLQDetectionLatency(eligibility, timeutil.Since(firstAttempt).Nanoseconds())
+	if err := lrw.dlqClient.Log(ctx, lrw.spec.JobID, event, row, applyErr, eligibility); err != nil {
+		lrw.metrics.DLQWriteFailures.Inc(1)
+		return errors.Wrap(err, "writing event to the DLQ")
+	}
+	return nil
 }
 
 type batchStats struct {
 	optimisticInsertConflicts int64
 	kvWriteFallbacks          int64
+	// conflicted is the number of events which required conflict handling,
+	// counting once an event which both failed its optimistic insert and fell
+	// back to a KV write.
+	conflicted int64
+	// writeBytes is the size of the KV writes made to the destination, if known
+	// to the row processor, and writeLogicalBytes the logical size of the events
+	// they were made for.
+	writeBytes, writeLogicalBytes int64
+	// droppedStale is the number of events which were not applied as the
+	// destination row had a newer origin timestamp.
+	droppedStale int64
+	// deduplicated is the number of events which were not applied as the
+	// destination row had already been written by them.
+	deduplicated int64
+	// noChange is the number of updates which were applied but left the values
+	// of the destination row unchanged.
+	noChange int64
+	// typeCoerced is the number of events with a value which had to be coerced
+	// to the type of its destination column.
+	typeCoerced int64
+	// projectedOutBytes is the encoded size of the values which were decoded
+	// but dropped before apply.
+	projectedOutBytes int64
 }
+
+func (b *batchStats) Add(o batchStats) {
+	b.optimisticInsertConflicts += o.optimisticInsertConflicts
+	b.kvWriteFallbacks += o.kvWriteFallbacks
+	b.conflicted += o.conflicted
+	b.writeBytes += o.writeBytes
+	b.writeLogicalBytes += o.writeLogicalBytes
+	b.droppedStale += o.droppedStale
+	b.deduplicated += o.deduplicated
+	b.noChange += o.noChange
+	b.typeCoerced += o.typeCoerced
+	b.projectedOutBytes += o.projectedOutBytes
+}
+
 type flushStats struct {
 	processed struct {
-		success, dlq, bytes int64
+		success, dlq, coalesced, bytes int64
 	}
 	notProcessed struct {
 		count, bytes int64
 	}
 	optimisticInsertConflicts, kvWriteFallbacks int64
+	conflicted                                  int64
+	writeBytes, writeLogicalBytes               int64
+	droppedStale, deduplicated, typeCoerced     int64
+	noChange                                    int64
+	projectedOutBytes                           int64
 }
 
 func (b *flushStats) Add(o flushStats) {
 	b.processed.success += o.processed.success
 	b.processed.dlq += o.processed.dlq
+	b.processed.coalesced += o.processed.coalesced
 	b.processed.bytes += o.processed.bytes
 	b.notProcessed.count += o.notProcessed.count
 	b.notProcessed.bytes += o.notProcessed.bytes
 	b.optimisticInsertConflicts += o.optimisticInsertConflicts
 	b.kvWriteFallbacks += o.kvWriteFallbacks
+	b.conflicted += o.conflicted
+	b.writeBytes += o.writeBytes
+	b.writeLogicalBytes += o.writeLogicalBytes
+	b.droppedStale += o.droppedStale
+	b.deduplicated += o.deduplicated
+	b.noChange += o.noChange
+	b.typeCoerced += o.typeCoerced
+	b.projectedOutBytes += o.projectedOutBytes
 }
 
 type BatchHandler interface {
@@ -968,6 +1619,9 @@ type txnBatch struct {
 	rp       RowProcessor
 	settings *cluster.Settings
 	sd       *sessiondata.SessionData
+	metrics  *Metrics
+	// metricsLabel is the metrics label of the job, if any.
+	metricsLabel string
 }
 
 var useImplicitTxns = settings.RegisterBoolSetting(
@@ -986,19 +1640,21 @@ func (t *txnBatch) HandleBatch(
 	stats := batchStats{}
 	var err error
 	if len(batch) == 1 {
-		s, err := t.rp.ProcessRow(ctx, nil /* txn */, batch[0].KeyValue, batch[0].PrevValue)
+		s, err := t.processRow(ctx, nil /* txn */, batch[0])
 		if err != nil {
 			return stats, err
 		}
-		stats.optimisticInsertConflicts += s.optimisticInsertConflicts
+		stats.Add(s)
 	} else {
 		err = t.db.Txn(ctx, func(ctx context.Context, txn isql.Txn) error {
+			// The txn may be retried, in which case only the last attempt counts.
+			stats = batchStats{}
 			for _, kv := range batch {
-				s, err := t.rp.ProcessRow(ctx, txn, kv.KeyValue, kv.PrevValue)
+				s, err := t.processRow(ctx, txn, kv)
 				if err != nil {
 					return err
 				}
-				stats.optimisticInsertConflicts += s.optimisticInsertConflicts
+				stats.Add(s)
 			}
 			return nil
 		}, isql.WithSessionData(t.sd))
@@ -1006,6 +1662,25 @@ func (t *txnBatch) HandleBatch(
 	return stats, err
 }
 
+// processRow applies a single event, recording how long it took by the type
+// of mutation it applies.
+func (t *txnBatch) processRow(
+	ctx context.Context, txn isql.Txn, kv streampb.StreamEvent_KV,
+) (batchStats, error) {
+	start := timeutil.Now()
+	s, err := t.rp.ProcessRow(ctx, txn, kv.KeyValue, kv.PrevValue)
+	if err == nil && t.metrics != nil {
+		t.metrics.recordApplyLatency(t.metricsLabel, mutationTypeOf(kv), timeutil.Since(start).Nanoseconds())
+	}
+	if s.writeBytes > 0 {
+		s.writeLogicalBytes = int64(kv.Size())
+	}
+	if s.optimisticInsertConflicts > 0 || s.kvWriteFallbacks > 0 {
+		s.conflicted = 1
+	}
+	return s, err
+}
+
 func (t *txnBatch) GetLastRow() cdcevent.Row {
 	return t.rp.GetLastRow()
 }
@@ -0,0 +1,340 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package logical
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/changefeedccl/cdcevent"
+	"github.com/cockroachdb/cockroach/pkg/ccl/crosscluster"
+	"github.com/cockroachdb/cockroach/pkg/ccl/crosscluster/streamclient"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/kvserverbase"
+	"github.com/cockroachdb/cockroach/pkg/repstream/streampb"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/isql"
+	"github.com/cockroachdb/cockroach/pkg/util/encoding"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/metric/aggmetric"
+	"github.com/cockroachdb/cockroach/pkg/util/mon"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoalesceBatch(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ev := func(pk int64, family uint32, wall int64, value, prev string) streampb.StreamEvent_KV {
+		key := encoding.EncodeVarintAscending(keys.SystemSQLCodec.IndexPrefix(104, 1), pk)
+		kv := streampb.StreamEvent_KV{
+			KeyValue: roachpb.KeyValue{
+				Key:   keys.MakeFamilyKey(key, family),
+				Value: roachpb.MakeValueFromString(value),
+			},
+			PrevValue: roachpb.MakeValueFromString(prev),
+		}
+		kv.KeyValue.Value.Timestamp = hlc.Timestamp{WallTime: wall}
+		return kv
+	}
+	values := func(batch []streampb.StreamEvent_KV) (vals, prevs []string) {
+		for _, kv := range batch {
+			v, err := kv.KeyValue.Value.GetBytes()
+			require.NoError(t, err)
+			p, err := kv.PrevValue.GetBytes()
+			require.NoError(t, err)
+			vals, prevs = append(vals, string(v)), append(prevs, string(p))
+		}
+		return vals, prevs
+	}
+
+	// Nothing to coalesce.
+	batch := []streampb.StreamEvent_KV{ev(1, 0, 1, "a", ""), ev(2, 0, 1, "b", "")}
+	require.Equal(t, 0, coalesceBatch(batch))
+	vals, _ := values(batch)
+	require.Equal(t, []string{"a", "b"}, vals)
+
+	// Row 1 is updated three times and row 2 has two families, one of which is
+	// updated twice.
+	batch = []streampb.StreamEvent_KV{
+		ev(1, 0, 1, "a1", "a0"),
+		ev(1, 0, 2, "a2", "a1"),
+		ev(1, 0, 3, "a3", "a2"),
+		ev(2, 0, 1, "b1", "b0"),
+		ev(2, 1, 1, "c1", "c0"),
+		ev(2, 0, 2, "b2", "b1"),
+		ev(3, 0, 1, "d1", "d0"),
+	}
+	require.Equal(t, 3, coalesceBatch(batch))
+	vals, prevs := values(batch)
+	require.Equal(t, []string{"a1", "a2", "b1", "a3", "c1", "b2", "d1"}, vals)
+	// The events which remain describe the change from the value before the
+	// first event they overwrote.
+	require.Equal(t, []string{"a0", "c0", "b0", "d0"}, prevs[3:])
+}
+
+func TestCountPKChangingUpdates(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ev := func(pk int64, family uint32, wall int64, value, prev bool) streampb.StreamEvent_KV {
+		key := encoding.EncodeVarintAscending(keys.SystemSQLCodec.IndexPrefix(104, 1), pk)
+		kv := streampb.StreamEvent_KV{KeyValue: roachpb.KeyValue{Key: keys.MakeFamilyKey(key, family)}}
+		if value {
+			kv.KeyValue.Value = roachpb.MakeValueFromString("v")
+		}
+		if prev {
+			kv.PrevValue = roachpb.MakeValueFromString("p")
+		}
+		kv.KeyValue.Value.Timestamp = hlc.Timestamp{WallTime: wall}
+		return kv
+	}
+	del := func(pk int64, family uint32, wall int64) streampb.StreamEvent_KV {
+		return ev(pk, family, wall, false, true)
+	}
+	ins := func(pk int64, family uint32, wall int64) streampb.StreamEvent_KV {
+		return ev(pk, family, wall, true, false)
+	}
+	upd := func(pk int64, family uint32, wall int64) streampb.StreamEvent_KV {
+		return ev(pk, family, wall, true, true)
+	}
+
+	for _, tc := range []struct {
+		name string
+		kvs  []streampb.StreamEvent_KV
+		exp  int64
+	}{
+		{name: "empty", exp: 0},
+		{name: "value only update", kvs: []streampb.StreamEvent_KV{upd(1, 0, 1)}, exp: 0},
+		{name: "pk change", kvs: []streampb.StreamEvent_KV{del(1, 0, 1), ins(2, 0, 1)}, exp: 1},
+		{
+			name: "pk change with two families",
+			kvs:  []streampb.StreamEvent_KV{del(1, 0, 1), del(1, 1, 1), ins(2, 0, 1), ins(2, 1, 1)},
+			exp:  1,
+		},
+		{
+			name: "delete and insert at different timestamps",
+			kvs:  []streampb.StreamEvent_KV{del(1, 0, 1), ins(2, 0, 2)},
+			exp:  0,
+		},
+		{
+			name: "two pk changes and an insert",
+			kvs:  []streampb.StreamEvent_KV{del(1, 0, 1), ins(2, 0, 1), del(3, 0, 1), ins(4, 0, 1), ins(5, 0, 1)},
+			exp:  2,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.exp, countPKChangingUpdates(tc.kvs))
+		})
+	}
+}
+
+func TestRecordRetryOutcomes(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	m := MakeMetrics(10 * time.Minute).(*Metrics)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	record := func(at time.Duration, retried, dlqed int64) float64 {
+		m.recordRetryOutcomes(start.Add(at), retried, dlqed)
+		return m.RetryToDLQRatio.Value()
+	}
+
+	require.Equal(t, 0.0, record(0, 4, 0))
+	require.Equal(t, 0.25, record(time.Minute, 0, 1))
+	// The first half window becomes the previous one.
+	require.Equal(t, 0.5, record(6*time.Minute, 0, 1))
+	// The first half window falls out of the window.
+	require.Equal(t, 0.5, record(11*time.Minute, 2, 0))
+	// Updates sent to the DLQ may have entered the retry queue before the
+	// window.
+	require.Equal(t, 1.0, record(30*time.Minute, 0, 3))
+	require.Equal(t, 1.0, record(31*time.Minute, 1, 0))
+}
+
+func TestRecordApplyMemory(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	m := MakeMetrics(10 * time.Minute).(*Metrics)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	record := func(at time.Duration, delta int64) int64 {
+		m.ApplyMemoryBytes.Inc(delta)
+		m.recordApplyMemory(start.Add(at))
+		return m.ApplyMemoryHighwaterBytes.Value()
+	}
+
+	require.Equal(t, int64(100), record(0, 100))
+	require.Equal(t, int64(300), record(time.Minute, 200))
+	require.Equal(t, int64(300), record(2*time.Minute, -250))
+	// The peak of the first half window is still within the window.
+	require.Equal(t, int64(300), record(6*time.Minute, 0))
+	// Once it falls out of the window, the peak of the second half window is
+	// reported.
+	require.Equal(t, int64(50), record(11*time.Minute, 0))
+	require.Equal(t, int64(80), record(12*time.Minute, 30))
+	// After a whole window without updates, only the current usage is left.
+	require.Equal(t, int64(0), record(30*time.Minute, -80))
+}
+
+func TestRecordDLQDetectionLatency(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	m := MakeMetrics(10 * time.Minute).(*Metrics)
+	count := func(h *aggmetric.Histogram) uint64 {
+		return h.ToPrometheusMetric().Histogram.GetSampleCount()
+	}
+
+	m.recordDLQDetectionLatency(errType, time.Millisecond.Nanoseconds())
+	m.recordDLQDetectionLatency(tooOld, time.Minute.Nanoseconds())
+	m.recordDLQDetectionLatency(noSpace, time.Second.Nanoseconds())
+	// Paused tables may send events to the DLQ without retrying them.
+	m.recordDLQDetectionLatency(retryAllowed, 0)
+
+	require.Equal(t, uint64(1), count(m.dlqDetectionLatencyImmediate))
+	require.Equal(t, uint64(2), count(m.dlqDetectionLatencyExhausted))
+}
+
+func TestTrackDestinationQuotaWaits(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	lrw := &logicalReplicationWriterProcessor{metrics: MakeMetrics(10 * time.Minute).(*Metrics)}
+	gauge := lrw.metrics.WorkersBlockedOnDestinationQuota
+
+	ctx := context.Background()
+	require.Nil(t, kvserverbase.ProposalQuotaWaitFuncFromContext(ctx))
+	first := kvserverbase.ProposalQuotaWaitFuncFromContext(lrw.trackDestinationQuotaWaits(ctx))
+	second := kvserverbase.ProposalQuotaWaitFuncFromContext(lrw.trackDestinationQuotaWaits(ctx))
+
+	// A worker is counted once while any of its requests are waiting.
+	first(true)
+	first(true)
+	require.Equal(t, int64(1), gauge.Value())
+	second(true)
+	require.Equal(t, int64(2), gauge.Value())
+	first(false)
+	require.Equal(t, int64(2), gauge.Value())
+	first(false)
+	second(false)
+	require.Equal(t, int64(0), gauge.Value())
+}
+
+func TestShouldRetryLaterQuota(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	lrw := &logicalReplicationWriterProcessor{metrics: MakeMetrics(10 * time.Minute).(*Metrics)}
+	quotaErr := errors.Wrap(errors.Mark(errors.New("r1: 3 proposals are waiting for proposal quota"),
+		kvserverbase.ErrProposalQuotaQueueTooDeep), "replicated insert")
+
+	// Quota backpressure is retried, and counted.
+	require.Equal(t, retryAllowed, lrw.shouldRetryLater(quotaErr, retryAllowed))
+	require.Equal(t, int64(1), lrw.metrics.ApplyRetriesQuota.Count())
+
+	// Other retried errors, and events which are not retried, are not.
+	require.Equal(t, retryAllowed, lrw.shouldRetryLater(errors.New("contention"), retryAllowed))
+	require.Equal(t, tooOld, lrw.shouldRetryLater(quotaErr, tooOld))
+	require.Equal(t, int64(1), lrw.metrics.ApplyRetriesQuota.Count())
+}
+
+// failedSubscription is a subscription which fails with err once it starts.
+type failedSubscription struct {
+	err error
+}
+
+var _ streamclient.Subscription = failedSubscription{}
+
+func (s failedSubscription) Subscribe(context.Context) error   { return s.err }
+func (s failedSubscription) Events() <-chan crosscluster.Event { return nil }
+func (s failedSubscription) Err() error                        { return s.err }
+
+func TestSourceRangefeedRestarts(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	lrw := &logicalReplicationWriterProcessor{
+		metrics: MakeMetrics(0).(*Metrics),
+		errCh:   make(chan error, 1),
+	}
+
+	// A subscription which fails is restarted by the job, and counted.
+	lrw.runSubscription(context.Background(), failedSubscription{err: errors.New("connection reset")})
+	require.Equal(t, int64(1), lrw.metrics.SourceRangefeedRestarts.Count())
+	require.Equal(t, int64(0), lrw.metrics.ActivePartitions.Value())
+	require.ErrorContains(t, <-lrw.errCh, "connection reset")
+
+	// A subscription cancelled by the processor closing is not.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	lrw.runSubscription(ctx, failedSubscription{err: ctx.Err()})
+	require.Equal(t, int64(1), lrw.metrics.SourceRangefeedRestarts.Count())
+	<-lrw.errCh
+}
+
+func TestSourceTxns(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ev := func(pk int64, wall int64) streampb.StreamEvent_KV {
+		key := encoding.EncodeVarintAscending(keys.SystemSQLCodec.IndexPrefix(104, 1), pk)
+		kv := streampb.StreamEvent_KV{KeyValue: roachpb.KeyValue{Key: keys.MakeFamilyKey(key, 0)}}
+		kv.KeyValue.Value.Timestamp = hlc.Timestamp{WallTime: wall}
+		return kv
+	}
+
+	txns := makeSourceTxns()
+	// Batches of two events: [1@1 2@1] [3@2 4@3] [5@3].
+	txns.addChunk([]streampb.StreamEvent_KV{ev(1, 1), ev(2, 1), ev(3, 2), ev(4, 3), ev(5, 3)}, 2)
+	// A second chunk, applied by another worker: [6@1].
+	txns.addChunk([]streampb.StreamEvent_KV{ev(6, 1)}, 2)
+	require.Equal(t, int64(3), txns.applied(nil))
+	// Transactions 1 and 3 were both applied in two batches.
+	require.Equal(t, int64(2), txns.splits())
+	// Transaction 3 is not fully processed, however many of its events are.
+	require.Equal(t, int64(2), txns.applied([]streampb.StreamEvent_KV{ev(4, 3), ev(5, 3)}))
+	require.Equal(t, int64(2), txns.splits())
+}
+
+// conflictingRowProcessor is a RowProcessor which reports that the rows with a
+// key starting with "c" both failed their optimistic insert and fell back to a
+// KV write.
+type conflictingRowProcessor struct{}
+
+var _ RowProcessor = conflictingRowProcessor{}
+
+func (conflictingRowProcessor) ProcessRow(
+	_ context.Context, _ isql.Txn, kv roachpb.KeyValue, _ roachpb.Value,
+) (batchStats, error) {
+	if kv.Key[0] == 'c' {
+		return batchStats{optimisticInsertConflicts: 1, kvWriteFallbacks: 1}, nil
+	}
+	return batchStats{}, nil
+}
+func (conflictingRowProcessor) GetLastRow() cdcevent.Row            { return cdcevent.Row{} }
+func (conflictingRowProcessor) SetSyntheticFailurePercent(_ uint32) {}
+func (conflictingRowProcessor) Close(context.Context)               {}
+
+func TestBatchConflictPercent(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	lrw := &logicalReplicationWriterProcessor{
+		metrics:      MakeMetrics(0).(*Metrics),
+		getBatchSize: func() int { return 1 },
+		memAcc:       *mon.NewStandaloneUnlimitedAccount(),
+	}
+	lrw.bh = []BatchHandler{&txnBatch{rp: conflictingRowProcessor{}}}
+
+	_, _, err := lrw.flushBuffer(ctx, []streampb.StreamEvent_KV{skv("a"), skv("c")},
+		false /* isRetry */, retryAllowed, timeutil.Now(), time.Time{})
+	require.NoError(t, err)
+
+	// The conflicting row is counted once, though it both failed its optimistic
+	// insert and fell back to a KV write.
+	h := lrw.metrics.BatchConflictPercent.ToPrometheusMetric().Histogram
+	require.Equal(t, uint64(2), h.GetSampleCount())
+	require.Equal(t, float64(100), h.GetSampleSum())
+}
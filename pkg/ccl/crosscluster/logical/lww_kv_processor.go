@@ -18,16 +18,21 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/kv"
 	"github.com/cockroachdb/cockroach/pkg/kv/kvpb"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/lease"
 	"github.com/cockroachdb/cockroach/pkg/sql/execinfra"
 	"github.com/cockroachdb/cockroach/pkg/sql/isql"
 	"github.com/cockroachdb/cockroach/pkg/sql/row"
+	"github.com/cockroachdb/cockroach/pkg/sql/rowinfra"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/eval"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
 	"github.com/cockroachdb/cockroach/pkg/util/admission/admissionpb"
+	"github.com/cockroachdb/cockroach/pkg/util/buildutil"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
 	"github.com/cockroachdb/errors"
 )
 
@@ -42,6 +47,8 @@ type kvRowProcessor struct {
 
 	dstBySrc map[descpb.ID]descpb.ID
 	writers  map[descpb.ID]*kvTableWriter
+	// metrics, if set, records refetches of the full destination row.
+	metrics *Metrics
 
 	failureInjector
 }
@@ -53,6 +60,7 @@ func newKVRowProcessor(
 	cfg *execinfra.ServerConfig,
 	evalCtx *eval.Context,
 	procConfigByDestID map[descpb.ID]sqlProcessorTableConfig,
+	metrics *Metrics,
 ) (*kvRowProcessor, error) {
 	cdcEventTargets := changefeedbase.Targets{}
 	srcTablesBySrcID := make(map[descpb.ID]catalog.TableDescriptor, len(procConfigByDestID))
@@ -83,12 +91,15 @@ func newKVRowProcessor(
 		writers:  make(map[descpb.ID]*kvTableWriter, len(procConfigByDestID)),
 		decoder:  cdcevent.NewEventDecoderWithCache(ctx, rfCache, false, false),
 		alloc:    &tree.DatumAlloc{},
+		metrics:  metrics,
 	}
 	return p, nil
 }
 
 var originID1Options = &kvpb.WriteOptions{OriginID: 1}
 
+var errTypeCoercionFailed = errors.New("value could not be coerced to the type of its destination column")
+
 func (p *kvRowProcessor) ProcessRow(
 	ctx context.Context, txn isql.Txn, keyValue roachpb.KeyValue, prevValue roachpb.Value,
 ) (batchStats, error) {
@@ -109,10 +120,7 @@ func (p *kvRowProcessor) ProcessRow(
 		return batchStats{}, err
 	}
 
-	if err := p.processParsedRow(ctx, txn, row, keyValue, prevValue, 0); err != nil {
-		return batchStats{}, err
-	}
-	return batchStats{}, nil
+	return p.processParsedRow(ctx, txn, row, keyValue, prevValue, 0)
 
 }
 
@@ -120,6 +128,10 @@ func (p *kvRowProcessor) ProcessRow(
 // ConditionFailedError with HadNewerOriginTimetamp=true.
 const maxRefreshCount = 10
 
+// processParsedRow applies row and returns the approximate size of the KV
+// writes which applied it, which is zero if the row lost to a newer write, in
+// which case it is counted as dropped as stale instead, or had already been
+// applied, in which case it is counted as deduplicated.
 func (p *kvRowProcessor) processParsedRow(
 	ctx context.Context,
 	txn isql.Txn,
@@ -127,10 +139,10 @@ func (p *kvRowProcessor) processParsedRow(
 	k roachpb.KeyValue,
 	prevValue roachpb.Value,
 	refreshCount int,
-) error {
+) (batchStats, error) {
 	dstTableID, ok := p.dstBySrc[row.TableID]
 	if !ok {
-		return errors.AssertionFailedf("replication configuration missing for table %d / %q", row.TableID, row.TableName)
+		return batchStats{}, errors.AssertionFailedf("replication configuration missing for table %d / %q", row.TableID, row.TableName)
 	}
 
 	makeBatch := func(txn *kv.Txn) *kv.Batch {
@@ -138,16 +150,28 @@ func (p *kvRowProcessor) processParsedRow(
 		b.Header.WriteOptions = originID1Options
 		b.AdmissionHeader.Priority = int32(admissionpb.BulkLowPri)
 		b.AdmissionHeader.Source = kvpb.AdmissionHeader_FROM_SQL
+		b.Header.ProposalQuotaMaxWaiters = applyProposalQuotaMaxWaiters.Get(&p.evalCtx.Settings.SV)
 		return b
 	}
 
 	if txn == nil {
+		var writeBytes, typeCoerced, noChange int64
+		start := timeutil.Now()
 		if err := p.cfg.DB.KV().Txn(ctx, func(ctx context.Context, txn *kv.Txn) error {
 			b := makeBatch(txn)
 
-			if err := p.addToBatch(ctx, txn, b, dstTableID, row, k, prevValue); err != nil {
+			coerced, unchanged, err := p.addToBatch(ctx, txn, b, dstTableID, row, k, prevValue)
+			if err != nil {
 				return err
 			}
+			typeCoerced, noChange = 0, 0
+			if coerced {
+				typeCoerced = 1
+			}
+			if unchanged {
+				noChange = 1
+			}
+			writeBytes = int64(b.ApproximateMutationBytes())
 			return txn.CommitInBatch(ctx, b)
 		}); err != nil {
 			if condErr := (*kvpb.ConditionFailedError)(nil); errors.As(err, &condErr) {
@@ -155,7 +179,14 @@ func (p *kvRowProcessor) processParsedRow(
 				// loser. We ignore the error and move onto the next row row we have
 				// to process.
 				if condErr.OriginTimestampOlderThan.IsSet() {
-					return nil
+					// The origin timestamp of the destination row identifies the
+					// event which wrote it, so if it is this event's then this is a
+					// replay of an event which was already applied, e.g. after the
+					// job resumed from an earlier checkpoint.
+					if condErr.OriginTimestampOlderThan == row.MvccTimestamp {
+						return batchStats{deduplicated: 1, typeCoerced: typeCoerced}, nil
+					}
+					return batchStats{droppedStale: 1, typeCoerced: typeCoerced}, nil
 				}
 				// If HadNewerOriginTimestamp is true, it implies that the row we
 				// are processing was the LWW winner but the previous value from the
@@ -176,7 +207,13 @@ func (p *kvRowProcessor) processParsedRow(
 					// running forever in the case of some bug in the above
 					// reasoning or our ConditionalPut code.
 					if refreshCount > maxRefreshCount {
-						return errors.Wrapf(err, "max refresh count (%d) reached", maxRefreshCount)
+						return batchStats{}, errors.Wrapf(err, "max refresh count (%d) reached", maxRefreshCount)
+					}
+					if p.metrics != nil {
+						// The failed attempt is what fetched the full row, so its
+						// duration is the latency of the refetch.
+						p.metrics.FullRowRefetches.Inc(1)
+						p.metrics.FullRowRefetchLatency.RecordValue(timeutil.Since(start).Nanoseconds())
 					}
 					var refreshedValue roachpb.Value
 					if condErr.ActualValue != nil {
@@ -185,9 +222,9 @@ func (p *kvRowProcessor) processParsedRow(
 					return p.processParsedRow(ctx, txn, row, k, refreshedValue, refreshCount+1)
 				}
 			}
-			return err
+			return batchStats{}, err
 		}
-		return nil
+		return batchStats{writeBytes: writeBytes, typeCoerced: typeCoerced, noChange: noChange}, nil
 	}
 	// TODO(ssd,dt): There are two levels of batching we may care about: putting multiple
 	// batches (each generated by 1 row) into a single transaction or putting multiple rows into
@@ -206,9 +243,13 @@ func (p *kvRowProcessor) processParsedRow(
 	// But, even then, since a LWW failure often means we are now processing duplicates, we may
 	// want batch handling with a bit of hysteresis that prevents constantly building
 	// multi-batch transactions that are likely to fail.
-	return errors.AssertionFailedf("TODO: multi-row transactions not supported by the kvRowProcessor")
+	return batchStats{}, errors.AssertionFailedf("TODO: multi-row transactions not supported by the kvRowProcessor")
 }
 
+// addToBatch adds the writes applying row to b, returning whether any of the
+// values written had to be coerced to the type of their destination column,
+// and whether row is an update which leaves all of the values of the
+// destination row as they were.
 func (p *kvRowProcessor) addToBatch(
 	ctx context.Context,
 	txn *kv.Txn,
@@ -217,15 +258,15 @@ func (p *kvRowProcessor) addToBatch(
 	row cdcevent.Row,
 	keyValue roachpb.KeyValue,
 	prevValue roachpb.Value,
-) error {
+) (coerced, unchanged bool, _ error) {
 	w, err := p.getWriter(ctx, dstTableID, txn.ProvisionalCommitTimestamp())
 	if err != nil {
-		return err
+		return false, false, err
 	}
 	// This batch should only commit if it can do so prior to the expiration of
 	// the lease of the descriptor used to encode it.
 	if err := txn.UpdateDeadline(ctx, w.leased.Expiration(ctx)); err != nil {
-		return err
+		return false, false, err
 	}
 
 	prevRow, err := p.decoder.DecodeKV(ctx, roachpb.KeyValue{
@@ -233,26 +274,27 @@ func (p *kvRowProcessor) addToBatch(
 		Value: prevValue,
 	}, cdcevent.PrevRow, prevValue.Timestamp, false)
 	if err != nil {
-		return err
+		return false, false, err
 	}
 
+	w.coerced, w.unchanged = false, false
 	if row.IsDeleted() {
 		if err := w.deleteRow(ctx, b, prevRow, row); err != nil {
-			return err
+			return false, false, err
 		}
 	} else {
 		if prevValue.IsPresent() {
 			if err := w.updateRow(ctx, b, prevRow, row); err != nil {
-				return err
+				return false, false, err
 			}
 		} else {
 			if err := w.insertRow(ctx, b, row); err != nil {
-				return err
+				return false, false, err
 			}
 		}
 	}
 
-	return nil
+	return w.coerced, w.unchanged, nil
 }
 
 // GetLastRow implements the RowProcessor interface.
@@ -297,7 +339,7 @@ func (p *kvRowProcessor) getWriter(
 	}
 
 	// New lease and desc version; make a new writer.
-	w, err = newKVTableWriter(ctx, l, p.alloc, p.evalCtx)
+	w, err = newKVTableWriter(ctx, l, p.alloc, p.evalCtx, p.cfg.InternalRowMetrics)
 	if err != nil {
 		return nil, err
 	}
@@ -316,10 +358,41 @@ type kvTableWriter struct {
 	ru               row.Updater
 	ri               row.Inserter
 	rd               row.Deleter
+
+	// cols are the destination columns of newVals and oldVals.
+	cols    []catalog.Column
+	evalCtx *eval.Context
+	// coerced is set if a value of the row being written had to be coerced to
+	// the type of its destination column.
+	coerced bool
+	// unchanged is set if the row being written is an update which has the
+	// same values as the row it replaces. The update is still applied, as it
+	// advances the origin timestamp of the row.
+	unchanged bool
 }
 
+// newKVTableWriter returns a kvTableWriter for the leased table. rowMetrics, if
+// set, counts the writes made through it, see rowinfra.Metrics.
+var kvValueCodec = settings.RegisterEnumSetting(
+	settings.ApplicationLevel,
+	"logical_replication.consumer.kv_value_codec",
+	"the format in which the kv writer encodes the values of replicated rows: current uses "+
+		"the encoding of this version, legacy_untagged stores column families other than "+
+		"family 0 as single untagged values wherever possible, to match the values written by "+
+		"older versions during mixed-version replication",
+	"current",
+	map[row.ValueCodecVersion]string{
+		row.ValueCodecCurrent:        "current",
+		row.ValueCodecLegacyUntagged: "legacy_untagged",
+	},
+)
+
 func newKVTableWriter(
-	ctx context.Context, leased lease.LeasedDescriptor, a *tree.DatumAlloc, evalCtx *eval.Context,
+	ctx context.Context,
+	leased lease.LeasedDescriptor,
+	a *tree.DatumAlloc,
+	evalCtx *eval.Context,
+	rowMetrics *rowinfra.Metrics,
 ) (*kvTableWriter, error) {
 
 	tableDesc := leased.Underlying().(catalog.TableDescriptor)
@@ -335,26 +408,51 @@ func newKVTableWriter(
 
 	// TODO(dt): pass these some sort fo flag to have them use versions of CPut
 	// or a new LWW KV API. For now they're not detecting/handling conflicts.
-	ri, err := row.MakeInserter(ctx, nil, evalCtx.Codec, tableDesc, writeCols, a, &evalCtx.Settings.SV, internal, nil)
+	ri, err := row.MakeInserter(ctx, nil, evalCtx.Codec, tableDesc, writeCols, a, &evalCtx.Settings.SV, internal, rowMetrics)
 	if err != nil {
 		return nil, err
 	}
-	rd := row.MakeDeleter(evalCtx.Codec, tableDesc, readCols, &evalCtx.Settings.SV, internal, nil)
+	rd := row.MakeDeleter(evalCtx.Codec, tableDesc, readCols, &evalCtx.Settings.SV, internal, rowMetrics)
 	ru, err := row.MakeUpdater(
-		ctx, nil, evalCtx.Codec, tableDesc, readCols, writeCols, row.UpdaterDefault, a, &evalCtx.Settings.SV, internal, nil,
+		ctx, nil, evalCtx.Codec, tableDesc, readCols, writeCols, row.UpdaterDefault, a, &evalCtx.Settings.SV, internal, rowMetrics,
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	return &kvTableWriter{
+	// The expected values of the CPuts must be encoded in the same format as
+	// the rows were written, so the codec must also be used for deletes.
+	codec := kvValueCodec.Get(&evalCtx.Settings.SV)
+	ri.Helper.ValueCodec = codec
+	rd.Helper.ValueCodec = codec
+	ru.SetValueCodec(codec)
+
+	w := &kvTableWriter{
 		leased:  leased,
 		oldVals: make([]tree.Datum, len(readCols)),
 		newVals: make([]tree.Datum, len(writeCols)),
+		cols:    writeCols,
+		evalCtx: evalCtx,
 		ri:      ri,
 		rd:      rd,
 		ru:      ru,
-	}, nil
+	}
+	w.ru.WrapPutter = w.wrapPutter
+	return w, nil
+}
+
+// wrapPutter returns the Putter to write a row to instead of p. Under test
+// builds, it verifies that the column families of the row are written in the
+// order which the origin timestamp conditional puts rely on.
+func (p *kvTableWriter) wrapPutter(putter row.Putter) row.Putter {
+	if !buildutil.CrdbTestBuild {
+		return putter
+	}
+	return &row.FamilyOrderPutter{
+		Putter: putter,
+		Codec:  p.evalCtx.Codec,
+		Table:  p.leased.Underlying().(catalog.TableDescriptor),
+	}
 }
 
 // writeableColumns are 'writable' in the sense that they are stored on disk in the primary index.
@@ -381,7 +479,7 @@ func writeableColunms(ctx context.Context, td catalog.TableDescriptor) ([]catalo
 }
 
 func (p *kvTableWriter) insertRow(ctx context.Context, b *kv.Batch, after cdcevent.Row) error {
-	if err := p.fillNew(after); err != nil {
+	if err := p.fillNew(ctx, after); err != nil {
 		return err
 	}
 
@@ -393,18 +491,21 @@ func (p *kvTableWriter) insertRow(ctx context.Context, b *kv.Batch, after cdceve
 		// and destination clusters.
 		ShouldWinTie: true,
 	}
-	return p.ri.InsertRow(ctx, &row.KVBatchAdapter{Batch: b}, p.newVals, ph, oth, false, false)
+	return p.ri.InsertRow(ctx, p.wrapPutter(&row.KVBatchAdapter{Batch: b}), p.newVals, ph, oth, false, false)
 }
 
 func (p *kvTableWriter) updateRow(
 	ctx context.Context, b *kv.Batch, before, after cdcevent.Row,
 ) error {
-	if err := p.fillOld(before); err != nil {
+	if err := p.fillOld(ctx, before); err != nil {
 		return err
 	}
-	if err := p.fillNew(after); err != nil {
+	if err := p.fillNew(ctx, after); err != nil {
 		return err
 	}
+	// The conditional puts of the update only succeed if the destination row
+	// has the values of before, so those are the values it is compared to.
+	p.unchanged = p.valuesUnchanged(ctx)
 
 	var ph row.PartialIndexUpdateHelper
 	// TODO(dt): support partial indexes.
@@ -421,7 +522,7 @@ func (p *kvTableWriter) updateRow(
 func (p *kvTableWriter) deleteRow(
 	ctx context.Context, b *kv.Batch, before, after cdcevent.Row,
 ) error {
-	if err := p.fillOld(before); err != nil {
+	if err := p.fillOld(ctx, before); err != nil {
 		return err
 	}
 
@@ -436,10 +537,14 @@ func (p *kvTableWriter) deleteRow(
 	return p.rd.DeleteRow(ctx, b, p.oldVals, ph, oth, false)
 }
 
-func (p *kvTableWriter) fillOld(vals cdcevent.Row) error {
+func (p *kvTableWriter) fillOld(ctx context.Context, vals cdcevent.Row) error {
 	p.oldVals = p.oldVals[:0]
 	if err := vals.ForAllColumns().Datum(func(d tree.Datum, col cdcevent.ResultColumn) error {
 		// TODO(dt): add indirection from col ID to offset.
+		d, err := p.coerce(ctx, len(p.oldVals), d, col.Typ)
+		if err != nil {
+			return err
+		}
 		p.oldVals = append(p.oldVals, d)
 		return nil
 	}); err != nil {
@@ -448,9 +553,13 @@ func (p *kvTableWriter) fillOld(vals cdcevent.Row) error {
 	return nil
 }
 
-func (p *kvTableWriter) fillNew(vals cdcevent.Row) error {
+func (p *kvTableWriter) fillNew(ctx context.Context, vals cdcevent.Row) error {
 	p.newVals = p.newVals[:0]
 	if err := vals.ForAllColumns().Datum(func(d tree.Datum, col cdcevent.ResultColumn) error {
+		d, err := p.coerce(ctx, len(p.newVals), d, col.Typ)
+		if err != nil {
+			return err
+		}
 		p.newVals = append(p.newVals, d)
 		return nil
 	}); err != nil {
@@ -458,3 +567,43 @@ func (p *kvTableWriter) fillNew(vals cdcevent.Row) error {
 	}
 	return nil
 }
+
+// valuesUnchanged returns whether newVals are equal to oldVals. Values which
+// cannot be compared count as changed.
+func (p *kvTableWriter) valuesUnchanged(ctx context.Context) bool {
+	if len(p.oldVals) != len(p.newVals) {
+		return false
+	}
+	for i := range p.newVals {
+		if cmp, err := p.newVals[i].Compare(ctx, p.evalCtx, p.oldVals[i]); err != nil || cmp != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// coerce casts d, the value of a source column of type src, to the type of the
+// i'th destination column if the two types differ, e.g. in width or collation.
+// As the SQL writer does, it uses an assignment cast, so a value which is too
+// wide for the destination column fails rather than being truncated. Values
+// which can be cast may still be rounded so, as such differences do not fail
+// the apply, coerced tracks them so that they can be counted. A value which
+// cannot be cast fails with errTypeCoercionFailed.
+func (p *kvTableWriter) coerce(
+	ctx context.Context, i int, d tree.Datum, src *types.T,
+) (tree.Datum, error) {
+	if i >= len(p.cols) || d == tree.DNull {
+		return d, nil
+	}
+	dst := p.cols[i].GetType()
+	if src.Identical(dst) {
+		return d, nil
+	}
+	coerced, err := eval.PerformAssignmentCast(ctx, p.evalCtx, d, dst)
+	if err != nil {
+		return nil, errors.Mark(errors.Wrapf(err, "coercing %s value of column %q to %s",
+			src.SQLString(), p.cols[i].GetName(), dst.SQLString()), errTypeCoercionFailed)
+	}
+	p.coerced = true
+	return coerced, nil
+}
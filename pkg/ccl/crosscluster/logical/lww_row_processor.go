@@ -29,6 +29,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/parser/statements"
 	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
 	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/rowenc/valueside"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/catconstants"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/catid"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/eval"
@@ -38,6 +39,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/cockroach/pkg/util/metamorphic"
 	"github.com/cockroachdb/cockroach/pkg/util/randutil"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
 	"github.com/cockroachdb/errors"
 )
 
@@ -54,6 +56,9 @@ type sqlRowProcessor struct {
 	ie       isql.Executor
 	lastRow  cdcevent.Row
 
+	// scratch is reused to encode the columns counted by projectedOutBytes.
+	scratch []byte
+
 	// testing knobs.
 	failureInjector
 }
@@ -65,6 +70,8 @@ type querier interface {
 	InsertRow(ctx context.Context, txn isql.Txn, ie isql.Executor, row cdcevent.Row, prevRow *cdcevent.Row, likelyInsert bool) (batchStats, error)
 	DeleteRow(ctx context.Context, txn isql.Txn, ie isql.Executor, row cdcevent.Row, prevRow *cdcevent.Row) (batchStats, error)
 	RequiresParsedBeforeRow(catid.DescID) bool
+	// Close releases the metrics the querier holds for its tables.
+	Close()
 }
 
 type queryBuilder struct {
@@ -227,7 +234,9 @@ func makeSQLProcessorFromQuerier(
 	}, nil
 }
 
-func (*sqlRowProcessor) Close(ctx context.Context) {}
+func (srp *sqlRowProcessor) Close(ctx context.Context) {
+	srp.querier.Close()
+}
 
 var errInjected = errors.New("injected synthetic error")
 
@@ -267,7 +276,42 @@ func (srp *sqlRowProcessor) ProcessRow(
 	}
 	srp.lastRow = row
 
-	return srp.processParsedRow(ctx, txn, row, kv.Key, prevValue)
+	projectedOut, err := srp.projectedOutBytes(row)
+	if err != nil {
+		return batchStats{}, err
+	}
+	s, err := srp.processParsedRow(ctx, txn, row, kv.Key, prevValue)
+	if err != nil {
+		return batchStats{}, err
+	}
+	s.projectedOutBytes = projectedOut
+	return s, nil
+}
+
+// projectedOutBytes returns the encoded size of the values of row which are
+// dropped before apply, as the columns they are for are not inputs to the
+// apply queries: computed columns are computed by the destination itself.
+// Virtual columns are not counted, as their values are never replicated.
+func (srp *sqlRowProcessor) projectedOutBytes(row cdcevent.Row) (int64, error) {
+	if row.IsDeleted() {
+		return 0, nil
+	}
+	var size int64
+	if err := row.ForEachColumn().Datum(func(d tree.Datum, col cdcevent.ResultColumn) error {
+		if !col.Computed || d == tree.DNull {
+			return nil
+		}
+		var err error
+		srp.scratch, err = valueside.Encode(srp.scratch[:0], valueside.NoColumnID, d, nil /* scratch */)
+		if err != nil {
+			return err
+		}
+		size += int64(len(srp.scratch))
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+	return size, nil
 }
 
 func (srp *sqlRowProcessor) processParsedRow(
@@ -332,8 +376,13 @@ const (
 	replicatedApplyUDFOpName         = "replicated-apply-udf"
 )
 
-func getIEOverride(opName string, jobID jobspb.JobID) sessiondata.InternalExecutorOverride {
+func getIEOverride(
+	opName string, jobID jobspb.JobID, sv *settings.Values,
+) sessiondata.InternalExecutorOverride {
 	o := ieOverrideBase
+	if maxWaiters := applyProposalQuotaMaxWaiters.Get(sv); maxWaiters > 0 {
+		o.MultiOverride = fmt.Sprintf("ProposalQuotaMaxWaiters=%d", maxWaiters)
+	}
 	// We want the ingestion queries to show up on the SQL Activity page
 	// alongside with the foreground traffic by default. We can achieve this
 	// by using the same naming scheme as AttributeToUser feature of the IE
@@ -371,6 +420,7 @@ func makeSQLProcessor(
 	tableConfigByDestID map[descpb.ID]sqlProcessorTableConfig,
 	jobID jobspb.JobID,
 	ie isql.Executor,
+	metrics *Metrics,
 ) (*sqlRowProcessor, error) {
 
 	needUDFQuerier := false
@@ -382,17 +432,18 @@ func makeSQLProcessor(
 
 	lwwQuerier := &lwwQuerier{
 		settings: settings,
+		metrics:  metrics,
 		queryBuffer: queryBuffer{
 			deleteQueries: make(map[catid.DescID]queryBuilder, len(tableConfigByDestID)),
 			insertQueries: make(map[catid.DescID]map[catid.FamilyID]queryBuilder, len(tableConfigByDestID)),
 		},
-		ieOverrideOptimisticInsert: getIEOverride(replicatedOptimisticInsertOpName, jobID),
-		ieOverrideInsert:           getIEOverride(replicatedInsertOpName, jobID),
-		ieOverrideDelete:           getIEOverride(replicatedDeleteOpName, jobID),
+		ieOverrideOptimisticInsert: getIEOverride(replicatedOptimisticInsertOpName, jobID, &settings.SV),
+		ieOverrideInsert:           getIEOverride(replicatedInsertOpName, jobID, &settings.SV),
+		ieOverrideDelete:           getIEOverride(replicatedDeleteOpName, jobID, &settings.SV),
 	}
 	var udfQuerier querier
 	if needUDFQuerier {
-		udfQuerier = makeApplierQuerier(ctx, settings, tableConfigByDestID, jobID, ie)
+		udfQuerier = makeApplierQuerier(ctx, settings, tableConfigByDestID, jobID, ie, metrics)
 	}
 
 	return makeSQLProcessorFromQuerier(ctx, settings, tableConfigByDestID, ie, &muxQuerier{
@@ -445,6 +496,13 @@ func (m *muxQuerier) RequiresParsedBeforeRow(id catid.DescID) bool {
 	return m.shouldUseUDF[id]
 }
 
+func (m *muxQuerier) Close() {
+	m.lwwQuerier.Close()
+	if m.udfQuerier != nil {
+		m.udfQuerier.Close()
+	}
+}
+
 // lwwQuerier is a querier that implements partial
 // last-write-wins semantics using SQL queries. We assume that the table has an
 // crdb_replication_origin_timestamp column defined as:
@@ -464,6 +522,8 @@ func (m *muxQuerier) RequiresParsedBeforeRow(id catid.DescID) bool {
 type lwwQuerier struct {
 	settings    *cluster.Settings
 	queryBuffer queryBuffer
+	// metrics may be nil in tests.
+	metrics *Metrics
 
 	ieOverrideOptimisticInsert sessiondata.InternalExecutorOverride
 	ieOverrideInsert           sessiondata.InternalExecutorOverride
@@ -488,6 +548,8 @@ func (lww *lwwQuerier) RequiresParsedBeforeRow(catid.DescID) bool {
 	return false
 }
 
+func (lww *lwwQuerier) Close() {}
+
 func (lww *lwwQuerier) InsertRow(
 	ctx context.Context,
 	txn isql.Txn,
@@ -534,11 +596,27 @@ func (lww *lwwQuerier) InsertRow(
 	if err != nil {
 		return batchStats{}, err
 	}
-	if _, err = ie.ExecParsed(ctx, replicatedInsertOpName, kvTxn, lww.ieOverrideInsert, stmt, datums...); err != nil {
+	// If the optimistic insert conflicted, the pessimistic insert is an extra
+	// round trip which has to read the existing row to decide whether the
+	// incoming one wins. The read and the write are made by the same statement,
+	// so the latency recorded is that of both.
+	start := timeutil.Now()
+	rowsAffected, err := ie.ExecParsed(ctx, replicatedInsertOpName, kvTxn, lww.ieOverrideInsert, stmt, datums...)
+	if optimisticInsertConflicts > 0 && lww.metrics != nil {
+		lww.metrics.ConflictReads.Inc(1)
+		lww.metrics.ConflictResolutionLatency.RecordValue(timeutil.Since(start).Nanoseconds())
+	}
+	if err != nil {
 		log.Warningf(ctx, "replicated insert failed (query: %s): %s", stmt.SQL, err.Error())
 		return batchStats{}, err
 	}
-	return batchStats{optimisticInsertConflicts: optimisticInsertConflicts}, nil
+	s := batchStats{optimisticInsertConflicts: optimisticInsertConflicts}
+	// The upsert only leaves the row unchanged if its origin timestamp
+	// comparison found the existing row to be newer.
+	if rowsAffected == 0 {
+		s.droppedStale = 1
+	}
+	return s, nil
 }
 
 func (lww *lwwQuerier) DeleteRow(
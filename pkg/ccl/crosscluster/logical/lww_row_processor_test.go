@@ -11,28 +11,42 @@ package logical
 import (
 	"context"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/cockroachdb/cockroach/pkg/base"
 	"github.com/cockroachdb/cockroach/pkg/ccl/crosscluster/replicationtestutils"
 	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvpb"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/kvserverbase"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/sql"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descs"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/desctestutils"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/lease"
 	"github.com/cockroachdb/cockroach/pkg/sql/execinfra"
 	"github.com/cockroachdb/cockroach/pkg/sql/isql"
+	"github.com/cockroachdb/cockroach/pkg/sql/row"
+	"github.com/cockroachdb/cockroach/pkg/sql/rowenc/valueside"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/eval"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
 	"github.com/cockroachdb/cockroach/pkg/testutils"
 	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
 	"github.com/cockroachdb/cockroach/pkg/testutils/skip"
 	"github.com/cockroachdb/cockroach/pkg/testutils/sqlutils"
+	"github.com/cockroachdb/cockroach/pkg/util/buildutil"
+	"github.com/cockroachdb/cockroach/pkg/util/encoding"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/cockroachdb/errors"
 	"github.com/stretchr/testify/require"
 )
 
@@ -92,7 +106,7 @@ func TestLWWInsertQueryGeneration(t *testing.T) {
 			dstDesc.GetID(): {
 				srcDesc: srcDesc,
 			},
-		}, jobspb.JobID(1), s.InternalExecutor().(isql.Executor))
+		}, jobspb.JobID(1), s.InternalExecutor().(isql.Executor), nil /* metrics */)
 		require.NoError(t, err)
 		return rp, func(datums ...interface{}) roachpb.KeyValue {
 			kv := replicationtestutils.EncodeKV(t, s.Codec(), srcDesc, datums...)
@@ -134,6 +148,103 @@ func TestLWWInsertQueryGeneration(t *testing.T) {
 	}
 }
 
+func TestSQLRowProcessorProjectedOutBytes(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+	ctx := context.Background()
+
+	srv, sqlDB, _ := serverutils.StartServer(t, base.TestServerArgs{})
+	defer srv.Stopper().Stop(ctx)
+	s := srv.ApplicationLayer()
+
+	runner := sqlutils.MakeSQLRunner(sqlDB)
+	for _, tableName := range []string{"src", "dst"} {
+		runner.Exec(t, fmt.Sprintf(
+			`CREATE TABLE %s (pk INT PRIMARY KEY, payload STRING, doubled STRING AS (payload || payload) STORED)`,
+			tableName))
+		runner.Exec(t, fmt.Sprintf("ALTER TABLE %s "+lwwColumnAdd, tableName))
+	}
+	srcDesc := desctestutils.TestingGetPublicTableDescriptor(s.DB(), s.Codec(), "defaultdb", "src")
+	dstDesc := desctestutils.TestingGetPublicTableDescriptor(s.DB(), s.Codec(), "defaultdb", "dst")
+	rp, err := makeSQLProcessor(ctx, s.ClusterSettings(), map[descpb.ID]sqlProcessorTableConfig{
+		dstDesc.GetID(): {
+			srcDesc: srcDesc,
+		},
+	}, jobspb.JobID(1), s.InternalExecutor().(isql.Executor), nil /* metrics */)
+	require.NoError(t, err)
+
+	// Only the value of the computed column is dropped.
+	expected, err := valueside.Encode(nil, valueside.NoColumnID, tree.NewDString("hellohello"), nil)
+	require.NoError(t, err)
+
+	keyValue := replicationtestutils.EncodeKV(t, s.Codec(), srcDesc, 1, "hello", "hellohello")
+	keyValue.Value.Timestamp = hlc.Timestamp{WallTime: timeutil.Now().UnixNano()}
+	stats, err := rp.ProcessRow(ctx, nil /* txn */, keyValue, roachpb.Value{})
+	require.NoError(t, err)
+	require.Equal(t, int64(len(expected)), stats.projectedOutBytes)
+	runner.CheckQueryResults(t, "SELECT pk, payload, doubled FROM dst", [][]string{{"1", "hello", "hellohello"}})
+
+	// Deletes carry no values to drop.
+	prevValue := keyValue.Value
+	keyValue.Value.RawBytes = nil
+	keyValue.Value.Timestamp = hlc.Timestamp{WallTime: timeutil.Now().UnixNano()}
+	stats, err = rp.ProcessRow(ctx, nil /* txn */, keyValue, prevValue)
+	require.NoError(t, err)
+	require.Zero(t, stats.projectedOutBytes)
+}
+
+// TestSQLRowProcessorConflictReads verifies that a row update which conflicts
+// with an existing row, and so has to read it to resolve the conflict, is
+// counted along with the latency of resolving it.
+func TestSQLRowProcessorConflictReads(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+	ctx := context.Background()
+
+	srv, sqlDB, _ := serverutils.StartServer(t, base.TestServerArgs{})
+	defer srv.Stopper().Stop(ctx)
+	s := srv.ApplicationLayer()
+	tryOptimisticInsertEnabled.Override(ctx, &s.ClusterSettings().SV, true)
+
+	runner := sqlutils.MakeSQLRunner(sqlDB)
+	for _, tableName := range []string{"src", "dst"} {
+		runner.Exec(t, fmt.Sprintf(`CREATE TABLE %s (pk INT PRIMARY KEY, payload STRING)`, tableName))
+		runner.Exec(t, fmt.Sprintf("ALTER TABLE %s "+lwwColumnAdd, tableName))
+	}
+	srcDesc := desctestutils.TestingGetPublicTableDescriptor(s.DB(), s.Codec(), "defaultdb", "src")
+	dstDesc := desctestutils.TestingGetPublicTableDescriptor(s.DB(), s.Codec(), "defaultdb", "dst")
+	metrics := MakeMetrics(time.Minute).(*Metrics)
+	rp, err := makeSQLProcessor(ctx, s.ClusterSettings(), map[descpb.ID]sqlProcessorTableConfig{
+		dstDesc.GetID(): {
+			srcDesc: srcDesc,
+		},
+	}, jobspb.JobID(1), s.InternalExecutor().(isql.Executor), metrics)
+	require.NoError(t, err)
+
+	insert := func(payload string) {
+		keyValue := replicationtestutils.EncodeKV(t, s.Codec(), srcDesc, 1, payload)
+		keyValue.Value.Timestamp = hlc.Timestamp{WallTime: timeutil.Now().UnixNano()}
+		_, err := rp.ProcessRow(ctx, nil /* txn */, keyValue, roachpb.Value{})
+		require.NoError(t, err)
+	}
+	resolutions := func() int64 {
+		count, _ := metrics.ConflictResolutionLatency.CumulativeSnapshot().Total()
+		return count
+	}
+
+	// The first insert of the row is applied by the optimistic insert.
+	insert("hello")
+	require.Zero(t, metrics.ConflictReads.Count())
+	require.Zero(t, resolutions())
+
+	// An insert of the same row on the source conflicts with it, and is applied
+	// by the upsert which reads it.
+	insert("world")
+	require.Equal(t, int64(1), metrics.ConflictReads.Count())
+	require.Equal(t, int64(1), resolutions())
+	runner.CheckQueryResults(t, "SELECT pk, payload FROM dst", [][]string{{"1", "world"}})
+}
+
 func BenchmarkLWWInsertBatch(b *testing.B) {
 	defer leaktest.AfterTest(b)()
 	defer log.Scope(b).Close(b)
@@ -161,7 +272,7 @@ func BenchmarkLWWInsertBatch(b *testing.B) {
 		desc.GetID(): {
 			srcDesc: desc,
 		},
-	}, jobspb.JobID(1), s.InternalDB().(isql.DB).Executor(isql.WithSessionData(sd)))
+	}, jobspb.JobID(1), s.InternalDB().(isql.DB).Executor(isql.WithSessionData(sd)), nil /* metrics */)
 	require.NoError(b, err)
 
 	// In some configs, we'll be simulating processing the same INSERT over and
@@ -351,7 +462,7 @@ func TestLWWConflictResolution(t *testing.T) {
 			dstDesc.GetID(): {
 				srcDesc: srcDesc,
 			},
-		}, jobspb.JobID(1), s.InternalExecutor().(isql.Executor))
+		}, jobspb.JobID(1), s.InternalExecutor().(isql.Executor), nil /* metrics */)
 		require.NoError(t, err)
 
 		if useKVProc {
@@ -366,7 +477,7 @@ func TestLWWConflictResolution(t *testing.T) {
 					dstDesc.GetID(): {
 						srcDesc: srcDesc,
 					},
-				})
+				}, nil /* metrics */)
 			require.NoError(t, err)
 		}
 		return tableNameDst, rp, func(originTimestamp hlc.Timestamp, datums ...interface{}) roachpb.KeyValue {
@@ -597,6 +708,56 @@ func TestLWWConflictResolution(t *testing.T) {
 				runner.CheckQueryResults(t, fmt.Sprintf("SELECT * from %s", tableNameDst), expectedRows)
 			})
 
+			t.Run("remote-update-replayed", func(t *testing.T) {
+				if !useKVProc {
+					skip.IgnoreLint(t, "the SQL processor cannot tell replayed events from stale ones")
+				}
+				tableNameDst, rp, encoder := setup(t, useKVProc)
+
+				keyValue1 := encoder(timeNow, row1...)
+				require.NoError(t, insertRow(rp, keyValue1, roachpb.Value{}))
+				keyValue2 := encoder(timeOneDayForward, row2...)
+				require.NoError(t, insertRow(rp, keyValue2, keyValue1.Value))
+
+				// Replaying the latest event is recognized as already applied,
+				// whereas replaying an older one is dropped as stale.
+				stats, err := rp.ProcessRow(ctx, nil, keyValue2, keyValue1.Value)
+				require.NoError(t, err)
+				require.Equal(t, batchStats{deduplicated: 1}, stats)
+				stats, err = rp.ProcessRow(ctx, nil, keyValue1, roachpb.Value{})
+				require.NoError(t, err)
+				require.Equal(t, batchStats{droppedStale: 1}, stats)
+
+				expectedRows := [][]string{
+					{"1", "row2"},
+				}
+				runner.CheckQueryResults(t, fmt.Sprintf("SELECT * from %s", tableNameDst), expectedRows)
+			})
+
+			t.Run("remote-update-no-change", func(t *testing.T) {
+				if !useKVProc {
+					skip.IgnoreLint(t, "the SQL processor does not know the values of the destination row")
+				}
+				_, rp, encoder := setup(t, useKVProc)
+
+				keyValue1 := encoder(timeNow, row1...)
+				stats, err := rp.ProcessRow(ctx, nil, keyValue1, roachpb.Value{})
+				require.NoError(t, err)
+				require.Zero(t, stats.noChange)
+
+				// An update which re-saves the row with the same values is
+				// applied, but counted as a no-op.
+				keyValue2 := encoder(timeNowPlusOne, row1...)
+				stats, err = rp.ProcessRow(ctx, nil, keyValue2, keyValue1.Value)
+				require.NoError(t, err)
+				require.Equal(t, int64(1), stats.noChange)
+
+				keyValue3 := encoder(timeOneDayForward, row2...)
+				stats, err = rp.ProcessRow(ctx, nil, keyValue3, keyValue2.Value)
+				require.NoError(t, err)
+				require.Zero(t, stats.noChange)
+			})
+
 			// From the perspective of the row processor, once the first row is processed, the next incoming event from the
 			// remote rangefeed should have a "previous row" that matches the row currently in the local table. If writes on
 			// the local and remote table occur too close together, both tables will attempt to propagate to the other, and
@@ -630,3 +791,201 @@ func TestLWWConflictResolution(t *testing.T) {
 		})
 	})
 }
+
+func TestKVTableWriterCoerce(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+	ctx := context.Background()
+
+	srv, sqlDB, _ := serverutils.StartServer(t, base.TestServerArgs{})
+	defer srv.Stopper().Stop(ctx)
+	s := srv.ApplicationLayer()
+
+	runner := sqlutils.MakeSQLRunner(sqlDB)
+	runner.Exec(t, `CREATE TABLE dst (pk INT PRIMARY KEY, s VARCHAR(3), i INT2)`)
+	dstDesc := desctestutils.TestingGetPublicTableDescriptor(s.DB(), s.Codec(), "defaultdb", "dst")
+	cols, err := writeableColunms(ctx, dstDesc)
+	require.NoError(t, err)
+
+	w := &kvTableWriter{cols: cols, evalCtx: &eval.Context{
+		Codec:    s.Codec(),
+		Settings: s.ClusterSettings(),
+	}}
+
+	// Values of identical types, and NULLs, are written as is.
+	d, err := w.coerce(ctx, 0, tree.NewDInt(1), types.Int)
+	require.NoError(t, err)
+	require.Equal(t, tree.NewDInt(1), d)
+	d, err = w.coerce(ctx, 1, tree.DNull, types.String)
+	require.NoError(t, err)
+	require.Equal(t, tree.DNull, d)
+	require.False(t, w.coerced)
+
+	// A value of a wider source column which fits the destination's width is
+	// coerced.
+	d, err = w.coerce(ctx, 1, tree.NewDString("abc"), types.String)
+	require.NoError(t, err)
+	require.Equal(t, tree.NewDString("abc"), d)
+	require.True(t, w.coerced)
+
+	// Values which do not fit the destination column, whether too wide or out
+	// of range, are not truncated, and not retried either.
+	var lrw logicalReplicationWriterProcessor
+	for _, tc := range []struct {
+		col int
+		d   tree.Datum
+		src *types.T
+	}{
+		{col: 1, d: tree.NewDString("abcdef"), src: types.String},
+		{col: 2, d: tree.NewDInt(1 << 20), src: types.Int},
+	} {
+		_, err = w.coerce(ctx, tc.col, tc.d, tc.src)
+		require.True(t, errors.Is(err, errTypeCoercionFailed), "%v", err)
+		require.Equal(t, errType, lrw.shouldRetryLater(err, retryAllowed))
+	}
+}
+
+func TestKVTableWriterFamilyOrder(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+	ctx := context.Background()
+
+	if !buildutil.CrdbTestBuild {
+		skip.IgnoreLint(t, "the column family order is only verified under test builds")
+	}
+
+	srv, sqlDB, _ := serverutils.StartServer(t, base.TestServerArgs{})
+	defer srv.Stopper().Stop(ctx)
+	s := srv.ApplicationLayer()
+
+	// Tables with multiple column families can't be replicated yet, but the
+	// writer must already write their families in order.
+	runner := sqlutils.MakeSQLRunner(sqlDB)
+	runner.Exec(t, `CREATE TABLE dst (pk INT PRIMARY KEY, a INT, b INT, FAMILY f0 (pk, a), FAMILY f1 (b))`)
+	dstDesc := desctestutils.TestingGetPublicTableDescriptor(s.DB(), s.Codec(), "defaultdb", "dst")
+	leased, err := s.LeaseManager().(*lease.Manager).Acquire(ctx, s.Clock().Now(), dstDesc.GetID())
+	require.NoError(t, err)
+	defer leased.Release(ctx)
+	w, err := newKVTableWriter(ctx, leased, &tree.DatumAlloc{}, &eval.Context{
+		Codec:    s.Codec(),
+		Settings: s.ClusterSettings(),
+	}, nil /* rowMetrics */)
+	require.NoError(t, err)
+
+	oth := &row.OriginTimestampCPutHelper{OriginTimestamp: s.Clock().Now(), ShouldWinTie: true}
+	oldVals := tree.Datums{tree.NewDInt(1), tree.NewDInt(2), tree.NewDInt(3)}
+	newVals := tree.Datums{tree.NewDInt(1), tree.NewDInt(4), tree.NewDInt(5)}
+	b := s.DB().NewBatch()
+	require.NoError(t, w.ri.InsertRow(ctx, w.wrapPutter(&row.KVBatchAdapter{Batch: b}), oldVals,
+		row.PartialIndexUpdateHelper{}, oth, false /* overwrite */, false /* traceKV */))
+	_, err = w.ru.UpdateRow(ctx, b, oldVals, newVals, row.PartialIndexUpdateHelper{}, oth, false /* traceKV */)
+	require.NoError(t, err)
+
+	// Writing family 0 of a row after another one of its families is caught.
+	rowKey := encoding.EncodeVarintAscending(
+		s.Codec().IndexPrefix(uint32(dstDesc.GetID()), uint32(dstDesc.GetPrimaryIndexID())), 2)
+	familyKey := func(id uint32) roachpb.Key {
+		return keys.MakeFamilyKey(rowKey[:len(rowKey):len(rowKey)], id)
+	}
+	value := roachpb.MakeValueFromString("v")
+	p := w.wrapPutter(&row.KVBatchAdapter{Batch: s.DB().NewBatch()})
+	p.CPutWithOriginTimestamp(familyKey(1), &value, nil /* expValue */, oth.OriginTimestamp, oth.ShouldWinTie)
+	p.CPutWithOriginTimestamp(familyKey(0), &value, nil /* expValue */, oth.OriginTimestamp, oth.ShouldWinTie)
+	require.ErrorContains(t, p.(row.ErrPutter).Err(), "column family f0 of key")
+}
+
+// TestKVRowProcessorProposalQuotaMaxWaiters verifies that the KV writer caps
+// the proposal quota waiters of its batches, and that the resulting error is
+// retried and counted as a quota retry.
+func TestKVRowProcessorProposalQuotaMaxWaiters(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	skip.UnderRace(t)
+	defer log.Scope(t).Close(t)
+	ctx := context.Background()
+
+	const quota = 256 << 10
+	var blockKey atomic.Value
+	blockKey.Store(roachpb.Key(nil))
+	unblock := make(chan struct{})
+	srv, sqlDB, _ := serverutils.StartServer(t, base.TestServerArgs{
+		RaftConfig: base.RaftConfig{RaftProposalQuota: quota},
+		Knobs: base.TestingKnobs{
+			Store: &kvserver.StoreTestingKnobs{
+				TestingProposalFilter: func(args kvserverbase.ProposalFilterArgs) *kvpb.Error {
+					key := blockKey.Load().(roachpb.Key)
+					if key == nil || args.QuotaAlloc == nil || args.Req == nil {
+						return nil
+					}
+					for _, ru := range args.Req.Requests {
+						if ru.GetInner().Header().Key.Equal(key) {
+							<-unblock
+						}
+					}
+					return nil
+				},
+			},
+		},
+	})
+	defer srv.Stopper().Stop(ctx)
+	var unblockOnce sync.Once
+	release := func() { unblockOnce.Do(func() { close(unblock) }) }
+	defer release()
+	s := srv.ApplicationLayer()
+
+	runner := sqlutils.MakeSQLRunner(sqlDB)
+	for _, tableName := range []string{"src", "dst"} {
+		runner.Exec(t, fmt.Sprintf(`CREATE TABLE %s (pk int primary key, payload string)`, tableName))
+		runner.Exec(t, fmt.Sprintf("ALTER TABLE %s "+lwwColumnAdd, tableName))
+	}
+	srcDesc := desctestutils.TestingGetPublicTableDescriptor(s.DB(), s.Codec(), "defaultdb", "src")
+	dstDesc := desctestutils.TestingGetPublicTableDescriptor(s.DB(), s.Codec(), "defaultdb", "dst")
+	applyProposalQuotaMaxWaiters.Override(ctx, &s.ClusterSettings().SV, 1)
+
+	rp, err := newKVRowProcessor(ctx,
+		&execinfra.ServerConfig{
+			DB:           s.InternalDB().(descs.DB),
+			LeaseManager: s.LeaseManager(),
+		}, &eval.Context{
+			Codec:    s.Codec(),
+			Settings: s.ClusterSettings(),
+		}, map[descpb.ID]sqlProcessorTableConfig{
+			dstDesc.GetID(): {
+				srcDesc: srcDesc,
+			},
+		}, nil /* metrics */)
+	require.NoError(t, err)
+
+	rowKey := func(pk int64) roachpb.Key {
+		key := encoding.EncodeVarintAscending(
+			s.Codec().IndexPrefix(uint32(dstDesc.GetID()), uint32(dstDesc.GetPrimaryIndexID())), pk)
+		return keys.MakeFamilyKey(key, 0)
+	}
+
+	// Hold all of the range's quota with a write which is blocked after its
+	// acquisition, and queue another write behind it.
+	blockKey.Store(rowKey(1))
+	errCh := make(chan error, 2)
+	go func() { errCh <- s.DB().Put(ctx, rowKey(1), make([]byte, quota)) }()
+	go func() { errCh <- s.DB().Put(ctx, rowKey(2), "queued") }()
+
+	// The replicated write finds a waiter in the queue and gives up.
+	kv := replicationtestutils.EncodeKV(t, s.Codec(), srcDesc, 3, "row3")
+	kv.Value.Timestamp = s.Clock().Now()
+	testutils.SucceedsSoon(t, func() error {
+		attemptCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+		_, err := rp.ProcessRow(attemptCtx, nil, kv, roachpb.Value{})
+		if !errors.Is(err, kvserverbase.ErrProposalQuotaQueueTooDeep) {
+			return errors.Newf("expected proposal quota error, got %v", err)
+		}
+		lrw := &logicalReplicationWriterProcessor{metrics: MakeMetrics(time.Minute).(*Metrics)}
+		require.Equal(t, retryAllowed, lrw.shouldRetryLater(err, retryAllowed))
+		require.Equal(t, int64(1), lrw.metrics.ApplyRetriesQuota.Count())
+		return nil
+	})
+
+	release()
+	for i := 0; i < 2; i++ {
+		require.NoError(t, <-errCh)
+	}
+}
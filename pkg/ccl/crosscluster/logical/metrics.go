@@ -9,9 +9,46 @@
 package logical
 
 import (
+	"context"
+	"strconv"
+	"sync/atomic"
 	"time"
 
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
 	"github.com/cockroachdb/cockroach/pkg/util/metric"
+	"github.com/cockroachdb/cockroach/pkg/util/metric/aggmetric"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/cockroachdb/cockroach/pkg/util/tracing"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var traceExemplarsEnabled = settings.RegisterBoolSetting(
+	settings.ApplicationLevel,
+	"logical_replication.consumer.metrics.trace_exemplars.enabled",
+	"if enabled, apply latency histograms attach the trace ID of a recent "+
+		"traced operation to each bucket as an exemplar",
+	false,
+)
+
+var admissionWaitRecordingEnabled = settings.RegisterBoolSetting(
+	settings.ApplicationLevel,
+	"logical_replication.consumer.metrics.admission_wait.enabled",
+	"if enabled, each applied batch is traced to record the time it spent "+
+		"waiting for admission control in logical_replication.admission_wait_nanos",
+	false,
+)
+
+var applyStallThreshold = settings.RegisterDurationSetting(
+	settings.ApplicationLevel,
+	"logical_replication.consumer.metrics.apply_stall_threshold",
+	"the apply latency above which a batch which mostly waited for the destination's "+
+		"stores to admit its writes is counted in logical_replication.apply_stalls_disk; "+
+		"requires logical_replication.consumer.metrics.admission_wait.enabled, 0 disables",
+	5*time.Second,
+	settings.NonNegativeDuration,
 )
 
 var (
@@ -34,6 +71,78 @@ var (
 		Measurement: "Bytes",
 		Unit:        metric.Unit_BYTES,
 	}
+	metaEventsCoalesced = metric.Metadata{
+		Name:        "logical_replication.events_coalesced",
+		Help:        "Row update events not applied because a later event in the same batch overwrote the same key",
+		Measurement: "Events",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaPKChangingUpdates = metric.Metadata{
+		Name:        "logical_replication.pk_changing_updates",
+		Help:        "Received row updates which changed the primary key of a row, replicated as the deletion of one row and the insertion of another",
+		Measurement: "Updates",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaSourceTxnsApplied = metric.Metadata{
+		Name:        "logical_replication.source_txns_applied",
+		Help:        "Source transactions, identified by their commit timestamp, all of whose row updates were applied or sent to the DLQ when flushed",
+		Measurement: "Transactions",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaSourceTxnSplits = metric.Metadata{
+		Name:        "logical_replication.source_txn_splits",
+		Help:        "Source transactions, identified by their commit timestamp, whose row updates were applied in more than one batch when flushed",
+		Measurement: "Transactions",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaEventsDroppedStale = metric.Metadata{
+		Name:        "logical_replication.events_dropped_stale",
+		Help:        "Row update events not applied because the destination row had a newer origin timestamp",
+		Measurement: "Events",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaEventsDeduplicated = metric.Metadata{
+		Name:        "logical_replication.events_deduplicated",
+		Help:        "Row update events not applied because the destination row had already been written by them",
+		Measurement: "Events",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaEventsNoChange = metric.Metadata{
+		Name:        "logical_replication.events_no_change",
+		Help:        "Row update events applied without changing any value of the destination row",
+		Measurement: "Events",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaEventsTypeCoerced = metric.Metadata{
+		Name:        "logical_replication.events_type_coerced",
+		Help:        "Row update events with a value coerced from the type of its source column to that of its destination column",
+		Measurement: "Events",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaProjectedOutBytes = metric.Metadata{
+		Name:        "logical_replication.projected_out_bytes",
+		Help:        "Encoded bytes of replicated column values dropped before apply because the destination does not write them",
+		Measurement: "Bytes",
+		Unit:        metric.Unit_BYTES,
+	}
+	metaEventsTypeCoercionFailed = metric.Metadata{
+		Name:        "logical_replication.events_type_coercion_failed",
+		Help:        "Row update events sent to the DLQ because a value could not be coerced to the type of its destination column",
+		Measurement: "Events",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaDestinationWriteBytes = metric.Metadata{
+		Name:        "logical_replication.destination_write_bytes",
+		Help:        "KV bytes written to the destination, including secondary indexes, by events applied by the KV writer",
+		Measurement: "Bytes",
+		Unit:        metric.Unit_BYTES,
+	}
+	metaDestinationWriteAmplification = metric.Metadata{
+		Name:        "logical_replication.destination_write_amplification",
+		Help:        "Ratio of the KV bytes written to the destination to the logical bytes of the events applied by the KV writer",
+		Measurement: "Ratio",
+		Unit:        metric.Unit_CONST,
+	}
 	metaCommitToCommitLatency = metric.Metadata{
 		Name: "logical_replication.commit_latency",
 		Help: "Event commit latency: a difference between event MVCC timestamp " +
@@ -48,6 +157,12 @@ var (
 		Measurement: "Seconds",
 		Unit:        metric.Unit_SECONDS,
 	}
+	metaReplicatedTimeLagSeconds = metric.Metadata{
+		Name:        "logical_replication.replicated_time_lag_seconds",
+		Help:        "The time in seconds by which the replicated time of the logical replication stream trails the current time.",
+		Measurement: "Seconds",
+		Unit:        metric.Unit_SECONDS,
+	}
 
 	// User-visible health and ops metrics.
 	metaRetryQueueBytes = metric.Metadata{
@@ -62,12 +177,192 @@ var (
 		Measurement: "Events",
 		Unit:        metric.Unit_COUNT,
 	}
+	metaRetryQueueMaxAgeSeconds = metric.Metadata{
+		Name:        "logical_replication.retry_queue.max_age_seconds",
+		Help:        "The maximum time row update events may be retried before being sent to the DLQ, per logical_replication.retry_queue.max_age",
+		Measurement: "Seconds",
+		Unit:        metric.Unit_SECONDS,
+	}
+	metaRetryQueueResidenceNanos = metric.Metadata{
+		Name:        "logical_replication.retry_queue_residence_nanos",
+		Help:        "Time spent in the retry queue by row update events which were then successfully applied by a retry",
+		Measurement: "Nanoseconds",
+		Unit:        metric.Unit_NANOSECONDS,
+	}
+	metaBufferedBytes = metric.Metadata{
+		Name:        "logical_replication.buffered_bytes",
+		Help:        "Bytes of events received from the source which have not yet been flushed",
+		Measurement: "Bytes",
+		Unit:        metric.Unit_BYTES,
+	}
+	metaCatchupScanRemainingBytes = metric.Metadata{
+		Name:        "logical_replication.catchup_scan_remaining_bytes",
+		Help:        "Estimated bytes left to receive before replication streams have caught up to the time they started",
+		Measurement: "Bytes",
+		Unit:        metric.Unit_BYTES,
+	}
+	metaCatchupScansStarted = metric.Metadata{
+		Name:        "logical_replication.catchup_scans_started",
+		Help:        "Catch-up scans started by replication stream processors",
+		Measurement: "Scans",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaSourceRangefeedRestarts = metric.Metadata{
+		Name:        "logical_replication.source_rangefeed_restarts",
+		Help:        "Subscriptions to the source which failed and are restarted from replicated progress",
+		Measurement: "Restarts",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaActivePartitions = metric.Metadata{
+		Name:        "logical_replication.active_partitions",
+		Help:        "Number of source partitions with an active subscription",
+		Measurement: "Partitions",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaFrontierLagSpreadSeconds = metric.Metadata{
+		Name:        "logical_replication.frontier_lag_spread_seconds",
+		Help:        "Largest difference, across running streams, between the replicated time of the most and least advanced source span",
+		Measurement: "Seconds",
+		Unit:        metric.Unit_SECONDS,
+	}
+	metaStuckPartitions = metric.Metadata{
+		Name:        "logical_replication.stuck_partitions",
+		Help:        "Number of source spans, across running streams, whose replicated time has not advanced for logical_replication.consumer.stuck_partition_threshold",
+		Measurement: "Partitions",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaEstimatedCatchupSeconds = metric.Metadata{
+		Name:        "logical_replication.estimated_catchup_seconds",
+		Help:        "Longest projected time, across running streams, for the replicated time lag to drop to logical_replication.consumer.catchup_target_lag",
+		Measurement: "Seconds",
+		Unit:        metric.Unit_SECONDS,
+	}
+	metaLastHeartbeatAgeSeconds = metric.Metadata{
+		Name:        "logical_replication.last_heartbeat_age_seconds",
+		Help:        "Longest time, across running streams, since a heartbeat was last acknowledged by the source",
+		Measurement: "Seconds",
+		Unit:        metric.Unit_SECONDS,
+	}
+	metaTablesReplicating = metric.Metadata{
+		Name:        "logical_replication.tables_replicating",
+		Help:        "Number of destination tables of the running streams coordinated by this node",
+		Measurement: "Tables",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaLabelsPaused = metric.Metadata{
+		Name:        "logical_replication.labels_paused",
+		Help:        "Number of destination tables of running streams whose events are not being applied as the table is paused",
+		Measurement: "Tables",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaWorkersBlockedOnDestinationQuota = metric.Metadata{
+		Name:        "logical_replication.workers_blocked_on_destination_quota",
+		Help:        "Number of apply workers waiting for the proposal quota of a destination range on the same node",
+		Measurement: "Workers",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaApplyMemoryBytes = metric.Metadata{
+		Name:        "logical_replication.apply_memory_bytes",
+		Help:        "Memory accounted for by the apply path for events which are buffered or being applied",
+		Measurement: "Bytes",
+		Unit:        metric.Unit_BYTES,
+	}
+	metaApplyMemoryHighwaterBytes = metric.Metadata{
+		Name:        "logical_replication.apply_memory_highwater_bytes",
+		Help:        "Peak of logical_replication.apply_memory_bytes over the last histogram window",
+		Measurement: "Bytes",
+		Unit:        metric.Unit_BYTES,
+	}
+	metaIngestRowsPerSec = metric.Metadata{
+		Name:        "logical_replication.ingest_rows_per_sec",
+		Help:        "Events ingested per second by all replication jobs, averaged over the last 30 seconds",
+		Measurement: "Events/Sec",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaIngestBytesPerSec = metric.Metadata{
+		Name:        "logical_replication.ingest_bytes_per_sec",
+		Help:        "Logical bytes of the events processed per second by all replication jobs, averaged over the last 30 seconds",
+		Measurement: "Bytes/Sec",
+		Unit:        metric.Unit_BYTES,
+	}
 	metaApplyBatchNanosHist = metric.Metadata{
 		Name:        "logical_replication.batch_hist_nanos",
 		Help:        "Time spent flushing a batch",
 		Measurement: "Nanoseconds",
 		Unit:        metric.Unit_NANOSECONDS,
 	}
+	metaBatchAssemblyNanos = metric.Metadata{
+		Name:        "logical_replication.batch_assembly_nanos",
+		Help:        "Time spent assembling a batch from its events before flushing it",
+		Measurement: "Nanoseconds",
+		Unit:        metric.Unit_NANOSECONDS,
+	}
+	metaBatchConflictPercent = metric.Metadata{
+		Name:        "logical_replication.batch_conflict_percent",
+		Help:        "Histogram of the percentage (0-100, rounded down) of events in each applied batch which required conflict handling",
+		Measurement: "Percent",
+		Unit:        metric.Unit_PERCENT,
+	}
+	metaDistinctKeysPerBatch = metric.Metadata{
+		Name:        "logical_replication.distinct_keys_per_batch",
+		Help:        "Histogram of the number of distinct rows updated by each applied batch",
+		Measurement: "Rows",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaOrderingWaitNanos = metric.Metadata{
+		Name:        "logical_replication.ordering_wait_nanos",
+		Help:        "Time spent by row update events waiting for the batch applying an earlier event to the same row",
+		Measurement: "Nanoseconds",
+		Unit:        metric.Unit_NANOSECONDS,
+	}
+	metaApplyConcurrencyLimited = metric.Metadata{
+		Name:        "logical_replication.apply_concurrency_limited",
+		Help:        "Number of batches which had to wait for a slot under logical_replication.consumer.apply_concurrency_limit before being applied",
+		Measurement: "Batches",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaApplyConcurrencyWaitNanos = metric.Metadata{
+		Name:        "logical_replication.apply_concurrency_wait_nanos",
+		Help:        "Time spent by batches waiting for a slot under logical_replication.consumer.apply_concurrency_limit",
+		Measurement: "Nanoseconds",
+		Unit:        metric.Unit_NANOSECONDS,
+	}
+	metaAdmissionWaitNanos = metric.Metadata{
+		Name:        "logical_replication.admission_wait_nanos",
+		Help:        "Time spent by each applied batch waiting for admission control on the destination",
+		Measurement: "Nanoseconds",
+		Unit:        metric.Unit_NANOSECONDS,
+	}
+	metaApplyStallsDisk = metric.Metadata{
+		Name:        "logical_replication.apply_stalls_disk",
+		Help:        "Applied batches slower than logical_replication.consumer.metrics.apply_stall_threshold which spent most of that time waiting in the store write admission queues of IO-overloaded destination stores; only counted if logical_replication.consumer.metrics.admission_wait.enabled is set",
+		Measurement: "Batches",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaConflictReads = metric.Metadata{
+		Name:        "logical_replication.conflict_reads",
+		Help:        "Extra reads of the destination table issued to resolve a conflicting row update",
+		Measurement: "Reads",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaConflictResolutionLatency = metric.Metadata{
+		Name:        "logical_replication.conflict_resolution_latency",
+		Help:        "Latency of the upserts which read the destination row to resolve a conflicting row update, and write the update if it wins",
+		Measurement: "Nanoseconds",
+		Unit:        metric.Unit_NANOSECONDS,
+	}
+	metaFullRowRefetches = metric.Metadata{
+		Name:        "logical_replication.full_row_refetches",
+		Help:        "Row updates retried using the full destination row returned by a failed conditional write, as the update's previous value did not match it",
+		Measurement: "Refetches",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaFullRowRefetchLatency = metric.Metadata{
+		Name:        "logical_replication.full_row_refetch_latency",
+		Help:        "Latency of the failed conditional writes which returned the full destination row for a retried row update",
+		Measurement: "Nanoseconds",
+		Unit:        metric.Unit_NANOSECONDS,
+	}
 	metaInitialApplySuccess = metric.Metadata{
 		Name:        "logical_replication.events_initial_success",
 		Help:        "Successful applications of an incoming row update",
@@ -92,6 +387,18 @@ var (
 		Measurement: "Failures",
 		Unit:        metric.Unit_COUNT,
 	}
+	metaApplyRetriesQuota = metric.Metadata{
+		Name:        "logical_replication.apply_retries_quota",
+		Help:        "Row update events queued for retry because a destination range had too many proposals waiting for proposal quota",
+		Measurement: "Events",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaRetryToDLQRatio = metric.Metadata{
+		Name:        "logical_replication.retry_to_dlq_ratio",
+		Help:        "Ratio of the row updates sent to the DLQ after being retried to the row updates which entered the retry queue, over a sliding window",
+		Measurement: "Ratio",
+		Unit:        metric.Unit_CONST,
+	}
 
 	metaDLQedDueToAge = metric.Metadata{
 		Name:        "logical_replication.events_dlqed_age",
@@ -111,6 +418,12 @@ var (
 		Measurement: "Failures",
 		Unit:        metric.Unit_COUNT,
 	}
+	metaDLQWriteFailures = metric.Metadata{
+		Name:        "logical_replication.dlq_write_failures",
+		Help:        "Attempts to write a row update event to the DLQ which failed, causing the event to be replayed after restarting from the last checkpoint",
+		Measurement: "Failures",
+		Unit:        metric.Unit_COUNT,
+	}
 
 	// Internal metrics.
 	metaCheckpointEvents = metric.Metadata{
@@ -125,6 +438,12 @@ var (
 		Measurement: "Events",
 		Unit:        metric.Unit_COUNT,
 	}
+	metaSourceSchemaChanges = metric.Metadata{
+		Name:        "logical_replication.source_schema_changes",
+		Help:        "Number of new versions of source table descriptors observed when planning, to be compared with replan_count",
+		Measurement: "Events",
+		Unit:        metric.Unit_COUNT,
+	}
 
 	// Labeled metrics.
 	metaLabeledReplicatedTime = metric.Metadata{
@@ -139,49 +458,510 @@ var (
 		Measurement: "Events",
 		Unit:        metric.Unit_COUNT,
 	}
+	metaLabeledReceivedBytes = metric.Metadata{
+		Name:        "logical_replication.logical_bytes_by_label",
+		Help:        "Logical bytes (sum of keys + values) received by all replication jobs by label",
+		Measurement: "Bytes",
+		Unit:        metric.Unit_BYTES,
+	}
 	metaLabeledEventsDLQed = metric.Metadata{
 		Name:        "logical_replication.events_dlqed_by_label",
 		Help:        "Row update events sent to DLQ by label",
 		Measurement: "Failures",
 		Unit:        metric.Unit_COUNT,
 	}
+	metaUDFLatency = metric.Metadata{
+		Name:        "logical_replication.udf_latency",
+		Help:        "Time spent executing the user-supplied conflict resolution function for each row update event, by destination table ID",
+		Measurement: "Nanoseconds",
+		Unit:        metric.Unit_NANOSECONDS,
+	}
+	metaUDFPlanCacheHits = metric.Metadata{
+		Name:        "logical_replication.udf_plan_cache_hits",
+		Help:        "Number of executions of the statement invoking the user-supplied conflict resolution function which reused a cached query plan",
+		Measurement: "Executions",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaUDFPlanCacheMisses = metric.Metadata{
+		Name:        "logical_replication.udf_plan_cache_misses",
+		Help:        "Number of executions of the statement invoking the user-supplied conflict resolution function which had to be planned from scratch",
+		Measurement: "Executions",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaLabeledApplyLatencyByType = metric.Metadata{
+		Name:        "logical_replication.apply_latency_by_type_by_label",
+		Help:        "Time spent applying each row update event, by label and the type of mutation (insert, update or delete)",
+		Measurement: "Nanoseconds",
+		Unit:        metric.Unit_NANOSECONDS,
+	}
+	metaDLQDetectionLatency = metric.Metadata{
+		Name:        "logical_replication.dlq_detection_latency",
+		Help:        "Time from the first attempt to apply an event to it being sent to the DLQ, by whether its error was immediately not retryable or its retries were exhausted",
+		Measurement: "Nanoseconds",
+		Unit:        metric.Unit_NANOSECONDS,
+	}
 )
 
 // Metrics are for production monitoring of logical replication jobs.
 type Metrics struct {
 	// Top-line user-facing numbers that how many events and how much data are
 	// bring moved and applied/rejected/etc.
-	AppliedRowUpdates     *metric.Counter
-	DLQedRowUpdates       *metric.Counter
-	ReceivedLogicalBytes  *metric.Counter
-	CommitToCommitLatency metric.IHistogram
-	ReplicatedTimeSeconds *metric.Gauge
+	AppliedRowUpdates    *metric.Counter
+	DLQedRowUpdates      *metric.Counter
+	ReceivedLogicalBytes *metric.Counter
+	// EventsCoalesced counts events which were received but, having been
+	// superseded by a later event in the same batch, were never applied; it
+	// accounts for AppliedRowUpdates trailing the events received.
+	EventsCoalesced *metric.Counter
+	// EventsDroppedStale counts events which lost last-write-wins conflict
+	// resolution. Deletes applied by SQL statements are not included, as a
+	// delete affecting no rows may also have found no row to delete.
+	EventsDroppedStale *metric.Counter
+	// EventsDeduplicated counts events which were replayed, e.g. after the job
+	// resumed from a checkpoint older than them, and were found to have already
+	// been applied as the destination row had their origin timestamp. Only the
+	// KV writer can tell these apart from events dropped as stale.
+	EventsDeduplicated *metric.Counter
+	// EventsNoChange counts updates which left the values of the destination
+	// row as they were, e.g. ones the source made by re-saving a row. Applying
+	// them is wasted work, other than advancing the origin timestamp, which
+	// could be avoided by filtering them out at the source. Only the KV writer
+	// knows the destination row's values and counts these.
+	EventsNoChange *metric.Counter
+	// EventsTypeCoerced and EventsTypeCoercionFailed count events whose values
+	// the KV writer had to coerce to the types of the destination's columns,
+	// see kvTableWriter.coerce. Coerced values may have been rounded, so these
+	// surface schema differences which don't fail the apply. Values too wide
+	// for their destination column fail to be coerced.
+	EventsTypeCoerced        *metric.Counter
+	EventsTypeCoercionFailed *metric.Counter
+	// ProjectedOutBytes is counted by the SQL writer, which drops the values of
+	// computed columns from the decoded rows it applies, see
+	// sqlRowProcessor.projectedOutBytes.
+	ProjectedOutBytes *metric.Counter
+	// PKChangingUpdates is estimated from the events received, see
+	// countPKChangingUpdates.
+	PKChangingUpdates *metric.Counter
+	// SourceTxnsApplied and SourceTxnSplits are counted by sourceTxns. Source
+	// transactions are not applied atomically, so a split transaction may be
+	// partially visible on the destination.
+	SourceTxnsApplied *metric.Counter
+	SourceTxnSplits   *metric.Counter
+	// DestinationWriteBytes and DestinationWriteAmplification are only
+	// measured for the events whose KV writes are known, i.e. those applied by
+	// the KV writer rather than by SQL statements.
+	DestinationWriteBytes         *metric.Counter
+	DestinationWriteAmplification *metric.GaugeFloat64
+	destinationWriteLogicalBytes  atomic.Int64
+	CommitToCommitLatency         metric.IHistogram
+	ReplicatedTimeSeconds         *metric.Gauge
+	ReplicatedTimeLagSeconds      *metric.Gauge
+	// FrontierLagSpreadSeconds is the maximum of frontierLagSpreads.
+	FrontierLagSpreadSeconds *metric.Gauge
+	frontierLagSpreads       frontierLagSpreads
+	// EstimatedCatchupSeconds is the maximum of catchupEstimates. Streams whose
+	// lag is not going down cannot be estimated and are not included.
+	EstimatedCatchupSeconds *metric.Gauge
+	catchupEstimates        catchupEstimates
+	// StuckPartitions is the sum of the counts of stuckPartitionTrackers.
+	StuckPartitions *metric.Gauge
+	// LastHeartbeatAgeSeconds is the maximum age of lastHeartbeats. As opposed
+	// to a lack of events, which may be due to an idle source, a growing age
+	// indicates that the connection to the source is unhealthy.
+	LastHeartbeatAgeSeconds *metric.Gauge
+	lastHeartbeats          lastHeartbeats
+	// TablesReplicating is the sum of tablesReplicating.
+	TablesReplicating *metric.Gauge
+	tablesReplicating tablesReplicating
+	// LabelsPaused is the number of tables in pausedTables.
+	LabelsPaused *metric.Gauge
+	pausedTables pausedTableSet
+	// WorkersBlockedOnDestinationQuota only accounts for destination ranges
+	// with a leaseholder on the worker's node, see
+	// trackDestinationQuotaWaits.
+	WorkersBlockedOnDestinationQuota *metric.Gauge
+	// ApplyMemoryBytes is the sum of the memory accounted for by the writer
+	// processors' monitors, and ApplyMemoryHighwaterBytes is its peak as
+	// tracked by applyMemoryPeak.
+	ApplyMemoryBytes          *metric.Gauge
+	ApplyMemoryHighwaterBytes *metric.Gauge
+	applyMemoryPeak           applyMemoryPeak
+	// IngestRowsPerSec and IngestBytesPerSec are computed from ingestRate over
+	// the last ingestRateWindow whenever they are read, e.g. every time series
+	// poll, so they go down to zero once ingestion stops.
+	IngestRowsPerSec  *metric.Gauge
+	IngestBytesPerSec *metric.Gauge
+	ingestRate        ingestRate
 
 	// User-surfaced information about the health/operation of the stream; this
 	// should be a narrow subset of numbers that are actually relevant to a user
 	// such as the latency of application as that could be their supplied UDF.
-	RetryQueueBytes     *metric.Gauge
-	RetryQueueEvents    *metric.Gauge
-	ApplyBatchNanosHist metric.IHistogram
+	RetryQueueBytes  *metric.Gauge
+	RetryQueueEvents *metric.Gauge
+	// RetryQueueMaxAgeSeconds is the current value of the setting controlling
+	// DLQedDueToAge.
+	RetryQueueMaxAgeSeconds *metric.Gauge
+	BufferedBytes           *metric.Gauge
+	ApplyBatchNanosHist     metric.IHistogram
+	// BatchAssemblyNanos covers the time between a batch being cut from a
+	// worker's chunk and it being handed to the BatchHandler, which
+	// ApplyBatchNanosHist starts at; waiting for the local clock to catch up to
+	// the batch's events is not included. ApplyBatchNanosHist includes the time
+	// spent waiting for a slot in acquireApplySlot.
+	BatchAssemblyNanos metric.IHistogram
+	// BatchConflictPercent uses a 0-100 scale as histograms only record
+	// integer values.
+	BatchConflictPercent      metric.IHistogram
+	ConflictReads             *metric.Counter
+	ConflictResolutionLatency metric.IHistogram
+	// FullRowRefetches and FullRowRefetchLatency are only recorded by the kv
+	// row processor.
+	FullRowRefetches      *metric.Counter
+	FullRowRefetchLatency metric.IHistogram
+	AdmissionWaitNanos    metric.IHistogram
+	DistinctKeysPerBatch  metric.IHistogram
+	OrderingWaitNanos     metric.IHistogram
+	// ApplyConcurrencyLimited and ApplyConcurrencyWaitNanos only count the
+	// batches which had to wait for a slot; see acquireApplySlot.
+	ApplyConcurrencyLimited   *metric.Counter
+	ApplyConcurrencyWaitNanos metric.IHistogram
+	applyConcurrency          applyConcurrencyLimiter
+	// ApplyStallsDisk is only counted if admission wait recording is enabled,
+	// see appliedStalledOnDisk.
+	ApplyStallsDisk *metric.Counter
+	// RetryQueueResidenceNanos approaching RetryQueueMaxAgeSeconds indicates
+	// that events are about to start being sent to the DLQ due to their age.
+	RetryQueueResidenceNanos metric.IHistogram
+
+	CatchupScanRemainingBytes *metric.Gauge
+	CatchupScansStarted       *metric.Counter
+	SourceRangefeedRestarts   *metric.Counter
+	ActivePartitions          *metric.Gauge
 
 	DLQedDueToAge        *metric.Counter
 	DLQedDueToQueueSpace *metric.Counter
 	DLQedDueToErrType    *metric.Counter
+	DLQWriteFailures     *metric.Counter
 
 	InitialApplySuccesses *metric.Counter
 	InitialApplyFailures  *metric.Counter
 	RetriedApplySuccesses *metric.Counter
 	RetriedApplyFailures  *metric.Counter
+	// ApplyRetriesQuota counts the events which shouldRetryLater queued for
+	// retry after failing with kvserverbase.ErrProposalQuotaQueueTooDeep,
+	// i.e. because of proposal quota backpressure on the destination rather
+	// than e.g. contention or a missing FK parent. Writes only fail this way
+	// if applyProposalQuotaMaxWaiters is set.
+	ApplyRetriesQuota *metric.Counter
+	// RetryToDLQRatio is computed by retryOutcomes. A ratio close to one
+	// indicates that retrying the events which fail to apply only delays
+	// sending them to the DLQ.
+	RetryToDLQRatio *metric.GaugeFloat64
+	retryOutcomes   retryOutcomes
 
 	// Internal numbers that are useful for determining why a stream is behaving
 	// a specific way.
 	CheckpointEvents *metric.Counter
 	ReplanCount      *metric.Counter
+	// SourceSchemaChanges is counted by the planner, see
+	// observeSourceDescriptors.
+	SourceSchemaChanges *metric.Counter
 
 	// Labeled export-only metrics.
 	LabeledReplicatedTime *metric.GaugeVec
 	LabeledEventsIngested *metric.CounterVec
+	LabeledReceivedBytes  *metric.CounterVec
 	LabeledEventsDLQed    *metric.CounterVec
+
+	// LabeledApplyLatencyByType is labeled by the metrics label of the job and
+	// the replicationMutationType of each event, of which there are only three,
+	// and is only recorded for jobs with a metrics label.
+	LabeledApplyLatencyByType *metric.HistogramVec
+
+	// DLQDetectionLatency is labeled by whether an event was sent to the DLQ
+	// as its error was not retryable ("immediate"), or as it exceeded the retry
+	// queue's age or size limits ("exhausted"); see recordDLQDetectionLatency.
+	DLQDetectionLatency          *aggmetric.AggHistogram
+	dlqDetectionLatencyImmediate *aggmetric.Histogram
+	dlqDetectionLatencyExhausted *aggmetric.Histogram
+
+	// UDFPlanCacheHits and UDFPlanCacheMisses count the query cache outcomes of
+	// the statements invoking conflict resolution functions. A steady rate of
+	// misses means the statement is re-planned for each event.
+	UDFPlanCacheHits   *metric.Counter
+	UDFPlanCacheMisses *metric.Counter
+
+	// UDFLatency is labeled by the ID of the destination table, and only has
+	// children for tables with a conflict resolution function. Its aggregate
+	// covers all such tables.
+	UDFLatency        *aggmetric.AggHistogram
+	udfLatencyByTable struct {
+		syncutil.Mutex
+		m map[descpb.ID]*udfLatencyChild
+	}
+}
+
+// udfLatencyChild is a child of UDFLatency, along with the number of
+// processors using it.
+type udfLatencyChild struct {
+	h    *aggmetric.Histogram
+	refs int
+}
+
+// acquireUDFLatency returns the child of UDFLatency for the destination table.
+// Children are shared by all of the table's processors on this node, each of
+// which must call releaseUDFLatency once it no longer applies the table's rows.
+func (m *Metrics) acquireUDFLatency(tableID descpb.ID) *aggmetric.Histogram {
+	m.udfLatencyByTable.Lock()
+	defer m.udfLatencyByTable.Unlock()
+	c, ok := m.udfLatencyByTable.m[tableID]
+	if !ok {
+		if m.udfLatencyByTable.m == nil {
+			m.udfLatencyByTable.m = make(map[descpb.ID]*udfLatencyChild)
+		}
+		c = &udfLatencyChild{h: m.UDFLatency.AddChild(strconv.Itoa(int(tableID)))}
+		m.udfLatencyByTable.m[tableID] = c
+	}
+	c.refs++
+	return c.h
+}
+
+// releaseUDFLatency releases a child of UDFLatency returned by
+// acquireUDFLatency, removing it once no processor on this node uses it.
+func (m *Metrics) releaseUDFLatency(tableID descpb.ID) {
+	m.udfLatencyByTable.Lock()
+	defer m.udfLatencyByTable.Unlock()
+	c, ok := m.udfLatencyByTable.m[tableID]
+	if !ok {
+		return
+	}
+	if c.refs--; c.refs <= 0 {
+		c.h.Unlink()
+		delete(m.udfLatencyByTable.m, tableID)
+	}
+}
+
+// recordDestinationWrites records writeBytes of KV writes to the destination
+// made to apply events of logicalBytes.
+func (m *Metrics) recordDestinationWrites(writeBytes, logicalBytes int64) {
+	if logicalBytes == 0 {
+		return
+	}
+	m.DestinationWriteBytes.Inc(writeBytes)
+	total := m.destinationWriteLogicalBytes.Add(logicalBytes)
+	m.DestinationWriteAmplification.Update(float64(m.DestinationWriteBytes.Count()) / float64(total))
+}
+
+// frontierLagSpreads tracks the frontier lag spread of each running job.
+type frontierLagSpreads struct {
+	syncutil.Mutex
+	byJob map[jobspb.JobID]time.Duration
+}
+
+// updateFrontierLagSpread records the spread between the most and least
+// advanced spans of a job's frontier. A spread of zero removes the job.
+func (m *Metrics) updateFrontierLagSpread(jobID jobspb.JobID, spread time.Duration) {
+	s := &m.frontierLagSpreads
+	s.Lock()
+	defer s.Unlock()
+	if spread == 0 {
+		delete(s.byJob, jobID)
+	} else {
+		if s.byJob == nil {
+			s.byJob = make(map[jobspb.JobID]time.Duration)
+		}
+		s.byJob[jobID] = spread
+	}
+	var maxSpread time.Duration
+	for _, spread := range s.byJob {
+		maxSpread = max(maxSpread, spread)
+	}
+	m.FrontierLagSpreadSeconds.Update(int64(maxSpread.Seconds()))
+}
+
+// catchupEstimates tracks the projected catch-up time of each running job
+// which has an estimate, see catchupEstimator.
+type catchupEstimates struct {
+	syncutil.Mutex
+	byJob map[jobspb.JobID]time.Duration
+}
+
+// updateCatchupEstimate records the projected catch-up time of a job, or
+// removes the job if it has no estimate.
+func (m *Metrics) updateCatchupEstimate(jobID jobspb.JobID, eta time.Duration, ok bool) {
+	e := &m.catchupEstimates
+	e.Lock()
+	defer e.Unlock()
+	if !ok {
+		delete(e.byJob, jobID)
+	} else {
+		if e.byJob == nil {
+			e.byJob = make(map[jobspb.JobID]time.Duration)
+		}
+		e.byJob[jobID] = eta
+	}
+	var maxETA time.Duration
+	for _, eta := range e.byJob {
+		maxETA = max(maxETA, eta)
+	}
+	m.EstimatedCatchupSeconds.Update(int64(maxETA.Seconds()))
+}
+
+// retryOutcomes counts the row updates which entered the retry queue, and
+// those which were sent to the DLQ when retried, over a sliding window made up
+// of the current and previous half windows, like the windowed histograms.
+//
+// Which DLQed update entered the retry queue when isn't tracked, so updates
+// sent to the DLQ within the window may have entered the retry queue before
+// it, and the ratio is capped at one.
+type retryOutcomes struct {
+	syncutil.Mutex
+	window time.Duration
+	// start is the start of the current half window.
+	start     time.Time
+	cur, prev retryOutcomeCounts
+}
+
+type retryOutcomeCounts struct {
+	retried, dlqed int64
+}
+
+// recordRetryOutcomes records, at now, that retried row updates entered the
+// retry queue and dlqed row updates were sent to the DLQ after being retried.
+func (m *Metrics) recordRetryOutcomes(now time.Time, retried, dlqed int64) {
+	if retried == 0 && dlqed == 0 {
+		return
+	}
+	r := &m.retryOutcomes
+	r.Lock()
+	defer r.Unlock()
+	if half := r.window / 2; now.Sub(r.start) >= r.window {
+		r.prev, r.cur = retryOutcomeCounts{}, retryOutcomeCounts{}
+		r.start = now
+	} else if now.Sub(r.start) >= half {
+		r.prev, r.cur = r.cur, retryOutcomeCounts{}
+		r.start = r.start.Add(half)
+	}
+	r.cur.retried += retried
+	r.cur.dlqed += dlqed
+	total := r.cur.retried + r.prev.retried
+	if total == 0 {
+		m.RetryToDLQRatio.Update(1)
+		return
+	}
+	m.RetryToDLQRatio.Update(min(1, float64(r.cur.dlqed+r.prev.dlqed)/float64(total)))
+}
+
+// applyMemoryPeak tracks the peak of ApplyMemoryBytes over a sliding window
+// made up of the current and previous half windows, like retryOutcomes, so
+// that a burst stops being reported once it is over a window old.
+type applyMemoryPeak struct {
+	syncutil.Mutex
+	window time.Duration
+	// start is the start of the current half window.
+	start     time.Time
+	cur, prev int64
+}
+
+// recordApplyMemory records the value of ApplyMemoryBytes at now.
+func (m *Metrics) recordApplyMemory(now time.Time) {
+	p := &m.applyMemoryPeak
+	p.Lock()
+	defer p.Unlock()
+	if half := p.window / 2; now.Sub(p.start) >= p.window {
+		p.prev, p.cur = 0, 0
+		p.start = now
+	} else if now.Sub(p.start) >= half {
+		p.prev, p.cur = p.cur, 0
+		p.start = p.start.Add(half)
+	}
+	p.cur = max(p.cur, m.ApplyMemoryBytes.Value())
+	m.ApplyMemoryHighwaterBytes.Update(max(p.cur, p.prev))
+}
+
+// tablesReplicating tracks the number of tables replicated by each running job.
+type tablesReplicating struct {
+	syncutil.Mutex
+	byJob map[jobspb.JobID]int
+}
+
+// updateTablesReplicating records the number of tables replicated by a job. A
+// count of zero removes the job.
+func (m *Metrics) updateTablesReplicating(jobID jobspb.JobID, tables int) {
+	t := &m.tablesReplicating
+	t.Lock()
+	defer t.Unlock()
+	if tables == 0 {
+		delete(t.byJob, jobID)
+	} else {
+		if t.byJob == nil {
+			t.byJob = make(map[jobspb.JobID]int)
+		}
+		t.byJob[jobID] = tables
+	}
+	var total int
+	for _, n := range t.byJob {
+		total += n
+	}
+	m.TablesReplicating.Update(int64(total))
+}
+
+// lastHeartbeats tracks the time of the last heartbeat of each running job.
+type lastHeartbeats struct {
+	syncutil.Mutex
+	byJob map[jobspb.JobID]time.Time
+}
+
+// recordHeartbeat records that a job's heartbeat was acknowledged at the given
+// time. A zero time removes the job.
+func (m *Metrics) recordHeartbeat(jobID jobspb.JobID, at time.Time) {
+	h := &m.lastHeartbeats
+	h.Lock()
+	now := at
+	if at.IsZero() {
+		delete(h.byJob, jobID)
+		now = timeutil.Now()
+	} else {
+		if h.byJob == nil {
+			h.byJob = make(map[jobspb.JobID]time.Time)
+		}
+		h.byJob[jobID] = at
+	}
+	h.Unlock()
+	m.sampleHeartbeatAge(now)
+}
+
+// sampleHeartbeatAge updates LastHeartbeatAgeSeconds as of now.
+func (m *Metrics) sampleHeartbeatAge(now time.Time) {
+	h := &m.lastHeartbeats
+	h.Lock()
+	defer h.Unlock()
+	var maxAge time.Duration
+	for _, at := range h.byJob {
+		maxAge = max(maxAge, now.Sub(at))
+	}
+	m.LastHeartbeatAgeSeconds.Update(int64(maxAge.Seconds()))
+}
+
+// recordApplyLatency records the time spent applying an event of the given
+// type by a job with the given metrics label, if it has one.
+func (m *Metrics) recordApplyLatency(label string, t replicationMutationType, nanos int64) {
+	if label != "" {
+		m.LabeledApplyLatencyByType.RecordValue(map[string]string{"label": label, "type": t.String()}, nanos)
+	}
+}
+
+// recordDLQDetectionLatency records the time between the first attempt to
+// apply an event and it being sent to the DLQ with the given eligibility.
+// Events of a paused table sent to the DLQ without being retried are not
+// recorded, as their error was not classified.
+func (m *Metrics) recordDLQDetectionLatency(eligibility retryEligibility, nanos int64) {
+	switch eligibility {
+	case errType:
+		m.dlqDetectionLatencyImmediate.RecordValue(nanos)
+	case tooOld, noSpace:
+		m.dlqDetectionLatencyExhausted.RecordValue(nanos)
+	}
 }
 
 // MetricStruct implements the metric.Struct interface.
@@ -189,39 +969,180 @@ func (*Metrics) MetricStruct() {}
 
 // MakeMetrics makes the metrics for logical replication job monitoring.
 func MakeMetrics(histogramWindow time.Duration) metric.Struct {
-	return &Metrics{
-		AppliedRowUpdates:    metric.NewCounter(metaAppliedRowUpdates),
-		DLQedRowUpdates:      metric.NewCounter(metaDLQedRowUpdates),
-		ReceivedLogicalBytes: metric.NewCounter(metaReceivedLogicalBytes),
+	m := &Metrics{
+		AppliedRowUpdates:             metric.NewCounter(metaAppliedRowUpdates),
+		DLQedRowUpdates:               metric.NewCounter(metaDLQedRowUpdates),
+		ReceivedLogicalBytes:          metric.NewCounter(metaReceivedLogicalBytes),
+		EventsCoalesced:               metric.NewCounter(metaEventsCoalesced),
+		EventsDroppedStale:            metric.NewCounter(metaEventsDroppedStale),
+		EventsDeduplicated:            metric.NewCounter(metaEventsDeduplicated),
+		EventsNoChange:                metric.NewCounter(metaEventsNoChange),
+		EventsTypeCoerced:             metric.NewCounter(metaEventsTypeCoerced),
+		EventsTypeCoercionFailed:      metric.NewCounter(metaEventsTypeCoercionFailed),
+		ProjectedOutBytes:             metric.NewCounter(metaProjectedOutBytes),
+		PKChangingUpdates:             metric.NewCounter(metaPKChangingUpdates),
+		SourceTxnsApplied:             metric.NewCounter(metaSourceTxnsApplied),
+		SourceTxnSplits:               metric.NewCounter(metaSourceTxnSplits),
+		DestinationWriteBytes:         metric.NewCounter(metaDestinationWriteBytes),
+		DestinationWriteAmplification: metric.NewGaugeFloat64(metaDestinationWriteAmplification),
 		CommitToCommitLatency: metric.NewHistogram(metric.HistogramOptions{
 			Mode:         metric.HistogramModePrometheus,
 			Metadata:     metaCommitToCommitLatency,
 			Duration:     histogramWindow,
 			BucketConfig: metric.LongRunning60mLatencyBuckets,
 		}),
-		ReplicatedTimeSeconds: metric.NewGauge(metaReplicatedTimeSeconds),
+		ReplicatedTimeSeconds:    metric.NewGauge(metaReplicatedTimeSeconds),
+		ReplicatedTimeLagSeconds: metric.NewGauge(metaReplicatedTimeLagSeconds),
+		FrontierLagSpreadSeconds: metric.NewGauge(metaFrontierLagSpreadSeconds),
+		EstimatedCatchupSeconds:  metric.NewGauge(metaEstimatedCatchupSeconds),
+		StuckPartitions:          metric.NewGauge(metaStuckPartitions),
+		LastHeartbeatAgeSeconds:  metric.NewGauge(metaLastHeartbeatAgeSeconds),
+		TablesReplicating:        metric.NewGauge(metaTablesReplicating),
+		LabelsPaused:             metric.NewGauge(metaLabelsPaused),
 		ApplyBatchNanosHist: metric.NewHistogram(metric.HistogramOptions{
 			Mode:         metric.HistogramModePrometheus,
 			Metadata:     metaApplyBatchNanosHist,
 			Duration:     histogramWindow,
 			BucketConfig: metric.IOLatencyBuckets,
 		}),
-		RetryQueueBytes:      metric.NewGauge(metaRetryQueueBytes),
-		RetryQueueEvents:     metric.NewGauge(metaRetryQueueEvents),
-		DLQedDueToAge:        metric.NewCounter(metaDLQedDueToAge),
-		DLQedDueToQueueSpace: metric.NewCounter(metaDLQedDueToQueueSpace),
-		DLQedDueToErrType:    metric.NewCounter(metaDLQedDueToErrType),
+		WorkersBlockedOnDestinationQuota: metric.NewGauge(metaWorkersBlockedOnDestinationQuota),
+		ApplyMemoryBytes:                 metric.NewGauge(metaApplyMemoryBytes),
+		ApplyMemoryHighwaterBytes:        metric.NewGauge(metaApplyMemoryHighwaterBytes),
+		BatchAssemblyNanos: metric.NewHistogram(metric.HistogramOptions{
+			Mode:         metric.HistogramModePrometheus,
+			Metadata:     metaBatchAssemblyNanos,
+			Duration:     histogramWindow,
+			BucketConfig: metric.IOLatencyBuckets,
+		}),
+		BatchConflictPercent: metric.NewHistogram(metric.HistogramOptions{
+			Mode:         metric.HistogramModePrometheus,
+			Metadata:     metaBatchConflictPercent,
+			Duration:     histogramWindow,
+			BucketConfig: metric.Percent100Buckets,
+		}),
+		ConflictReads: metric.NewCounter(metaConflictReads),
+		ConflictResolutionLatency: metric.NewHistogram(metric.HistogramOptions{
+			Mode:         metric.HistogramModePrometheus,
+			Metadata:     metaConflictResolutionLatency,
+			Duration:     histogramWindow,
+			BucketConfig: metric.IOLatencyBuckets,
+		}),
+		FullRowRefetches: metric.NewCounter(metaFullRowRefetches),
+		FullRowRefetchLatency: metric.NewHistogram(metric.HistogramOptions{
+			Mode:         metric.HistogramModePrometheus,
+			Metadata:     metaFullRowRefetchLatency,
+			Duration:     histogramWindow,
+			BucketConfig: metric.IOLatencyBuckets,
+		}),
+		AdmissionWaitNanos: metric.NewHistogram(metric.HistogramOptions{
+			Mode:         metric.HistogramModePrometheus,
+			Metadata:     metaAdmissionWaitNanos,
+			Duration:     histogramWindow,
+			BucketConfig: metric.IOLatencyBuckets,
+		}),
+		ApplyStallsDisk: metric.NewCounter(metaApplyStallsDisk),
+		DistinctKeysPerBatch: metric.NewHistogram(metric.HistogramOptions{
+			Mode:         metric.HistogramModePrometheus,
+			Metadata:     metaDistinctKeysPerBatch,
+			Duration:     histogramWindow,
+			BucketConfig: metric.Count1KBuckets,
+		}),
+		OrderingWaitNanos: metric.NewHistogram(metric.HistogramOptions{
+			Mode:         metric.HistogramModePrometheus,
+			Metadata:     metaOrderingWaitNanos,
+			Duration:     histogramWindow,
+			BucketConfig: metric.IOLatencyBuckets,
+		}),
+		ApplyConcurrencyLimited: metric.NewCounter(metaApplyConcurrencyLimited),
+		ApplyConcurrencyWaitNanos: metric.NewHistogram(metric.HistogramOptions{
+			Mode:         metric.HistogramModePrometheus,
+			Metadata:     metaApplyConcurrencyWaitNanos,
+			Duration:     histogramWindow,
+			BucketConfig: metric.IOLatencyBuckets,
+		}),
+		RetryQueueResidenceNanos: metric.NewHistogram(metric.HistogramOptions{
+			Mode:         metric.HistogramModePrometheus,
+			Metadata:     metaRetryQueueResidenceNanos,
+			Duration:     histogramWindow,
+			BucketConfig: metric.LongRunning60mLatencyBuckets,
+		}),
+		RetryQueueBytes:         metric.NewGauge(metaRetryQueueBytes),
+		RetryQueueEvents:        metric.NewGauge(metaRetryQueueEvents),
+		RetryQueueMaxAgeSeconds: metric.NewGauge(metaRetryQueueMaxAgeSeconds),
+		BufferedBytes:           metric.NewGauge(metaBufferedBytes),
+		DLQedDueToAge:           metric.NewCounter(metaDLQedDueToAge),
+		DLQedDueToQueueSpace:    metric.NewCounter(metaDLQedDueToQueueSpace),
+		DLQedDueToErrType:       metric.NewCounter(metaDLQedDueToErrType),
+		DLQWriteFailures:        metric.NewCounter(metaDLQWriteFailures),
 
 		InitialApplySuccesses: metric.NewCounter(metaInitialApplySuccess),
 		InitialApplyFailures:  metric.NewCounter(metaInitialApplyFailures),
 		RetriedApplySuccesses: metric.NewCounter(metaRetriedApplySuccesses),
 		RetriedApplyFailures:  metric.NewCounter(metaRetriedApplyFailures),
+		ApplyRetriesQuota:     metric.NewCounter(metaApplyRetriesQuota),
+		RetryToDLQRatio:       metric.NewGaugeFloat64(metaRetryToDLQRatio),
 		CheckpointEvents:      metric.NewCounter(metaCheckpointEvents),
 		ReplanCount:           metric.NewCounter(metaDistSQLReplanCount),
+		SourceSchemaChanges:   metric.NewCounter(metaSourceSchemaChanges),
+
+		CatchupScanRemainingBytes: metric.NewGauge(metaCatchupScanRemainingBytes),
+		CatchupScansStarted:       metric.NewCounter(metaCatchupScansStarted),
+		SourceRangefeedRestarts:   metric.NewCounter(metaSourceRangefeedRestarts),
+		ActivePartitions:          metric.NewGauge(metaActivePartitions),
 
 		// Labeled export-only metrics.
 		LabeledReplicatedTime: metric.NewExportedGaugeVec(metaLabeledReplicatedTime, []string{"label"}),
 		LabeledEventsIngested: metric.NewExportedCounterVec(metaLabeledEventsIngetsted, []string{"label"}),
+		LabeledReceivedBytes:  metric.NewExportedCounterVec(metaLabeledReceivedBytes, []string{"label"}),
 		LabeledEventsDLQed:    metric.NewExportedCounterVec(metaLabeledEventsDLQed, []string{"label"}),
+		LabeledApplyLatencyByType: metric.NewExportedHistogramVec(metaLabeledApplyLatencyByType,
+			metric.IOLatencyBuckets.GetBucketsFromBucketConfig(), []string{"label", "type"}),
+		DLQDetectionLatency: aggmetric.NewHistogram(metric.HistogramOptions{
+			Mode:         metric.HistogramModePrometheus,
+			Metadata:     metaDLQDetectionLatency,
+			Duration:     histogramWindow,
+			BucketConfig: metric.LongRunning60mLatencyBuckets,
+		}, "reason"),
+		UDFPlanCacheHits:   metric.NewCounter(metaUDFPlanCacheHits),
+		UDFPlanCacheMisses: metric.NewCounter(metaUDFPlanCacheMisses),
+		UDFLatency: aggmetric.NewHistogram(metric.HistogramOptions{
+			Mode:         metric.HistogramModePrometheus,
+			Metadata:     metaUDFLatency,
+			Duration:     histogramWindow,
+			BucketConfig: metric.IOLatencyBuckets,
+		}, "table_id"),
+	}
+	m.dlqDetectionLatencyImmediate = m.DLQDetectionLatency.AddChild("immediate")
+	m.dlqDetectionLatencyExhausted = m.DLQDetectionLatency.AddChild("exhausted")
+	m.retryOutcomes.window = histogramWindow
+	m.applyMemoryPeak.window = histogramWindow
+	m.IngestRowsPerSec = metric.NewFunctionalGauge(metaIngestRowsPerSec, func() int64 {
+		events, _ := m.ingestRate.rates(timeutil.Now())
+		return events
+	})
+	m.IngestBytesPerSec = metric.NewFunctionalGauge(metaIngestBytesPerSec, func() int64 {
+		_, size := m.ingestRate.rates(timeutil.Now())
+		return size
+	})
+	return m
+}
+
+// recordWithTraceExemplar records v in h. If trace exemplars are enabled and
+// ctx carries an active tracing span, the span's trace ID is attached to the
+// recorded value as an exemplar, so that a slow bucket can be linked to a
+// trace. A nil sv disables exemplars.
+func recordWithTraceExemplar(
+	ctx context.Context, sv *settings.Values, h metric.IHistogram, v int64,
+) {
+	if sv != nil && traceExemplarsEnabled.Get(sv) {
+		if eh, ok := h.(*metric.Histogram); ok {
+			if sp := tracing.SpanFromContext(ctx); sp != nil && !sp.IsNoop() {
+				eh.RecordValueWithExemplar(v, prometheus.Labels{
+					"trace_id": strconv.FormatUint(uint64(sp.TraceID()), 10),
+				})
+				return
+			}
+		}
 	}
+	h.RecordValue(v)
 }
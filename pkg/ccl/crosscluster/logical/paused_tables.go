@@ -0,0 +1,264 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package logical
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/changefeedccl/cdcevent"
+	"github.com/cockroachdb/cockroach/pkg/ccl/changefeedccl/changefeedbase"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/repstream/streampb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"github.com/cockroachdb/errors"
+)
+
+// pausedTables lists the destination tables whose events should not currently
+// be applied. Pausing a table rather than the job replicating it leaves the job
+// running and applying the events of its other tables: the events of the
+// table continue to be received, and are handled as directed by
+// pausedTablePolicy until the table is resumed by removing it.
+var pausedTables = settings.RegisterStringSetting(
+	settings.ApplicationLevel,
+	"logical_replication.consumer.paused_tables",
+	"comma-separated list of fully-qualified names (database.schema.table) of the destination "+
+		"tables of logical replication streams whose events are not applied",
+	"",
+)
+
+type pausedTablePolicy int64
+
+const (
+	// pausedTableRetry holds the events of a paused table in the retry queue,
+	// from which they are applied once the table is resumed. As the events
+	// held in the retry queue hold back the checkpoints of the processor which
+	// received them, the replicated time of the job does not advance while the
+	// table is paused, and catches up once its events have been applied. Events
+	// which exceed the retry queue's size or age limits while paused are sent
+	// to the DLQ.
+	pausedTableRetry pausedTablePolicy = iota
+	// pausedTableDLQ sends the events of a paused table to the DLQ.
+	pausedTableDLQ
+)
+
+var pausedTablePolicySetting = settings.RegisterEnumSetting(
+	settings.ApplicationLevel,
+	"logical_replication.consumer.paused_table_policy",
+	"determines how events of paused tables are handled: retry holds them in the retry queue "+
+		"until resumed, holding back the replicated time of their job, dlq sends them to the DLQ",
+	"retry",
+	map[pausedTablePolicy]string{
+		pausedTableRetry: "retry",
+		pausedTableDLQ:   "dlq",
+	},
+)
+
+var errTablePaused = errors.New("logical replication of the destination table is paused")
+
+// qualifiedTableName returns the name of a destination table as it is listed
+// in pausedTables.
+func qualifiedTableName(md dstTableMetadata) string {
+	return fmt.Sprintf("%s.%s.%s", md.database, md.schema, md.table)
+}
+
+// eventSrcTableID returns the ID of the source table an event was written to.
+func eventSrcTableID(kv streampb.StreamEvent_KV) (descpb.ID, bool) {
+	key, err := keys.StripTenantPrefix(kv.KeyValue.Key)
+	if err != nil {
+		return 0, false
+	}
+	_, id, err := keys.SystemSQLCodec.DecodeTablePrefix(key)
+	if err != nil {
+		return 0, false
+	}
+	return descpb.ID(id), true
+}
+
+// newPausedEventDecoder returns a decoder for the events of the source tables
+// of a processor, used to send the events of its paused tables to the DLQ.
+func newPausedEventDecoder(
+	ctx context.Context,
+	settings *cluster.Settings,
+	procConfigByDestID map[descpb.ID]sqlProcessorTableConfig,
+) (cdcevent.Decoder, error) {
+	targets := changefeedbase.Targets{}
+	srcTablesBySrcID := make(map[descpb.ID]catalog.TableDescriptor, len(procConfigByDestID))
+	for _, s := range procConfigByDestID {
+		srcTablesBySrcID[s.srcDesc.GetID()] = s.srcDesc
+		targets.Add(changefeedbase.Target{
+			Type:              jobspb.ChangefeedTargetSpecification_EACH_FAMILY,
+			TableID:           s.srcDesc.GetID(),
+			StatementTimeName: changefeedbase.StatementTimeName(s.srcDesc.GetName()),
+		})
+	}
+	rfCache, err := cdcevent.NewFixedRowFetcherCache(
+		ctx, keys.SystemSQLCodec, settings, targets, srcTablesBySrcID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return cdcevent.NewEventDecoderWithCache(ctx, rfCache, false, false), nil
+}
+
+// splitPaused separates the events of the processor's paused tables from the
+// rest of a buffer, updating the paused tables gauge for the tables which were
+// paused or resumed since the last flush.
+func (lrw *logicalReplicationWriterProcessor) splitPaused(
+	kvs []streampb.StreamEvent_KV,
+) (unpaused, paused []streampb.StreamEvent_KV) {
+	var anyPaused bool
+	for id, name := range lrw.tableNames {
+		p := lrw.metrics.pausedTables.paused(name)
+		if p != lrw.pausedTables[id] {
+			if lrw.pausedTables == nil {
+				lrw.pausedTables = make(map[descpb.ID]bool)
+			}
+			lrw.pausedTables[id] = p
+			lrw.metrics.pausedTables.update(lrw.metrics, name, p)
+		}
+		anyPaused = anyPaused || p
+	}
+	if !anyPaused {
+		return kvs, nil
+	}
+	unpaused = make([]streampb.StreamEvent_KV, 0, len(kvs))
+	for i := range kvs {
+		if id, ok := eventSrcTableID(kvs[i]); ok && lrw.pausedTables[id] {
+			paused = append(paused, kvs[i])
+		} else {
+			unpaused = append(unpaused, kvs[i])
+		}
+	}
+	return unpaused, paused
+}
+
+// flushPaused handles the events of paused tables without applying them, per
+// pausedTablePolicy, and follows the same contract as flushBuffer.
+func (lrw *logicalReplicationWriterProcessor) flushPaused(
+	ctx context.Context,
+	kvs []streampb.StreamEvent_KV,
+	isRetry bool,
+	canRetry retryEligibility,
+	firstAttempt time.Time,
+) (notProcessed []streampb.StreamEvent_KV, notProcessedByteSize int64, _ error) {
+	var bytes int64
+	for i := range kvs {
+		bytes += int64(kvs[i].Size())
+	}
+	if !isRetry {
+		lrw.recordReceived(bytes)
+		lrw.stats.Lock()
+		lrw.stats.ReceivedBytes += bytes
+		lrw.stats.Unlock()
+	}
+
+	policy := pausedTableRetry
+	if lrw.FlowCtx != nil { // Some unit tests don't set this.
+		policy = pausedTablePolicySetting.Get(&lrw.FlowCtx.Cfg.Settings.SV)
+	}
+	if canRetry == retryAllowed && policy == pausedTableRetry {
+		return kvs, bytes, nil
+	}
+
+	for i := range kvs {
+		kv := kvs[i].KeyValue
+		var err error
+		if kv.Key, err = keys.StripTenantPrefix(kv.Key); err != nil {
+			return nil, 0, errors.Wrap(err, "stripping tenant prefix")
+		}
+		row, err := lrw.pausedDecoder.DecodeKV(ctx, kv, cdcevent.CurrentRow, kv.Value.Timestamp, false)
+		if err != nil {
+			return nil, 0, errors.Wrap(err, "decoding KeyValue")
+		}
+		if err := lrw.dlq(ctx, kvs[i], row, errTablePaused, canRetry, firstAttempt); err != nil {
+			return nil, 0, err
+		}
+		kvs[i] = streampb.StreamEvent_KV{}
+	}
+	dlqed := int64(len(kvs))
+	lrw.metrics.DLQedRowUpdates.Inc(dlqed)
+	if l := lrw.spec.MetricsLabel; l != "" {
+		lrw.metrics.LabeledEventsDLQed.Inc(map[string]string{"label": l}, dlqed)
+	}
+	lrw.stats.Lock()
+	lrw.stats.EventsDLQed += dlqed
+	lrw.stats.Unlock()
+	return nil, 0, nil
+}
+
+// pausedTableSet holds the parsed value of pausedTables, and tracks the paused
+// tables of the processors running on this node so that each table is only
+// counted once in the LabelsPaused gauge.
+type pausedTableSet struct {
+	watchOnce sync.Once
+	// names is the set of tables listed in pausedTables.
+	names atomic.Pointer[map[string]struct{}]
+
+	mu struct {
+		syncutil.Mutex
+		// processors is the number of processors of each paused table.
+		processors map[string]int
+	}
+}
+
+// watch parses pausedTables, and again whenever it changes.
+func (s *pausedTableSet) watch(sv *settings.Values) {
+	s.watchOnce.Do(func() {
+		s.set(pausedTables.Get(sv))
+		pausedTables.SetOnChange(sv, func(context.Context) {
+			s.set(pausedTables.Get(sv))
+		})
+	})
+}
+
+// set parses a comma-separated list of table names.
+func (s *pausedTableSet) set(v string) {
+	names := make(map[string]struct{})
+	for _, name := range strings.Split(v, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names[name] = struct{}{}
+		}
+	}
+	s.names.Store(&names)
+}
+
+// paused returns true if the named table is listed in pausedTables.
+func (s *pausedTableSet) paused(name string) bool {
+	names := s.names.Load()
+	if names == nil {
+		return false
+	}
+	_, ok := (*names)[name]
+	return ok
+}
+
+// update records that a processor's table was paused or resumed.
+func (s *pausedTableSet) update(m *Metrics, name string, paused bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.mu.processors == nil {
+		s.mu.processors = make(map[string]int)
+	}
+	if paused {
+		s.mu.processors[name]++
+	} else if s.mu.processors[name]--; s.mu.processors[name] <= 0 {
+		delete(s.mu.processors, name)
+	}
+	m.LabelsPaused.Update(int64(len(s.mu.processors)))
+}
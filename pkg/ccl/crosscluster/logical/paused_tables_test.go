@@ -0,0 +1,107 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package logical
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/repstream/streampb"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/metric"
+	"github.com/cockroachdb/cockroach/pkg/util/mon"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPausedTableSet(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	m := &Metrics{LabelsPaused: metric.NewGauge(metric.Metadata{})}
+	var s pausedTableSet
+
+	require.False(t, s.paused("db.public.a"))
+	s.set(" db.public.a,, db.public.b ")
+	require.True(t, s.paused("db.public.a"))
+	require.True(t, s.paused("db.public.b"))
+	require.False(t, s.paused("db.public.c"))
+
+	// Processors of the same table only count the table once.
+	s.update(m, "db.public.a", true)
+	s.update(m, "db.public.a", true)
+	s.update(m, "db.public.b", true)
+	require.Equal(t, int64(2), m.LabelsPaused.Value())
+
+	s.update(m, "db.public.a", false)
+	require.Equal(t, int64(2), m.LabelsPaused.Value())
+	s.update(m, "db.public.a", false)
+	require.Equal(t, int64(1), m.LabelsPaused.Value())
+	s.update(m, "db.public.b", false)
+	require.Equal(t, int64(0), m.LabelsPaused.Value())
+}
+
+// TestFlushPausedTables tests that the events of a paused table are held in the
+// retry queue, holding back checkpoints, while those of the other tables of the
+// processor are applied, and that they are applied and the held checkpoints
+// emitted once the table is resumed.
+func TestFlushPausedTables(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	dlq := mockDLQ(0)
+	lrw := &logicalReplicationWriterProcessor{
+		metrics:      MakeMetrics(0).(*Metrics),
+		getBatchSize: func() int { return 1 },
+		dlqClient:    &dlq,
+		memAcc:       *mon.NewStandaloneUnlimitedAccount(),
+		tableNames:   map[descpb.ID]string{104: "db.public.a", 105: "db.public.b"},
+	}
+	var checkpoints [][]jobspb.ResolvedSpan
+	lrw.purgatory.flush = lrw.flushBuffer
+	lrw.purgatory.checkpoint = func(_ context.Context, spans []jobspb.ResolvedSpan) error {
+		checkpoints = append(checkpoints, spans)
+		return nil
+	}
+	lrw.purgatory.byteLimit = func() int64 { return 1 << 20 }
+	lrw.purgatory.bytesGauge = lrw.metrics.RetryQueueBytes
+	lrw.purgatory.eventsGauge = lrw.metrics.RetryQueueEvents
+	lrw.purgatory.debug = &streampb.DebugLogicalConsumerStatus{}
+	lrw.bh = []BatchHandler{mockBatchHandler(false)}
+
+	tableKV := func(id uint32, k string) streampb.StreamEvent_KV {
+		key := append(keys.SystemSQLCodec.TablePrefix(id), k...)
+		return streampb.StreamEvent_KV{KeyValue: roachpb.KeyValue{Key: key}}
+	}
+
+	lrw.metrics.pausedTables.set("db.public.a")
+	require.NoError(t, lrw.handleStreamBuffer(ctx, []streampb.StreamEvent_KV{
+		tableKV(104, "x"), tableKV(105, "y"), tableKV(104, "z"),
+	}))
+	require.Equal(t, int64(1), lrw.metrics.LabelsPaused.Value())
+	require.Equal(t, int64(1), lrw.metrics.AppliedRowUpdates.Count())
+	require.Equal(t, int64(2), lrw.metrics.RetryQueueEvents.Value())
+
+	// The checkpoint is held back while the table is paused.
+	require.NoError(t, lrw.maybeCheckpoint(ctx, ts(1)))
+	require.Empty(t, checkpoints)
+	require.Equal(t, int64(2), lrw.metrics.RetryQueueEvents.Value())
+
+	// Once resumed, the next checkpoint applies the held events and emits the
+	// checkpoints which were held back.
+	lrw.metrics.pausedTables.set("")
+	require.NoError(t, lrw.maybeCheckpoint(ctx, ts(2)))
+	require.Equal(t, [][]jobspb.ResolvedSpan{ts(1), ts(2)}, checkpoints)
+	require.Equal(t, int64(0), lrw.metrics.RetryQueueEvents.Value())
+	require.Equal(t, int64(3), lrw.metrics.AppliedRowUpdates.Count())
+	require.Equal(t, int64(0), lrw.metrics.LabelsPaused.Value())
+	require.Equal(t, 0, int(dlq))
+}
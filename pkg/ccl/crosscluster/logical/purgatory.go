@@ -19,11 +19,16 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
 )
 
+// retryQueueAgeLimit is the maximum age of the events in the retry queue; the
+// events of a level older than this are sent to the DLQ, and counted by
+// DLQedDueToAge, if they fail to apply once more. Its current value is
+// exported as RetryQueueMaxAgeSeconds.
 var retryQueueAgeLimit = settings.RegisterDurationSetting(
 	settings.ApplicationLevel,
 	"logical_replication.consumer.retry_queue_duration",
 	"maximum time an incoming update can be retried before it is sent to the DLQ",
 	time.Minute,
+	settings.WithName("logical_replication.retry_queue.max_age"),
 )
 
 var retryQueueBackoff = settings.RegisterDurationSetting(
@@ -51,7 +56,7 @@ type purgatory struct {
 	delay      func() time.Duration // delay to wait between attempts of a level.
 	deadline   func() time.Duration // age of a level after which drain is mandatory.
 	byteLimit  func() int64
-	flush      func(context.Context, []streampb.StreamEvent_KV, bool, retryEligibility) ([]streampb.StreamEvent_KV, int64, error)
+	flush      func(context.Context, []streampb.StreamEvent_KV, bool, retryEligibility, time.Time, time.Time) ([]streampb.StreamEvent_KV, int64, error)
 	checkpoint func(context.Context, []jobspb.ResolvedSpan) error
 
 	// internally managed state.
@@ -66,6 +71,10 @@ type purgatoryLevel struct {
 	events                  []streampb.StreamEvent_KV
 	willResolve             []jobspb.ResolvedSpan
 	closedAt, lastAttempted time.Time
+	// firstAttempted is when the level's events were first attempted to be
+	// applied, before they were stored in purgatory at storedAt. As opposed to
+	// closedAt, storedAt is not updated when the level is closed.
+	firstAttempted, storedAt time.Time
 }
 
 func (p *purgatory) Checkpoint(ctx context.Context, checkpoint []jobspb.ResolvedSpan) {
@@ -79,7 +88,7 @@ func (p *purgatory) Checkpoint(ctx context.Context, checkpoint []jobspb.Resolved
 }
 
 func (p *purgatory) Store(
-	ctx context.Context, events []streampb.StreamEvent_KV, byteSize int64,
+	ctx context.Context, events []streampb.StreamEvent_KV, byteSize int64, firstAttempted time.Time,
 ) error {
 	if len(events) == 0 {
 		return nil
@@ -91,8 +100,11 @@ func (p *purgatory) Store(
 		}
 	}
 
-	p.levels = append(p.levels, purgatoryLevel{events: events, bytes: byteSize})
-	p.levels[len(p.levels)-1].closedAt = timeutil.Now()
+	now := timeutil.Now()
+	p.levels = append(p.levels, purgatoryLevel{
+		events: events, bytes: byteSize, firstAttempted: firstAttempted, storedAt: now,
+	})
+	p.levels[len(p.levels)-1].closedAt = now
 	p.bytes += byteSize
 	p.bytesGauge.Inc(byteSize)
 	p.eventsGauge.Inc(int64(len(events)))
@@ -122,7 +134,9 @@ func (p *purgatory) Drain(ctx context.Context) error {
 
 		const isRetry = true
 		levelBytes, levelCount := p.levels[i].bytes, len(p.levels[i].events)
-		remaining, remainingSize, err := p.flush(ctx, p.levels[i].events, isRetry, allowRetry)
+		remaining, remainingSize, err := p.flush(
+			ctx, p.levels[i].events, isRetry, allowRetry, p.levels[i].firstAttempted, p.levels[i].storedAt,
+		)
 		if err != nil {
 			return err
 		}
@@ -42,8 +42,11 @@ func TestPurgatory(t *testing.T) {
 		bytesGauge:  metric.NewGauge(metric.Metadata{}),
 		eventsGauge: metric.NewGauge(metric.Metadata{}),
 		flush: func(
-			_ context.Context, ev []streampb.StreamEvent_KV, _ bool, _ retryEligibility,
+			_ context.Context, ev []streampb.StreamEvent_KV, _ bool, _ retryEligibility, _, storedAt time.Time,
 		) ([]streampb.StreamEvent_KV, int64, error) {
+			// Events are flushed with the time they were stored at, which is
+			// when they entered the retry queue.
+			require.False(t, storedAt.IsZero())
 			var unappliedBytes int64
 			for i := range ev {
 				if i%2 == 0 {
@@ -67,18 +70,18 @@ func TestPurgatory(t *testing.T) {
 	t.Logf("size of one kv: %d", sz)
 	// Adding events makes it non-empty.
 	require.True(t, p.Empty())
-	require.NoError(t, p.Store(ctx, []streampb.StreamEvent_KV{skv("a"), skv("b")}, sz*2))
+	require.NoError(t, p.Store(ctx, []streampb.StreamEvent_KV{skv("a"), skv("b")}, sz*2, time.Time{}))
 	require.Equal(t, sz*2, p.bytes)
-	require.NoError(t, p.Store(ctx, []streampb.StreamEvent_KV{skv("c"), skv("d")}, sz*2))
+	require.NoError(t, p.Store(ctx, []streampb.StreamEvent_KV{skv("c"), skv("d")}, sz*2, time.Time{}))
 	require.Equal(t, sz*4, p.bytes)
 	p.Checkpoint(ctx, ts(1))
 
-	require.NoError(t, p.Store(ctx, []streampb.StreamEvent_KV{skv("e"), skv("f"), skv("g"), skv("h")}, sz*4))
+	require.NoError(t, p.Store(ctx, []streampb.StreamEvent_KV{skv("e"), skv("f"), skv("g"), skv("h")}, sz*4, time.Time{}))
 	require.Equal(t, int64(8), p.eventsGauge.Value())
 	require.Equal(t, sz*8, p.bytes)
 	p.Checkpoint(ctx, ts(2))
 
-	require.NoError(t, p.Store(ctx, []streampb.StreamEvent_KV{skv("x")}, sz*1))
+	require.NoError(t, p.Store(ctx, []streampb.StreamEvent_KV{skv("x")}, sz*1, time.Time{}))
 	require.False(t, p.Empty())
 	require.Equal(t, 4, len(p.levels))
 	require.Equal(t, sz*9, p.bytes)
@@ -0,0 +1,94 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package logical
+
+import (
+	"context"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/metric"
+)
+
+var stuckPartitionThreshold = settings.RegisterDurationSetting(
+	settings.ApplicationLevel,
+	"logical_replication.consumer.stuck_partition_threshold",
+	"the duration for which the replicated time of a span of a logical replication "+
+		"job must not have advanced for it to be counted as stuck; if 0, disabled",
+	10*time.Minute,
+	settings.NonNegativeDuration,
+)
+
+type partitionProgress struct {
+	ts hlc.Timestamp
+	// since is when ts was first seen.
+	since time.Time
+}
+
+// stuckPartitionTracker counts the spans of a job's frontier whose replicated
+// time has not advanced for stuckPartitionThreshold. The spans of the frontier
+// are those of the partitions assigned to the writer processors, so while a
+// span which is behind the others holds back the overall replicated time, as
+// shown by the frontier lag spread, a stuck span will never let it advance
+// past it, e.g. because the range it covers keeps failing to apply.
+type stuckPartitionTracker struct {
+	progress map[string]partitionProgress
+	// reported is this tracker's current contribution to gauge.
+	reported int64
+
+	gauge *metric.Gauge
+}
+
+// update records the replicated time of the spans of the frontier as of now,
+// and reports the number of stuck spans to the gauge. The stuck spans are
+// logged at verbosity 2.
+func (s *stuckPartitionTracker) update(
+	ctx context.Context, now time.Time, threshold time.Duration, frontier []jobspb.ResolvedSpan,
+) {
+	if s.gauge == nil {
+		return
+	}
+	progress := make(map[string]partitionProgress, len(frontier))
+	var stuck []roachpb.Span
+	for _, rs := range frontier {
+		key := rs.Span.String()
+		p, ok := s.progress[key]
+		if !ok || p.ts.Less(rs.Timestamp) {
+			p = partitionProgress{ts: rs.Timestamp, since: now}
+		}
+		progress[key] = p
+		if threshold > 0 && now.Sub(p.since) >= threshold {
+			stuck = append(stuck, rs.Span)
+		}
+	}
+	// Spans which are no longer in the frontier, e.g. after a replan, are
+	// forgotten.
+	s.progress = progress
+	if len(stuck) > 0 && log.V(2) {
+		log.Infof(ctx, "%d spans have not advanced for at least %s: %v", len(stuck), threshold, stuck)
+	}
+	s.report(int64(len(stuck)))
+}
+
+// close removes this tracker's contribution from the gauge.
+func (s *stuckPartitionTracker) close() {
+	if s.gauge == nil {
+		return
+	}
+	s.report(0)
+}
+
+func (s *stuckPartitionTracker) report(stuck int64) {
+	s.gauge.Inc(stuck - s.reported)
+	s.reported = stuck
+}
@@ -0,0 +1,69 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package logical
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/metric"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStuckPartitionTracker(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	sp := func(start, end string) roachpb.Span {
+		return roachpb.Span{Key: roachpb.Key(start), EndKey: roachpb.Key(end)}
+	}
+	frontier := func(tsA, tsB, tsC int64) []jobspb.ResolvedSpan {
+		return []jobspb.ResolvedSpan{
+			{Span: sp("a", "b"), Timestamp: hlc.Timestamp{WallTime: tsA}},
+			{Span: sp("b", "c"), Timestamp: hlc.Timestamp{WallTime: tsB}},
+			{Span: sp("c", "d"), Timestamp: hlc.Timestamp{WallTime: tsC}},
+		}
+	}
+	start := time.Unix(1000000, 0)
+	const threshold = time.Minute
+
+	gauge := metric.NewGauge(metric.Metadata{})
+	// The gauge is shared between jobs, and each only accounts for its own
+	// spans.
+	gauge.Inc(10)
+	s := stuckPartitionTracker{gauge: gauge}
+	s.update(ctx, start, threshold, frontier(1, 1, 1))
+	require.Equal(t, int64(10), gauge.Value())
+
+	// Spans which advance are not stuck, even if they are behind the others.
+	s.update(ctx, start.Add(30*time.Second), threshold, frontier(2, 1, 1))
+	s.update(ctx, start.Add(time.Minute), threshold, frontier(3, 2, 1))
+	require.Equal(t, int64(11), gauge.Value())
+
+	s.update(ctx, start.Add(2*time.Minute), threshold, frontier(4, 2, 1))
+	require.Equal(t, int64(12), gauge.Value())
+
+	// A span which advances again is no longer stuck.
+	s.update(ctx, start.Add(3*time.Minute), threshold, frontier(5, 3, 1))
+	require.Equal(t, int64(11), gauge.Value())
+
+	// Disabling the threshold reports no spans as stuck.
+	s.update(ctx, start.Add(4*time.Minute), 0, frontier(6, 3, 1))
+	require.Equal(t, int64(10), gauge.Value())
+	s.update(ctx, start.Add(5*time.Minute), threshold, frontier(7, 3, 1))
+	require.Equal(t, int64(12), gauge.Value())
+
+	s.close()
+	require.Equal(t, int64(10), gauge.Value())
+}
@@ -28,6 +28,8 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/sessiondata"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/metric/aggmetric"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
 	"github.com/cockroachdb/errors"
 )
 
@@ -88,6 +90,17 @@ type applierQuerier struct {
 
 	ieoInsert, ieoDelete, ieoApplyUDF sessiondata.InternalExecutorOverride
 
+	// metrics may be nil in tests.
+	metrics *Metrics
+	// udfLatency is the child of metrics.UDFLatency for each table, by the
+	// source descriptor ID of the rows applied to it, and udfLatencyTables the
+	// destination descriptor IDs whose children to release on Close.
+	udfLatency       map[catid.DescID]*aggmetric.Histogram
+	udfLatencyTables []descpb.ID
+	// planCacheStats counts the query cache outcomes of the applier queries,
+	// which are reported to metrics after each execution.
+	planCacheStats sessiondata.PlanCacheStats
+
 	// Used for the applier query to reduce allocations.
 	proposedMVCCTs tree.DDecimal
 }
@@ -98,18 +111,23 @@ func makeApplierQuerier(
 	tableConfigByDestID map[descpb.ID]sqlProcessorTableConfig,
 	jobID jobspb.JobID,
 	ie isql.Executor,
+	metrics *Metrics,
 ) *applierQuerier {
-	return &applierQuerier{
+	aq := &applierQuerier{
 		queryBuffer: queryBuffer{
 			deleteQueries:  make(map[catid.DescID]queryBuilder, len(tableConfigByDestID)),
 			insertQueries:  make(map[catid.DescID]map[catid.FamilyID]queryBuilder, len(tableConfigByDestID)),
 			applierQueries: make(map[catid.DescID]map[catid.FamilyID]queryBuilder, len(tableConfigByDestID)),
 		},
 		settings:    settings,
-		ieoInsert:   getIEOverride(replicatedInsertOpName, jobID),
-		ieoDelete:   getIEOverride(replicatedDeleteOpName, jobID),
-		ieoApplyUDF: getIEOverride(replicatedApplyUDFOpName, jobID),
+		ieoInsert:   getIEOverride(replicatedInsertOpName, jobID, &settings.SV),
+		ieoDelete:   getIEOverride(replicatedDeleteOpName, jobID, &settings.SV),
+		ieoApplyUDF: getIEOverride(replicatedApplyUDFOpName, jobID, &settings.SV),
+		metrics:     metrics,
+		udfLatency:  make(map[catid.DescID]*aggmetric.Histogram, len(tableConfigByDestID)),
 	}
+	aq.ieoApplyUDF.PlanCacheStats = &aq.planCacheStats
+	return aq
 }
 
 func (aq *applierQuerier) AddTable(targetDescID int32, tc sqlProcessorTableConfig) error {
@@ -127,7 +145,21 @@ func (aq *applierQuerier) AddTable(targetDescID int32, tc sqlProcessorTableConfi
 		return err
 	}
 	aq.queryBuffer.deleteQueries[td.GetID()], err = makeApplierDeleteQuery(targetDescID, td)
-	return err
+	if err != nil {
+		return err
+	}
+	if aq.metrics != nil {
+		aq.udfLatency[td.GetID()] = aq.metrics.acquireUDFLatency(descpb.ID(targetDescID))
+		aq.udfLatencyTables = append(aq.udfLatencyTables, descpb.ID(targetDescID))
+	}
+	return nil
+}
+
+func (aq *applierQuerier) Close() {
+	for _, id := range aq.udfLatencyTables {
+		aq.metrics.releaseUDFLatency(id)
+	}
+	aq.udfLatencyTables = nil
 }
 
 func (aq *applierQuerier) RequiresParsedBeforeRow(catid.DescID) bool { return true }
@@ -208,10 +240,15 @@ func (aq *applierQuerier) applyUDF(
 
 	aq.proposedMVCCTs.Decimal = eval.TimestampToDecimal(row.MvccTimestamp)
 	datums = append(datums, &aq.proposedMVCCTs)
+	start := timeutil.Now()
 	decisionRow, err := aq.queryRowExParsed(
 		ctx, replicatedApplyUDFOpName, txn, ie, aq.ieoApplyUDF, stmt,
 		datums...,
 	)
+	if h, ok := aq.udfLatency[row.TableID]; ok {
+		h.RecordValue(timeutil.Since(start).Nanoseconds())
+	}
+	aq.recordPlanCacheStats()
 	if err != nil {
 		return noDecision, err
 	}
@@ -234,6 +271,17 @@ func (aq *applierQuerier) applyUDF(
 	return decision, nil
 }
 
+// recordPlanCacheStats reports the query cache hits and misses of the applier
+// queries executed since the last call to the metrics.
+func (aq *applierQuerier) recordPlanCacheStats() {
+	hits, misses := aq.planCacheStats.Hits.Swap(0), aq.planCacheStats.Misses.Swap(0)
+	if aq.metrics == nil {
+		return
+	}
+	aq.metrics.UDFPlanCacheHits.Inc(hits)
+	aq.metrics.UDFPlanCacheMisses.Inc(misses)
+}
+
 func (aq *applierQuerier) applyDecision(
 	ctx context.Context, txn *kv.Txn, ie isql.Executor, row cdcevent.Row, decision applierDecision,
 ) (batchStats, error) {
@@ -14,6 +14,7 @@ import (
 	"testing"
 
 	"github.com/cockroachdb/cockroach/pkg/ccl/crosscluster/replicationtestutils"
+	"github.com/cockroachdb/cockroach/pkg/jobs"
 	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
 	"github.com/cockroachdb/cockroach/pkg/sql/randgen"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
@@ -161,6 +162,12 @@ func TestUDFInsertOnly(t *testing.T) {
 		{"4", "44"},
 		{"5", "55"},
 	})
+
+	// The queries invoking the function are planned once per variant, and the
+	// plans reused for later events.
+	m := s.JobRegistry().(*jobs.Registry).MetricsStruct().JobSpecificMetrics[jobspb.TypeLogicalReplication].(*Metrics)
+	require.Greater(t, m.UDFPlanCacheHits.Count(), int64(0))
+	require.Greater(t, m.UDFPlanCacheMisses.Count(), int64(0))
 }
 
 func TestUDFPreviousValue(t *testing.T) {
@@ -210,3 +217,25 @@ func TestUDFPreviousValue(t *testing.T) {
 		{"1", "25"},
 	})
 }
+
+// TestUDFLatencyChildren tests that the children of the udf_latency histogram
+// are shared by the processors of a table and removed once none use it.
+func TestUDFLatencyChildren(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	m := MakeMetrics(0).(*Metrics)
+	h := m.acquireUDFLatency(104)
+	require.Same(t, h, m.acquireUDFLatency(104))
+	require.NotSame(t, h, m.acquireUDFLatency(105))
+	require.Len(t, m.udfLatencyByTable.m, 2)
+
+	m.releaseUDFLatency(104)
+	require.Len(t, m.udfLatencyByTable.m, 2)
+	m.releaseUDFLatency(104)
+	m.releaseUDFLatency(105)
+	require.Empty(t, m.udfLatencyByTable.m)
+
+	// Releasing a table which holds no child is a no-op.
+	m.releaseUDFLatency(104)
+	require.Empty(t, m.udfLatencyByTable.m)
+}
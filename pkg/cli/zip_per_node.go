@@ -534,11 +534,54 @@ func (zc *debugZipContext) collectPerNodeData(
 			if err := zc.z.createJSON(s, name, ranges.Ranges); err != nil {
 				return err
 			}
+			s = nodePrinter.start("writing proposal quota pool state")
+			name = fmt.Sprintf("%s/quota_pool_state.json", prefix)
+			if err := zc.z.createJSON(s, name, makeQuotaPoolStates(ranges.Ranges)); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
 }
 
+// quotaPoolState is the state of the proposal quota pool of a range's leader
+// replica, as written to quota_pool_state.json. It is a subset of the range
+// info also found in ranges.json, collected in one place so that the quota
+// dynamics of a cluster can be reconstructed from the files of all nodes.
+type quotaPoolState struct {
+	RangeID         roachpb.RangeID   `json:"range_id"`
+	StoreID         roachpb.StoreID   `json:"store_id"`
+	Capacity        int64             `json:"capacity"`
+	Available       int64             `json:"available"`
+	QueueLength     int64             `json:"queue_length"`
+	BaseIndex       int64             `json:"base_index"`
+	SlowestFollower roachpb.ReplicaID `json:"slowest_follower_id"`
+	LastAdvance     time.Time         `json:"last_advance"`
+}
+
+// makeQuotaPoolStates returns the quota pool states of the leader replicas
+// among ranges. Only leaders have a quota pool, with a non-zero capacity.
+func makeQuotaPoolStates(ranges []serverpb.RangeInfo) []quotaPoolState {
+	states := []quotaPoolState{}
+	for i := range ranges {
+		ri := &ranges[i].State
+		if ri.ProposalQuotaCapacity == 0 {
+			continue
+		}
+		states = append(states, quotaPoolState{
+			RangeID:         ri.Desc.RangeID,
+			StoreID:         ranges[i].SourceStoreID,
+			Capacity:        ri.ProposalQuotaCapacity,
+			Available:       ri.ApproximateProposalQuota,
+			QueueLength:     ri.ProposalQuotaWaiters,
+			BaseIndex:       ri.ProposalQuotaBaseIndex,
+			SlowestFollower: ri.ProposalQuotaSlowestFollower,
+			LastAdvance:     ri.ProposalQuotaBaseIndexAdvanced,
+		})
+	}
+	return states
+}
+
 func redactStackTrace(stacksDataWithLabels []byte) []byte {
 	re := regexp.MustCompile(fmt.Sprintf("%s=%s", rpc.RemoteAddressTag, regexpOfRemoteAddress))
 	data := re.ReplaceAll(stacksDataWithLabels, []byte(redactedAddress))
@@ -17,6 +17,8 @@ func _() {
 	_ = x[Constraints-7]
 	_ = x[VoterConstraints-8]
 	_ = x[LeasePreferences-9]
+	_ = x[ForceProposalQuota-10]
+	_ = x[ProposalQuotaExcludedFamilyIDs-11]
 }
 
 func (i Field) String() string {
@@ -39,6 +41,10 @@ func (i Field) String() string {
 		return "voter_constraints"
 	case LeasePreferences:
 		return "lease_preferences"
+	case ForceProposalQuota:
+		return "force_proposal_quota"
+	case ProposalQuotaExcludedFamilyIDs:
+		return "proposal_quota_excluded_family_ids"
 	default:
 		return "Field(" + strconv.FormatInt(int64(i), 10) + ")"
 	}
@@ -15,6 +15,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -283,6 +284,13 @@ func (z *ZoneConfig) InheritedVoterConstraints() bool {
 	return len(z.VoterConstraints) == 0 && !z.NullVoterConstraintsIsEmpty
 }
 
+// InheritedProposalQuotaExcludedFamilyIDs determines whether the
+// `ProposalQuotaExcludedFamilyIDs` field is explicitly set on this zone or if
+// it is to be inherited from its parent.
+func (z *ZoneConfig) InheritedProposalQuotaExcludedFamilyIDs() bool {
+	return len(z.ProposalQuotaExcludedFamilyIDs) == 0 && !z.NullProposalQuotaExcludedFamilyIDsIsEmpty
+}
+
 // ShouldInheritGC returns true if the zone config should inherit the GC policy
 // from the parent.
 func (z *ZoneConfig) ShouldInheritGC(parent *ZoneConfig) bool {
@@ -580,6 +588,15 @@ func (z *ZoneConfig) InheritFromParent(parent *ZoneConfig) {
 			z.GlobalReads = proto.Bool(*parent.GlobalReads)
 		}
 	}
+	if z.ForceProposalQuota == nil {
+		if parent.ForceProposalQuota != nil {
+			z.ForceProposalQuota = proto.Bool(*parent.ForceProposalQuota)
+		}
+	}
+	if z.InheritedProposalQuotaExcludedFamilyIDs() {
+		z.ProposalQuotaExcludedFamilyIDs = parent.ProposalQuotaExcludedFamilyIDs
+		z.NullProposalQuotaExcludedFamilyIDsIsEmpty = parent.NullProposalQuotaExcludedFamilyIDsIsEmpty
+	}
 	if z.RangeMinBytes == nil {
 		if parent.RangeMinBytes != nil {
 			z.RangeMinBytes = proto.Int64(*parent.RangeMinBytes)
@@ -639,6 +656,14 @@ func (z *ZoneConfig) CopyFromZone(other ZoneConfig, fieldList []tree.Name) {
 			if other.GlobalReads != nil {
 				z.GlobalReads = proto.Bool(*other.GlobalReads)
 			}
+		case "force_proposal_quota":
+			z.ForceProposalQuota = nil
+			if other.ForceProposalQuota != nil {
+				z.ForceProposalQuota = proto.Bool(*other.ForceProposalQuota)
+			}
+		case "proposal_quota_excluded_family_ids":
+			z.ProposalQuotaExcludedFamilyIDs = other.ProposalQuotaExcludedFamilyIDs
+			z.NullProposalQuotaExcludedFamilyIDsIsEmpty = other.NullProposalQuotaExcludedFamilyIDsIsEmpty
 		case "gc.ttlseconds":
 			z.GC = nil
 			if other.GC != nil {
@@ -775,6 +800,27 @@ func (z *ZoneConfig) DiffWithZone(
 					Actual:   boolToString(z.GlobalReads),
 				}, nil
 			}
+		case "force_proposal_quota":
+			if other.ForceProposalQuota == nil && z.ForceProposalQuota == nil {
+				continue
+			}
+			if z.ForceProposalQuota == nil || other.ForceProposalQuota == nil ||
+				*z.ForceProposalQuota != *other.ForceProposalQuota {
+				return false, DiffWithZoneMismatch{
+					Field:    "force_proposal_quota",
+					Expected: boolToString(other.ForceProposalQuota),
+					Actual:   boolToString(z.ForceProposalQuota),
+				}, nil
+			}
+		case "proposal_quota_excluded_family_ids":
+			if !slices.Equal(z.ProposalQuotaExcludedFamilyIDs, other.ProposalQuotaExcludedFamilyIDs) ||
+				z.InheritedProposalQuotaExcludedFamilyIDs() != other.InheritedProposalQuotaExcludedFamilyIDs() {
+				return false, DiffWithZoneMismatch{
+					Field:    "proposal_quota_excluded_family_ids",
+					Expected: fmt.Sprint(other.ProposalQuotaExcludedFamilyIDs),
+					Actual:   fmt.Sprint(z.ProposalQuotaExcludedFamilyIDs),
+				}, nil
+			}
 		case "gc.ttlseconds":
 			if other.GC == nil && z.GC == nil {
 				continue
@@ -1258,6 +1304,13 @@ func (z *ZoneConfig) toSpanConfig() (roachpb.SpanConfig, error) {
 	if z.GlobalReads != nil {
 		sc.GlobalReads = *z.GlobalReads
 	}
+	// ForceProposalQuota is false by default.
+	if z.ForceProposalQuota != nil {
+		sc.ForceProposalQuota = *z.ForceProposalQuota
+	}
+	if len(z.ProposalQuotaExcludedFamilyIDs) != 0 {
+		sc.ProposalQuotaExcludedFamilyIDs = append([]uint32(nil), z.ProposalQuotaExcludedFamilyIDs...)
+	}
 	sc.NumReplicas = *z.NumReplicas
 	if z.NumVoters != nil {
 		sc.NumVoters = *z.NumVoters
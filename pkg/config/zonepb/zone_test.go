@@ -1431,6 +1431,46 @@ func TestZoneConfigToSpanConfigConversion(t *testing.T) {
 				},
 			},
 		},
+		{
+			zoneConfig: ZoneConfig{
+				RangeMinBytes: proto.Int64(100000),
+				RangeMaxBytes: proto.Int64(200000),
+				GC: &GCPolicy{
+					TTLSeconds: 2400,
+				},
+				NumReplicas:        proto.Int32(3),
+				ForceProposalQuota: proto.Bool(true),
+			},
+			expectSpanConfig: roachpb.SpanConfig{
+				RangeMinBytes: 100000,
+				RangeMaxBytes: 200000,
+				GCPolicy: roachpb.GCPolicy{
+					TTLSeconds: 2400,
+				},
+				NumReplicas:        3,
+				ForceProposalQuota: true,
+			},
+		},
+		{
+			zoneConfig: ZoneConfig{
+				RangeMinBytes: proto.Int64(100000),
+				RangeMaxBytes: proto.Int64(200000),
+				GC: &GCPolicy{
+					TTLSeconds: 2400,
+				},
+				NumReplicas:                    proto.Int32(3),
+				ProposalQuotaExcludedFamilyIDs: []uint32{1, 3},
+			},
+			expectSpanConfig: roachpb.SpanConfig{
+				RangeMinBytes: 100000,
+				RangeMaxBytes: 200000,
+				GCPolicy: roachpb.GCPolicy{
+					TTLSeconds: 2400,
+				},
+				NumReplicas:                    3,
+				ProposalQuotaExcludedFamilyIDs: []uint32{1, 3},
+			},
+		},
 	}
 	for _, tc := range testCases {
 		spanConfig, err := tc.zoneConfig.toSpanConfig()
@@ -1451,3 +1491,46 @@ func TestHardCodedSpanConfigs(t *testing.T) {
 		require.True(t, converted.Equal(roachpb.TestingSystemSpanConfig()))
 	}
 }
+
+func TestZoneConfigInheritProposalQuotaExcludedFamilyIDs(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	parent := ZoneConfig{ProposalQuotaExcludedFamilyIDs: []uint32{1, 3}}
+	testCases := []struct {
+		name     string
+		child    ZoneConfig
+		expected []uint32
+	}{
+		{
+			name:     "unset",
+			expected: []uint32{1, 3},
+		},
+		{
+			name:     "explicitly empty",
+			child:    ZoneConfig{NullProposalQuotaExcludedFamilyIDsIsEmpty: true},
+			expected: nil,
+		},
+		{
+			name:     "set",
+			child:    ZoneConfig{ProposalQuotaExcludedFamilyIDs: []uint32{2}},
+			expected: []uint32{2},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			child := tc.child
+			child.InheritFromParent(&parent)
+			require.Equal(t, tc.expected, child.ProposalQuotaExcludedFamilyIDs)
+			require.False(t, child.InheritedProposalQuotaExcludedFamilyIDs())
+
+			// An explicitly empty list must survive a YAML round trip, while an
+			// inherited one is omitted.
+			body, err := yaml.Marshal(tc.child)
+			require.NoError(t, err)
+			var unmarshaled ZoneConfig
+			require.NoError(t, yaml.UnmarshalStrict(body, &unmarshaled))
+			require.Equal(t, tc.child.InheritedProposalQuotaExcludedFamilyIDs(),
+				unmarshaled.InheritedProposalQuotaExcludedFamilyIDs(), "yaml: %s", body)
+		})
+	}
+}
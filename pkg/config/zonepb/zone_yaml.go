@@ -190,6 +190,36 @@ func (c *ConstraintsList) UnmarshalYAML(unmarshal func(interface{}) error) error
 	return nil
 }
 
+// familyIDList is a list of column family IDs that can be marshaled to/from
+// YAML while distinguishing an explicitly empty list from an inherited one. The
+// zero value is an inherited list and is omitted from the output.
+type familyIDList struct {
+	IDs      []uint32
+	Explicit bool
+}
+
+var _ yaml.Marshaler = familyIDList{}
+var _ yaml.Unmarshaler = &familyIDList{}
+
+// MarshalYAML implements yaml.Marshaler.
+func (l familyIDList) MarshalYAML() (interface{}, error) {
+	if l.IDs == nil {
+		return []uint32{}, nil
+	}
+	return l.IDs, nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (l *familyIDList) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var ids []uint32
+	if err := unmarshal(&ids); err != nil {
+		return err
+	}
+	l.IDs = ids
+	l.Explicit = true
+	return nil
+}
+
 // marshalableZoneConfig should be kept up-to-date with the real,
 // auto-generated ZoneConfig type, but with []Constraints changed to
 // ConstraintsList for backwards-compatible yaml marshaling and unmarshaling.
@@ -199,18 +229,20 @@ func (c *ConstraintsList) UnmarshalYAML(unmarshal func(interface{}) error) error
 //
 // TODO(a-robinson,v2.2): Remove the experimental_lease_preferences field.
 type marshalableZoneConfig struct {
-	RangeMinBytes                *int64            `json:"range_min_bytes" yaml:"range_min_bytes"`
-	RangeMaxBytes                *int64            `json:"range_max_bytes" yaml:"range_max_bytes"`
-	GC                           *GCPolicy         `json:"gc"`
-	GlobalReads                  *bool             `json:"global_reads" yaml:"global_reads"`
-	NumReplicas                  *int32            `json:"num_replicas" yaml:"num_replicas"`
-	NumVoters                    *int32            `json:"num_voters" yaml:"num_voters"`
-	Constraints                  ConstraintsList   `json:"constraints" yaml:"constraints,flow"`
-	VoterConstraints             ConstraintsList   `json:"voter_constraints" yaml:"voter_constraints,flow"`
-	LeasePreferences             []LeasePreference `json:"lease_preferences" yaml:"lease_preferences,flow"`
-	ExperimentalLeasePreferences []LeasePreference `json:"experimental_lease_preferences" yaml:"experimental_lease_preferences,flow,omitempty"`
-	Subzones                     []Subzone         `json:"subzones" yaml:"-"`
-	SubzoneSpans                 []SubzoneSpan     `json:"subzone_spans" yaml:"-"`
+	RangeMinBytes                  *int64            `json:"range_min_bytes" yaml:"range_min_bytes"`
+	RangeMaxBytes                  *int64            `json:"range_max_bytes" yaml:"range_max_bytes"`
+	GC                             *GCPolicy         `json:"gc"`
+	GlobalReads                    *bool             `json:"global_reads" yaml:"global_reads"`
+	NumReplicas                    *int32            `json:"num_replicas" yaml:"num_replicas"`
+	NumVoters                      *int32            `json:"num_voters" yaml:"num_voters"`
+	Constraints                    ConstraintsList   `json:"constraints" yaml:"constraints,flow"`
+	VoterConstraints               ConstraintsList   `json:"voter_constraints" yaml:"voter_constraints,flow"`
+	LeasePreferences               []LeasePreference `json:"lease_preferences" yaml:"lease_preferences,flow"`
+	ForceProposalQuota             *bool             `json:"force_proposal_quota,omitempty" yaml:"force_proposal_quota,omitempty"`
+	ProposalQuotaExcludedFamilyIDs familyIDList      `json:"proposal_quota_excluded_family_ids,omitempty" yaml:"proposal_quota_excluded_family_ids,flow,omitempty"`
+	ExperimentalLeasePreferences   []LeasePreference `json:"experimental_lease_preferences" yaml:"experimental_lease_preferences,flow,omitempty"`
+	Subzones                       []Subzone         `json:"subzones" yaml:"-"`
+	SubzoneSpans                   []SubzoneSpan     `json:"subzone_spans" yaml:"-"`
 }
 
 func zoneConfigToMarshalable(c ZoneConfig) marshalableZoneConfig {
@@ -243,6 +275,14 @@ func zoneConfigToMarshalable(c ZoneConfig) marshalableZoneConfig {
 	if !c.InheritedLeasePreferences {
 		m.LeasePreferences = c.LeasePreferences
 	}
+	if c.ForceProposalQuota != nil {
+		m.ForceProposalQuota = proto.Bool(*c.ForceProposalQuota)
+	}
+	// As with VoterConstraints, NullProposalQuotaExcludedFamilyIDsIsEmpty is used
+	// directly to preserve round-trippability.
+	m.ProposalQuotaExcludedFamilyIDs = familyIDList{
+		c.ProposalQuotaExcludedFamilyIDs, c.NullProposalQuotaExcludedFamilyIDsIsEmpty,
+	}
 	// We intentionally do not round-trip ExperimentalLeasePreferences. We never
 	// want to return yaml containing it.
 	m.Subzones = c.Subzones
@@ -280,6 +320,11 @@ func zoneConfigFromMarshalable(m marshalableZoneConfig, c ZoneConfig) ZoneConfig
 	if m.LeasePreferences != nil {
 		c.LeasePreferences = m.LeasePreferences
 	}
+	if m.ForceProposalQuota != nil {
+		c.ForceProposalQuota = proto.Bool(*m.ForceProposalQuota)
+	}
+	c.ProposalQuotaExcludedFamilyIDs = m.ProposalQuotaExcludedFamilyIDs.IDs
+	c.NullProposalQuotaExcludedFamilyIDsIsEmpty = m.ProposalQuotaExcludedFamilyIDs.Explicit
 
 	// Prefer a provided m.ExperimentalLeasePreferences value over whatever is in
 	// m.LeasePreferences, since we know that m.ExperimentalLeasePreferences can
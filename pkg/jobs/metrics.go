@@ -39,6 +39,10 @@ type Metrics struct {
 	// ResolvedMetrics are the per job type metrics for resolved timestamps.
 	ResolvedMetrics [jobspb.NumJobTypes]*metric.Gauge
 
+	// ResolvedLagMetrics are the per job type metrics for the lag of resolved
+	// timestamps behind the current time.
+	ResolvedLagMetrics [jobspb.NumJobTypes]*metric.Gauge
+
 	// RunningNonIdleJobs is the total number of running jobs that are not idle.
 	RunningNonIdleJobs *metric.Gauge
 
@@ -298,6 +302,9 @@ func (m *Metrics) init(histogramWindowInterval time.Duration, lookup *cidr.Looku
 			if opts.resolvedMetric != nil {
 				m.ResolvedMetrics[jt] = opts.resolvedMetric
 			}
+			if opts.resolvedLagMetric != nil {
+				m.ResolvedLagMetrics[jt] = opts.resolvedLagMetric
+			}
 		}
 	}
 }
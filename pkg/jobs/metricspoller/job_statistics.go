@@ -92,9 +92,10 @@ const lowTSForTypeQuery = `SELECT min(high_water_timestamp) FROM crdb_internal.j
 // tracking.
 func updateTSMetrics(ctx context.Context, execCtx sql.JobExecContext) error {
 	for _, typ := range jobspb.Type_value {
-		m := execCtx.ExecCfg().JobRegistry.MetricsStruct().ResolvedMetrics[typ]
+		metrics := execCtx.ExecCfg().JobRegistry.MetricsStruct()
+		m, lag := metrics.ResolvedMetrics[typ], metrics.ResolvedLagMetrics[typ]
 		// If this job type does not register a resolved TS metric, skip it.
-		if m == nil {
+		if m == nil && lag == nil {
 			continue
 		}
 
@@ -118,7 +119,18 @@ func updateTSMetrics(ctx context.Context, execCtx sql.JobExecContext) error {
 		}); err != nil {
 			return errors.Wrap(err, "could not query jobs table")
 		}
-		m.Update(ts.GoTime().Unix())
+		if m != nil {
+			m.Update(ts.GoTime().Unix())
+		}
+		if lag != nil {
+			// As with the resolved ts metric, a zero ts means there is no data, in
+			// which case there is no lag to report either.
+			if ts.IsEmpty() {
+				lag.Update(0)
+			} else {
+				lag.Update(int64(timeutil.Since(ts.GoTime()).Seconds()))
+			}
+		}
 	}
 	return nil
 }
@@ -1419,6 +1419,15 @@ func WithResolvedMetric(m *metric.Gauge) RegisterOption {
 	}
 }
 
+// WithResolvedLagMetric registers a gauge metric that the poller will update
+// to reflect how far, in seconds, the minimum resolved timestamp of all the
+// jobs of this type is behind the current time.
+func WithResolvedLagMetric(m *metric.Gauge) RegisterOption {
+	return func(opts *registerOptions) {
+		opts.resolvedLagMetric = m
+	}
+}
+
 // registerOptions are passed to RegisterConstructor and control how a job
 // resumer is created and configured.
 type registerOptions struct {
@@ -1436,6 +1445,10 @@ type registerOptions struct {
 
 	// resolvedMetric, if set, is the metric to update using the min resolved ts.
 	resolvedMetric *metric.Gauge
+
+	// resolvedLagMetric, if set, is the metric to update using the lag of the
+	// min resolved ts behind the current time.
+	resolvedLagMetric *metric.Gauge
 }
 
 // JobResultsReporter is an interface for reporting the results of the job execution.
@@ -14,6 +14,7 @@ import (
 	"context"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/concurrency/isolation"
 	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/concurrency/lock"
@@ -732,6 +733,14 @@ func TestBatchResponseCombine(t *testing.T) {
 			t.Fatal("Combine() did not update the header")
 		}
 	}
+	// The longest of the backpressure hints of the combined responses is kept.
+	for _, hint := range []time.Duration{time.Second, 2 * time.Second, 0} {
+		brHint := &BatchResponse{
+			BatchResponse_Header: BatchResponse_Header{BackpressureHint: hint},
+		}
+		require.NoError(t, br.Combine(context.Background(), brHint, nil, &BatchRequest{}))
+	}
+	require.Equal(t, 2*time.Second, br.BackpressureHint)
 
 	br.Responses = make([]ResponseUnion, 1)
 
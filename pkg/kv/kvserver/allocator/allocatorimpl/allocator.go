@@ -2009,6 +2009,8 @@ func (a *Allocator) ScorerOptionsForScatter(ctx context.Context) *ScatterScorerO
 // method is not the Raft leader (meaning that it doesn't know whether follower
 // replicas need a snapshot or not), produces no results.
 // - It excludes replicas that are on stores which are IO overloaded.
+// - It excludes replicas other than the leaseholder that are on stores with
+// quota stalled leaders, unless no other candidates remain.
 func (a *Allocator) ValidLeaseTargets(
 	ctx context.Context,
 	storePool storepool.AllocatorStorePool,
@@ -2101,7 +2103,36 @@ func (a *Allocator) ValidLeaseTargets(
 		a.IOOverloadOptions(),
 	)
 
-	return nonIOOverloadedPreferred
+	return a.nonQuotaStalledLeaseTargets(storePool, nonIOOverloadedPreferred, leaseRepl.StoreID())
+}
+
+// nonQuotaStalledLeaseTargets returns the lease replica targets which are not
+// on stores with quota stalled leaders (see
+// roachpb.StoreCapacity.QuotaStalledLeaders), ranking such stores below all
+// other candidates: they are only returned if no other candidate, including
+// the leaseholder, remains. The leaseholder is never excluded, as moving its
+// lease off of a store with stalled leaders would only move the stall
+// elsewhere.
+func (a *Allocator) nonQuotaStalledLeaseTargets(
+	storePool storepool.AllocatorStorePool,
+	existingReplicas []roachpb.ReplicaDescriptor,
+	leaseStoreID roachpb.StoreID,
+) []roachpb.ReplicaDescriptor {
+	sl, _, _ := storePool.GetStoreListFromIDs(replDescsToStoreIDs(existingReplicas), storepool.StoreFilterSuspect)
+
+	var candidates []roachpb.ReplicaDescriptor
+	for _, replDesc := range existingReplicas {
+		if replDesc.StoreID != leaseStoreID {
+			if store, ok := sl.FindStoreByID(replDesc.StoreID); ok && store.Capacity.QuotaStalledLeaders > 0 {
+				continue
+			}
+		}
+		candidates = append(candidates, replDesc)
+	}
+	if len(candidates) == 0 {
+		return existingReplicas
+	}
+	return candidates
 }
 
 // nonIOOverloadedLeaseTargets returns a list of non IO overloaded lease
@@ -2268,6 +2268,89 @@ func TestAllocatorTransferLeaseToReplicasNeedingSnapshot(t *testing.T) {
 	}
 }
 
+// TestAllocatorTransferLeaseTargetQuotaStalled tests that stores with quota
+// stalled leaders are only picked as lease transfer targets when no other
+// candidate remains, and that the leaseholder is not moved off of such a
+// store.
+func TestAllocatorTransferLeaseTargetQuotaStalled(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+	ctx := context.Background()
+
+	testCases := []struct {
+		name             string
+		stalled          []roachpb.StoreID
+		leaseholder      roachpb.StoreID
+		excludeLeaseRepl bool
+		expected         roachpb.StoreID
+	}{
+		{name: "no stalled stores", leaseholder: 1, expected: 2},
+		{name: "keep lease over stalled stores", stalled: []roachpb.StoreID{2, 3}, leaseholder: 1, expected: 0},
+		{name: "stalled leaseholder", stalled: []roachpb.StoreID{1}, leaseholder: 1, expected: 2},
+		{name: "no stalled stores excluding leaseholder", leaseholder: 1, excludeLeaseRepl: true, expected: 2},
+		{
+			name:             "skip stalled store",
+			stalled:          []roachpb.StoreID{2},
+			leaseholder:      1,
+			excludeLeaseRepl: true,
+			expected:         3,
+		},
+		{
+			name:             "stalled stores as last resort",
+			stalled:          []roachpb.StoreID{2, 3},
+			leaseholder:      1,
+			excludeLeaseRepl: true,
+			expected:         2,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stopper, g, sp, a, _ := CreateTestAllocator(ctx, 10, true /* deterministic */)
+			defer stopper.Stop(ctx)
+
+			// Only store 2 has fewer leases than the mean, so among the other
+			// stores, store 3 is only picked if the lease must move.
+			leaseCounts := []int32{200, 0, 100}
+			var stores []*roachpb.StoreDescriptor
+			for i, leaseCount := range leaseCounts {
+				storeID := roachpb.StoreID(i + 1)
+				capacity := roachpb.StoreCapacity{LeaseCount: leaseCount}
+				for _, stalled := range tc.stalled {
+					if stalled == storeID {
+						capacity.QuotaStalledLeaders = 1
+					}
+				}
+				stores = append(stores, &roachpb.StoreDescriptor{
+					StoreID:  storeID,
+					Node:     roachpb.NodeDescriptor{NodeID: roachpb.NodeID(i + 1)},
+					Capacity: capacity,
+				})
+			}
+			sg := gossiputil.NewStoreGossiper(g)
+			sg.GossipStores(stores, t)
+
+			target := a.TransferLeaseTarget(
+				ctx,
+				sp,
+				&roachpb.RangeDescriptor{},
+				emptySpanConfig(),
+				replicas(1, 2, 3),
+				&mockRepl{
+					replicationFactor: 3,
+					storeID:           tc.leaseholder,
+				},
+				allocator.RangeUsageInfo{}, /* stats */
+				false,                      /* forceDecisionWithoutStats */
+				allocator.TransferLeaseOptions{
+					ExcludeLeaseRepl:       tc.excludeLeaseRepl,
+					CheckCandidateFullness: true,
+				},
+			)
+			require.Equal(t, tc.expected, target.StoreID)
+		})
+	}
+}
+
 func TestAllocatorTransferLeaseTargetConstraints(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	defer log.Scope(t).Close(t)
@@ -53,6 +53,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/storage/fs"
 	"github.com/cockroachdb/cockroach/pkg/testutils"
 	"github.com/cockroachdb/cockroach/pkg/testutils/listenerutil"
+	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
 	"github.com/cockroachdb/cockroach/pkg/testutils/skip"
 	"github.com/cockroachdb/cockroach/pkg/testutils/sqlutils"
 	"github.com/cockroachdb/cockroach/pkg/testutils/storageutils"
@@ -2648,6 +2649,326 @@ func TestQuotaPool(t *testing.T) {
 	}
 }
 
+// TestQuotaPoolReleaseQueueCap verifies that once the quota release queue of a
+// leader grows past kv.raft.proposal_quota.max_release_queue_length because a
+// follower is stuck, further writes are held back even though the quota pool
+// has plenty of capacity, and that they proceed once the follower catches up.
+func TestQuotaPoolReleaseQueueCap(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const maxQueueLen = 2
+	const numReplicas = 3
+
+	ctx := context.Background()
+
+	tc := testcluster.StartTestCluster(t, numReplicas,
+		base.TestClusterArgs{
+			ReplicationMode: base.ReplicationManual,
+			ServerArgs: base.TestServerArgs{
+				RaftConfig: base.RaftConfig{
+					// Suppress timeout-based elections to avoid leadership changes in ways
+					// this test doesn't expect.
+					RaftElectionTimeoutTicks: 100000,
+				},
+			},
+		})
+	defer tc.Stopper().Stop(ctx)
+
+	for i := range tc.Servers {
+		kvserver.MaxProposalQuotaReleaseQueueLength.Override(ctx, &tc.Server(i).ClusterSettings().SV, maxQueueLen)
+	}
+
+	key := tc.ScratchRange(t)
+	tc.AddVotersOrFatal(t, key, tc.Targets(1, 2)...)
+
+	raftLockReplica := func(repl *kvserver.Replica) {
+		ch := make(chan struct{})
+		go func() { repl.RaftLock(); close(ch) }()
+		<-ch
+	}
+
+	leaderRepl := tc.GetRaftLeader(t, roachpb.RKey(key))
+	// Wait until the followers have caught up so that neither is ignored by
+	// updateProposalQuotaRaftMuLocked.
+	testutils.SucceedsSoon(t, func() error {
+		status := leaderRepl.RaftStatus()
+		for id, progress := range status.Progress {
+			if progress.Match < status.Applied {
+				return errors.Errorf("replica %d is behind leader expected %d but was %d", id, status.Applied, progress.Match)
+			}
+		}
+		return nil
+	})
+
+	var followerRepl *kvserver.Replica
+	for i := range tc.Servers {
+		repl := tc.GetFirstStoreFromServer(t, i).LookupReplica(roachpb.RKey(key))
+		require.NotNil(t, repl)
+		if repl != leaderRepl {
+			followerRepl = repl
+			break
+		}
+	}
+	require.NotNil(t, followerRepl)
+
+	put := func() *kvpb.Error {
+		ba := &kvpb.BatchRequest{}
+		ba.Add(putArgs(key.Next(), []byte("v")))
+		if err := ba.SetActiveTimestamp(tc.Servers[0].Clock()); err != nil {
+			return kvpb.NewError(err)
+		}
+		_, pErr := leaderRepl.Send(ctx, ba)
+		return pErr
+	}
+
+	initialQuota := leaderRepl.QuotaAvailable()
+	// Block a follower, so that quota for new entries is not released.
+	raftLockReplica(followerRepl)
+	ch := make(chan *kvpb.Error, 1)
+	func() {
+		defer followerRepl.RaftUnlock()
+
+		// Small writes are admitted until the queue exceeds the cap.
+		testutils.SucceedsSoon(t, func() error {
+			if qLen := leaderRepl.QuotaReleaseQueueLen(); qLen <= maxQueueLen {
+				if pErr := put(); pErr != nil {
+					t.Fatal(pErr)
+				}
+				return errors.Errorf("expected more than %d queued quota releases, found: %d", maxQueueLen, qLen)
+			}
+			return nil
+		})
+		if curQuota := leaderRepl.QuotaAvailable(); curQuota < initialQuota/2 {
+			t.Fatalf("expected most of the quota to be available, found %d", curQuota)
+		}
+
+		go func() { ch <- put() }()
+		select {
+		case pErr := <-ch:
+			t.Fatalf("write was not blocked by the release queue cap: %v", pErr)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}()
+
+	// With the follower unblocked, the queue drains and the write goes through.
+	if pErr := <-ch; pErr != nil {
+		t.Fatal(pErr)
+	}
+	testutils.SucceedsSoon(t, func() error {
+		if qLen := leaderRepl.QuotaReleaseQueueLen(); qLen != 0 {
+			return errors.Errorf("expected no queued quota releases, found: %d", qLen)
+		}
+		return nil
+	})
+}
+
+// TestQuotaPoolRelaxation verifies that once proposals have been waiting for
+// longer than kv.raft.proposal_quota.relax_after for quota which no follower
+// has caught up to release, the leader releases it regardless, so that writes
+// continue while the follower is stuck.
+func TestQuotaPoolRelaxation(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const quota = 10000
+	const numReplicas = 3
+
+	ctx := context.Background()
+
+	tc := testcluster.StartTestCluster(t, numReplicas,
+		base.TestClusterArgs{
+			ReplicationMode: base.ReplicationManual,
+			ServerArgs: base.TestServerArgs{
+				RaftConfig: base.RaftConfig{
+					// Suppress timeout-based elections to avoid leadership changes in ways
+					// this test doesn't expect.
+					RaftElectionTimeoutTicks: 100000,
+				},
+			},
+		})
+	defer tc.Stopper().Stop(ctx)
+
+	key := tc.ScratchRange(t)
+	tc.AddVotersOrFatal(t, key, tc.Targets(1, 2)...)
+
+	raftLockReplica := func(repl *kvserver.Replica) {
+		ch := make(chan struct{})
+		go func() { repl.RaftLock(); close(ch) }()
+		<-ch
+	}
+
+	leaderRepl := tc.GetRaftLeader(t, roachpb.RKey(key))
+	raftLockReplica(leaderRepl)
+	require.NoError(t, leaderRepl.InitQuotaPool(quota))
+	leaderRepl.RaftUnlock()
+	// Wait until the followers have caught up so that neither is ignored by
+	// updateProposalQuotaRaftMuLocked.
+	testutils.SucceedsSoon(t, func() error {
+		status := leaderRepl.RaftStatus()
+		for id, progress := range status.Progress {
+			if progress.Match < status.Applied {
+				return errors.Errorf("replica %d is behind leader expected %d but was %d", id, status.Applied, progress.Match)
+			}
+		}
+		return nil
+	})
+
+	var followerRepl *kvserver.Replica
+	var leaderStore *kvserver.Store
+	for i := range tc.Servers {
+		store := tc.GetFirstStoreFromServer(t, i)
+		repl := store.LookupReplica(roachpb.RKey(key))
+		require.NotNil(t, repl)
+		if repl == leaderRepl {
+			leaderStore = store
+		} else if followerRepl == nil {
+			followerRepl = repl
+		}
+	}
+	require.NotNil(t, followerRepl)
+
+	value := bytes.Repeat([]byte("v"), (3*quota)/4)
+	put := func() *kvpb.Error {
+		ba := &kvpb.BatchRequest{}
+		ba.Add(putArgs(key.Next(), value))
+		if err := ba.SetActiveTimestamp(tc.Servers[0].Clock()); err != nil {
+			return kvpb.NewError(err)
+		}
+		_, pErr := leaderRepl.Send(ctx, ba)
+		return pErr
+	}
+
+	// Block a follower, so that it holds up the release of quota for the
+	// entries it is missing even though they are committed by the others.
+	raftLockReplica(followerRepl)
+	defer followerRepl.RaftUnlock()
+
+	relaxed := leaderStore.Metrics().RaftProposalQuotaRelaxed.Count()
+	require.Nil(t, put())
+	ch := make(chan *kvpb.Error, 1)
+	go func() { ch <- put() }()
+	select {
+	case pErr := <-ch:
+		t.Fatalf("write was not blocked on proposal quota: %v", pErr)
+	case <-time.After(50 * time.Millisecond):
+	}
+	require.Equal(t, relaxed, leaderStore.Metrics().RaftProposalQuotaRelaxed.Count())
+
+	// Once relaxation is enabled, the quota is released regardless of the
+	// follower and the write goes through.
+	for i := range tc.Servers {
+		kvserver.ProposalQuotaRelaxAfter.Override(ctx, &tc.Server(i).ClusterSettings().SV, time.Millisecond)
+	}
+	require.Nil(t, <-ch)
+	require.Less(t, relaxed, leaderStore.Metrics().RaftProposalQuotaRelaxed.Count())
+}
+
+// TestForceProposalQuotaZoneConfig verifies that force_proposal_quota can be
+// set through ALTER ... CONFIGURE ZONE, and that it reaches the span configs
+// of the table's ranges.
+func TestForceProposalQuotaZoneConfig(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+	ctx := context.Background()
+
+	srv, db, _ := serverutils.StartServer(t, base.TestServerArgs{
+		DefaultTestTenant: base.TestIsSpecificToStorageLayerAndNeedsASystemTenant,
+	})
+	defer srv.Stopper().Stop(ctx)
+	sqlDB := sqlutils.MakeSQLRunner(db)
+
+	sqlDB.Exec(t, `CREATE TABLE t (k INT PRIMARY KEY)`)
+	var tableID uint32
+	sqlDB.QueryRow(t, `SELECT 't'::regclass::oid`).Scan(&tableID)
+	tableKey := keys.SystemSQLCodec.TablePrefix(tableID)
+	waitForForced := func(t *testing.T, exp bool) {
+		t.Helper()
+		testutils.SucceedsSoon(t, func() error {
+			_, r := getFirstStoreReplica(t, srv, tableKey)
+			if !r.Desc().StartKey.Equal(tableKey) {
+				return errors.Errorf("table has not been split off yet: %s", r)
+			}
+			conf, err := r.LoadSpanConfig(ctx)
+			if err != nil {
+				return err
+			}
+			if conf.ForceProposalQuota != exp {
+				return errors.Errorf("expected force_proposal_quota=%t, found %t", exp, conf.ForceProposalQuota)
+			}
+			return nil
+		})
+	}
+	waitForForced(t, false)
+
+	sqlDB.Exec(t, `ALTER TABLE t CONFIGURE ZONE USING force_proposal_quota = true`)
+	waitForForced(t, true)
+	var rawConfigSQL string
+	sqlDB.QueryRow(t, `SELECT raw_config_sql FROM [SHOW ZONE CONFIGURATION FOR TABLE t]`).Scan(&rawConfigSQL)
+	require.Contains(t, rawConfigSQL, "force_proposal_quota = true")
+
+	sqlDB.Exec(t, `ALTER TABLE t CONFIGURE ZONE USING force_proposal_quota = COPY FROM PARENT`)
+	waitForForced(t, false)
+}
+
+// TestProposalQuotaExcludedFamilyIDsZoneConfig verifies that the column
+// families excluded from the proposal quota charge can be set through ALTER
+// ... CONFIGURE ZONE, and that writes to them are then excluded.
+func TestProposalQuotaExcludedFamilyIDsZoneConfig(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+	ctx := context.Background()
+
+	srv, db, _ := serverutils.StartServer(t, base.TestServerArgs{
+		DefaultTestTenant: base.TestIsSpecificToStorageLayerAndNeedsASystemTenant,
+	})
+	defer srv.Stopper().Stop(ctx)
+	sqlDB := sqlutils.MakeSQLRunner(db)
+	store, err := srv.GetStores().(*kvserver.Stores).GetStore(srv.GetFirstStoreID())
+	require.NoError(t, err)
+
+	sqlDB.Exec(t, `SET CLUSTER SETTING sql.mutations.attribute_index_writes.enabled = true`)
+	sqlDB.Exec(t, `CREATE TABLE t (k INT PRIMARY KEY, hot INT, blob STRING, FAMILY f0 (k, hot), FAMILY f1 (blob))`)
+	var tableID uint32
+	sqlDB.QueryRow(t, `SELECT 't'::regclass::oid`).Scan(&tableID)
+	tableKey := keys.SystemSQLCodec.TablePrefix(tableID)
+
+	sqlDB.Exec(t, `ALTER TABLE t CONFIGURE ZONE USING proposal_quota_excluded_family_ids = '[1]'`)
+	testutils.SucceedsSoon(t, func() error {
+		_, r := getFirstStoreReplica(t, srv, tableKey)
+		if !r.Desc().StartKey.Equal(tableKey) {
+			return errors.Errorf("table has not been split off yet: %s", r)
+		}
+		conf, err := r.LoadSpanConfig(ctx)
+		if err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(conf.ProposalQuotaExcludedFamilyIDs, []uint32{1}) {
+			return errors.Errorf("expected excluded families [1], found %v", conf.ProposalQuotaExcludedFamilyIDs)
+		}
+		return nil
+	})
+	var rawConfigSQL string
+	sqlDB.QueryRow(t, `SELECT raw_config_sql FROM [SHOW ZONE CONFIGURATION FOR TABLE t]`).Scan(&rawConfigSQL)
+	require.Contains(t, rawConfigSQL, "proposal_quota_excluded_family_ids")
+
+	// Writes to the excluded family are excluded from the charge, once the
+	// setting to identify the primary index in the batches has propagated.
+	excluded := store.Metrics().RaftProposalQuotaExcludedFamilyBytes.Count
+	before := excluded()
+	sqlDB.Exec(t, `INSERT INTO t VALUES (1, 1, NULL)`)
+	require.Equal(t, before, excluded())
+	k := 1
+	testutils.SucceedsSoon(t, func() error {
+		k++
+		sqlDB.Exec(t, `INSERT INTO t VALUES ($1, $1, repeat('x', 1000))`, k)
+		if excluded() == before {
+			return errors.New("write to the excluded family was not excluded")
+		}
+		return nil
+	})
+}
+
 // TestWedgedReplicaDetection verifies that a leader replica is able to
 // correctly detect a wedged follower replica and no longer consider it
 // as active for the purpose of proposal throttling.
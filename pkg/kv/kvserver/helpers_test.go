@@ -378,6 +378,7 @@ func (r *Replica) InitQuotaPool(quota uint64) error {
 		r.mu.proposalQuota.Close("re-creating")
 	}
 	r.mu.proposalQuota = quotapool.NewIntPool(r.rangeStr.String(), quota)
+	r.store.proposalQuotaReleaseQueues.add(-len(r.mu.quotaReleaseQueue))
 	r.mu.quotaReleaseQueue = nil
 	return nil
 }
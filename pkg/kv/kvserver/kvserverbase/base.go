@@ -23,6 +23,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/settings"
 	"github.com/cockroachdb/cockroach/pkg/util/quotapool"
+	"github.com/cockroachdb/errors"
 	"github.com/cockroachdb/redact"
 )
 
@@ -322,3 +323,33 @@ var MaxCommandSize = settings.RegisterByteSizeSetting(
 	MaxCommandSizeDefault,
 	settings.ByteSizeWithMinimum(MaxCommandSizeFloor),
 )
+
+// ErrProposalQuotaQueueTooDeep is returned for a write which would have had
+// to wait for proposal quota behind at least Header.ProposalQuotaMaxWaiters
+// other proposals. It lives here, rather than in kvserver, so that clients
+// setting that header can tell these errors apart.
+var ErrProposalQuotaQueueTooDeep = errors.New("proposal quota queue too deep")
+
+// ProposalQuotaWaitFunc is called with waiting set when a request is about to
+// block waiting for the proposal quota of a range, and with waiting unset once
+// it is no longer waiting, whether or not it acquired the quota.
+type ProposalQuotaWaitFunc func(waiting bool)
+
+type proposalQuotaWaitFuncCtxKey struct{}
+
+// ContextWithProposalQuotaWaitFunc returns a context under which requests call
+// f when they wait for proposal quota. Context values are not sent with
+// requests to other nodes, so f is only called for requests evaluated by a
+// replica on the node of the caller.
+func ContextWithProposalQuotaWaitFunc(
+	ctx context.Context, f ProposalQuotaWaitFunc,
+) context.Context {
+	return context.WithValue(ctx, proposalQuotaWaitFuncCtxKey{}, f)
+}
+
+// ProposalQuotaWaitFuncFromContext returns the ProposalQuotaWaitFunc of ctx,
+// if any.
+func ProposalQuotaWaitFuncFromContext(ctx context.Context) ProposalQuotaWaitFunc {
+	f, _ := ctx.Value(proposalQuotaWaitFuncCtxKey{}).(ProposalQuotaWaitFunc)
+	return f
+}
@@ -1291,6 +1291,120 @@ or the delegate being too busy to send.
 		// (0 to 1.0) so it probably won't produce useful results here.
 		Unit: metric.Unit_COUNT,
 	}
+	metaRaftProposalQuotaSecondaryIndexPercent = metric.Metadata{
+		Name:        "raft.proposal_quota.secondary_index_percent",
+		Help:        `Histogram of the percentage (0-100) of proposal quota charged for SQL table writes that is attributable to secondary index entries; only populated while sql.mutations.attribute_index_writes.enabled is set`,
+		Measurement: "Percent",
+		Unit:        metric.Unit_PERCENT,
+	}
+	metaRaftProposalQuotaBypassed = metric.Metadata{
+		Name:        "raft.proposal_quota.bypassed",
+		Help:        `Number of proposals by internal system work which did not acquire proposal quota`,
+		Measurement: "Proposals",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaRaftProposalQuotaStructuralBypassed = metric.Metadata{
+		Name:        "raft.proposal_quota.structural_bypassed",
+		Help:        `Number of proposals by range splits and merges which did not acquire proposal quota`,
+		Measurement: "Proposals",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaRaftProposalQuotaBackpressureHints = metric.Metadata{
+		Name:        "raft.proposal_quota.backpressure_hints",
+		Help:        `Number of write responses carrying a backpressure hint because the range was low on proposal quota`,
+		Measurement: "Responses",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaRaftProposalQuotaRefunds = metric.Metadata{
+		Name:        "raft.proposal_quota.refunds",
+		Help:        `Number of proposals whose proposal quota was released as they were rejected before they could apply`,
+		Measurement: "Proposals",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaRaftProposalQuotaReplicationMultiplier = metric.Metadata{
+		Name:        "raft.proposal_quota.replication_multiplier",
+		Help:        `Histogram of the multiplier applied to the proposal quota charged for commands per kv.raft.proposal_quota.replication_factor_weighting, recorded only while weighting is enabled`,
+		Measurement: "Multiplier",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaRaftProposalQuotaAvailableByRange = metric.Metadata{
+		Name:        "raft.proposal_quota.available_by_range",
+		Help:        `Proposal quota available to the ranges led by this store with the least of it, labeled by range ID; exported for up to kv.raft.proposal_quota.available_by_range.top_n ranges, and not persisted`,
+		Measurement: "Bytes",
+		Unit:        metric.Unit_BYTES,
+	}
+	metaRaftProposalQuotaExcludedFamilyBytes = metric.Metadata{
+		Name:        "raft.proposal_quota.excluded_family_bytes",
+		Help:        `Size of SQL table writes to column families excluded from the proposal quota charge by the span config of their range`,
+		Measurement: "Bytes",
+		Unit:        metric.Unit_BYTES,
+	}
+	metaRaftProposalQuotaWakeupProposals = metric.Metadata{
+		Name:        "raft.proposal_quota.wakeup_proposals",
+		Help:        `Number of proposals charged proposal quota which were made to a quiescent range, waking it up`,
+		Measurement: "Proposals",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaRaftProposalQuotaWakeupBytes = metric.Metadata{
+		Name:        "raft.proposal_quota.wakeup_proposal_bytes",
+		Help:        `Proposal quota charged for proposals which were made to a quiescent range, waking it up`,
+		Measurement: "Bytes",
+		Unit:        metric.Unit_BYTES,
+	}
+	metaRaftProposalQuotaExemptRanges = metric.Metadata{
+		Name:        "raft.proposal_quota.exempt_ranges",
+		Help:        `Number of leaseholder replicas of tables temporarily exempt from acquiring proposal quota`,
+		Measurement: "Replicas",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaRaftProposalQuotaForcedRanges = metric.Metadata{
+		Name:        "raft.proposal_quota.force_enabled_ranges",
+		Help:        `Number of leaseholder replicas whose span config forces them to acquire proposal quota`,
+		Measurement: "Replicas",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaRaftProposalQuotaStoreQueueMemoryBytes = metric.Metadata{
+		Name:        "raft.proposal_quota.store_queue_memory_bytes",
+		Help:        `Estimated memory retained by the entries awaiting follower acknowledgement in the proposal quota release queues of all leader replicas on the store`,
+		Measurement: "Memory",
+		Unit:        metric.Unit_BYTES,
+	}
+	metaRaftProposalQuotaRangesBelow10Pct = metric.Metadata{
+		Name:        "raft.proposal_quota.ranges_below_10pct",
+		Help:        `Number of leader replicas with less than 10% of their proposal quota available`,
+		Measurement: "Replicas",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaRaftProposalQuotaAcquireNonBlocking = metric.Metadata{
+		Name:        "raft.proposal_quota.acquire_nonblocking",
+		Help:        `Number of proposal quota acquisitions which were satisfied immediately`,
+		Measurement: "Acquisitions",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaRaftProposalQuotaAcquireBlocked = metric.Metadata{
+		Name:        "raft.proposal_quota.acquire_blocked",
+		Help:        `Number of proposal quota acquisitions which had to wait for quota to be released`,
+		Measurement: "Acquisitions",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaRaftProposalQuotaUntracedAcquisitions = metric.Metadata{
+		Name:        "raft.proposal_quota.untraced_acquisitions",
+		Help:        `Number of proposal quota acquisitions by requests without a tracing span`,
+		Measurement: "Acquisitions",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaRaftProposalQuotaRelaxed = metric.Metadata{
+		Name:        "raft.proposal_quota.relaxed",
+		Help:        `Number of times a leader released proposal quota which no follower had caught up to release, as proposals had been waiting for longer than kv.raft.proposal_quota.relax_after`,
+		Measurement: "Events",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaRaftProposalQuotaReleaseBurstSize = metric.Metadata{
+		Name:        "raft.proposal_quota.release_burst_size",
+		Help:        `Histogram of the number of log entries whose proposal quota is released at once by the leaseholder`,
+		Measurement: "Entries",
+		Unit:        metric.Unit_COUNT,
+	}
 	// Raft entry bytes loaded in memory.
 	metaRaftLoadedEntriesBytes = metric.Metadata{
 		Name:        "raft.loaded_entries.bytes",
@@ -2814,6 +2928,27 @@ type StoreMetrics struct {
 
 	RaftCoalescedHeartbeatsPending *metric.Gauge
 
+	// Proposal quota metrics.
+	RaftProposalQuotaSecondaryIndexPercent metric.IHistogram
+	RaftProposalQuotaBypassed              *metric.Counter
+	RaftProposalQuotaExcludedFamilyBytes   *metric.Counter
+	RaftProposalQuotaStructuralBypassed    *metric.Counter
+	RaftProposalQuotaBackpressureHints     *metric.Counter
+	RaftProposalQuotaRefunds               *metric.Counter
+	RaftProposalQuotaReplicationMultiplier metric.IHistogram
+	RaftProposalQuotaAvailableByRange      *metric.GaugeVec
+	RaftProposalQuotaWakeupProposals       *metric.Counter
+	RaftProposalQuotaWakeupBytes           *metric.Counter
+	RaftProposalQuotaExemptRanges          *metric.Gauge
+	RaftProposalQuotaForcedRanges          *metric.Gauge
+	RaftProposalQuotaRangesBelow10Pct      *metric.Gauge
+	RaftProposalQuotaAcquireNonBlocking    *metric.Counter
+	RaftProposalQuotaAcquireBlocked        *metric.Counter
+	RaftProposalQuotaReleaseBurstSize      metric.IHistogram
+	RaftProposalQuotaRelaxed               *metric.Counter
+	RaftProposalQuotaStoreQueueMemoryBytes *metric.Gauge
+	RaftProposalQuotaUntracedAcquisitions  *metric.Counter
+
 	// Replica queue metrics.
 	StoreFailures                             *metric.Counter
 	MVCCGCQueueSuccesses                      *metric.Counter
@@ -3580,6 +3715,46 @@ func newStoreMetrics(histogramWindow time.Duration) *StoreMetrics {
 		// the queue is cleared, to avoid flapping wildly.
 		RaftCoalescedHeartbeatsPending: metric.NewGauge(metaRaftCoalescedHeartbeatsPending),
 
+		// Proposal quota metrics.
+		RaftProposalQuotaSecondaryIndexPercent: metric.NewHistogram(metric.HistogramOptions{
+			Metadata:     metaRaftProposalQuotaSecondaryIndexPercent,
+			Duration:     histogramWindow,
+			MaxVal:       100,
+			SigFigs:      1,
+			BucketConfig: metric.Percent100Buckets,
+		}),
+		RaftProposalQuotaBypassed:           metric.NewCounter(metaRaftProposalQuotaBypassed),
+		RaftProposalQuotaWakeupProposals:    metric.NewCounter(metaRaftProposalQuotaWakeupProposals),
+		RaftProposalQuotaWakeupBytes:        metric.NewCounter(metaRaftProposalQuotaWakeupBytes),
+		RaftProposalQuotaExemptRanges:       metric.NewGauge(metaRaftProposalQuotaExemptRanges),
+		RaftProposalQuotaForcedRanges:       metric.NewGauge(metaRaftProposalQuotaForcedRanges),
+		RaftProposalQuotaRangesBelow10Pct:   metric.NewGauge(metaRaftProposalQuotaRangesBelow10Pct),
+		RaftProposalQuotaAcquireNonBlocking: metric.NewCounter(metaRaftProposalQuotaAcquireNonBlocking),
+		RaftProposalQuotaAcquireBlocked:     metric.NewCounter(metaRaftProposalQuotaAcquireBlocked),
+		RaftProposalQuotaReleaseBurstSize: metric.NewHistogram(metric.HistogramOptions{
+			Metadata:     metaRaftProposalQuotaReleaseBurstSize,
+			Duration:     histogramWindow,
+			MaxVal:       1024,
+			SigFigs:      1,
+			BucketConfig: metric.Count1KBuckets,
+		}),
+		RaftProposalQuotaRelaxed:               metric.NewCounter(metaRaftProposalQuotaRelaxed),
+		RaftProposalQuotaStoreQueueMemoryBytes: metric.NewGauge(metaRaftProposalQuotaStoreQueueMemoryBytes),
+		RaftProposalQuotaUntracedAcquisitions:  metric.NewCounter(metaRaftProposalQuotaUntracedAcquisitions),
+		RaftProposalQuotaExcludedFamilyBytes:   metric.NewCounter(metaRaftProposalQuotaExcludedFamilyBytes),
+		RaftProposalQuotaStructuralBypassed:    metric.NewCounter(metaRaftProposalQuotaStructuralBypassed),
+		RaftProposalQuotaBackpressureHints:     metric.NewCounter(metaRaftProposalQuotaBackpressureHints),
+		RaftProposalQuotaRefunds:               metric.NewCounter(metaRaftProposalQuotaRefunds),
+		RaftProposalQuotaReplicationMultiplier: metric.NewHistogram(metric.HistogramOptions{
+			Metadata:     metaRaftProposalQuotaReplicationMultiplier,
+			Duration:     histogramWindow,
+			MaxVal:       100,
+			SigFigs:      1,
+			BucketConfig: metric.Count1KBuckets,
+		}),
+		RaftProposalQuotaAvailableByRange: metric.NewExportedGaugeVec(
+			metaRaftProposalQuotaAvailableByRange, []string{"range_id"}),
+
 		// Replica queue metrics.
 		StoreFailures:                             metric.NewCounter(metaStoreFailures),
 		MVCCGCQueueSuccesses:                      metric.NewCounter(metaMVCCGCQueueSuccesses),
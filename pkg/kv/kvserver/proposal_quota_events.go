@@ -0,0 +1,77 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package kvserver
+
+import (
+	"fmt"
+	"time"
+)
+
+// proposalQuotaEventLogMaxEntries is the number of proposal quota events each
+// replica retains.
+const proposalQuotaEventLogMaxEntries = 16
+
+// proposalQuotaEventBurstSize is the number of entries whose quota has to be
+// released at once for the release to be recorded as an event.
+const proposalQuotaEventBurstSize = 64
+
+// proposalQuotaEvent is an entry of a proposalQuotaEventLog.
+type proposalQuotaEvent struct {
+	at  time.Time
+	msg string
+}
+
+func (e proposalQuotaEvent) String() string {
+	return fmt.Sprintf("%s: %s", e.at.UTC().Format(time.RFC3339Nano), e.msg)
+}
+
+// proposalQuotaEventLog is a circular buffer of the events of significance to
+// a replica's proposal quota: the creation and closing of its quota pool as
+// it gains and loses raft leadership, large bursts of released quota, and
+// stalls. It is included in the range's debug info, so that quota stalls can
+// be correlated with leadership changes. Events are only recorded when the
+// quota's state changes, so the log is not flooded on every raft tick.
+//
+// It is protected by Replica.mu.
+type proposalQuotaEventLog struct {
+	index  int
+	events []proposalQuotaEvent // A circular buffer with index.
+}
+
+func (l *proposalQuotaEventLog) add(at time.Time, format string, args ...interface{}) {
+	e := proposalQuotaEvent{at: at, msg: fmt.Sprintf(format, args...)}
+	if l.events == nil {
+		l.events = make([]proposalQuotaEvent, 0, proposalQuotaEventLogMaxEntries)
+	}
+	// Not through the first pass through the buffer.
+	if l.index == len(l.events) {
+		l.events = append(l.events, e)
+	} else {
+		l.events[l.index] = e
+	}
+	l.index++
+	if l.index >= cap(l.events) {
+		l.index = 0
+	}
+}
+
+// get returns the events, from oldest to newest.
+func (l *proposalQuotaEventLog) get() []proposalQuotaEvent {
+	if len(l.events) == 0 {
+		return nil
+	}
+	result := make([]proposalQuotaEvent, 0, len(l.events))
+	if len(l.events) < cap(l.events) {
+		return append(result, l.events...)
+	}
+	result = append(result, l.events[l.index:]...)
+	return append(result, l.events[:l.index]...)
+}
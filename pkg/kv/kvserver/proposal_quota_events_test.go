@@ -0,0 +1,60 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package kvserver
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProposalQuotaEventLog(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	var l proposalQuotaEventLog
+	require.Nil(t, l.get())
+
+	msgs := func() []string {
+		var msgs []string
+		for _, e := range l.get() {
+			msgs = append(msgs, e.msg)
+		}
+		return msgs
+	}
+	expected := func(from, to int) []string {
+		var msgs []string
+		for i := from; i < to; i++ {
+			msgs = append(msgs, fmt.Sprintf("event %d", i))
+		}
+		return msgs
+	}
+
+	start := timeutil.Unix(100, 0)
+	for i := 0; i < proposalQuotaEventLogMaxEntries; i++ {
+		l.add(start.Add(time.Duration(i)*time.Second), "event %d", i)
+		require.Equal(t, expected(0, i+1), msgs())
+	}
+
+	// Overflow the circular buffer, evicting the oldest events.
+	for i := proposalQuotaEventLogMaxEntries; i < 2*proposalQuotaEventLogMaxEntries+3; i++ {
+		l.add(start.Add(time.Duration(i)*time.Second), "event %d", i)
+		require.Equal(t, expected(i+1-proposalQuotaEventLogMaxEntries, i+1), msgs())
+	}
+
+	require.Equal(t, "1970-01-01T00:01:40Z: event 0",
+		proposalQuotaEvent{at: start, msg: "event 0"}.String())
+}
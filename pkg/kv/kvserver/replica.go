@@ -795,6 +795,29 @@ type Replica struct {
 		// released. That is, the first element in quotaReleaseQueue below is
 		// released as the base index moves up by one, etc.
 		proposalQuotaBaseIndex kvpb.RaftIndex
+		// proposalQuotaBaseIndexAdvanced is the time at which
+		// proposalQuotaBaseIndex was last initialized or moved up, i.e. at which
+		// quota was last released. See QuotaStalled.
+		proposalQuotaBaseIndexAdvanced time.Time
+		// proposalQuotaWaitingSince is the time at which proposals started
+		// waiting for proposal quota, or zero if none are waiting. Stalls of the
+		// proposal quota are measured from the later of this and
+		// proposalQuotaBaseIndexAdvanced. See proposalQuotaStalledForRLocked.
+		proposalQuotaWaitingSince time.Time
+		// proposalQuotaSlowestFollower is the active follower which held up the
+		// release of proposal quota the most as of the last update of the
+		// proposal quota, or zero if none did.
+		proposalQuotaSlowestFollower roachpb.ReplicaID
+		// proposalQuotaEvents records the events of significance to the
+		// proposal quota, for debugging.
+		proposalQuotaEvents proposalQuotaEventLog
+		// proposalQuotaStallRecorded is set once a stall of the proposal quota
+		// has been recorded in proposalQuotaEvents, until quota is released
+		// again.
+		proposalQuotaStallRecorded bool
+		// proposalQuotaAssertionErr is the last violation of the release queue
+		// invariant detected with StoreTestingKnobs.QuotaAssertionAsError set.
+		proposalQuotaAssertionErr error
 
 		// Once the leader observes a proposal come 'out of Raft', we add the size
 		// of the associated command to a queue of quotas we have yet to release
@@ -987,11 +1010,22 @@ func (r *Replica) ID() storage.FullReplicaID {
 }
 
 // cleanupFailedProposal cleans up after a proposal that has failed. It
-// clears any references to the proposal and releases associated quota.
+// clears any references to the proposal and refunds associated quota.
 // It requires that Replica.mu is exclusively held.
 func (r *Replica) cleanupFailedProposalLocked(p *ProposalData) {
 	r.mu.AssertHeld()
 	delete(r.mu.proposals, p.idKey)
+	r.refundProposalQuota(p)
+}
+
+// refundProposalQuota releases the quota of a proposal which failed before it
+// could apply. Only proposals which apply hand their quota over to the
+// quotaReleaseQueue, so the quota of a failed proposal would otherwise never
+// be returned to the pool.
+func (r *Replica) refundProposalQuota(p *ProposalData) {
+	if p.quotaAlloc != nil {
+		r.store.metrics.RaftProposalQuotaRefunds.Inc(1)
+	}
 	p.releaseQuota()
 }
 
@@ -1676,12 +1710,24 @@ func (r *Replica) State(ctx context.Context) kvserverpb.RangeInfo {
 	if r.mu.proposalQuota != nil {
 		ri.ApproximateProposalQuota = int64(r.mu.proposalQuota.ApproximateQuota())
 		ri.ProposalQuotaBaseIndex = int64(r.mu.proposalQuotaBaseIndex)
+		ri.ProposalQuotaCapacity = int64(r.mu.proposalQuota.Capacity())
+		ri.ProposalQuotaWaiters = int64(r.mu.proposalQuota.Len())
+		ri.ProposalQuotaSlowestFollower = r.mu.proposalQuotaSlowestFollower
+		ri.ProposalQuotaBaseIndexAdvanced = r.mu.proposalQuotaBaseIndexAdvanced
 		ri.ProposalQuotaReleaseQueue = make([]int64, len(r.mu.quotaReleaseQueue))
 		for i, a := range r.mu.quotaReleaseQueue {
 			if a != nil {
 				ri.ProposalQuotaReleaseQueue[i] = int64(a.Acquired())
 			}
 		}
+		ri.ProposalQuotaReleaseQueueByIndex = formatQuotaReleaseQueue(
+			r.mu.proposalQuotaBaseIndex, r.mu.quotaReleaseQueue)
+		for _, size := range r.mu.proposalQuota.HeldAllocs() {
+			ri.ProposalQuotaOutstandingAllocs = append(ri.ProposalQuotaOutstandingAllocs, int64(size))
+		}
+	}
+	for _, e := range r.mu.proposalQuotaEvents.get() {
+		ri.ProposalQuotaEvents = append(ri.ProposalQuotaEvents, e.String())
 	}
 	ri.RangeMaxBytes = r.mu.conf.RangeMaxBytes
 	if r.mu.tenantID != (roachpb.TenantID{}) {
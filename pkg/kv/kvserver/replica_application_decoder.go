@@ -114,6 +114,7 @@ func (d *replicaDecoder) retrieveLocalProposals() (anyLocal bool) {
 				cmd.proposal.v2SeenDuringApplication = true
 				anyLocal = true
 				delete(d.r.mu.proposals, cmd.ID)
+				cmd.proposal.releaseTenantQuota()
 				if d.r.mu.proposalQuota != nil {
 					alloc = cmd.proposal.quotaAlloc
 					cmd.proposal.quotaAlloc = nil
@@ -130,6 +131,7 @@ func (d *replicaDecoder) retrieveLocalProposals() (anyLocal bool) {
 		// b.r.mu.proposalQuota. We can bring it back.
 		if d.r.mu.proposalQuota != nil {
 			d.r.mu.quotaReleaseQueue = append(d.r.mu.quotaReleaseQueue, alloc)
+			d.r.store.proposalQuotaReleaseQueues.add(1)
 		}
 	}
 	return anyLocal
@@ -18,6 +18,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/kv/kvpb"
 	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/liveness/livenesspb"
 	"github.com/cockroachdb/cockroach/pkg/raft"
+	"github.com/cockroachdb/cockroach/pkg/raft/raftpb"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/server/telemetry"
 	"github.com/cockroachdb/cockroach/pkg/settings"
@@ -239,6 +240,7 @@ func replicaUnavailableError(
 	lm livenesspb.IsLiveMap,
 	rs *raft.Status,
 	closedTS hlc.Timestamp,
+	pq *proposalQuotaState,
 ) error {
 	nonLiveRepls := roachpb.MakeReplicaSet(nil)
 	for _, rDesc := range desc.Replicas().Descriptors() {
@@ -269,6 +271,9 @@ func replicaUnavailableError(
 		redact.Safe(timeutil.Unix(0, closedTS.WallTime).UTC().Format("2006-01-02 15:04:05")),
 		redact.Safe(rs), /* raft status contains no PII */
 	)
+	if pq != nil {
+		buf.Printf("; %s", pq)
+	}
 
 	return kvpb.NewReplicaUnavailableError(errors.Wrapf(err, "%s", buf), desc, replDesc)
 }
@@ -279,5 +284,62 @@ func (r *Replica) replicaUnavailableError(err error) error {
 
 	isLiveMap, _ := r.store.livenessMap.Load().(livenesspb.IsLiveMap)
 	ct := r.GetCurrentClosedTimestamp(context.Background())
-	return replicaUnavailableError(err, desc, replDesc, isLiveMap, r.RaftStatus(), ct)
+	rs := r.RaftStatus()
+	return replicaUnavailableError(err, desc, replDesc, isLiveMap, rs, ct, r.proposalQuotaState(rs))
+}
+
+// proposalQuotaState is a snapshot of a leader's proposal quota pool, included
+// in the replica unavailable error since a stalled quota pool is a common cause
+// of unavailability.
+type proposalQuotaState struct {
+	available, capacity uint64
+	releaseQueueLen     int
+	baseIndex           kvpb.RaftIndex
+	// slowestFollower is the active follower which held up the release of
+	// quota the most as of the last update of the quota pool, or zero if none
+	// did; see proposalQuotaSlowestFollower. slowestFollowerMatch is its match
+	// index, or zero if unknown.
+	slowestFollower      roachpb.ReplicaID
+	slowestFollowerMatch kvpb.RaftIndex
+}
+
+// SafeFormat implements redact.SafeFormatter.
+func (s *proposalQuotaState) SafeFormat(w redact.SafePrinter, _ rune) {
+	w.Printf("proposal quota: %d/%d available, %d entries pending release, base index %d",
+		s.available, s.capacity, s.releaseQueueLen, s.baseIndex)
+	if s.slowestFollower != 0 {
+		w.Printf(", slowest follower %d", s.slowestFollower)
+		if s.slowestFollowerMatch != 0 {
+			w.Printf(" (match %d)", s.slowestFollowerMatch)
+		}
+	}
+}
+
+func (s *proposalQuotaState) String() string {
+	return redact.StringWithoutMarkers(s)
+}
+
+// proposalQuotaState returns a snapshot of the replica's proposal quota pool,
+// or nil if the replica is not maintaining one, i.e. is not the leader. The
+// match index of the slowest follower is taken from the provided raft status,
+// which may be nil.
+func (r *Replica) proposalQuotaState(rs *raft.Status) *proposalQuotaState {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.mu.proposalQuota == nil {
+		return nil
+	}
+	s := &proposalQuotaState{
+		available:       r.mu.proposalQuota.ApproximateQuota(),
+		capacity:        r.mu.proposalQuota.Capacity(),
+		releaseQueueLen: len(r.mu.quotaReleaseQueue),
+		baseIndex:       r.mu.proposalQuotaBaseIndex,
+		slowestFollower: r.mu.proposalQuotaSlowestFollower,
+	}
+	if rs != nil && s.slowestFollower != 0 {
+		if pr, ok := rs.Progress[raftpb.PeerID(s.slowestFollower)]; ok {
+			s.slowestFollowerMatch = kvpb.RaftIndex(pr.Match)
+		}
+	}
+	return s
 }
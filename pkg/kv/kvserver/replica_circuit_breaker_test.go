@@ -44,10 +44,18 @@ func TestReplicaUnavailableError(t *testing.T) {
 	ts := hlc.Timestamp{WallTime: time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC).UnixNano()}
 	wrappedErr := errors.New("probe failed")
 	rs := raft.Status{}
+	pq := &proposalQuotaState{
+		available:            512,
+		capacity:             8 << 20,
+		releaseQueueLen:      20,
+		baseIndex:            10,
+		slowestFollower:      2,
+		slowestFollowerMatch: 5,
+	}
 	ctx := context.Background()
 
 	rue := errors.Mark(
-		replicaUnavailableError(wrappedErr, desc, desc.Replicas().Descriptors()[0], lm, &rs, ts),
+		replicaUnavailableError(wrappedErr, desc, desc.Replicas().Descriptors()[0], lm, &rs, ts, pq),
 		circuit.ErrBreakerOpen)
 
 	// A Protobuf roundtrip retains the error details.
@@ -173,6 +173,10 @@ func (r *Replica) disconnectReplicationRaftMuLocked(ctx context.Context) {
 	if pq := r.mu.proposalQuota; pq != nil {
 		pq.Close("destroyed")
 	}
+	// The replica won't release the entries of its quota release queue, so
+	// stop accounting for them.
+	r.store.proposalQuotaReleaseQueues.add(-len(r.mu.quotaReleaseQueue))
+	r.mu.quotaReleaseQueue = nil
 	r.mu.replicaFlowControlIntegration.onDestroyed(ctx)
 	r.mu.proposalBuf.FlushLockedWithoutProposing(ctx)
 	for _, p := range r.mu.proposals {
@@ -60,6 +60,15 @@ type ReplicaMetrics struct {
 	SlowRaftProposalCount    int64
 
 	QuotaPoolPercentUsed int64 // [0,100]
+	// QuotaPoolAvailable is the available proposal quota, if the replica is
+	// maintaining a proposal quota pool, and -1 otherwise.
+	QuotaPoolAvailable int64
+	// QuotaPoolLow is set if the replica is maintaining a proposal quota pool
+	// and the available quota is low; see proposalQuotaLow.
+	QuotaPoolLow bool
+	// QuotaPoolForced is set if the replica's span config forces it to acquire
+	// proposal quota; see proposalQuotaEnabled.
+	QuotaPoolForced bool
 
 	// Latching and locking metrics.
 	LatchMetrics     concurrency.LatchMetrics
@@ -210,11 +219,21 @@ func calcReplicaMetrics(d calcReplicaMetricsInput) ReplicaMetrics {
 		PendingRaftProposalCount: d.pendingRaftProposalCount,
 		SlowRaftProposalCount:    d.slowRaftProposalCount,
 		QuotaPoolPercentUsed:     calcQuotaPoolPercentUsed(d.qpUsed, d.qpCapacity),
+		QuotaPoolAvailable:       calcQuotaPoolAvailable(d.qpUsed, d.qpCapacity),
+		QuotaPoolLow:             d.qpCapacity > 0 && proposalQuotaLow(uint64(d.qpCapacity-d.qpUsed), uint64(d.qpCapacity)),
+		QuotaPoolForced:          d.conf.ForceProposalQuota,
 		LatchMetrics:             d.latchMetrics,
 		LockTableMetrics:         d.lockTableMetrics,
 	}
 }
 
+func calcQuotaPoolAvailable(qpUsed, qpCapacity int64) int64 {
+	if qpCapacity == 0 {
+		return -1
+	}
+	return qpCapacity - qpUsed
+}
+
 func calcQuotaPoolPercentUsed(qpUsed, qpCapacity int64) int64 {
 	if qpCapacity < 1 {
 		qpCapacity++ // defense in depth against divide by zero below
@@ -153,6 +153,11 @@ type ProposalData struct {
 	// released when the command comes up for application (even if it will be
 	// reproposed). See retrieveLocalProposals and tryReproposeWithNewLeaseIndex.
 	quotaAlloc *quotapool.IntAlloc
+	// tenantQuotaAlloc is the allocation retrieved from the proposal quota pool
+	// of the range's tenant, if any. Unlike quotaAlloc it is not handed to the
+	// quotaReleaseQueue, but released directly when the command comes up for
+	// application. See tenantProposalQuotaPools.
+	tenantQuotaAlloc *quotapool.IntAlloc
 
 	// ec.done is called after command application to update the timestamp
 	// cache and optionally release latches and exits lock wait-queues.
@@ -279,13 +284,23 @@ func (proposal *ProposalData) signalProposalResult(pr proposalResult) {
 	}
 }
 
-// releaseQuota releases the proposal's quotaAlloc and sets it to nil.
-// If the quotaAlloc is already nil it is a no-op.
+// releaseQuota releases the proposal's quotaAlloc and tenantQuotaAlloc and
+// sets them to nil. If they are already nil it is a no-op.
 func (proposal *ProposalData) releaseQuota() {
 	if proposal.quotaAlloc != nil {
 		proposal.quotaAlloc.Release()
 		proposal.quotaAlloc = nil
 	}
+	proposal.releaseTenantQuota()
+}
+
+// releaseTenantQuota releases the proposal's tenantQuotaAlloc and sets it to
+// nil. If the tenantQuotaAlloc is already nil it is a no-op.
+func (proposal *ProposalData) releaseTenantQuota() {
+	if proposal.tenantQuotaAlloc != nil {
+		proposal.tenantQuotaAlloc.Release()
+		proposal.tenantQuotaAlloc = nil
+	}
 }
 
 // leaseJumpOption controls what assertions leasePostApplyLocked can make.
@@ -12,19 +12,30 @@ package kvserver
 
 import (
 	"bytes"
+	"cmp"
 	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
+	"unsafe"
 
 	"github.com/cockroachdb/cockroach/pkg/base"
 	"github.com/cockroachdb/cockroach/pkg/keys"
 	"github.com/cockroachdb/cockroach/pkg/kv/kvpb"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/kvserverbase"
 	"github.com/cockroachdb/cockroach/pkg/raft"
 	"github.com/cockroachdb/cockroach/pkg/raft/raftpb"
 	"github.com/cockroachdb/cockroach/pkg/raft/tracker"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/util/admission/admissionpb"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/cockroach/pkg/util/quotapool"
+	"github.com/cockroachdb/cockroach/pkg/util/retry"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
 	"github.com/cockroachdb/errors"
 )
 
@@ -36,29 +47,292 @@ var enableRaftProposalQuota = settings.RegisterBoolSetting(
 	true,
 )
 
-func (r *Replica) maybeAcquireProposalQuota(
-	ctx context.Context, ba *kvpb.BatchRequest, quota uint64,
-) (*quotapool.IntAlloc, error) {
-	// We don't want to delay lease requests or transfers, in particular
-	// expiration lease extensions. These are small and latency-sensitive.
+var enableRaftProposalQuotaSessionFairness = settings.RegisterBoolSetting(
+	settings.SystemOnly,
+	"kv.raft.proposal_quota.session_fairness.enabled",
+	"set to true to serve proposals waiting for quota round-robin across the "+
+		"SQL sessions that issued them, false to serve them in FIFO order",
+	false,
+)
+
+// MaxProposalQuotaReleaseQueueLength bounds the number of applied entries
+// whose quota a leader holds on to while waiting for followers to catch up.
+// Each entry in the quotaReleaseQueue is retained until every active follower
+// has applied it, so a stalled follower makes the queue grow for as long as
+// writes keep trickling in under the pool's capacity.
+var MaxProposalQuotaReleaseQueueLength = settings.RegisterIntSetting(
+	settings.SystemOnly,
+	"kv.raft.proposal_quota.max_release_queue_length",
+	"the number of applied entries awaiting follower acknowledgement above which "+
+		"the leader stops admitting new proposals, regardless of available proposal "+
+		"quota; set to 0 to disable",
+	0,
+	settings.NonNegativeInt,
+)
+
+// proposalQuotaReleaseQueueRetryOpts are the options with which proposals
+// poll for the quotaReleaseQueue to drain below
+// MaxProposalQuotaReleaseQueueLength.
+var proposalQuotaReleaseQueueRetryOpts = retry.Options{
+	InitialBackoff: 10 * time.Millisecond,
+	MaxBackoff:     250 * time.Millisecond,
+	Multiplier:     2,
+}
+
+var proposalQuotaReleaseQueueCapLogEvery = log.Every(10 * time.Second)
+
+// maxStoreProposalQuotaReleaseQueueBytes bounds the memory retained by the
+// quotaReleaseQueues of all the leaders on a store. Unlike
+// MaxProposalQuotaReleaseQueueLength, which bounds each range's queue, this
+// protects against many ranges stalling at once, e.g. during a network
+// partition, each of which stays within its own limit.
+var maxStoreProposalQuotaReleaseQueueBytes = settings.RegisterByteSizeSetting(
+	settings.SystemOnly,
+	"kv.raft.proposal_quota.store_max_release_queue_bytes",
+	"the memory retained by the entries awaiting follower acknowledgement of all "+
+		"the leaders on a store above which those leaders stop admitting new "+
+		"proposals until their followers catch up; set to 0 to disable",
+	0,
+	settings.NonNegativeInt,
+)
+
+// proposalQuotaStallThreshold is how long a leader's proposal quota may go
+// without being released while proposals are waiting for it before the
+// leader is considered stalled; see Replica.QuotaStalled.
+var proposalQuotaStallThreshold = settings.RegisterDurationSetting(
+	settings.SystemOnly,
+	"kv.raft.proposal_quota.stall_threshold",
+	"the duration for which proposals must have been waiting for proposal quota "+
+		"without any being released for the leader to be reported as stalled to "+
+		"the allocator; set to 0 to disable",
+	30*time.Second,
+	settings.NonNegativeDuration,
+)
+
+// ProposalQuotaRelaxAfter is how long a leader's proposal quota may go without
+// being released while proposals are waiting for it before the leader releases
+// the quota of all applied entries regardless of the followers.
+var ProposalQuotaRelaxAfter = settings.RegisterDurationSetting(
+	settings.SystemOnly,
+	"kv.raft.proposal_quota.relax_after",
+	"the duration for which proposals must have been waiting for proposal quota "+
+		"without any follower catching up far enough to release it for the leader "+
+		"to release the quota of all applied entries regardless, so that the range "+
+		"keeps accepting writes; set to 0 to disable",
+	0,
+	settings.NonNegativeDuration,
+)
+
+// proposalQuotaRelaxLogEvery rate limits the logging of quota relaxations.
+var proposalQuotaRelaxLogEvery = log.Every(10 * time.Second)
+
+// proposalQuotaBackpressureHintMaxDelay is the delay suggested to clients, via
+// BatchResponse.BackpressureHint, for writes proposed to a range whose
+// proposal quota is exhausted.
+var proposalQuotaBackpressureHintMaxDelay = settings.RegisterDurationSetting(
+	settings.SystemOnly,
+	"kv.raft.proposal_quota.backpressure_hint.max_delay",
+	"the delay suggested to clients before sending further writes to a range whose "+
+		"proposal quota has run out; less is suggested the more quota is left, and "+
+		"nothing once more than 10% of it is; set to 0 to disable",
+	0,
+	settings.NonNegativeDuration,
+)
+
+// proposalQuotaAvailableByRangeTopN is the number of ranges for which each
+// store exports raft.proposal_quota.available_by_range. The metric is labeled
+// by range ID, so it is disabled by default to bound its cardinality.
+var proposalQuotaAvailableByRangeTopN = settings.RegisterIntSetting(
+	settings.SystemOnly,
+	"kv.raft.proposal_quota.available_by_range.top_n",
+	"the number of ranges led by each store with the least proposal quota available "+
+		"for which to export raft.proposal_quota.available_by_range, labeled by range "+
+		"ID; set to 0 to disable",
+	0,
+	settings.NonNegativeIntWithMaximum(100),
+)
+
+// proposalQuotaVoterActivityWindow is how recently a voter (or learner) must
+// have communicated with the leader to hold up the release of proposal quota.
+var proposalQuotaVoterActivityWindow = settings.RegisterDurationSetting(
+	settings.SystemOnly,
+	"kv.raft.proposal_quota.voter_activity_window",
+	"the duration since its last communication with the leader for which a voting "+
+		"replica is considered active and holds up the release of proposal quota; "+
+		"set to 0 to use the range lease duration",
+	0,
+	settings.NonNegativeDuration,
+)
+
+// proposalQuotaNonVoterActivityWindow is like proposalQuotaVoterActivityWindow
+// but for non-voting replicas, which are often placed far away from the
+// leader and are not needed for writes to commit.
+var proposalQuotaNonVoterActivityWindow = settings.RegisterDurationSetting(
+	settings.SystemOnly,
+	"kv.raft.proposal_quota.non_voter_activity_window",
+	"the duration since its last communication with the leader for which a "+
+		"non-voting replica is considered active and holds up the release of proposal "+
+		"quota; set to 0 for non-voting replicas to never hold up proposal quota",
+	0,
+	settings.NonNegativeDuration,
+)
+
+// proposalQuotaActivityWindow returns how recently a follower of the given
+// type must have communicated with the leader to be considered active for the
+// purpose of releasing proposal quota, or false if followers of that type
+// never hold up the release of quota.
+func proposalQuotaActivityWindow(
+	sv *settings.Values, typ roachpb.ReplicaType, leaseDuration time.Duration,
+) (time.Duration, bool) {
+	if typ == roachpb.NON_VOTER {
+		window := proposalQuotaNonVoterActivityWindow.Get(sv)
+		return window, window > 0
+	}
+	if window := proposalQuotaVoterActivityWindow.Get(sv); window > 0 {
+		return window, true
+	}
+	return leaseDuration, true
+}
+
+// tenantProposalQuotaCapacity bounds the proposal quota held across all of a
+// tenant's ranges on a store; see tenantProposalQuotaPools.
+var tenantProposalQuotaCapacity = settings.RegisterByteSizeSetting(
+	settings.SystemOnly,
+	"kv.raft.proposal_quota.tenant_capacity",
+	"the maximum size of the proposals of a single secondary tenant's ranges which "+
+		"may be in flight on a store, i.e. proposed but not yet applied by the leader; "+
+		"the system tenant's ranges are not limited; set to 0 for no limit",
+	0,
+	settings.NonNegativeInt,
+)
+
+// proposalQuotaReplicationWeighting determines whether and how the proposal
+// quota charged for a command is weighted by the replication factor of the
+// range, see proposalQuotaReplicationMultiplier.
+type proposalQuotaReplicationWeighting int64
+
+const (
+	// proposalQuotaWeightingOff charges the same quota regardless of the
+	// replication factor.
+	proposalQuotaWeightingOff proposalQuotaReplicationWeighting = iota
+	// proposalQuotaWeightingFollowers multiplies the charge by the number of
+	// voting followers, i.e. the number of voters the leader sends the command
+	// to.
+	proposalQuotaWeightingFollowers
+	// proposalQuotaWeightingVoters multiplies the charge by the number of
+	// voters, including the leader.
+	proposalQuotaWeightingVoters
+)
+
+var proposalQuotaReplicationWeightingSetting = settings.RegisterEnumSetting(
+	settings.SystemOnly,
+	"kv.raft.proposal_quota.replication_factor_weighting",
+	"whether to multiply the proposal quota charged for a command by the number "+
+		"of voting followers or of voters of its range, so that quota reflects the "+
+		"bytes in flight across the network rather than from the leader alone",
+	"off",
+	map[proposalQuotaReplicationWeighting]string{
+		proposalQuotaWeightingOff:       "off",
+		proposalQuotaWeightingFollowers: "followers",
+		proposalQuotaWeightingVoters:    "voters",
+	},
+)
+
+// proposalQuotaReplicationMultiplier returns the multiplier applied to the
+// proposal quota charged for a command to the range with the given descriptor,
+// per proposalQuotaReplicationWeightingSetting. It returns false if the charge
+// is not weighted. The multiplier is at least 1, so that the commands of a
+// range with a single voter are still charged what they would be unweighted.
+func proposalQuotaReplicationMultiplier(
+	sv *settings.Values, desc *roachpb.RangeDescriptor,
+) (uint64, bool) {
+	voters := len(desc.Replicas().VoterDescriptors())
+	switch proposalQuotaReplicationWeightingSetting.Get(sv) {
+	case proposalQuotaWeightingFollowers:
+		return uint64(max(voters-1, 1)), true
+	case proposalQuotaWeightingVoters:
+		return uint64(max(voters, 1)), true
+	default:
+		return 1, false
+	}
+}
+
+// ProposalQuotaCharge returns the proposal quota charged for proposing the
+// command of the given encoded size which evaluated ba.
+//
+// Quota is meant to bound how far followers can fall behind the leader, which
+// is a matter of how much data they have yet to receive and apply rather than
+// of how many commands, so the charge is the size of the command. It is not
+// derived from the size of ba: the evaluated command carries the resulting
+// writes, which for e.g. a DeleteRange can be far larger than the request.
+// Single lease requests and transfers are small and latency-sensitive, and
+// are not charged at all.
+func ProposalQuotaCharge(ba *kvpb.BatchRequest, commandSize int) uint64 {
 	if ba.IsSingleRequestLeaseRequest() || ba.IsSingleTransferLeaseRequest() {
-		return nil, nil
+		return 0
 	}
+	return uint64(commandSize)
+}
 
-	// If the quota pool is disabled via the setting, we don't need to acquire
-	// quota.
-	if !enableRaftProposalQuota.Get(&r.store.cfg.Settings.SV) {
-		// TODO(kvoli): Once we have a setting for RACv2 pull vs push mode, we
-		// should abstract this check into a function that also disables quota
-		// acquisition for pull mode.
-		return nil, nil
+// ProposalQuotaCharger determines the proposal quota charged for proposing a
+// command, allowing policies other than ProposalQuotaCharge to be used by
+// setting StoreConfig.ProposalQuotaCharger.
+type ProposalQuotaCharger interface {
+	// Charge returns the proposal quota charged for proposing the command of
+	// the given encoded size which evaluated ba. A charge of 0 exempts the
+	// proposal from acquiring quota.
+	Charge(ba *kvpb.BatchRequest, commandSize int) uint64
+}
+
+// ProposalQuotaChargerFunc adapts a function to a ProposalQuotaCharger.
+type ProposalQuotaChargerFunc func(ba *kvpb.BatchRequest, commandSize int) uint64
+
+// Charge implements the ProposalQuotaCharger interface.
+func (f ProposalQuotaChargerFunc) Charge(ba *kvpb.BatchRequest, commandSize int) uint64 {
+	return f(ba, commandSize)
+}
+
+// DefaultProposalQuotaCharger charges proposals per ProposalQuotaCharge.
+var DefaultProposalQuotaCharger ProposalQuotaCharger = ProposalQuotaChargerFunc(ProposalQuotaCharge)
+
+// maybeAcquireProposalQuota acquires the charge of a proposal of the given
+// command size, as determined by the store's ProposalQuotaCharger, from the
+// range's proposal quota pool, if it applies to ba. It returns nil allocs if no
+// quota needs to be acquired.
+//
+// In addition to the range's quota, quota is acquired from the pool of the
+// range's tenant if tenantProposalQuotaCapacity is set and the range belongs to
+// a secondary tenant. That allocation is returned separately, as it is released
+// as soon as the proposal comes up for application rather than once all
+// followers have caught up.
+func (r *Replica) maybeAcquireProposalQuota(
+	ctx context.Context, ba *kvpb.BatchRequest, commandSize int,
+) (alloc, tenantAlloc *quotapool.IntAlloc, _ error) {
+	// We don't want to delay lease requests or transfers, in particular
+	// expiration lease extensions, which are not charged.
+	quota := r.store.cfg.ProposalQuotaCharger.Charge(ba, commandSize)
+	if quota == 0 {
+		return nil, nil, nil
 	}
 
 	r.mu.RLock()
 	quotaPool := r.mu.proposalQuota
 	desc := r.mu.state.Desc
+	tenantID := r.mu.tenantID
+	forced := r.mu.conf.ForceProposalQuota
+	excludedFamilies := r.mu.conf.ProposalQuotaExcludedFamilyIDs
+	quiescent := r.mu.quiescent
 	r.mu.RUnlock()
 
+	// If the quota pool is disabled via the setting, we don't need to acquire
+	// quota, unless the range's span config forces it.
+	if !proposalQuotaEnabled(&r.store.cfg.Settings.SV, forced) {
+		// TODO(kvoli): Once we have a setting for RACv2 pull vs push mode, we
+		// should abstract this check into a function that also disables quota
+		// acquisition for pull mode.
+		return nil, nil, nil
+	}
+
 	// Quota acquisition only takes place on the leader replica,
 	// r.mu.proposalQuota is set to nil if a node is a follower (see
 	// updateProposalQuotaRaftMuLocked). For the cases where the range lease
@@ -71,26 +345,416 @@ func (r *Replica) maybeAcquireProposalQuota(
 	// through, for otherwise a follower could never request the lease.
 
 	if quotaPool == nil {
-		return nil, nil
+		return nil, nil, nil
 	}
 
 	if !quotaPoolEnabledForRange(desc) {
-		return nil, nil
+		return nil, nil, nil
+	}
+
+	if r.store.proposalQuotaExemptions.exempt(desc, r.store.Clock().PhysicalTime()) {
+		return nil, nil, nil
+	}
+
+	if bypassesProposalQuota(ba, tenantID) {
+		r.store.metrics.RaftProposalQuotaBypassed.Inc(1)
+		return nil, nil, nil
+	}
+
+	if isStructuralProposal(ba) {
+		r.store.metrics.RaftProposalQuotaStructuralBypassed.Inc(1)
+		return nil, nil, nil
+	}
+
+	if err := r.waitForProposalQuotaReleaseQueue(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	// Writes to families excluded by the span config are not charged, though
+	// the proposal is always charged something so that it still waits behind
+	// the proposals ahead of it.
+	if excluded := excludedFamilyQuotaBytes(ba, excludedFamilies); excluded > 0 {
+		excluded = min(excluded, quota-1)
+		quota -= excluded
+		r.store.metrics.RaftProposalQuotaExcludedFamilyBytes.Inc(int64(excluded))
+	}
+	if multiplier, ok := proposalQuotaReplicationMultiplier(&r.store.cfg.Settings.SV, desc); ok {
+		quota *= multiplier
+		r.store.metrics.RaftProposalQuotaReplicationMultiplier.RecordValue(int64(multiplier))
 	}
 
 	// Trace if we're running low on available proposal quota; it might explain
 	// why we're taking so long.
 	if log.HasSpan(ctx) {
-		if q := quotaPool.ApproximateQuota(); q < quotaPool.Capacity()/10 {
+		if q := quotaPool.ApproximateQuota(); proposalQuotaLow(q, quotaPool.Capacity()) {
 			log.Eventf(ctx, "quota running low, currently available ~%d", q)
 		}
 	}
-	alloc, err := quotaPool.Acquire(ctx, quota)
+	// Try to acquire without waiting first, so that we can tell how often
+	// proposals are held up by a lack of quota.
+	alloc, err := quotaPool.TryAcquire(ctx, quota)
+	if errors.Is(err, quotapool.ErrNotEnoughQuota) {
+		r.store.metrics.RaftProposalQuotaAcquireBlocked.Inc(1)
+		r.noteProposalQuotaWaiting(quotaPool)
+		waitFunc := kvserverbase.ProposalQuotaWaitFuncFromContext(ctx)
+		if waitFunc != nil {
+			waitFunc(true /* waiting */)
+		}
+		alloc, err = quotaPool.Acquire(r.withProposalQuotaQueuePosition(ctx, ba), quota)
+		if waitFunc != nil {
+			waitFunc(false /* waiting */)
+		}
+	} else if err == nil {
+		r.store.metrics.RaftProposalQuotaAcquireNonBlocking.Inc(1)
+	}
 	// Let quotapool errors due to being closed pass through.
 	if errors.HasType(err, (*quotapool.ErrClosed)(nil)) {
 		err = nil
 	}
-	return alloc, err
+	if err != nil || alloc == nil {
+		return alloc, nil, err
+	}
+	if !log.HasSpan(ctx) {
+		r.store.metrics.RaftProposalQuotaUntracedAcquisitions.Inc(1)
+	}
+
+	// Only acquire the tenant's quota once the range's has been acquired, so
+	// that a range waiting behind a slow follower doesn't hold up the tenant's
+	// other ranges.
+	tenantAlloc, err = r.store.tenantProposalQuota.acquire(
+		ctx, tenantID, uint64(tenantProposalQuotaCapacity.Get(&r.store.cfg.Settings.SV)), quota,
+		r.Clock().PhysicalTime())
+	if err != nil {
+		alloc.Release()
+		r.store.metrics.RaftProposalQuotaRefunds.Inc(1)
+		return nil, nil, err
+	}
+
+	// A proposal to a quiescent range wakes it up, see
+	// maybeUnquiesceLocked. Ranges which repeatedly quiesce and wake up
+	// acquire quota in bursts.
+	if quiescent {
+		r.store.metrics.RaftProposalQuotaWakeupProposals.Inc(1)
+		r.store.metrics.RaftProposalQuotaWakeupBytes.Inc(int64(alloc.Acquired()))
+	}
+	return alloc, tenantAlloc, nil
+}
+
+// noteProposalQuotaWaiting records that a proposal is about to wait for quota
+// from quotaPool, unless proposals already were. Stalls of the proposal quota
+// are measured from when proposals started waiting, see
+// proposalQuotaStalledForRLocked.
+func (r *Replica) noteProposalQuotaWaiting(quotaPool *quotapool.IntPool) {
+	now := r.Clock().PhysicalTime()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.mu.proposalQuota == quotaPool && r.mu.proposalQuotaWaitingSince.IsZero() {
+		r.mu.proposalQuotaWaitingSince = now
+	}
+}
+
+// proposalQuotaStalledForRLocked returns for how long proposals have been
+// waiting for proposal quota without any being released, or zero if none are
+// waiting. This is measured from the later of when proposals started waiting
+// and when quota was last released: quota which was last released long ago
+// on a range taking few writes doesn't make a proposal that only just started
+// waiting stalled.
+func (r *Replica) proposalQuotaStalledForRLocked(now time.Time) time.Duration {
+	if r.mu.proposalQuota == nil || r.mu.proposalQuota.Len() == 0 ||
+		r.mu.proposalQuotaWaitingSince.IsZero() {
+		return 0
+	}
+	since := r.mu.proposalQuotaWaitingSince
+	if since.Before(r.mu.proposalQuotaBaseIndexAdvanced) {
+		since = r.mu.proposalQuotaBaseIndexAdvanced
+	}
+	return now.Sub(since)
+}
+
+// tenantProposalQuotaPools are the per-tenant proposal quota pools of a store.
+// They bound the size of the proposals in flight across all of a secondary
+// tenant's ranges on the store, so that a burst of writes by one tenant cannot
+// monopolize the store's raft throughput at the expense of the others. The
+// system tenant's ranges, which include the ranges of the system tables every
+// tenant depends on, are not limited.
+//
+// Unlike a range's quota, which is held until all followers have caught up, a
+// tenant's quota is released when the proposal comes up for application on the
+// leader: it limits the rate at which the tenant's writes are replicated, not
+// how far its followers may fall behind.
+type tenantProposalQuotaPools struct {
+	// num is the number of pools, which lets acquisitions skip the mutex while
+	// tenants are not limited and no pools remain.
+	num atomic.Int32
+	mu  struct {
+		syncutil.Mutex
+		// pools are created on first use, and closed once idle for
+		// tenantProposalQuotaPoolIdleTimeout or once tenants are no longer
+		// limited.
+		pools map[roachpb.TenantID]*tenantProposalQuotaPool
+		// lastGC is when idle pools were last closed.
+		lastGC time.Time
+	}
+}
+
+// tenantProposalQuotaPool is the proposal quota pool of a tenant.
+type tenantProposalQuotaPool struct {
+	*quotapool.IntPool
+	// lastUsed is when quota was last acquired from the pool.
+	lastUsed time.Time
+}
+
+// tenantProposalQuotaPoolIdleTimeout is for how long a tenant's pool may go
+// unused, with none of its quota held, before it is closed. This bounds the
+// pools of a store to those of the tenants it recently held the ranges of.
+const tenantProposalQuotaPoolIdleTimeout = 10 * time.Minute
+
+// pool returns the pool of the tenant, updated to the given capacity, and
+// closes the pools of the other tenants which are idle.
+func (t *tenantProposalQuotaPools) pool(
+	tenantID roachpb.TenantID, capacity uint64, now time.Time,
+) *quotapool.IntPool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if now.Sub(t.mu.lastGC) >= tenantProposalQuotaPoolIdleTimeout {
+		t.gcLocked(now)
+	}
+	p, ok := t.mu.pools[tenantID]
+	if !ok {
+		if t.mu.pools == nil {
+			t.mu.pools = make(map[roachpb.TenantID]*tenantProposalQuotaPool)
+		}
+		p = &tenantProposalQuotaPool{IntPool: quotapool.NewIntPool(
+			fmt.Sprintf("tenant %s raft proposal", tenantID), capacity, logSlowRaftProposalQuotaAcquisition,
+		)}
+		t.mu.pools[tenantID] = p
+		t.num.Store(int32(len(t.mu.pools)))
+	} else if p.Capacity() != capacity {
+		p.UpdateCapacity(capacity)
+	}
+	p.lastUsed = now
+	return p.IntPool
+}
+
+// gcLocked closes the pools which have not been used for
+// tenantProposalQuotaPoolIdleTimeout and of which no quota is held.
+func (t *tenantProposalQuotaPools) gcLocked(now time.Time) {
+	t.mu.lastGC = now
+	for id, p := range t.mu.pools {
+		if now.Sub(p.lastUsed) >= tenantProposalQuotaPoolIdleTimeout && p.Full() && p.Len() == 0 {
+			p.Close("idle")
+			delete(t.mu.pools, id)
+		}
+	}
+	t.num.Store(int32(len(t.mu.pools)))
+}
+
+// closeAll closes all of the pools. Quota held from them can still be
+// released, and is discarded.
+func (t *tenantProposalQuotaPools) closeAll() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for id, p := range t.mu.pools {
+		p.Close("tenant proposal quota disabled")
+		delete(t.mu.pools, id)
+	}
+	t.num.Store(0)
+}
+
+// acquire acquires quota from the pool of the tenant, which has the given
+// capacity. A capacity of zero means that the tenant's proposals are not
+// limited, in which case a nil alloc is returned, as it is for the system
+// tenant.
+func (t *tenantProposalQuotaPools) acquire(
+	ctx context.Context, tenantID roachpb.TenantID, capacity, quota uint64, now time.Time,
+) (*quotapool.IntAlloc, error) {
+	if capacity == 0 {
+		if t.num.Load() > 0 {
+			t.closeAll()
+		}
+		return nil, nil
+	}
+	if !tenantID.IsSet() || tenantID.IsSystem() {
+		return nil, nil
+	}
+	for {
+		alloc, err := t.pool(tenantID, capacity, now).Acquire(ctx, quota)
+		// The pool may have been closed since it was returned, in which case
+		// the acquisition is retried from the tenant's new pool.
+		if quotapool.HasErrClosed(err) {
+			continue
+		}
+		return alloc, err
+	}
+}
+
+// withProposalQuotaQueuePosition returns a context under which an acquisition
+// of proposal quota for ba which has to wait records how many proposals are
+// ahead of it in the trace, and fails instead of waiting behind more than the
+// batch's ProposalQuotaMaxWaiters.
+func (r *Replica) withProposalQuotaQueuePosition(
+	ctx context.Context, ba *kvpb.BatchRequest,
+) context.Context {
+	maxWaiters := ba.ProposalQuotaMaxWaiters
+	if maxWaiters == 0 && !log.HasSpan(ctx) {
+		return ctx
+	}
+	return quotapool.ContextWithQueuePositionFunc(ctx, func(ahead int) error {
+		log.Eventf(ctx, "waiting for proposal quota behind %d other proposals", ahead)
+		if maxWaiters > 0 && int64(ahead) >= maxWaiters {
+			return errors.Mark(errors.Newf("r%d: %d proposals are waiting for proposal quota "+
+				"(proposal_quota_max_waiters = %d)", r.RangeID, ahead, maxWaiters),
+				kvserverbase.ErrProposalQuotaQueueTooDeep)
+		}
+		return nil
+	})
+}
+
+// waitForProposalQuotaReleaseQueue blocks while the quotaReleaseQueue is longer
+// than MaxProposalQuotaReleaseQueueLength. Every proposal acquires at least one
+// unit of quota, but small proposals can individually fit in the pool long
+// after a follower has stopped acknowledging them, so this backpressures on the
+// queue's length directly to bound the memory it retains.
+//
+// It also blocks while the queues of all the store's leaders retain more than
+// maxStoreProposalQuotaReleaseQueueBytes, unless this replica's queue is
+// empty; see proposalQuotaReleaseQueues.blocks.
+//
+// It stops waiting, returning an error, if the replica is destroyed or the
+// store is quiescing.
+func (r *Replica) waitForProposalQuotaReleaseQueue(ctx context.Context) error {
+	maxLen := MaxProposalQuotaReleaseQueueLength.Get(&r.store.cfg.Settings.SV)
+	maxStoreBytes := maxStoreProposalQuotaReleaseQueueBytes.Get(&r.store.cfg.Settings.SV)
+	if maxLen == 0 && maxStoreBytes == 0 {
+		return nil
+	}
+	opts := proposalQuotaReleaseQueueRetryOpts
+	opts.Closer = r.store.stopper.ShouldQuiesce()
+	for re := retry.StartWithCtx(ctx, opts); re.Next(); {
+		r.mu.RLock()
+		_, destroyErr := r.isDestroyedRLocked()
+		queueLen := int64(len(r.mu.quotaReleaseQueue))
+		// The queue is released if the replica loses leadership.
+		isLeader := r.mu.proposalQuota != nil
+		r.mu.RUnlock()
+		if destroyErr != nil {
+			return destroyErr
+		}
+		if !isLeader {
+			return nil
+		}
+		overLen := maxLen > 0 && queueLen > maxLen
+		overStore := r.store.proposalQuotaReleaseQueues.blocks(queueLen, maxStoreBytes)
+		if !overLen && !overStore {
+			return nil
+		}
+		if re.CurrentAttempt() == 0 {
+			if overLen {
+				log.VEventf(ctx, 2, "quota release queue length %d exceeds %d, waiting", queueLen, maxLen)
+				if proposalQuotaReleaseQueueCapLogEvery.ShouldLog() {
+					log.Warningf(ctx, "quota release queue length %d exceeds %d; "+
+						"blocking proposals until followers catch up", queueLen, maxLen)
+				}
+			} else {
+				storeBytes := r.store.proposalQuotaReleaseQueues.bytes()
+				log.VEventf(ctx, 2, "store quota release queues retain %d bytes, exceeding %d, waiting",
+					storeBytes, maxStoreBytes)
+				if proposalQuotaReleaseQueueCapLogEvery.ShouldLog() {
+					log.Warningf(ctx, "store quota release queues retain %d bytes, exceeding %d; "+
+						"blocking proposals to ranges with entries awaiting release until "+
+						"followers catch up", storeBytes, maxStoreBytes)
+				}
+			}
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return &kvpb.NodeUnavailableError{}
+}
+
+// proposalQuotaReleaseQueueEntryBytes estimates the memory retained by an
+// entry of a quotaReleaseQueue: the pointer in the queue, and the alloc it
+// points to. The quota of the alloc is not memory, and is accounted for by the
+// range's quota pool.
+const proposalQuotaReleaseQueueEntryBytes = int64(unsafe.Sizeof(&quotapool.IntAlloc{})) +
+	int64(unsafe.Sizeof(quotapool.IntAlloc{}))
+
+// maxFormattedQuotaReleaseQueueEntries bounds the number of entries of a
+// quotaReleaseQueue listed by formatQuotaReleaseQueue.
+const maxFormattedQuotaReleaseQueueEntries = 100
+
+// formatQuotaReleaseQueue renders a quotaReleaseQueue, whose first entry is
+// at baseIndex+1, as pairs of the raft index of each entry and the size of its
+// alloc, followed by the totals. Entries without an alloc, i.e. of commands
+// which did not acquire quota, are rendered as "-". Only the oldest entries,
+// which are the ones holding up the release of quota, are listed, up to
+// maxFormattedQuotaReleaseQueueEntries. The queue is not modified, so this can
+// be called with Replica.mu held for reading. An empty queue renders as "".
+func formatQuotaReleaseQueue(baseIndex kvpb.RaftIndex, queue []*quotapool.IntAlloc) string {
+	if len(queue) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	var total uint64
+	for i, alloc := range queue {
+		if alloc != nil {
+			total += alloc.Acquired()
+		}
+		if i >= maxFormattedQuotaReleaseQueueEntries {
+			continue
+		}
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "%d: ", baseIndex+kvpb.RaftIndex(i+1))
+		if alloc == nil {
+			b.WriteString("-")
+		} else {
+			fmt.Fprintf(&b, "%d", alloc.Acquired())
+		}
+	}
+	if extra := len(queue) - maxFormattedQuotaReleaseQueueEntries; extra > 0 {
+		fmt.Fprintf(&b, ", ... (%d more)", extra)
+	}
+	fmt.Fprintf(&b, " [%d entries, %d bytes]", len(queue), total)
+	return b.String()
+}
+
+// proposalQuotaReleaseQueues accounts for the entries of the quotaReleaseQueues
+// of all the leaders on a store, see maxStoreProposalQuotaReleaseQueueBytes.
+type proposalQuotaReleaseQueues struct {
+	entries atomic.Int64
+}
+
+// add records n entries being added to a quotaReleaseQueue, or removed from it
+// if negative.
+func (q *proposalQuotaReleaseQueues) add(n int) {
+	q.entries.Add(int64(n))
+}
+
+// bytes returns the memory retained by the store's quotaReleaseQueues.
+func (q *proposalQuotaReleaseQueues) bytes() int64 {
+	return q.entries.Load() * proposalQuotaReleaseQueueEntryBytes
+}
+
+// blocks returns whether a leader whose quotaReleaseQueue holds queueLen
+// entries should stop admitting proposals, as the store's queues retain more
+// than maxBytes. Leaders whose queue is empty are not contributing to the
+// store's total and are let through, so that the backpressure falls on the
+// ranges whose followers are behind. Leaders whose followers keep up empty
+// their queues within a round trip, so they are only briefly held up.
+func (q *proposalQuotaReleaseQueues) blocks(queueLen, maxBytes int64) bool {
+	return maxBytes > 0 && queueLen > 0 && q.bytes() > maxBytes
+}
+
+// proposalQuotaEnabled returns whether proposals acquire proposal quota, given
+// whether the range's span config forces them to. The span config takes
+// precedence over kv.raft.proposal_quota.enabled, but not over a temporary
+// exemption of the range's table, nor over the ranges which never use a quota
+// pool, see quotaPoolEnabledForRange.
+func proposalQuotaEnabled(sv *settings.Values, forced bool) bool {
+	return forced || enableRaftProposalQuota.Get(sv)
 }
 
 func quotaPoolEnabledForRange(desc *roachpb.RangeDescriptor) bool {
@@ -100,10 +764,529 @@ func quotaPoolEnabledForRange(desc *roachpb.RangeDescriptor) bool {
 	return !bytes.HasPrefix(desc.StartKey, keys.NodeLivenessPrefix)
 }
 
+// proposalQuotaLow returns whether the available proposal quota is running
+// low, i.e. below 10% of the pool's capacity.
+func proposalQuotaLow(available, capacity uint64) bool {
+	return available < capacity/10
+}
+
+// proposalQuotaBackpressureHint returns the delay to suggest to clients
+// writing to a range with the given available proposal quota. It is zero
+// unless the quota is low, and from there grows linearly with the quota used
+// up to maxDelay once the quota is exhausted.
+func proposalQuotaBackpressureHint(available, capacity uint64, maxDelay time.Duration) time.Duration {
+	if maxDelay <= 0 || !proposalQuotaLow(available, capacity) {
+		return 0
+	}
+	low := capacity / 10
+	return time.Duration(float64(maxDelay) * (1 - float64(available)/float64(low)))
+}
+
+// rangeProposalQuota is the proposal quota available to a range.
+type rangeProposalQuota struct {
+	rangeID   roachpb.RangeID
+	available int64
+}
+
+// lowestProposalQuota returns the n ranges with the least proposal quota
+// available, in increasing order of available quota and then range ID. The
+// ranges are sorted in place.
+func lowestProposalQuota(ranges []rangeProposalQuota, n int) []rangeProposalQuota {
+	slices.SortFunc(ranges, func(a, b rangeProposalQuota) int {
+		if c := cmp.Compare(a.available, b.available); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.rangeID, b.rangeID)
+	})
+	if len(ranges) > n {
+		ranges = ranges[:n]
+	}
+	return ranges
+}
+
+// maybeSetBackpressureHint sets the BackpressureHint of br if the proposal
+// quota of the range is low, as of just after a proposal acquired its share.
+func (r *Replica) maybeSetBackpressureHint(br *kvpb.BatchResponse) {
+	maxDelay := proposalQuotaBackpressureHintMaxDelay.Get(&r.store.cfg.Settings.SV)
+	if br == nil || maxDelay == 0 {
+		return
+	}
+	r.mu.RLock()
+	quotaPool := r.mu.proposalQuota
+	r.mu.RUnlock()
+	if quotaPool == nil {
+		return
+	}
+	hint := proposalQuotaBackpressureHint(quotaPool.ApproximateQuota(), quotaPool.Capacity(), maxDelay)
+	if hint > 0 {
+		br.BackpressureHint = hint
+		r.store.metrics.RaftProposalQuotaBackpressureHints.Inc(1)
+	}
+}
+
+// proposalQuotaExemptTables lists the tables whose ranges do not acquire
+// proposal quota until the given time, for example to let the writes of a
+// planned bulk operation through without throttling them behind slow
+// followers. This is finer-grained than disabling
+// kv.raft.proposal_quota.enabled, and exemptions lapse on their own.
+var proposalQuotaExemptTables = settings.RegisterStringSetting(
+	settings.SystemOnly,
+	"kv.raft.proposal_quota.exempt_tables",
+	"comma-separated list of tenant_id/table_id@expiration entries, each exempting the "+
+		"ranges of a table from acquiring proposal quota until the given RFC3339 time",
+	"",
+	settings.WithValidateString(func(_ *settings.Values, v string) error {
+		_, err := parseProposalQuotaExemptTables(v)
+		return err
+	}),
+)
+
+// parseProposalQuotaExemptTables parses the value of
+// kv.raft.proposal_quota.exempt_tables into the expiration of each table.
+func parseProposalQuotaExemptTables(
+	v string,
+) (map[proposalQuotaExemptTable]time.Time, error) {
+	expirations := make(map[proposalQuotaExemptTable]time.Time)
+	for _, entry := range strings.Split(v, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		table, expiration, ok := strings.Cut(entry, "@")
+		if !ok {
+			return nil, errors.Newf("%q: missing @expiration", entry)
+		}
+		tenant, tableID, ok := strings.Cut(table, "/")
+		if !ok {
+			return nil, errors.Newf("%q: expected tenant_id/table_id", entry)
+		}
+		tid, err := strconv.ParseUint(tenant, 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%q: invalid tenant ID", entry)
+		}
+		tenantID, err := roachpb.MakeTenantID(tid)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%q", entry)
+		}
+		id, err := strconv.ParseUint(tableID, 10, 32)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%q: invalid table ID", entry)
+		}
+		t, err := time.Parse(time.RFC3339, expiration)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%q: invalid expiration", entry)
+		}
+		expirations[proposalQuotaExemptTable{tenantID: tenantID, tableID: uint32(id)}] = t
+	}
+	return expirations, nil
+}
+
+// proposalQuotaExemptTable identifies a table of a tenant.
+type proposalQuotaExemptTable struct {
+	tenantID roachpb.TenantID
+	tableID  uint32
+}
+
+// proposalQuotaExemptions tracks the tables whose ranges are temporarily
+// exempt from acquiring proposal quota, as listed in
+// kv.raft.proposal_quota.exempt_tables.
+type proposalQuotaExemptions struct {
+	// num is the number of entries in mu.expirations, so that the common case of
+	// no exemptions doesn't need to acquire the mutex.
+	num atomic.Int32
+	mu  struct {
+		syncutil.Mutex
+		// expirations maps each exempt table to the time its exemption expires.
+		expirations map[proposalQuotaExemptTable]time.Time
+	}
+}
+
+// watch sets the exemptions from kv.raft.proposal_quota.exempt_tables, and
+// again whenever it changes.
+func (e *proposalQuotaExemptions) watch(ctx context.Context, sv *settings.Values) {
+	update := func(ctx context.Context) {
+		expirations, err := parseProposalQuotaExemptTables(proposalQuotaExemptTables.Get(sv))
+		if err != nil {
+			// The setting is validated, so this is only reached if the value was
+			// set by a node which didn't validate it.
+			log.Warningf(ctx, "ignoring invalid proposal quota exemptions: %v", err)
+			return
+		}
+		e.replace(expirations)
+	}
+	update(ctx)
+	proposalQuotaExemptTables.SetOnChange(sv, update)
+}
+
+// replace replaces all exemptions with the given ones.
+func (e *proposalQuotaExemptions) replace(expirations map[proposalQuotaExemptTable]time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.mu.expirations = expirations
+	e.num.Store(int32(len(e.mu.expirations)))
+}
+
+// exempt returns true if the range belongs to a table which is exempt as of
+// now. A range belongs to the table its start key is in. Expired exemptions are
+// removed.
+func (e *proposalQuotaExemptions) exempt(desc *roachpb.RangeDescriptor, now time.Time) bool {
+	if e.num.Load() == 0 {
+		return false
+	}
+	rest, tenantID, err := keys.DecodeTenantPrefix(desc.StartKey.AsRawKey())
+	if err != nil {
+		return false
+	}
+	_, tableID, err := keys.SystemSQLCodec.DecodeTablePrefix(rest)
+	if err != nil {
+		return false
+	}
+	table := proposalQuotaExemptTable{tenantID: tenantID, tableID: tableID}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	expiration, ok := e.mu.expirations[table]
+	if !ok {
+		return false
+	}
+	if !now.Before(expiration) {
+		delete(e.mu.expirations, table)
+		e.num.Store(int32(len(e.mu.expirations)))
+		return false
+	}
+	return true
+}
+
+// proposalQuotaExemptMethods are the requests which do not acquire proposal
+// quota, provided the batch consists only of such requests. They are internal
+// maintenance operations which are either small or reclaim resources, so
+// throttling them behind a slow follower does more harm than good:
+//
+//   - GC: MVCC garbage collection, which must keep up to bound the amount of
+//     garbage accumulating on a range which is also seeing heavy writes.
+//   - RecomputeStats: fixes up MVCC stats which have drifted.
+//   - ComputeChecksum: consistency checks, which would otherwise hold up the
+//     consistency queue.
+var proposalQuotaExemptMethods = map[kvpb.Method]struct{}{
+	kvpb.GC:              {},
+	kvpb.RecomputeStats:  {},
+	kvpb.ComputeChecksum: {},
+}
+
+// bypassesProposalQuota returns true if ba, proposed to a range of the given
+// tenant, is internal system work which should not wait for proposal quota.
+// That is the case if it consists only of proposalQuotaExemptMethods, or if it
+// was submitted by KV-internal code (rather than SQL) at the highest admission
+// priority to a range of the system tenant. The admission header is set by the
+// client, so secondary tenants, which can only write to their own ranges,
+// cannot use it to skip quota.
+func bypassesProposalQuota(ba *kvpb.BatchRequest, tenantID roachpb.TenantID) bool {
+	if tenantID.IsSystem() && ba.AdmissionHeader.Source == kvpb.AdmissionHeader_OTHER &&
+		admissionpb.WorkPriority(ba.AdmissionHeader.Priority) == admissionpb.HighPri {
+		return true
+	}
+	if len(ba.Requests) == 0 {
+		return false
+	}
+	for _, ru := range ba.Requests {
+		if _, ok := proposalQuotaExemptMethods[ru.GetInner().Method()]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// isStructuralProposal returns true if ba is part of a split or merge of the
+// range, which should not wait for proposal quota: splits in particular are
+// how a hot range's load gets spread out, and must not be starved by that very
+// load. AdminSplit and AdminMerge aren't proposed themselves, but run
+// transactions which are anchored at and write to range descriptor keys, and
+// commit with a split or merge trigger; merges also subsume the right-hand
+// range. Other updates of range descriptors, i.e. replication changes, are
+// recognized by the keys they write to as well.
+func isStructuralProposal(ba *kvpb.BatchRequest) bool {
+	if len(ba.Requests) == 0 {
+		return false
+	}
+	descriptorKeysOnly := true
+	for _, ru := range ba.Requests {
+		switch req := ru.GetInner().(type) {
+		case *kvpb.SubsumeRequest:
+			return true
+		case *kvpb.EndTxnRequest:
+			if ct := req.InternalCommitTrigger; ct != nil && (ct.SplitTrigger != nil || ct.MergeTrigger != nil) {
+				return true
+			}
+		}
+		if descriptorKeysOnly {
+			descriptorKeysOnly = isRangeDescriptorKey(ru.GetInner().Header().Key)
+		}
+	}
+	return descriptorKeysOnly
+}
+
+// isRangeDescriptorKey returns true if key is the range-local key of a range
+// descriptor.
+func isRangeDescriptorKey(key roachpb.Key) bool {
+	if !bytes.HasPrefix(key, keys.LocalRangePrefix) {
+		return false
+	}
+	_, suffix, _, err := keys.DecodeRangeKey(key)
+	return err == nil && bytes.Equal(suffix, keys.LocalRangeDescriptorSuffix)
+}
+
+// secondaryIndexQuotaPercent returns the percentage (0-100) of the size of the
+// writes in ba to the SQL tables identified by ba.PrimaryIndexIDs that goes to
+// secondary indexes of those tables. It returns false if ba contains no such
+// writes. The proposal quota charged for a command is the size of the command,
+// which is dominated by the keys and values written, so the sizes of the
+// requests are used to apportion it.
+func secondaryIndexQuotaPercent(ba *kvpb.BatchRequest) (int64, bool) {
+	if len(ba.PrimaryIndexIDs) == 0 {
+		return 0, false
+	}
+	var total, secondary int64
+	for _, ru := range ba.Requests {
+		req := ru.GetInner()
+		if !kvpb.IsIntentWrite(req) {
+			continue
+		}
+		rest, _, err := keys.DecodeTenantPrefix(req.Header().Key)
+		if err != nil {
+			continue
+		}
+		_, tableID, indexID, err := keys.SystemSQLCodec.DecodeIndexPrefix(rest)
+		if err != nil {
+			continue
+		}
+		primaryIndexID, ok := ba.PrimaryIndexIDs[tableID]
+		if !ok {
+			continue
+		}
+		size := int64(req.Size())
+		total += size
+		if indexID != primaryIndexID {
+			secondary += size
+		}
+	}
+	if total == 0 {
+		return 0, false
+	}
+	return 100 * secondary / total, true
+}
+
+// excludedFamilyQuotaBytes returns the size of the writes in ba to the column
+// families of the primary indexes of the SQL tables identified by
+// ba.PrimaryIndexIDs which are excluded from the proposal quota charge, per the
+// range's span config. The SQL writers suffix the key of each write with the
+// ID of its family, and identify the primary index of the tables they write to
+// in ba.PrimaryIndexIDs, so the family of a write can be decoded from its key.
+// As for secondaryIndexQuotaPercent, the sizes of the requests are used to
+// apportion the charge. Ranged writes, which don't target a single family, and
+// writes to secondary indexes are never excluded.
+func excludedFamilyQuotaBytes(ba *kvpb.BatchRequest, familyIDs []uint32) uint64 {
+	if len(familyIDs) == 0 || len(ba.PrimaryIndexIDs) == 0 {
+		return 0
+	}
+	var excluded uint64
+	for _, ru := range ba.Requests {
+		req := ru.GetInner()
+		if !kvpb.IsIntentWrite(req) || len(req.Header().EndKey) != 0 {
+			continue
+		}
+		key := req.Header().Key
+		rest, _, err := keys.DecodeTenantPrefix(key)
+		if err != nil {
+			continue
+		}
+		_, tableID, indexID, err := keys.SystemSQLCodec.DecodeIndexPrefix(rest)
+		if err != nil {
+			continue
+		}
+		// Secondary index keys are suffixed with a family ID too, but the
+		// families excluded by the span config are those of the primary index.
+		if primaryIndexID, ok := ba.PrimaryIndexIDs[tableID]; !ok || indexID != primaryIndexID {
+			continue
+		}
+		familyID, err := keys.DecodeFamilyKey(key)
+		if err != nil {
+			continue
+		}
+		if slices.Contains(familyIDs, familyID) {
+			excluded += uint64(req.Size())
+		}
+	}
+	return excluded
+}
+
 var logSlowRaftProposalQuotaAcquisition = quotapool.OnSlowAcquisition(
 	base.SlowRequestThreshold, quotapool.LogSlowAcquisition,
 )
 
+// proposalQuotaGroupingOption configures the proposal quota pool to group
+// waiting proposals by the SQL session which issued them, if enabled. The
+// session is identified by the group key the SQL layer attaches to the
+// context (see quotapool.ContextWithGroupKey), so only requests evaluated on
+// the gateway node carry it; other requests are served in FIFO order.
+func (r *Replica) proposalQuotaGroupingOption() quotapool.Option {
+	sv := &r.store.cfg.Settings.SV
+	return quotapool.WithGrouping(func(ctx context.Context) string {
+		if !enableRaftProposalQuotaSessionFairness.Get(sv) {
+			return ""
+		}
+		return quotapool.GroupKeyFromContext(ctx)
+	})
+}
+
+// QuotaStalled returns whether the replica is a leader whose proposal quota is
+// stalled: proposals have been waiting for quota for at least
+// kv.raft.proposal_quota.stall_threshold, without any being released, i.e.
+// without the base index advancing. This typically means that a follower which
+// is still considered active is not acknowledging entries, and unlike a leader
+// which is merely busy, the range cannot make progress until that changes.
+func (r *Replica) QuotaStalled(now time.Time) bool {
+	threshold := proposalQuotaStallThreshold.Get(&r.store.cfg.Settings.SV)
+	if threshold == 0 {
+		return false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	stalled := r.proposalQuotaStalledForRLocked(now)
+	return stalled > 0 && stalled >= threshold
+}
+
+// OutstandingProposalQuotaAllocs returns the sizes of the proposal quota
+// allocs currently held, largest first, if the replica is the leader. This
+// includes the quota of proposals which are in flight and of those which have
+// applied but are waiting in the quotaReleaseQueue for followers to catch up,
+// telling apart a pool exhausted by a few large proposals from one full of
+// small ones.
+func (r *Replica) OutstandingProposalQuotaAllocs() []uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.mu.proposalQuota == nil {
+		return nil
+	}
+	return r.mu.proposalQuota.HeldAllocs()
+}
+
+// proposalQuotaFollower is the progress of a replica of a range, as
+// considered by the leader when releasing proposal quota.
+type proposalQuotaFollower struct {
+	desc  roachpb.ReplicaDescriptor
+	match kvpb.RaftIndex
+	// paused is set if MsgApps to the replica are being dropped, see
+	// Replica.mu.pausedFollowers.
+	paused bool
+}
+
+// holdsProposalQuota returns whether the follower holds up the release of the
+// proposal quota of the entries beyond its match index as of now.
+func (f proposalQuotaFollower) holdsProposalQuota(
+	sv *settings.Values,
+	leaseDuration time.Duration,
+	now time.Time,
+	baseIndex kvpb.RaftIndex,
+	lastUpdateTimes lastUpdateTimesMap,
+) bool {
+	// Only consider followers that are active. Inactive ones don't decrease
+	// minIndex - i.e. they don't hold up releasing quota.
+	//
+	// The policy for determining who's active is stricter than the one used
+	// for purposes of quiescing. Failure to consider a dead/stuck node as
+	// such for the purposes of releasing quota can have bad consequences
+	// (writes will stall), whereas for quiescing the downside is lower. It
+	// depends on the type of the replica, and non-voters are by default not
+	// considered at all, as they are not needed for writes to commit.
+	window, ok := proposalQuotaActivityWindow(sv, f.desc.Type, leaseDuration)
+	if !ok || !lastUpdateTimes.isFollowerActiveSince(f.desc.ReplicaID, now, window) {
+		return false
+	}
+	// At this point, we know that either we communicated with this replica
+	// recently, or we became the leader recently. The latter case is ambiguous
+	// w.r.t. the actual state of that replica, but it is temporary.
+
+	// Note that the Match field has different semantics depending on
+	// the State.
+	//
+	// In state ProgressStateReplicate, the Match index is optimistically
+	// updated whenever a message is *sent* (not received). Due to Raft
+	// flow control, only a reasonably small amount of data can be en
+	// route to a given follower at any point in time.
+	//
+	// In state ProgressStateProbe, the Match index equals Next-1, and
+	// it tells us the leader's optimistic best guess for the right log
+	// index (and will try once per heartbeat interval to update its
+	// estimate). In the usual case, the follower responds with a hint
+	// when it rejects the first probe and the leader replicates or
+	// sends a snapshot. In the case in which the follower does not
+	// respond, the leader reduces Match by one each heartbeat interval.
+	// But if the follower does not respond, we've already filtered it
+	// out above. We use the Match index as is, even though the follower
+	// likely isn't there yet because that index won't go up unless the
+	// follower is actually catching up, so it won't cause it to fall
+	// behind arbitrarily.
+	//
+	// Another interesting tidbit about this state is that the Paused
+	// field is usually true as it is used to limit the number of probes
+	// (i.e. appends) sent to this follower to one per heartbeat
+	// interval.
+	//
+	// In state ProgressStateSnapshot, the Match index is the last known
+	// (possibly optimistic, depending on previous state) index before
+	// the snapshot went out. Once the snapshot applies, the follower
+	// will enter ProgressStateReplicate again. So here the Match index
+	// works as advertised too.
+
+	// Only consider followers who are in advance of the quota base
+	// index. This prevents a follower from coming back online and
+	// preventing throughput to the range until it has caught up.
+	if f.match < baseIndex {
+		return false
+	}
+	if f.paused {
+		// We are dropping MsgApp to this store, so we are effectively treating
+		// it as non-live for the purpose of replication and are letting it fall
+		// behind intentionally.
+		//
+		// See #79215.
+		return false
+	}
+	return true
+}
+
+// computeProposalQuotaMinIndex returns the index up to which the proposal
+// quota of the applied entries can be released as of now, given the progress
+// of the range's replicas, and the follower holding it up, if any.
+//
+// The index is the minimum index acknowledged by the followers which hold up
+// the release of quota, see holdsProposalQuota, and at most the applied index:
+// given that the quota release queue cannot correspond to values beyond the
+// applied index there's no reason to consider progress beyond it as
+// meaningful.
+//
+// The activity of the followers is judged based on now, which the leader takes
+// from the store's clock, so that tests can control it with a manual clock.
+func computeProposalQuotaMinIndex(
+	sv *settings.Values,
+	leaseDuration time.Duration,
+	now time.Time,
+	applied, baseIndex kvpb.RaftIndex,
+	lastUpdateTimes lastUpdateTimesMap,
+	followers []proposalQuotaFollower,
+) (minIndex kvpb.RaftIndex, slowest roachpb.ReplicaID) {
+	minIndex = applied
+	for _, f := range followers {
+		if !f.holdsProposalQuota(sv, leaseDuration, now, baseIndex, lastUpdateTimes) {
+			continue
+		}
+		if f.match > 0 && f.match < minIndex {
+			minIndex = f.match
+			slowest = f.desc.ReplicaID
+		}
+	}
+	return minIndex, slowest
+}
+
 func (r *Replica) updateProposalQuotaRaftMuLocked(
 	ctx context.Context, lastLeaderID roachpb.ReplicaID,
 ) {
@@ -120,6 +1303,7 @@ func (r *Replica) updateProposalQuotaRaftMuLocked(
 			// will be appended to the quotaReleaseQueue. The proposalQuotaBaseIndex
 			// and the quotaReleaseQueue together track status.Applied exactly.
 			r.mu.proposalQuotaBaseIndex = kvpb.RaftIndex(status.Applied)
+			r.mu.proposalQuotaBaseIndexAdvanced = now
 			if r.mu.proposalQuota != nil {
 				log.Fatal(ctx, "proposalQuota was not nil before becoming the leader")
 			}
@@ -136,7 +1320,13 @@ func (r *Replica) updateProposalQuotaRaftMuLocked(
 				"raft proposal",
 				uint64(r.store.cfg.RaftProposalQuota),
 				logSlowRaftProposalQuotaAcquisition,
+				r.proposalQuotaGroupingOption(),
+				quotapool.WithAllocTracking(),
 			)
+			r.mu.proposalQuotaStallRecorded = false
+			r.mu.proposalQuotaWaitingSince = time.Time{}
+			r.mu.proposalQuotaEvents.add(now, "became leader: created quota pool of %d bytes at base index %d",
+				r.store.cfg.RaftProposalQuota, r.mu.proposalQuotaBaseIndex)
 			r.mu.lastUpdateTimes = make(map[roachpb.ReplicaID]time.Time)
 			r.mu.lastUpdateTimes.updateOnBecomeLeader(r.mu.state.Desc.Replicas().Descriptors(), now)
 			r.mu.replicaFlowControlIntegration.onBecameLeader(ctx)
@@ -145,12 +1335,18 @@ func (r *Replica) updateProposalQuotaRaftMuLocked(
 			// We're becoming a follower.
 			// We unblock all ongoing and subsequent quota acquisition goroutines
 			// (if any) and release the quotaReleaseQueue so its allocs are pooled.
+			r.mu.proposalQuotaEvents.add(now, "lost leadership to r%d: closed quota pool with %d "+
+				"entries pending release at base index %d",
+				r.mu.leaderID, len(r.mu.quotaReleaseQueue), r.mu.proposalQuotaBaseIndex)
 			r.mu.proposalQuota.Close("leader change")
 			r.mu.proposalQuota.Release(r.mu.quotaReleaseQueue...)
+			r.store.proposalQuotaReleaseQueues.add(-len(r.mu.quotaReleaseQueue))
 			r.mu.quotaReleaseQueue = nil
 			r.mu.proposalQuota = nil
+			r.mu.proposalQuotaSlowestFollower = 0
 			r.mu.lastUpdateTimes = nil
 			r.mu.replicaFlowControlIntegration.onBecameFollower(ctx)
+			r.loadBasedSplitter.RecordQuotaPressure(now, false)
 		}
 		return
 	} else if r.mu.proposalQuota == nil {
@@ -167,90 +1363,61 @@ func (r *Replica) updateProposalQuotaRaftMuLocked(
 	// commitIndex is used to determine whether a newly added replica has fully
 	// caught up.
 	commitIndex := kvpb.RaftIndex(status.Commit)
-	// Initialize minIndex to the currently applied index. The below progress
-	// checks will only decrease the minIndex. Given that the quotaReleaseQueue
-	// cannot correspond to values beyond the applied index there's no reason
-	// to consider progress beyond it as meaningful.
-	minIndex := kvpb.RaftIndex(status.Applied)
 
+	followers := make([]proposalQuotaFollower, 0, len(r.mu.state.Desc.InternalReplicas))
 	r.mu.internalRaftGroup.WithProgress(func(id raftpb.PeerID, _ raft.ProgressType, progress tracker.Progress) {
 		rep, ok := r.mu.state.Desc.GetReplicaDescriptorByID(roachpb.ReplicaID(id))
 		if !ok {
 			return
 		}
-
-		// Only consider followers that are active. Inactive ones don't decrease
-		// minIndex - i.e. they don't hold up releasing quota.
-		//
-		// The policy for determining who's active is stricter than the one used
-		// for purposes of quiescing. Failure to consider a dead/stuck node as
-		// such for the purposes of releasing quota can have bad consequences
-		// (writes will stall), whereas for quiescing the downside is lower.
-
-		if !r.mu.lastUpdateTimes.isFollowerActiveSince(rep.ReplicaID, now, r.store.cfg.RangeLeaseDuration) {
-			return
-		}
-		// At this point, we know that either we communicated with this replica
-		// recently, or we became the leader recently. The latter case is ambiguous
-		// w.r.t. the actual state of that replica, but it is temporary.
-
-		// Note that the Match field has different semantics depending on
-		// the State.
-		//
-		// In state ProgressStateReplicate, the Match index is optimistically
-		// updated whenever a message is *sent* (not received). Due to Raft
-		// flow control, only a reasonably small amount of data can be en
-		// route to a given follower at any point in time.
-		//
-		// In state ProgressStateProbe, the Match index equals Next-1, and
-		// it tells us the leader's optimistic best guess for the right log
-		// index (and will try once per heartbeat interval to update its
-		// estimate). In the usual case, the follower responds with a hint
-		// when it rejects the first probe and the leader replicates or
-		// sends a snapshot. In the case in which the follower does not
-		// respond, the leader reduces Match by one each heartbeat interval.
-		// But if the follower does not respond, we've already filtered it
-		// out above. We use the Match index as is, even though the follower
-		// likely isn't there yet because that index won't go up unless the
-		// follower is actually catching up, so it won't cause it to fall
-		// behind arbitrarily.
-		//
-		// Another interesting tidbit about this state is that the Paused
-		// field is usually true as it is used to limit the number of probes
-		// (i.e. appends) sent to this follower to one per heartbeat
-		// interval.
-		//
-		// In state ProgressStateSnapshot, the Match index is the last known
-		// (possibly optimistic, depending on previous state) index before
-		// the snapshot went out. Once the snapshot applies, the follower
-		// will enter ProgressStateReplicate again. So here the Match index
-		// works as advertised too.
-
-		// Only consider followers who are in advance of the quota base
-		// index. This prevents a follower from coming back online and
-		// preventing throughput to the range until it has caught up.
-		if kvpb.RaftIndex(progress.Match) < r.mu.proposalQuotaBaseIndex {
-			return
-		}
-		if _, paused := r.mu.pausedFollowers[roachpb.ReplicaID(id)]; paused {
-			// We are dropping MsgApp to this store, so we are effectively treating
-			// it as non-live for the purpose of replication and are letting it fall
-			// behind intentionally.
-			//
-			// See #79215.
-			return
-		}
-		if progress.Match > 0 && kvpb.RaftIndex(progress.Match) < minIndex {
-			minIndex = kvpb.RaftIndex(progress.Match)
-		}
+		_, paused := r.mu.pausedFollowers[rep.ReplicaID]
+		f := proposalQuotaFollower{desc: rep, match: kvpb.RaftIndex(progress.Match), paused: paused}
+		followers = append(followers, f)
 		// If this is the most recently added replica, and it has caught up, clear
 		// our state that was tracking it. This is unrelated to managing proposal
 		// quota, but this is a convenient place to do so.
-		if rep.ReplicaID == r.mu.lastReplicaAdded && kvpb.RaftIndex(progress.Match) >= commitIndex {
+		if rep.ReplicaID == r.mu.lastReplicaAdded && f.match >= commitIndex &&
+			f.holdsProposalQuota(&r.store.cfg.Settings.SV, r.store.cfg.RangeLeaseDuration, now,
+				r.mu.proposalQuotaBaseIndex, r.mu.lastUpdateTimes) {
 			r.mu.lastReplicaAdded = 0
 			r.mu.lastReplicaAddedTime = time.Time{}
 		}
 	})
+	minIndex, slowest := computeProposalQuotaMinIndex(&r.store.cfg.Settings.SV,
+		r.store.cfg.RangeLeaseDuration, now, kvpb.RaftIndex(status.Applied),
+		r.mu.proposalQuotaBaseIndex, r.mu.lastUpdateTimes, followers)
+	r.mu.proposalQuotaSlowestFollower = slowest
+
+	// Track for how long proposals have been waiting for quota. They are noted
+	// as they start waiting in noteProposalQuotaWaiting, but one may only have
+	// queued up after the last time this found none waiting.
+	if r.mu.proposalQuota.Len() == 0 {
+		r.mu.proposalQuotaWaitingSince = time.Time{}
+	} else if r.mu.proposalQuotaWaitingSince.IsZero() {
+		r.mu.proposalQuotaWaitingSince = now
+	}
+
+	// If no follower has caught up far enough to release any quota for a while
+	// and proposals are waiting for it, the range is stalled even though it
+	// could be making progress with the other replicas. Release the quota of all
+	// applied entries regardless, if configured to do so, and leave it to the
+	// lagging followers to catch up, possibly via a snapshot.
+	if minIndex <= r.mu.proposalQuotaBaseIndex && r.mu.proposalQuotaBaseIndex < kvpb.RaftIndex(status.Applied) &&
+		r.mu.proposalQuota.Len() > 0 {
+		if relaxAfter := ProposalQuotaRelaxAfter.Get(&r.store.cfg.Settings.SV); relaxAfter > 0 {
+			if stalled := r.proposalQuotaStalledForRLocked(now); stalled > 0 && stalled >= relaxAfter {
+				if proposalQuotaRelaxLogEvery.ShouldLog() {
+					log.Warningf(ctx, "r%d: no follower has caught up beyond the proposal quota base index %d "+
+						"in %s while proposals are waiting for quota; releasing the quota of all entries up "+
+						"to the applied index %d regardless", r.RangeID, r.mu.proposalQuotaBaseIndex, stalled, status.Applied)
+				}
+				r.store.metrics.RaftProposalQuotaRelaxed.Inc(1)
+				r.mu.proposalQuotaEvents.add(now, "relaxed: released quota held up for %s regardless "+
+					"of the followers", stalled)
+				minIndex = kvpb.RaftIndex(status.Applied)
+			}
+		}
+	}
 
 	if r.mu.proposalQuotaBaseIndex < minIndex {
 		// We've persisted at least minIndex-r.mu.proposalQuotaBaseIndex entries
@@ -258,6 +1425,24 @@ func (r *Replica) updateProposalQuotaRaftMuLocked(
 		// entries locally since last we checked, so we are able to release the
 		// difference back to the quota pool.
 		numReleases := minIndex - r.mu.proposalQuotaBaseIndex
+		// minIndex never exceeds the applied index, so this can only happen if
+		// the base index and the queue have already fallen out of sync. Clamp
+		// instead of panicking on the slice bounds so that the assertion below
+		// reports the inconsistency.
+		if releaseQueueLen := kvpb.RaftIndex(len(r.mu.quotaReleaseQueue)); numReleases > releaseQueueLen {
+			log.Errorf(ctx, "r%d: cannot release %d entries of proposal quota (minIndex %d - "+
+				"proposalQuotaBaseIndex %d) with only %d entries in the release queue (applied index %d)",
+				r.RangeID, numReleases, minIndex, r.mu.proposalQuotaBaseIndex, releaseQueueLen, status.Applied)
+			numReleases = releaseQueueLen
+		}
+		// A follower catching up after lagging behind releases the quota of all
+		// the entries it was missing at once. Frequent large bursts point at
+		// ranges which repeatedly stall and then release their quota.
+		r.store.metrics.RaftProposalQuotaReleaseBurstSize.RecordValue(int64(numReleases))
+		if numReleases >= proposalQuotaEventBurstSize {
+			r.mu.proposalQuotaEvents.add(now, "released the quota of %d entries at once, advancing "+
+				"the base index to %d", numReleases, r.mu.proposalQuotaBaseIndex+numReleases)
+		}
 
 		// NB: Release deals with cases where allocs being released do not originate
 		// from this incarnation of quotaReleaseQueue, which can happen if a
@@ -265,18 +1450,42 @@ func (r *Replica) updateProposalQuotaRaftMuLocked(
 		// term and then commits while at a different term.
 		r.mu.proposalQuota.Release(r.mu.quotaReleaseQueue[:numReleases]...)
 		r.mu.quotaReleaseQueue = r.mu.quotaReleaseQueue[numReleases:]
+		r.store.proposalQuotaReleaseQueues.add(-int(numReleases))
 		r.mu.proposalQuotaBaseIndex += numReleases
+		r.mu.proposalQuotaBaseIndexAdvanced = now
+		r.mu.proposalQuotaStallRecorded = false
+	} else if !r.mu.proposalQuotaStallRecorded && r.mu.proposalQuota.Len() > 0 {
+		// Record a stall once, rather than on every tick it persists for. See
+		// QuotaStalled.
+		threshold := proposalQuotaStallThreshold.Get(&r.store.cfg.Settings.SV)
+		if stalled := r.proposalQuotaStalledForRLocked(now); threshold > 0 && stalled >= threshold {
+			r.mu.proposalQuotaStallRecorded = true
+			r.mu.proposalQuotaEvents.add(now, "stalled: no quota released for %s at base index %d "+
+				"while %d proposals are waiting", stalled, r.mu.proposalQuotaBaseIndex, r.mu.proposalQuota.Len())
+		}
 	}
+	// A range whose followers persistently can't keep up is saturated, so let
+	// the load-based splitter take that into account; see
+	// split.Decider.RecordQuotaPressure.
+	r.loadBasedSplitter.RecordQuotaPressure(now,
+		proposalQuotaLow(r.mu.proposalQuota.ApproximateQuota(), r.mu.proposalQuota.Capacity()))
+
 	// Assert the sanity of the base index and the queue. Queue entries should
 	// correspond to applied entries. It should not be possible for the base
 	// index and the not yet released applied entries to not equal the applied
 	// index.
 	releasableIndex := r.mu.proposalQuotaBaseIndex + kvpb.RaftIndex(len(r.mu.quotaReleaseQueue))
 	if releasableIndex != kvpb.RaftIndex(status.Applied) {
-		log.Fatalf(ctx, "proposalQuotaBaseIndex (%d) + quotaReleaseQueueLen (%d) = %d"+
+		err := errors.AssertionFailedf("proposalQuotaBaseIndex (%d) + quotaReleaseQueueLen (%d) = %d"+
 			" must equal the applied index (%d)",
 			r.mu.proposalQuotaBaseIndex, len(r.mu.quotaReleaseQueue), releasableIndex,
 			status.Applied)
+		if r.store.TestingKnobs().QuotaAssertionAsError {
+			log.Errorf(ctx, "%v", err)
+			r.mu.proposalQuotaAssertionErr = err
+		} else {
+			log.Fatalf(ctx, "%v", err)
+		}
 	}
 
 	// Tick the replicaFlowControlIntegration interface. This is as convenient a
@@ -285,3 +1494,116 @@ func (r *Replica) updateProposalQuotaRaftMuLocked(
 	// individual replicas, and whether they've been recently active.
 	r.mu.replicaFlowControlIntegration.onRaftTicked(ctx)
 }
+
+// ResetProposalQuotaRaftMuLocked re-initializes the proposal quota pool of a
+// leader replica as if it had just become the leader, without giving up
+// leadership: the pool is recreated at full capacity, the release queue is
+// cleared, and the base index is set to the applied index. It returns false if
+// the replica is not the leader, in which case it has no pool to reset.
+//
+// It is meant to recover a replica whose release queue has fallen out of sync
+// with the applied index (see ProposalQuotaInvariantViolation) before
+// updateProposalQuotaRaftMuLocked crashes the node on it, and for testing. As
+// on a leadership change, proposals waiting on the old pool proceed without
+// quota, and the quota held by proposals in flight is not accounted for.
+//
+// Replica.raftMu must be held, so that no entries are applied concurrently.
+func (r *Replica) ResetProposalQuotaRaftMuLocked(ctx context.Context) bool {
+	r.raftMu.AssertHeld()
+	now := r.Clock().PhysicalTime()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.mu.proposalQuota == nil || r.mu.internalRaftGroup == nil {
+		return false
+	}
+	status := r.mu.internalRaftGroup.BasicStatus()
+	log.Infof(ctx, "resetting proposal quota pool with %d entries pending release at base "+
+		"index %d, applied index %d", len(r.mu.quotaReleaseQueue), r.mu.proposalQuotaBaseIndex, status.Applied)
+	r.mu.proposalQuotaEvents.add(now, "reset: recreated quota pool of %d bytes at base index %d, "+
+		"dropping %d entries pending release at base index %d", r.store.cfg.RaftProposalQuota,
+		status.Applied, len(r.mu.quotaReleaseQueue), r.mu.proposalQuotaBaseIndex)
+	r.mu.proposalQuota.Close("proposal quota reset")
+	r.mu.proposalQuota.Release(r.mu.quotaReleaseQueue...)
+	r.store.proposalQuotaReleaseQueues.add(-len(r.mu.quotaReleaseQueue))
+	r.mu.quotaReleaseQueue = nil
+	r.mu.proposalQuota = quotapool.NewIntPool(
+		"raft proposal",
+		uint64(r.store.cfg.RaftProposalQuota),
+		logSlowRaftProposalQuotaAcquisition,
+		r.proposalQuotaGroupingOption(),
+		quotapool.WithAllocTracking(),
+	)
+	r.mu.proposalQuotaBaseIndex = kvpb.RaftIndex(status.Applied)
+	r.mu.proposalQuotaBaseIndexAdvanced = now
+	r.mu.proposalQuotaStallRecorded = false
+	r.mu.proposalQuotaWaitingSince = time.Time{}
+	return true
+}
+
+// ProposalQuotaInvariantViolation describes a leader replica whose proposal
+// quota release queue does not account for exactly the entries applied since
+// the quota base index, i.e. for which
+//
+//	proposalQuotaBaseIndex + len(quotaReleaseQueue) != appliedIndex
+//
+// which would cause updateProposalQuotaRaftMuLocked to crash the node.
+type ProposalQuotaInvariantViolation struct {
+	RangeID         roachpb.RangeID
+	BaseIndex       kvpb.RaftIndex
+	ReleaseQueueLen int
+	AppliedIndex    kvpb.RaftIndex
+}
+
+// Mismatch returns the number of entries by which the release queue is ahead
+// of (if positive) or behind (if negative) the applied index.
+func (v ProposalQuotaInvariantViolation) Mismatch() int64 {
+	return int64(v.BaseIndex) + int64(v.ReleaseQueueLen) - int64(v.AppliedIndex)
+}
+
+func (v ProposalQuotaInvariantViolation) String() string {
+	return fmt.Sprintf("r%d: proposalQuotaBaseIndex (%d) + quotaReleaseQueueLen (%d) != applied index (%d), off by %+d",
+		v.RangeID, v.BaseIndex, v.ReleaseQueueLen, v.AppliedIndex, v.Mismatch())
+}
+
+// checkProposalQuotaInvariant verifies the invariant asserted at the end of
+// updateProposalQuotaRaftMuLocked, returning the violation, if any. Unlike the
+// assertion, it does not crash the node. Replicas which are not the raft
+// leader have no release queue and are never in violation.
+func (r *Replica) checkProposalQuotaInvariant() (ProposalQuotaInvariantViolation, bool) {
+	// The release queue is appended to before the corresponding entries are
+	// applied, so the invariant only holds in between raft ready iterations.
+	r.raftMu.Lock()
+	defer r.raftMu.Unlock()
+	return r.checkProposalQuotaInvariantRaftMuLocked()
+}
+
+func (r *Replica) checkProposalQuotaInvariantRaftMuLocked() (ProposalQuotaInvariantViolation, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.mu.proposalQuota == nil || r.mu.internalRaftGroup == nil {
+		return ProposalQuotaInvariantViolation{}, false
+	}
+	v := ProposalQuotaInvariantViolation{
+		RangeID:         r.RangeID,
+		BaseIndex:       r.mu.proposalQuotaBaseIndex,
+		ReleaseQueueLen: len(r.mu.quotaReleaseQueue),
+		AppliedIndex:    kvpb.RaftIndex(r.raftBasicStatusRLocked().Applied),
+	}
+	return v, v.Mismatch() != 0
+}
+
+// CheckProposalQuotaInvariants walks all replicas on the store and returns
+// those leaders whose proposal quota state violates the invariant checked by
+// updateProposalQuotaRaftMuLocked. It is meant as an on-demand diagnostic.
+func (s *Store) CheckProposalQuotaInvariants() []ProposalQuotaInvariantViolation {
+	var violations []ProposalQuotaInvariantViolation
+	s.VisitReplicas(func(r *Replica) bool {
+		if v, violated := r.checkProposalQuotaInvariant(); violated {
+			violations = append(violations, v)
+		}
+		return true
+	})
+	return violations
+}
@@ -0,0 +1,196 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package kvserver
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/kv/kvpb"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/testutils/datapathutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/cockroachdb/datadriven"
+	"github.com/stretchr/testify/require"
+)
+
+// TestComputeProposalQuotaMinIndex exercises the decision of which followers
+// hold up the release of proposal quota, using a manual clock to move their
+// last communication with the leader in and out of the activity window.
+//
+// The commands are:
+//
+//	init applied=<index> base=<index> [lease-duration=<duration>]
+//	replica id=<id> match=<index> [type=non-voter] [paused]
+//	communicate id=<id>
+//	advance by=<duration>
+//	min-index
+func TestComputeProposalQuotaMinIndex(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	datadriven.Walk(t, datapathutils.TestDataPath(t, "proposal_quota_min_index"), func(t *testing.T, path string) {
+		ctx := context.Background()
+		st := cluster.MakeTestingClusterSettings()
+		var clock *timeutil.ManualTime
+		var applied, baseIndex kvpb.RaftIndex
+		var leaseDuration time.Duration
+		var lastUpdateTimes lastUpdateTimesMap
+		var followers []proposalQuotaFollower
+
+		datadriven.RunTest(t, path, func(t *testing.T, d *datadriven.TestData) string {
+			switch d.Cmd {
+			case "init":
+				clock = timeutil.NewManualTime(timeutil.Unix(0, 123))
+				var a, b uint64
+				d.ScanArgs(t, "applied", &a)
+				d.ScanArgs(t, "base", &b)
+				applied, baseIndex = kvpb.RaftIndex(a), kvpb.RaftIndex(b)
+				leaseDuration = 6 * time.Second
+				if d.HasArg("lease-duration") {
+					var s string
+					d.ScanArgs(t, "lease-duration", &s)
+					var err error
+					leaseDuration, err = time.ParseDuration(s)
+					require.NoError(t, err)
+				}
+				if d.HasArg("voter-window") {
+					var s string
+					d.ScanArgs(t, "voter-window", &s)
+					window, err := time.ParseDuration(s)
+					require.NoError(t, err)
+					proposalQuotaVoterActivityWindow.Override(ctx, &st.SV, window)
+				}
+				if d.HasArg("non-voter-window") {
+					var s string
+					d.ScanArgs(t, "non-voter-window", &s)
+					window, err := time.ParseDuration(s)
+					require.NoError(t, err)
+					proposalQuotaNonVoterActivityWindow.Override(ctx, &st.SV, window)
+				}
+				lastUpdateTimes = make(lastUpdateTimesMap)
+				followers = nil
+				return ""
+
+			case "replica":
+				var id int
+				var match uint64
+				d.ScanArgs(t, "id", &id)
+				d.ScanArgs(t, "match", &match)
+				f := proposalQuotaFollower{
+					desc: roachpb.ReplicaDescriptor{
+						NodeID:    roachpb.NodeID(id),
+						StoreID:   roachpb.StoreID(id),
+						ReplicaID: roachpb.ReplicaID(id),
+						Type:      roachpb.VOTER_FULL,
+					},
+					match:  kvpb.RaftIndex(match),
+					paused: d.HasArg("paused"),
+				}
+				if d.HasArg("type") {
+					var typ string
+					d.ScanArgs(t, "type", &typ)
+					require.Equal(t, "non-voter", typ)
+					f.desc.Type = roachpb.NON_VOTER
+				}
+				for i := range followers {
+					if followers[i].desc.ReplicaID == f.desc.ReplicaID {
+						followers[i] = f
+						return ""
+					}
+				}
+				followers = append(followers, f)
+				return ""
+
+			case "communicate":
+				var id int
+				d.ScanArgs(t, "id", &id)
+				lastUpdateTimes.update(roachpb.ReplicaID(id), clock.Now())
+				return ""
+
+			case "advance":
+				var s string
+				d.ScanArgs(t, "by", &s)
+				by, err := time.ParseDuration(s)
+				require.NoError(t, err)
+				clock.Advance(by)
+				return ""
+
+			case "min-index":
+				minIndex, slowest := computeProposalQuotaMinIndex(
+					&st.SV, leaseDuration, clock.Now(), applied, baseIndex, lastUpdateTimes, followers)
+				released := "withheld"
+				if minIndex > baseIndex {
+					released = fmt.Sprintf("released %d entries", minIndex-baseIndex)
+				}
+				return fmt.Sprintf("min-index=%d slowest=%d: %s", minIndex, slowest, released)
+
+			default:
+				t.Fatalf("unknown command: %s", d.Cmd)
+				return ""
+			}
+		})
+	})
+}
+
+// TestProposalQuotaReleaseQueuesStoreCap simulates many ranges on a store
+// stalling at once, none of whose release queues is long enough on its own to
+// be held up by MaxProposalQuotaReleaseQueueLength, and checks that the store's
+// cap backpressures them until enough of them have caught up.
+func TestProposalQuotaReleaseQueuesStoreCap(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	const numRanges = 100
+	const entriesPerRange = 50
+	const maxBytes = numRanges * entriesPerRange * proposalQuotaReleaseQueueEntryBytes / 2
+
+	var q proposalQuotaReleaseQueues
+	queueLens := make([]int64, numRanges)
+	numBlocked := func() int {
+		var n int
+		for _, l := range queueLens {
+			if q.blocks(l, maxBytes) {
+				n++
+			}
+		}
+		return n
+	}
+
+	// The followers of all ranges stop acknowledging entries, and their queues
+	// grow until the store exceeds its cap.
+	for i := 0; i < entriesPerRange; i++ {
+		for r := range queueLens {
+			queueLens[r]++
+			q.add(1)
+		}
+		if q.bytes() <= maxBytes {
+			require.Zero(t, numBlocked())
+		}
+	}
+	require.Equal(t, int64(numRanges*entriesPerRange*proposalQuotaReleaseQueueEntryBytes), q.bytes())
+	require.Equal(t, numRanges, numBlocked())
+	// Without a cap, nothing is blocked.
+	require.False(t, q.blocks(entriesPerRange, 0))
+	// A range whose queue is empty doesn't contribute, and is let through.
+	require.False(t, q.blocks(0, maxBytes))
+
+	// Once enough of the ranges have caught up, the remaining ones are let
+	// through as well.
+	for r := 0; r < numRanges/2; r++ {
+		q.add(-int(queueLens[r]))
+		queueLens[r] = 0
+	}
+	require.Equal(t, int64(maxBytes), q.bytes())
+	require.Zero(t, numBlocked())
+}
@@ -278,24 +278,31 @@ func (r *Replica) evalAndPropose(
 	// closed timestamp tracker is acquired. This is better anyway; right now many
 	// commands can evaluate but then be blocked on quota, which has worse memory
 	// behavior.
-	quotaSize := uint64(proposal.command.Size())
-	if maxSize := uint64(kvserverbase.MaxCommandSize.Get(&r.store.cfg.Settings.SV)); quotaSize > maxSize {
+	commandSize := proposal.command.Size()
+	if maxSize := uint64(kvserverbase.MaxCommandSize.Get(&r.store.cfg.Settings.SV)); uint64(commandSize) > maxSize {
 		return nil, nil, "", nil, kvpb.NewError(errors.Errorf(
-			"command is too large: %d bytes (max: %d)", quotaSize, maxSize,
+			"command is too large: %d bytes (max: %d)", commandSize, maxSize,
 		))
 	}
-	log.VEventf(proposal.ctx, 2, "acquiring proposal quota (%d bytes)", quotaSize)
+	log.VEventf(proposal.ctx, 2, "acquiring proposal quota (%d bytes)", commandSize)
 	var err error
-	proposal.quotaAlloc, err = r.maybeAcquireProposalQuota(ctx, ba, quotaSize)
+	proposal.quotaAlloc, proposal.tenantQuotaAlloc, err = r.maybeAcquireProposalQuota(ctx, ba, commandSize)
 	if err != nil {
 		return nil, nil, "", nil, kvpb.NewError(err)
 	}
+	if proposal.quotaAlloc != nil {
+		if pct, ok := secondaryIndexQuotaPercent(ba); ok {
+			r.store.metrics.RaftProposalQuotaSecondaryIndexPercent.RecordValue(pct)
+		}
+		r.maybeSetBackpressureHint(proposal.Local.Reply)
+	}
 	// Make sure we clean up the proposal if we fail to insert it into the
 	// proposal buffer successfully. This ensures that we always release any
-	// quota that we acquire.
+	// quota that we acquire. Nothing between the acquisition above and here
+	// may return early.
 	defer func() {
 		if pErr != nil {
-			proposal.releaseQuota()
+			r.refundProposalQuota(proposal)
 		}
 	}()
 
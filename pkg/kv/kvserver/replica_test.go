@@ -18,6 +18,7 @@ import (
 	"math/rand"
 	"reflect"
 	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -64,6 +65,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
 	"github.com/cockroachdb/cockroach/pkg/util/admission/admissionpb"
 	"github.com/cockroachdb/cockroach/pkg/util/ctxgroup"
+	"github.com/cockroachdb/cockroach/pkg/util/encoding"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
@@ -7205,6 +7207,30 @@ func TestReplicaDestroy(t *testing.T) {
 	require.Equal(t, expectedKeys, actualKeys)
 }
 
+// startQuotaTestContext starts a testContext with the given store config, or
+// the default one if nil, and flushes a write all the way through the Raft
+// proposal pipeline to ensure that the replica becomes the Raft leader and
+// sets up its quota pool, which is returned.
+func startQuotaTestContext(
+	ctx context.Context, t *testing.T, stopper *stop.Stopper, tsc *StoreConfig,
+) (*testContext, *quotapool.IntPool) {
+	tc := &testContext{}
+	if tsc == nil {
+		tc.Start(ctx, t, stopper)
+	} else {
+		tc.StartWithStoreConfig(ctx, t, stopper, *tsc)
+	}
+
+	_, pErr := tc.SendWrapped(incrementArgs([]byte("a"), 1))
+	require.Nil(t, pErr)
+
+	tc.repl.mu.RLock()
+	defer tc.repl.mu.RUnlock()
+	quotaPool := tc.repl.mu.proposalQuota
+	require.NotNil(t, quotaPool)
+	return tc, quotaPool
+}
+
 // TestQuotaPoolDisabled tests that the no quota is acquired by proposals when
 // the quota pool enablement setting is disabled.
 func TestQuotaPoolDisabled(t *testing.T) {
@@ -7212,7 +7238,6 @@ func TestQuotaPoolDisabled(t *testing.T) {
 	defer log.Scope(t).Close(t)
 	ctx := context.Background()
 
-	tc := testContext{}
 	stopper := stop.NewStopper()
 	defer stopper.Stop(ctx)
 
@@ -7223,23 +7248,316 @@ func TestQuotaPoolDisabled(t *testing.T) {
 		require.Nil(t, args.QuotaAlloc)
 		return nil
 	}
-	tc.StartWithStoreConfig(ctx, t, stopper, tsc)
-
-	// Flush a write all the way through the Raft proposal pipeline to ensure
-	// that the replica becomes the Raft leader and sets up its quota pool.
-	iArgs := incrementArgs([]byte("a"), 1)
-	_, pErr := tc.SendWrapped(iArgs)
-	require.Nil(t, pErr)
+	tc, _ := startQuotaTestContext(ctx, t, stopper, &tsc)
 
 	initialQuota := tc.repl.QuotaAvailable()
 	for i := 0; i < 10; i++ {
 		pArg := putArgs(roachpb.Key("a"), make([]byte, 1<<10))
-		_, pErr = tc.SendWrapped(&pArg)
+		_, pErr := tc.SendWrapped(&pArg)
 		require.Nil(t, pErr)
 	}
 	require.Equal(t, initialQuota, tc.repl.QuotaAvailable())
 }
 
+// TestProposalQuotaRefundedOnRejection verifies that the quota acquired by a
+// proposal which is rejected before it is handed to raft is returned to the
+// pool right away, and counted as a refund.
+func TestProposalQuotaRefundedOnRejection(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+	ctx := context.Background()
+
+	stopper := stop.NewStopper()
+	defer stopper.Stop(ctx)
+
+	var reject atomic.Bool
+	tsc := TestStoreConfig(nil /* clock */)
+	tsc.TestingKnobs.TestingProposalFilter = func(args kvserverbase.ProposalFilterArgs) *kvpb.Error {
+		if reject.Load() && args.QuotaAlloc != nil && args.Req.IsSingleRequest() &&
+			args.Req.Requests[0].GetPut() != nil {
+			return kvpb.NewErrorf("injected rejection after quota acquisition")
+		}
+		return nil
+	}
+	tc, quotaPool := startQuotaTestContext(ctx, t, stopper, &tsc)
+
+	waitForFullQuota := func() {
+		testutils.SucceedsSoon(t, func() error {
+			if available := tc.repl.QuotaAvailable(); available != quotaPool.Capacity() {
+				return errors.Errorf("expected %d quota available, found %d", quotaPool.Capacity(), available)
+			}
+			return nil
+		})
+	}
+	waitForFullQuota()
+
+	refunds := tc.store.metrics.RaftProposalQuotaRefunds.Count()
+	reject.Store(true)
+	const numRejected = 10
+	for i := 0; i < numRejected; i++ {
+		pArg := putArgs(roachpb.Key("a"), make([]byte, 1<<10))
+		_, pErr := tc.SendWrapped(&pArg)
+		require.ErrorContains(t, pErr.GoError(), "injected rejection")
+	}
+	require.Equal(t, refunds+numRejected, tc.store.metrics.RaftProposalQuotaRefunds.Count())
+	// None of the rejected proposals' quota leaked.
+	waitForFullQuota()
+
+	// Proposals which apply don't have their quota refunded, but released
+	// through the quotaReleaseQueue.
+	reject.Store(false)
+	_, pErr := tc.SendWrapped(incrementArgs([]byte("a"), 1))
+	require.Nil(t, pErr)
+	waitForFullQuota()
+	require.Equal(t, refunds+numRejected, tc.store.metrics.RaftProposalQuotaRefunds.Count())
+}
+
+// TestProposalQuotaHighPriBypassSystemTenantOnly tests that high priority
+// KV-internal batches only bypass proposal quota on ranges of the system
+// tenant, as the admission header can be set by any client.
+func TestProposalQuotaHighPriBypassSystemTenantOnly(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+	ctx := context.Background()
+
+	stopper := stop.NewStopper()
+	defer stopper.Stop(ctx)
+	tc, _ := startQuotaTestContext(ctx, t, stopper, nil /* tsc */)
+
+	put := putArgs(roachpb.Key("a"), []byte("v"))
+	ba := &kvpb.BatchRequest{AdmissionHeader: kvpb.AdmissionHeader{
+		Priority: int32(admissionpb.HighPri),
+		Source:   kvpb.AdmissionHeader_OTHER,
+	}}
+	ba.Add(&put)
+
+	bypassed := tc.store.metrics.RaftProposalQuotaBypassed.Count()
+	alloc, tenantAlloc, err := tc.repl.maybeAcquireProposalQuota(ctx, ba, 100 /* commandSize */)
+	require.NoError(t, err)
+	require.Nil(t, alloc)
+	require.Nil(t, tenantAlloc)
+	require.Equal(t, bypassed+1, tc.store.metrics.RaftProposalQuotaBypassed.Count())
+
+	// The same batch proposed to a secondary tenant's range has to acquire
+	// quota.
+	tc.repl.mu.Lock()
+	systemTenantID := tc.repl.mu.tenantID
+	tc.repl.mu.tenantID = roachpb.MustMakeTenantID(10)
+	tc.repl.mu.Unlock()
+	defer func() {
+		tc.repl.mu.Lock()
+		tc.repl.mu.tenantID = systemTenantID
+		tc.repl.mu.Unlock()
+	}()
+	alloc, tenantAlloc, err = tc.repl.maybeAcquireProposalQuota(ctx, ba, 100 /* commandSize */)
+	require.NoError(t, err)
+	require.NotNil(t, alloc)
+	require.Equal(t, uint64(100), alloc.Acquired())
+	alloc.Release()
+	if tenantAlloc != nil {
+		tenantAlloc.Release()
+	}
+	require.Equal(t, bypassed+1, tc.store.metrics.RaftProposalQuotaBypassed.Count())
+}
+
+// TestProposalQuotaWakeupMetrics tests that proposals to a quiescent range
+// are only counted as waking it up when they acquire proposal quota.
+func TestProposalQuotaWakeupMetrics(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+	ctx := context.Background()
+
+	stopper := stop.NewStopper()
+	defer stopper.Stop(ctx)
+	tc, _ := startQuotaTestContext(ctx, t, stopper, nil /* tsc */)
+
+	tc.repl.mu.Lock()
+	tc.repl.mu.quiescent = true
+	tc.repl.mu.Unlock()
+	defer func() {
+		tc.repl.mu.Lock()
+		tc.repl.mu.quiescent = false
+		tc.repl.mu.Unlock()
+	}()
+
+	metrics := tc.store.metrics
+	proposals := metrics.RaftProposalQuotaWakeupProposals.Count()
+	wakeupBytes := metrics.RaftProposalQuotaWakeupBytes.Count()
+	acquire := func(ba *kvpb.BatchRequest) *quotapool.IntAlloc {
+		alloc, tenantAlloc, err := tc.repl.maybeAcquireProposalQuota(ctx, ba, 100 /* commandSize */)
+		require.NoError(t, err)
+		if tenantAlloc != nil {
+			tenantAlloc.Release()
+		}
+		return alloc
+	}
+
+	put := putArgs(roachpb.Key("a"), []byte("v"))
+	ba := &kvpb.BatchRequest{}
+	ba.Add(&put)
+	alloc := acquire(ba)
+	require.NotNil(t, alloc)
+	wakeupBytes += int64(alloc.Acquired())
+	alloc.Release()
+	require.Equal(t, proposals+1, metrics.RaftProposalQuotaWakeupProposals.Count())
+	require.Equal(t, wakeupBytes, metrics.RaftProposalQuotaWakeupBytes.Count())
+
+	// Proposals which bypass the quota pool are not counted.
+	highPri := &kvpb.BatchRequest{AdmissionHeader: kvpb.AdmissionHeader{
+		Priority: int32(admissionpb.HighPri),
+		Source:   kvpb.AdmissionHeader_OTHER,
+	}}
+	highPri.Add(&put)
+	require.Nil(t, acquire(highPri))
+	require.Equal(t, proposals+1, metrics.RaftProposalQuotaWakeupProposals.Count())
+
+	// Nor are proposals when proposal quota is disabled.
+	enableRaftProposalQuota.Override(ctx, &tc.store.cfg.Settings.SV, false)
+	defer enableRaftProposalQuota.Override(ctx, &tc.store.cfg.Settings.SV, true)
+	require.Nil(t, acquire(ba))
+	require.Equal(t, proposals+1, metrics.RaftProposalQuotaWakeupProposals.Count())
+	require.Equal(t, wakeupBytes, metrics.RaftProposalQuotaWakeupBytes.Count())
+}
+
+// TestQuotaPoolForceEnabled tests that proposals acquire quota when the quota
+// pool enablement setting is disabled but the range's span config forces them
+// to, and only then.
+func TestQuotaPoolForceEnabled(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+	ctx := context.Background()
+
+	testutils.RunTrueAndFalse(t, "forced", func(t *testing.T, forced bool) {
+		stopper := stop.NewStopper()
+		defer stopper.Stop(ctx)
+
+		var allocs atomic.Int64
+		tsc := TestStoreConfig(nil /* clock */)
+		enableRaftProposalQuota.Override(ctx, &tsc.Settings.SV, false)
+		tsc.TestingKnobs.TestingProposalFilter = func(args kvserverbase.ProposalFilterArgs) *kvpb.Error {
+			if args.QuotaAlloc != nil {
+				allocs.Add(1)
+			}
+			return nil
+		}
+		tc, _ := startQuotaTestContext(ctx, t, stopper, &tsc)
+
+		desc, conf := tc.repl.DescAndSpanConfig()
+		conf.ForceProposalQuota = forced
+		tc.repl.SetSpanConfig(*conf, desc.RSpan().AsRawSpanWithNoLocals())
+
+		allocs.Store(0)
+		for i := 0; i < 10; i++ {
+			pArg := putArgs(roachpb.Key("a"), make([]byte, 1<<10))
+			_, pErr := tc.SendWrapped(&pArg)
+			require.Nil(t, pErr)
+		}
+		if forced {
+			require.Equal(t, int64(10), allocs.Load())
+		} else {
+			require.Zero(t, allocs.Load())
+		}
+	})
+}
+
+// TestResetProposalQuota tests that resetting the proposal quota pool of a
+// leader heals a release queue which is out of sync with the applied index,
+// and that writes proceed and release their quota afterwards.
+func TestResetProposalQuota(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+	ctx := context.Background()
+
+	stopper := stop.NewStopper()
+	defer stopper.Stop(ctx)
+	tc, _ := startQuotaTestContext(ctx, t, stopper, nil /* tsc */)
+
+	func() {
+		// Hold raftMu throughout, so that the corrupted queue isn't checked by
+		// updateProposalQuotaRaftMuLocked, which would crash.
+		tc.repl.raftMu.Lock()
+		defer tc.repl.raftMu.Unlock()
+
+		// Corrupt the release queue with an entry which does not correspond to
+		// an applied entry.
+		tc.repl.mu.Lock()
+		alloc, err := tc.repl.mu.proposalQuota.TryAcquire(ctx, 1)
+		require.NoError(t, err)
+		tc.repl.mu.quotaReleaseQueue = append(tc.repl.mu.quotaReleaseQueue, alloc)
+		tc.repl.mu.Unlock()
+		v, violated := tc.repl.checkProposalQuotaInvariantRaftMuLocked()
+		require.True(t, violated)
+		require.Equal(t, int64(1), v.Mismatch())
+
+		require.True(t, tc.repl.ResetProposalQuotaRaftMuLocked(ctx))
+		_, violated = tc.repl.checkProposalQuotaInvariantRaftMuLocked()
+		require.False(t, violated)
+	}()
+	require.Equal(t, uint64(tc.store.cfg.RaftProposalQuota), tc.repl.QuotaAvailable())
+
+	for i := 0; i < 10; i++ {
+		pArg := putArgs(roachpb.Key("a"), make([]byte, 1<<10))
+		_, pErr := tc.SendWrapped(&pArg)
+		require.Nil(t, pErr)
+	}
+	testutils.SucceedsSoon(t, func() error {
+		if _, violated := tc.repl.checkProposalQuotaInvariant(); violated {
+			return errors.New("proposal quota invariant violated")
+		}
+		if q := tc.repl.QuotaAvailable(); q != uint64(tc.store.cfg.RaftProposalQuota) {
+			return errors.Newf("%d bytes of quota not yet released", uint64(tc.store.cfg.RaftProposalQuota)-q)
+		}
+		return nil
+	})
+}
+
+// TestQuotaPoolBypassedBySplits tests that a range can be split while its
+// proposal quota pool is exhausted, as it would be by user writes waiting for
+// a slow follower, and that the proposals of user writes still need quota.
+func TestQuotaPoolBypassedBySplits(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+	ctx := context.Background()
+
+	s, _, kvDB := serverutils.StartServer(t, base.TestServerArgs{})
+	defer s.Stopper().Stop(ctx)
+	store, err := s.GetStores().(*Stores).GetStore(s.GetFirstStoreID())
+	require.NoError(t, err)
+
+	// Flush a write all the way through the Raft proposal pipeline to ensure
+	// that the replica becomes the Raft leader and sets up its quota pool.
+	key := roachpb.Key("b")
+	require.NoError(t, kvDB.Put(ctx, key, "v"))
+	repl := store.LookupReplica(roachpb.RKey(key))
+	require.NotNil(t, repl)
+	var pool *quotapool.IntPool
+	testutils.SucceedsSoon(t, func() error {
+		repl.mu.RLock()
+		defer repl.mu.RUnlock()
+		if pool = repl.mu.proposalQuota; pool == nil {
+			return errors.New("quota pool not set up")
+		}
+		return nil
+	})
+
+	// Exhaust the quota pool.
+	alloc, err := pool.Acquire(ctx, pool.Capacity())
+	require.NoError(t, err)
+	defer alloc.Release()
+	_, err = pool.TryAcquire(ctx, 1)
+	require.ErrorIs(t, err, quotapool.ErrNotEnoughQuota)
+	userWrite := &kvpb.BatchRequest{}
+	pArgs := putArgs(key, []byte("v"))
+	userWrite.Add(&pArgs)
+	require.False(t, isStructuralProposal(userWrite))
+
+	before := store.Metrics().RaftProposalQuotaStructuralBypassed.Count()
+	splitCtx, cancel := context.WithTimeout(ctx, testutils.DefaultSucceedsSoonDuration)
+	defer cancel()
+	require.NoError(t, kvDB.AdminSplit(splitCtx, key.Next(), hlc.MaxTimestamp /* expirationTime */))
+	require.Greater(t, store.Metrics().RaftProposalQuotaStructuralBypassed.Count(), before)
+}
+
 // TestQuotaPoolReleasedOnFailedProposal tests that the quota acquired by
 // proposals is released back into the quota pool if the proposal fails before
 // being submitted to Raft.
@@ -7247,7 +7565,6 @@ func TestQuotaPoolReleasedOnFailedProposal(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	defer log.Scope(t).Close(t)
 	ctx := context.Background()
-	tc := testContext{}
 	stopper := stop.NewStopper()
 	defer stopper.Stop(ctx)
 
@@ -7255,6 +7572,7 @@ func TestQuotaPoolReleasedOnFailedProposal(t *testing.T) {
 	var minQuotaSize uint64
 	propErr := errors.New("proposal error")
 
+	var tc *testContext
 	tsc := TestStoreConfig(nil /* clock */)
 	tsc.TestingKnobs.TestingProposalFilter = func(args kvserverbase.ProposalFilterArgs) *kvpb.Error {
 		if v := args.Ctx.Value(magicKey{}); v != nil {
@@ -7263,14 +7581,7 @@ func TestQuotaPoolReleasedOnFailedProposal(t *testing.T) {
 		}
 		return nil
 	}
-	tc.StartWithStoreConfig(ctx, t, stopper, tsc)
-
-	// Flush a write all the way through the Raft proposal pipeline to ensure
-	// that the replica becomes the Raft leader and sets up its quota pool.
-	iArgs := incrementArgs([]byte("a"), 1)
-	if _, pErr := tc.SendWrapped(iArgs); pErr != nil {
-		t.Fatal(pErr)
-	}
+	tc, _ = startQuotaTestContext(ctx, t, stopper, &tsc)
 
 	ba := &kvpb.BatchRequest{}
 	pArg := putArgs(roachpb.Key("a"), make([]byte, 1<<10))
@@ -7322,6 +7633,368 @@ func TestQuotaPoolAccessOnDestroyedReplica(t *testing.T) {
 	}
 }
 
+// TestQuotaPoolReleaseQueueUnderflow verifies that an accounting bug which
+// has more proposal quota to be released than is tracked by the release queue
+// results in the descriptive fatal error, not a slice bounds panic.
+func TestQuotaPoolReleaseQueueUnderflow(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	var exitStatus exit.Code
+	log.SetExitFunc(true /* hideStack */, func(i exit.Code) {
+		exitStatus = i
+	})
+	defer log.ResetExitFunc()
+
+	ctx := context.Background()
+	stopper := stop.NewStopper()
+	defer stopper.Stop(ctx)
+	tc, _ := startQuotaTestContext(ctx, t, stopper, nil /* tsc */)
+
+	repl := tc.repl
+	repl.raftMu.Lock()
+	defer repl.raftMu.Unlock()
+
+	// Inject the inconsistency: pretend the release queue is missing entries
+	// for some of the commands applied since the base index.
+	repl.mu.Lock()
+	require.NotNil(t, repl.mu.proposalQuota)
+	leaderID := repl.mu.leaderID
+	repl.mu.proposalQuotaBaseIndex -= 5
+	repl.mu.Unlock()
+
+	require.NotPanics(t, func() {
+		repl.updateProposalQuotaRaftMuLocked(ctx, leaderID)
+	})
+	require.Equal(t, exit.FatalError(), exitStatus)
+
+	// Restore the invariant before the raft scheduler gets to the replica.
+	repl.mu.Lock()
+	defer repl.mu.Unlock()
+	repl.mu.proposalQuotaBaseIndex = kvpb.RaftIndex(repl.mu.internalRaftGroup.BasicStatus().Applied)
+	repl.mu.quotaReleaseQueue = nil
+}
+
+// TestQuotaPoolAssertionAsError verifies that with QuotaAssertionAsError set,
+// a release queue which has fallen out of sync with the applied index is
+// detected and recorded on the replica rather than crashing the node.
+func TestQuotaPoolAssertionAsError(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	stopper := stop.NewStopper()
+	defer stopper.Stop(ctx)
+	cfg := TestStoreConfig(nil)
+	cfg.TestingKnobs.QuotaAssertionAsError = true
+	tc, _ := startQuotaTestContext(ctx, t, stopper, &cfg)
+
+	repl := tc.repl
+	repl.raftMu.Lock()
+	defer repl.raftMu.Unlock()
+
+	repl.mu.Lock()
+	require.NoError(t, repl.mu.proposalQuotaAssertionErr)
+	leaderID := repl.mu.leaderID
+	repl.mu.proposalQuotaBaseIndex -= 5
+	repl.mu.Unlock()
+
+	v, violated := repl.checkProposalQuotaInvariantRaftMuLocked()
+	require.True(t, violated)
+	require.Equal(t, int64(-5), v.Mismatch())
+
+	repl.updateProposalQuotaRaftMuLocked(ctx, leaderID)
+	repl.mu.Lock()
+	err := repl.mu.proposalQuotaAssertionErr
+	repl.mu.Unlock()
+	require.True(t, errors.IsAssertionFailure(err))
+	require.ErrorContains(t, err, "must equal the applied index")
+
+	// Restore the invariant before the raft scheduler gets to the replica.
+	repl.mu.Lock()
+	defer repl.mu.Unlock()
+	repl.mu.proposalQuotaBaseIndex = kvpb.RaftIndex(repl.mu.internalRaftGroup.BasicStatus().Applied)
+	repl.mu.quotaReleaseQueue = nil
+	repl.mu.proposalQuotaAssertionErr = nil
+}
+
+// TestStoreCheckProposalQuotaInvariants verifies that the store-level check
+// reports exactly the leader whose release queue is out of sync with its
+// applied index, without crashing the node.
+func TestStoreCheckProposalQuotaInvariants(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	stopper := stop.NewStopper()
+	defer stopper.Stop(ctx)
+	// The raft scheduler may process the replica while its release queue is
+	// corrupted, which must not crash the node.
+	cfg := TestStoreConfig(nil)
+	cfg.TestingKnobs.QuotaAssertionAsError = true
+	tc, _ := startQuotaTestContext(ctx, t, stopper, &cfg)
+	require.Empty(t, tc.store.CheckProposalQuotaInvariants())
+
+	// Corrupt the release queue with two entries which do not correspond to
+	// applied entries. Releasing quota from the queue advances the base index
+	// by as many entries as it pops, so the mismatch persists.
+	repl := tc.repl
+	repl.raftMu.Lock()
+	repl.mu.Lock()
+	for i := 0; i < 2; i++ {
+		alloc, err := repl.mu.proposalQuota.TryAcquire(ctx, 1)
+		require.NoError(t, err)
+		repl.mu.quotaReleaseQueue = append(repl.mu.quotaReleaseQueue, alloc)
+		repl.store.proposalQuotaReleaseQueues.add(1)
+	}
+	repl.mu.Unlock()
+	repl.raftMu.Unlock()
+
+	violations := tc.store.CheckProposalQuotaInvariants()
+	require.Len(t, violations, 1)
+	require.Equal(t, repl.RangeID, violations[0].RangeID)
+	require.Equal(t, int64(2), violations[0].Mismatch())
+
+	// Heal the release queue before the raft scheduler gets to the replica.
+	repl.raftMu.Lock()
+	defer repl.raftMu.Unlock()
+	require.True(t, repl.ResetProposalQuotaRaftMuLocked(ctx))
+	_, violated := repl.checkProposalQuotaInvariantRaftMuLocked()
+	require.False(t, violated)
+}
+
+// TestQuotaPoolAcquireBlockingMetrics verifies that proposal quota
+// acquisitions are counted as blocked only when they had to wait for quota to
+// be released.
+func TestQuotaPoolAcquireBlockingMetrics(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+	ctx := context.Background()
+	stopper := stop.NewStopper()
+	defer stopper.Stop(ctx)
+	tc, quotaPool := startQuotaTestContext(ctx, t, stopper, nil /* tsc */)
+
+	iArgs := incrementArgs([]byte("a"), 1)
+
+	metrics := tc.store.metrics
+	nonBlocking := metrics.RaftProposalQuotaAcquireNonBlocking.Count()
+	blocked := metrics.RaftProposalQuotaAcquireBlocked.Count()
+	_, pErr := tc.SendWrapped(iArgs)
+	require.Nil(t, pErr)
+	require.Equal(t, nonBlocking+1, metrics.RaftProposalQuotaAcquireNonBlocking.Count())
+	require.Equal(t, blocked, metrics.RaftProposalQuotaAcquireBlocked.Count())
+
+	// Take all of the available quota so that the next proposal has to wait.
+	alloc, err := quotaPool.Acquire(ctx, quotaPool.Capacity())
+	require.NoError(t, err)
+
+	errCh := make(chan *kvpb.Error, 1)
+	go func() {
+		_, pErr := tc.SendWrapped(iArgs)
+		errCh <- pErr
+	}()
+	testutils.SucceedsSoon(t, func() error {
+		if n := metrics.RaftProposalQuotaAcquireBlocked.Count(); n != blocked+1 {
+			return errors.Errorf("expected %d blocked acquisitions, found %d", blocked+1, n)
+		}
+		return nil
+	})
+	alloc.Release()
+	require.Nil(t, <-errCh)
+	require.Equal(t, nonBlocking+1, metrics.RaftProposalQuotaAcquireNonBlocking.Count())
+}
+
+// TestProposalQuotaMaxWaiters verifies that a write fails instead of waiting
+// for proposal quota behind at least Header.ProposalQuotaMaxWaiters other
+// proposals.
+func TestProposalQuotaMaxWaiters(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+	ctx := context.Background()
+	stopper := stop.NewStopper()
+	defer stopper.Stop(ctx)
+	tc, quotaPool := startQuotaTestContext(ctx, t, stopper, nil /* tsc */)
+
+	iArgs := incrementArgs([]byte("a"), 1)
+
+	// Take all of the available quota so that the next proposals have to wait.
+	alloc, err := quotaPool.Acquire(ctx, quotaPool.Capacity())
+	require.NoError(t, err)
+
+	// The first write has nobody ahead of it, so it waits.
+	h := kvpb.Header{ProposalQuotaMaxWaiters: 1}
+	errCh := make(chan *kvpb.Error, 1)
+	go func() {
+		_, pErr := tc.SendWrappedWith(h, iArgs)
+		errCh <- pErr
+	}()
+	testutils.SucceedsSoon(t, func() error {
+		if n := quotaPool.Len(); n != 1 {
+			return errors.Errorf("expected 1 waiter, found %d", n)
+		}
+		return nil
+	})
+
+	// The second one would have to wait behind the first.
+	_, pErr := tc.SendWrappedWith(h, incrementArgs([]byte("b"), 1))
+	require.NotNil(t, pErr)
+	require.True(t, errors.Is(pErr.GoError(), kvserverbase.ErrProposalQuotaQueueTooDeep), "%v", pErr)
+
+	alloc.Release()
+	require.Nil(t, <-errCh)
+}
+
+// TestWaitForProposalQuotaReleaseQueueStops verifies that a proposal held up
+// by the length of the quota release queue stops waiting if the replica is
+// destroyed or the store quiesces.
+func TestWaitForProposalQuotaReleaseQueueStops(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+	ctx := context.Background()
+	stopper := stop.NewStopper()
+	defer stopper.Stop(ctx)
+	tc, _ := startQuotaTestContext(ctx, t, stopper, nil /* tsc */)
+
+	// Hold raftMu so that the queue, which is made to exceed the cap, isn't
+	// released or checked against the applied index.
+	MaxProposalQuotaReleaseQueueLength.Override(ctx, &tc.store.cfg.Settings.SV, 1)
+	tc.repl.raftMu.Lock()
+	tc.repl.mu.Lock()
+	queue := tc.repl.mu.quotaReleaseQueue
+	tc.repl.mu.quotaReleaseQueue = append(queue[:len(queue):len(queue)], nil, nil)
+	tc.repl.mu.Unlock()
+	var restoreOnce sync.Once
+	restore := func() {
+		restoreOnce.Do(func() {
+			tc.repl.mu.Lock()
+			tc.repl.mu.quotaReleaseQueue = queue
+			tc.repl.mu.Unlock()
+			tc.repl.raftMu.Unlock()
+		})
+	}
+	defer restore()
+
+	waitCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, tc.repl.waitForProposalQuotaReleaseQueue(waitCtx), context.DeadlineExceeded)
+
+	destroyErr := errors.New("replica removed")
+	tc.repl.mu.Lock()
+	tc.repl.mu.destroyStatus.Set(destroyErr, destroyReasonRemoved)
+	tc.repl.mu.Unlock()
+	require.ErrorIs(t, tc.repl.waitForProposalQuotaReleaseQueue(ctx), destroyErr)
+	tc.repl.mu.Lock()
+	tc.repl.mu.destroyStatus.Set(nil, destroyReasonAlive)
+	tc.repl.mu.Unlock()
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- tc.repl.waitForProposalQuotaReleaseQueue(ctx) }()
+	quiesced := make(chan struct{})
+	go func() {
+		defer close(quiesced)
+		stopper.Quiesce(ctx)
+	}()
+	require.True(t, errors.HasType(<-errCh, (*kvpb.NodeUnavailableError)(nil)))
+	restore()
+	<-quiesced
+}
+
+// TestReplicaQuotaStalled verifies that a leader is reported as quota
+// stalled, including in its store's capacity, only once proposals have been
+// waiting for quota that is not being released.
+func TestReplicaQuotaStalled(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+	ctx := context.Background()
+	stopper := stop.NewStopper()
+	defer stopper.Stop(ctx)
+	tc, quotaPool := startQuotaTestContext(ctx, t, stopper, nil /* tsc */)
+
+	const threshold = time.Minute
+	proposalQuotaStallThreshold.Override(ctx, &tc.store.cfg.Settings.SV, threshold)
+	require.False(t, tc.repl.QuotaStalled(tc.Clock().PhysicalTime().Add(threshold)))
+
+	// Take all of the available quota, and wait for a proposal to queue up
+	// behind it. The quota was last released well over the threshold ago, as
+	// on a range taking few writes.
+	now := tc.Clock().PhysicalTime()
+	tc.repl.mu.Lock()
+	tc.repl.mu.proposalQuotaBaseIndexAdvanced = now.Add(-2 * threshold)
+	tc.repl.mu.Unlock()
+	alloc, err := quotaPool.Acquire(ctx, quotaPool.Capacity())
+	require.NoError(t, err)
+	stalledAt := now.Add(threshold)
+	errCh := make(chan *kvpb.Error, 1)
+	go func() {
+		_, pErr := tc.SendWrapped(incrementArgs([]byte("b"), 1))
+		errCh <- pErr
+	}()
+	testutils.SucceedsSoon(t, func() error {
+		if quotaPool.Len() == 0 {
+			return errors.New("proposal is not waiting yet")
+		}
+		return nil
+	})
+
+	// The proposal only just started waiting, so the leader is not stalled
+	// yet, no matter how long ago quota was last released.
+	require.False(t, tc.repl.QuotaStalled(now))
+	require.False(t, tc.repl.QuotaStalled(stalledAt.Add(-time.Nanosecond)))
+	require.True(t, tc.repl.QuotaStalled(stalledAt))
+
+	proposalQuotaStallThreshold.Override(ctx, &tc.store.cfg.Settings.SV, time.Nanosecond)
+	testutils.SucceedsSoon(t, func() error {
+		tc.manualClock.Advance(time.Microsecond)
+		capacity, err := tc.store.Capacity(ctx, false /* useCached */)
+		if err != nil {
+			return err
+		}
+		if capacity.QuotaStalledLeaders != 1 {
+			return errors.Errorf("expected 1 quota stalled leader, found %d", capacity.QuotaStalledLeaders)
+		}
+		return nil
+	})
+
+	// A threshold of zero disables the detection.
+	proposalQuotaStallThreshold.Override(ctx, &tc.store.cfg.Settings.SV, 0)
+	require.False(t, tc.repl.QuotaStalled(stalledAt))
+	proposalQuotaStallThreshold.Override(ctx, &tc.store.cfg.Settings.SV, threshold)
+
+	alloc.Release()
+	require.Nil(t, <-errCh)
+	require.False(t, tc.repl.QuotaStalled(stalledAt))
+}
+
+func TestProposalQuotaActivityWindow(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+	ctx := context.Background()
+	st := cluster.MakeTestingClusterSettings()
+	const leaseDuration = 6 * time.Second
+
+	check := func(typ roachpb.ReplicaType, expWindow time.Duration, expOK bool) {
+		t.Helper()
+		window, ok := proposalQuotaActivityWindow(&st.SV, typ, leaseDuration)
+		require.Equal(t, expOK, ok, "%s", typ)
+		if ok {
+			require.Equal(t, expWindow, window, "%s", typ)
+		}
+	}
+
+	// By default, voters use the lease duration and non-voters never hold up
+	// the release of quota.
+	check(roachpb.VOTER_FULL, leaseDuration, true)
+	check(roachpb.VOTER_INCOMING, leaseDuration, true)
+	check(roachpb.LEARNER, leaseDuration, true)
+	check(roachpb.NON_VOTER, 0, false)
+
+	proposalQuotaVoterActivityWindow.Override(ctx, &st.SV, time.Second)
+	proposalQuotaNonVoterActivityWindow.Override(ctx, &st.SV, time.Minute)
+	check(roachpb.VOTER_FULL, time.Second, true)
+	check(roachpb.LEARNER, time.Second, true)
+	check(roachpb.NON_VOTER, time.Minute, true)
+}
+
 func TestEntries(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	defer log.Scope(t).Close(t)
@@ -9208,6 +9881,464 @@ func TestCalcQuotaPoolPercentUsed(t *testing.T) {
 	}
 }
 
+func TestSecondaryIndexQuotaPercent(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	codec := keys.MakeSQLCodec(roachpb.MustMakeTenantID(10))
+	indexKey := func(tableID, indexID uint32) roachpb.Key {
+		return codec.IndexPrefix(tableID, indexID)
+	}
+	value := bytes.Repeat([]byte("v"), 100)
+
+	primaryPut := putArgs(indexKey(104, 2), value)
+	secondaryPut := putArgs(indexKey(104, 5), value)
+	// Reads and writes to tables not in PrimaryIndexIDs are not attributed.
+	get := getArgs(indexKey(104, 5))
+	otherPut := putArgs(indexKey(105, 1), value)
+	ba := &kvpb.BatchRequest{}
+	ba.Add(&primaryPut, &secondaryPut, &get, &otherPut)
+
+	_, ok := secondaryIndexQuotaPercent(ba)
+	require.False(t, ok)
+
+	ba.PrimaryIndexIDs = map[uint32]uint32{104: 2}
+	pct, ok := secondaryIndexQuotaPercent(ba)
+	require.True(t, ok)
+	require.Equal(t, int64(50), pct)
+
+	ba.PrimaryIndexIDs = map[uint32]uint32{105: 1}
+	pct, ok = secondaryIndexQuotaPercent(ba)
+	require.True(t, ok)
+	require.Equal(t, int64(0), pct)
+}
+
+func TestBypassesProposalQuota(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	key := roachpb.Key("a")
+	gc := &kvpb.GCRequest{RequestHeader: kvpb.RequestHeader{Key: key, EndKey: key.Next()}}
+	put := putArgs(key, []byte("v"))
+
+	for _, tc := range []struct {
+		name      string
+		reqs      []kvpb.Request
+		admission kvpb.AdmissionHeader
+		tenantID  roachpb.TenantID
+		exp       bool
+	}{
+		{name: "empty", exp: false},
+		{name: "gc", reqs: []kvpb.Request{gc}, exp: true},
+		{name: "gc and put", reqs: []kvpb.Request{gc, &put}, exp: false},
+		{name: "put", reqs: []kvpb.Request{&put}, exp: false},
+		{
+			name:      "high priority internal put",
+			reqs:      []kvpb.Request{&put},
+			admission: kvpb.AdmissionHeader{Priority: int32(admissionpb.HighPri)},
+			exp:       true,
+		},
+		{
+			name: "high priority sql put",
+			reqs: []kvpb.Request{&put},
+			admission: kvpb.AdmissionHeader{
+				Priority: int32(admissionpb.HighPri),
+				Source:   kvpb.AdmissionHeader_FROM_SQL,
+			},
+			exp: false,
+		},
+		{
+			name:      "high priority internal put to a secondary tenant",
+			reqs:      []kvpb.Request{&put},
+			admission: kvpb.AdmissionHeader{Priority: int32(admissionpb.HighPri)},
+			tenantID:  roachpb.MustMakeTenantID(10),
+			exp:       false,
+		},
+		{
+			name:     "gc of a secondary tenant",
+			reqs:     []kvpb.Request{gc},
+			tenantID: roachpb.MustMakeTenantID(10),
+			exp:      true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tenantID := tc.tenantID
+			if !tenantID.IsSet() {
+				tenantID = roachpb.SystemTenantID
+			}
+			ba := &kvpb.BatchRequest{AdmissionHeader: tc.admission}
+			ba.Add(tc.reqs...)
+			require.Equal(t, tc.exp, bypassesProposalQuota(ba, tenantID))
+		})
+	}
+}
+
+func TestProposalQuotaCharge(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	key := roachpb.Key("a")
+	put := putArgs(key, []byte("v"))
+	gc := &kvpb.GCRequest{RequestHeader: kvpb.RequestHeader{Key: key, EndKey: key.Next()}}
+	delRange := &kvpb.DeleteRangeRequest{RequestHeader: kvpb.RequestHeader{Key: key, EndKey: key.Next()}}
+	sst := &kvpb.AddSSTableRequest{RequestHeader: kvpb.RequestHeader{Key: key, EndKey: key.Next()}}
+	lease := &kvpb.RequestLeaseRequest{RequestHeader: kvpb.RequestHeader{Key: key}}
+	transfer := &kvpb.TransferLeaseRequest{RequestHeader: kvpb.RequestHeader{Key: key}}
+
+	const commandSize = 1000
+	for _, tc := range []struct {
+		name string
+		reqs []kvpb.Request
+		exp  uint64
+	}{
+		// Writes are charged the size of the command, whatever the size of the
+		// request.
+		{name: "put", reqs: []kvpb.Request{&put}, exp: commandSize},
+		{name: "delete range", reqs: []kvpb.Request{delRange}, exp: commandSize},
+		{name: "add sstable", reqs: []kvpb.Request{sst}, exp: commandSize},
+		// Charged, though bypassesProposalQuota lets it through without waiting.
+		{name: "gc", reqs: []kvpb.Request{gc}, exp: commandSize},
+		{name: "lease request", reqs: []kvpb.Request{lease}, exp: 0},
+		{name: "lease transfer", reqs: []kvpb.Request{transfer}, exp: 0},
+		{name: "lease request and put", reqs: []kvpb.Request{lease, &put}, exp: commandSize},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ba := &kvpb.BatchRequest{}
+			ba.Add(tc.reqs...)
+			require.Equal(t, tc.exp, ProposalQuotaCharge(ba, commandSize))
+		})
+	}
+}
+
+// TestProposalQuotaCharger tests that proposals are charged proposal quota
+// per the store's ProposalQuotaCharger.
+func TestProposalQuotaCharger(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+	ctx := context.Background()
+
+	const flatCharge = 7
+	for _, tc := range []struct {
+		name    string
+		charger ProposalQuotaCharger
+		// exp returns the expected charge of a put of the given command size.
+		exp func(commandSize int) uint64
+	}{
+		{
+			name: "default",
+			exp:  func(commandSize int) uint64 { return uint64(commandSize) },
+		},
+		{
+			name: "flat",
+			charger: ProposalQuotaChargerFunc(func(ba *kvpb.BatchRequest, commandSize int) uint64 {
+				if ProposalQuotaCharge(ba, commandSize) == 0 {
+					return 0
+				}
+				return flatCharge
+			}),
+			exp: func(int) uint64 { return flatCharge },
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			testCtx := testContext{}
+			stopper := stop.NewStopper()
+			defer stopper.Stop(ctx)
+
+			var mu syncutil.Mutex
+			var charged, expected []uint64
+			tsc := TestStoreConfig(nil /* clock */)
+			tsc.ProposalQuotaCharger = tc.charger
+			tsc.TestingKnobs.TestingProposalFilter = func(args kvserverbase.ProposalFilterArgs) *kvpb.Error {
+				if args.QuotaAlloc == nil || !args.Req.IsSingleRequest() {
+					return nil
+				}
+				if _, ok := args.Req.GetArg(kvpb.Put); !ok {
+					return nil
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				charged = append(charged, args.QuotaAlloc.Acquired())
+				expected = append(expected, tc.exp(args.Cmd.Size()))
+				return nil
+			}
+			testCtx.StartWithStoreConfig(ctx, t, stopper, tsc)
+
+			for i := 0; i < 10; i++ {
+				pArg := putArgs(roachpb.Key("a"), make([]byte, 1<<10))
+				_, pErr := testCtx.SendWrapped(&pArg)
+				require.Nil(t, pErr)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			require.NotEmpty(t, charged)
+			require.Equal(t, expected, charged)
+		})
+	}
+}
+
+func TestProposalQuotaExemptions(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	tenantID := roachpb.MustMakeTenantID(10)
+	codec := keys.MakeSQLCodec(tenantID)
+	rangeOf := func(codec keys.SQLCodec, tableID uint32) *roachpb.RangeDescriptor {
+		return &roachpb.RangeDescriptor{StartKey: roachpb.RKey(codec.TablePrefix(tableID))}
+	}
+
+	ctx := context.Background()
+	st := cluster.MakeTestingClusterSettings()
+	var e proposalQuotaExemptions
+	e.watch(ctx, &st.SV)
+	now := timeutil.Unix(100, 0)
+	require.False(t, e.exempt(rangeOf(codec, 104), now))
+
+	for _, invalid := range []string{
+		"10/104", "104@1970-01-01T00:01:40Z", "0/104@1970-01-01T00:01:40Z",
+		"10/x@1970-01-01T00:01:40Z", "10/104@tomorrow",
+	} {
+		_, err := parseProposalQuotaExemptTables(invalid)
+		require.Error(t, err, invalid)
+	}
+
+	proposalQuotaExemptTables.Override(ctx, &st.SV, "10/104@1970-01-01T00:02:40Z, 1/106@1970-01-01T00:02:40Z")
+	require.True(t, e.exempt(rangeOf(codec, 104), now))
+	require.True(t, e.exempt(rangeOf(keys.SystemSQLCodec, 106), now))
+	// Other tables, and the same table of other tenants, are not exempt.
+	require.False(t, e.exempt(rangeOf(codec, 105), now))
+	require.False(t, e.exempt(rangeOf(keys.SystemSQLCodec, 104), now))
+	require.False(t, e.exempt(&roachpb.RangeDescriptor{StartKey: roachpb.RKeyMin}, now))
+
+	// The exemption expires.
+	require.False(t, e.exempt(rangeOf(codec, 104), now.Add(time.Minute)))
+	require.Equal(t, int32(1), e.num.Load())
+
+	// Removing a table from the setting removes its exemption.
+	proposalQuotaExemptTables.Override(ctx, &st.SV, "")
+	require.False(t, e.exempt(rangeOf(keys.SystemSQLCodec, 106), now))
+	require.Equal(t, int32(0), e.num.Load())
+}
+
+// TestTenantProposalQuotaPools verifies that tenants sharing a store acquire
+// proposal quota from separate pools, so that one tenant exhausting its quota
+// does not hold up the other, that the system tenant is not limited, and that
+// idle pools are closed.
+func TestTenantProposalQuotaPools(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	tenant1, tenant2 := roachpb.MustMakeTenantID(10), roachpb.MustMakeTenantID(11)
+	now := timeutil.Unix(100, 0)
+	var pools tenantProposalQuotaPools
+
+	// A capacity of zero doesn't limit proposals.
+	alloc, err := pools.acquire(ctx, tenant1, 0 /* capacity */, 100, now)
+	require.NoError(t, err)
+	require.Nil(t, alloc)
+
+	// The system tenant is not limited.
+	alloc, err = pools.acquire(ctx, roachpb.SystemTenantID, 100 /* capacity */, 1000, now)
+	require.NoError(t, err)
+	require.Nil(t, alloc)
+	require.Equal(t, int32(0), pools.num.Load())
+
+	// Tenant 1 exhausts its quota.
+	held, err := pools.acquire(ctx, tenant1, 100 /* capacity */, 100, now)
+	require.NoError(t, err)
+	_, err = pools.pool(tenant1, 100, now).TryAcquire(ctx, 1)
+	require.ErrorIs(t, err, quotapool.ErrNotEnoughQuota)
+
+	// Tenant 2 is not affected.
+	alloc, err = pools.acquire(ctx, tenant2, 100 /* capacity */, 60, now)
+	require.NoError(t, err)
+	require.Equal(t, uint64(60), alloc.Acquired())
+	alloc.Release()
+
+	// Tenant 1 waits until its quota is released.
+	acquired := make(chan *quotapool.IntAlloc)
+	go func() {
+		alloc, err := pools.acquire(ctx, tenant1, 100 /* capacity */, 10, now)
+		if err != nil {
+			t.Error(err)
+		}
+		acquired <- alloc
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("acquired quota of exhausted tenant")
+	case <-time.After(10 * time.Millisecond):
+	}
+	held.Release()
+	(<-acquired).Release()
+
+	// Changes to the capacity apply to existing pools.
+	alloc, err = pools.acquire(ctx, tenant1, 200 /* capacity */, 200, now)
+	require.NoError(t, err)
+	require.Equal(t, uint64(200), alloc.Acquired())
+	alloc.Release()
+	require.Equal(t, int32(2), pools.num.Load())
+
+	// Pools which go unused are closed, unless quota is still held from them.
+	held, err = pools.acquire(ctx, tenant2, 100 /* capacity */, 10, now)
+	require.NoError(t, err)
+	now = now.Add(tenantProposalQuotaPoolIdleTimeout)
+	tenant3 := roachpb.MustMakeTenantID(12)
+	alloc, err = pools.acquire(ctx, tenant3, 100 /* capacity */, 10, now)
+	require.NoError(t, err)
+	alloc.Release()
+	require.Equal(t, int32(2), pools.num.Load())
+	require.NotContains(t, pools.mu.pools, tenant1)
+	held.Release()
+
+	// Pools are closed once tenants are no longer limited. Quota held from
+	// them can still be released.
+	held, err = pools.acquire(ctx, tenant3, 100 /* capacity */, 10, now)
+	require.NoError(t, err)
+	alloc, err = pools.acquire(ctx, tenant3, 0 /* capacity */, 10, now)
+	require.NoError(t, err)
+	require.Nil(t, alloc)
+	require.Equal(t, int32(0), pools.num.Load())
+	require.Empty(t, pools.mu.pools)
+	held.Release()
+}
+
+func TestExcludedFamilyQuotaBytes(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	codec := keys.MakeSQLCodec(roachpb.MustMakeTenantID(10))
+	rowKey := func(tableID, indexID, familyID uint32) roachpb.Key {
+		key := encoding.EncodeVarintAscending(codec.IndexPrefix(tableID, indexID), 1)
+		return keys.MakeFamilyKey(key, familyID)
+	}
+	value := bytes.Repeat([]byte("v"), 100)
+
+	hotPut := putArgs(rowKey(104, 1, 0), value)
+	blobPut := putArgs(rowKey(104, 1, 2), value)
+	// Reads, ranged writes, writes to tables not in PrimaryIndexIDs and writes
+	// to secondary indexes are never excluded.
+	get := getArgs(rowKey(104, 1, 2))
+	delRange := deleteRangeArgs(codec.IndexPrefix(104, 1), codec.IndexPrefix(104, 2))
+	otherPut := putArgs(rowKey(105, 1, 2), value)
+	secondaryPut := putArgs(rowKey(104, 2, 0), value)
+	ba := &kvpb.BatchRequest{}
+	ba.Add(&hotPut, &blobPut, &get, &delRange, &otherPut, &secondaryPut)
+
+	require.Zero(t, excludedFamilyQuotaBytes(ba, []uint32{2}))
+
+	ba.PrimaryIndexIDs = map[uint32]uint32{104: 1}
+	require.Zero(t, excludedFamilyQuotaBytes(ba, nil))
+	require.Equal(t, uint64(blobPut.Size()), excludedFamilyQuotaBytes(ba, []uint32{2}))
+	require.Equal(t, uint64(hotPut.Size()+blobPut.Size()), excludedFamilyQuotaBytes(ba, []uint32{0, 2}))
+}
+
+func TestProposalQuotaBackpressureHint(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	const capacity = 1000
+	for _, tc := range []struct {
+		available uint64
+		maxDelay  time.Duration
+		expHint   time.Duration
+	}{
+		{available: 0, maxDelay: 0, expHint: 0},
+		{available: capacity, maxDelay: time.Second, expHint: 0},
+		// No hint until less than 10% of the quota is left.
+		{available: 100, maxDelay: time.Second, expHint: 0},
+		{available: 50, maxDelay: time.Second, expHint: 500 * time.Millisecond},
+		{available: 0, maxDelay: time.Second, expHint: time.Second},
+	} {
+		require.Equal(t, tc.expHint, proposalQuotaBackpressureHint(tc.available, capacity, tc.maxDelay), "%+v", tc)
+	}
+}
+
+func TestFormatQuotaReleaseQueue(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	pool := quotapool.NewIntPool("test", 10000)
+	acquire := func(n uint64) *quotapool.IntAlloc {
+		alloc, err := pool.Acquire(ctx, n)
+		require.NoError(t, err)
+		return alloc
+	}
+
+	require.Empty(t, formatQuotaReleaseQueue(10, nil))
+	queue := []*quotapool.IntAlloc{acquire(100), nil, acquire(250)}
+	require.Equal(t, "11: 100, 12: -, 13: 250 [3 entries, 350 bytes]", formatQuotaReleaseQueue(10, queue))
+	// Formatting the queue does not release or otherwise change its allocs.
+	require.Equal(t, uint64(100), queue[0].Acquired())
+	require.Equal(t, uint64(10000-350), pool.ApproximateQuota())
+
+	// Only the oldest entries are listed, but all of them are counted.
+	queue = nil
+	for i := 0; i < maxFormattedQuotaReleaseQueueEntries+2; i++ {
+		queue = append(queue, acquire(1))
+	}
+	s := formatQuotaReleaseQueue(0, queue)
+	require.True(t, strings.HasPrefix(s, "1: 1, 2: 1, "), s)
+	require.True(t, strings.HasSuffix(s, fmt.Sprintf(
+		"%d: 1, ... (2 more) [%d entries, %d bytes]", maxFormattedQuotaReleaseQueueEntries,
+		maxFormattedQuotaReleaseQueueEntries+2, maxFormattedQuotaReleaseQueueEntries+2)), s)
+}
+
+func TestLowestProposalQuota(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ranges := []rangeProposalQuota{
+		{rangeID: 1, available: 500},
+		{rangeID: 2, available: 0},
+		{rangeID: 3, available: 1000},
+		{rangeID: 4, available: 100},
+		{rangeID: 5, available: 100},
+	}
+	require.Empty(t, lowestProposalQuota(slices.Clone(ranges), 0))
+	require.Equal(t, []rangeProposalQuota{
+		{rangeID: 2, available: 0},
+		{rangeID: 4, available: 100},
+		{rangeID: 5, available: 100},
+	}, lowestProposalQuota(slices.Clone(ranges), 3))
+	require.Len(t, lowestProposalQuota(slices.Clone(ranges), 10), len(ranges))
+	require.Empty(t, lowestProposalQuota(nil, 10))
+}
+
+func TestProposalQuotaReplicationMultiplier(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	st := cluster.MakeTestingClusterSettings()
+	desc := func(voters, nonVoters int) *roachpb.RangeDescriptor {
+		d := &roachpb.RangeDescriptor{}
+		for i := 0; i < voters+nonVoters; i++ {
+			typ := roachpb.VOTER_FULL
+			if i >= voters {
+				typ = roachpb.NON_VOTER
+			}
+			d.InternalReplicas = append(d.InternalReplicas, roachpb.ReplicaDescriptor{
+				NodeID: roachpb.NodeID(i + 1), StoreID: roachpb.StoreID(i + 1),
+				ReplicaID: roachpb.ReplicaID(i + 1), Type: typ,
+			})
+		}
+		return d
+	}
+
+	for _, tc := range []struct {
+		weighting          proposalQuotaReplicationWeighting
+		voters, nonVoters  int
+		expMultiplier      uint64
+		expWeightingActive bool
+	}{
+		{weighting: proposalQuotaWeightingOff, voters: 5, expMultiplier: 1},
+		{weighting: proposalQuotaWeightingFollowers, voters: 1, expMultiplier: 1, expWeightingActive: true},
+		{weighting: proposalQuotaWeightingFollowers, voters: 3, expMultiplier: 2, expWeightingActive: true},
+		// Non-voters are not counted.
+		{weighting: proposalQuotaWeightingFollowers, voters: 5, nonVoters: 2, expMultiplier: 4, expWeightingActive: true},
+		{weighting: proposalQuotaWeightingVoters, voters: 3, expMultiplier: 3, expWeightingActive: true},
+		{weighting: proposalQuotaWeightingVoters, voters: 5, nonVoters: 2, expMultiplier: 5, expWeightingActive: true},
+	} {
+		proposalQuotaReplicationWeightingSetting.Override(ctx, &st.SV, tc.weighting)
+		multiplier, ok := proposalQuotaReplicationMultiplier(&st.SV, desc(tc.voters, tc.nonVoters))
+		require.Equal(t, tc.expMultiplier, multiplier, "%+v", tc)
+		require.Equal(t, tc.expWeightingActive, ok, "%+v", tc)
+	}
+}
+
 // TestCancelPendingCommands verifies that cancelPendingCommands sends
 // an error to each command awaiting execution.
 func TestCancelPendingCommands(t *testing.T) {
@@ -28,6 +28,20 @@ const minSplitSuggestionInterval = time.Minute
 const minNoSplitKeyLoggingMetricsInterval = time.Minute
 const minPerSecondSampleDuration = time.Second
 
+// quotaPressureDuration is how long a range must have been continuously short
+// of proposal quota before the pressure is factored into the split decision.
+// Brief dips in quota are expected whenever a follower falls slightly behind,
+// and shouldn't make a range a split candidate.
+const quotaPressureDuration = 10 * time.Second
+
+// quotaPressureThresholdFraction is the fraction of the stat threshold above
+// which a range under sustained proposal quota pressure becomes a candidate
+// for load-based splitting. A range whose followers can't keep up with the
+// write rate is already saturated, even though it is serving less load than
+// the threshold; halving the threshold lets such a range engage the split
+// finder sooner, while still requiring enough load for a split to spread.
+const quotaPressureThresholdFraction = 0.5
+
 type LoadBasedSplitter interface {
 	redact.SafeFormatter
 	// Record informs the LoadBasedSplitter about where the span lies with regard
@@ -128,6 +142,12 @@ func GlobalRandSource() RandSource {
 // SplitObjective controls which load stat threshold and split finder are used.
 // We keep the SplitObjective under the finder mutex to prevent inconsistency
 // that could result from separate calls to the decider, then split objective.
+//
+// The Decider is also told by the Replica whether the range is running short
+// of proposal quota, via RecordQuotaPressure. Once that pressure has been
+// sustained for quotaPressureDuration, the load threshold is scaled down by
+// quotaPressureThresholdFraction, so that a range held back by its followers
+// becomes a split candidate sooner than its load alone would make it one.
 
 // LoadSplitterMetrics consists of metrics for load-based splitter split key.
 type LoadSplitterMetrics struct {
@@ -161,6 +181,10 @@ type Decider struct {
 
 		// Fields tracking logging / metrics around load-based splitter split key.
 		lastNoSplitKeyLoggingMetrics time.Time
+
+		// quotaPressureStart is when the range last began to run short of
+		// proposal quota, or zero if it currently isn't.
+		quotaPressureStart time.Time
 	}
 }
 
@@ -238,7 +262,7 @@ func (d *Decider) recordLocked(
 		// begin to Record requests so it can find a split point. If a
 		// splitFinder already exists, we check if a split point is ready
 		// to be used.
-		if d.mu.lastStatVal >= d.config.StatThreshold(d.mu.objective) {
+		if d.mu.lastStatVal >= d.statThresholdLocked(now) {
 			if d.mu.splitFinder == nil {
 				d.mu.splitFinder = d.config.NewLoadBasedSplitter(now, d.mu.objective)
 			}
@@ -283,6 +307,31 @@ func (d *Decider) recordLocked(
 	return false
 }
 
+// statThresholdLocked returns the stat value above which the range becomes a
+// candidate for load-based splitting, lowered if the range has been under
+// sustained proposal quota pressure.
+func (d *Decider) statThresholdLocked(now time.Time) float64 {
+	threshold := d.config.StatThreshold(d.mu.objective)
+	if start := d.mu.quotaPressureStart; !start.IsZero() && now.Sub(start) >= quotaPressureDuration {
+		threshold *= quotaPressureThresholdFraction
+	}
+	return threshold
+}
+
+// RecordQuotaPressure notifies the Decider whether the range is currently
+// running short of proposal quota. It is expected to be called periodically
+// by the range's leader, and with pressured=false when it stops being one.
+func (d *Decider) RecordQuotaPressure(now time.Time, pressured bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !pressured {
+		d.mu.quotaPressureStart = time.Time{}
+	} else if d.mu.quotaPressureStart.IsZero() {
+		d.mu.quotaPressureStart = now
+	}
+}
+
 // RecordMax adds a stat measurement directly into the Decider's historical
 // stat value tracker. The stat sample is considered to have been captured at
 // the provided time.
@@ -373,6 +422,7 @@ func (d *Decider) resetLocked(now time.Time) {
 	d.mu.suggestionsMade = 0
 	d.mu.lastSplitSuggestion = time.Time{}
 	d.mu.lastNoSplitKeyLoggingMetrics = time.Time{}
+	d.mu.quotaPressureStart = time.Time{}
 }
 
 // SetSplitObjective sets the decider split objective to the given value and
@@ -288,6 +288,83 @@ func TestDecider_MaxStat(t *testing.T) {
 	assertMaxStat(25000, 6, true)
 }
 
+// TestDeciderQuotaPressure verifies that a range under sustained proposal
+// quota pressure becomes a candidate for load-based splitting below the load
+// threshold which would otherwise apply.
+func TestDeciderQuotaPressure(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	makeDecider := func() *Decider {
+		var d Decider
+		Init(&d, &testLoadSplitConfig{
+			randSource:    rand.New(rand.NewSource(11)),
+			statRetention: 10 * time.Second,
+			statThreshold: 10,
+		}, &LoadSplitterMetrics{
+			PopularKeyCount: metric.NewCounter(metric.Metadata{}),
+			NoSplitKeyCount: metric.NewCounter(metric.Metadata{}),
+		}, SplitQPS)
+		return &d
+	}
+	op := func(s string) func() roachpb.Span {
+		return func() roachpb.Span { return roachpb.Span{Key: roachpb.Key(s)} }
+	}
+	ctx := context.Background()
+
+	// Both ranges serve 6 requests per second, which is below the threshold
+	// but above the threshold of a range under quota pressure.
+	pressured, unpressured := makeDecider(), makeDecider()
+	pressured.RecordQuotaPressure(ms(0), true)
+	pressured.Record(ctx, ms(0), ld(0), nil)
+	unpressured.Record(ctx, ms(0), ld(0), nil)
+	record := func(tick int) {
+		o := op("a")
+		if tick/1000%2 == 0 {
+			o = op("z")
+		}
+		pressured.Record(ctx, ms(tick), ld(6), o)
+		unpressured.Record(ctx, ms(tick), ld(6), o)
+	}
+
+	// The pressure only counts once it has been sustained.
+	tick := 1000
+	for ; tick < int(quotaPressureDuration/time.Millisecond); tick += 1000 {
+		record(tick)
+		require.Nil(t, pressured.mu.splitFinder)
+		require.Nil(t, unpressured.mu.splitFinder)
+	}
+	// Reporting continued pressure doesn't restart the clock.
+	pressured.RecordQuotaPressure(ms(tick), true)
+	record(tick)
+	require.NotNil(t, pressured.mu.splitFinder)
+	require.Nil(t, unpressured.mu.splitFinder)
+
+	// The pressured range is eventually told to split, while the other never
+	// engages the split finder at all.
+	for tick += 1000; pressured.MaybeSplitKey(ctx, ms(tick)) == nil; tick += 1000 {
+		require.Less(t, tick, 1000*1000)
+		record(tick)
+	}
+	require.Nil(t, unpressured.mu.splitFinder)
+
+	// Once the pressure subsides, the regular threshold applies again.
+	pressured.RecordQuotaPressure(ms(tick), false)
+	tick += 1000
+	record(tick)
+	require.Nil(t, pressured.mu.splitFinder)
+	require.Nil(t, pressured.MaybeSplitKey(ctx, ms(tick)))
+
+	// Resetting the decider discards the pressure too, so that pressure
+	// reported before a split isn't credited to either of its halves.
+	pressured.RecordQuotaPressure(ms(0), true)
+	require.False(t, pressured.mu.quotaPressureStart.IsZero())
+	pressured.Reset(ms(tick))
+	require.True(t, pressured.mu.quotaPressureStart.IsZero())
+	tick += 1000
+	record(tick)
+	require.Nil(t, pressured.mu.splitFinder)
+}
+
 func TestMaxStatTracker(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 
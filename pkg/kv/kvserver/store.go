@@ -1105,6 +1105,18 @@ type Store struct {
 	// all range leases it held due to becoming IO overloaded.
 	lastIOOverloadLeaseShed atomic.Value
 
+	// proposalQuotaExemptions are the tables whose ranges temporarily do not
+	// acquire proposal quota, see kv.raft.proposal_quota.exempt_tables.
+	proposalQuotaExemptions proposalQuotaExemptions
+
+	// tenantProposalQuota are the proposal quota pools shared by the ranges of
+	// each tenant, see kv.raft.proposal_quota.tenant_capacity.
+	tenantProposalQuota tenantProposalQuotaPools
+
+	// proposalQuotaReleaseQueues accounts for the quota release queues of the
+	// leaders on the store, see kv.raft.proposal_quota.store_max_release_queue_bytes.
+	proposalQuotaReleaseQueues proposalQuotaReleaseQueues
+
 	counts struct {
 		// Number of placeholders removed due to error. Not a good fit for meaningful
 		// metrics, as snapshots to initialized ranges don't get a placeholder.
@@ -1311,6 +1323,10 @@ type StoreConfig struct {
 	// RangeCount is populated by the node and represents the total number of
 	// ranges this node has.
 	RangeCount *atomic.Int64
+
+	// ProposalQuotaCharger determines the proposal quota charged for each
+	// proposal. Defaults to DefaultProposalQuotaCharger.
+	ProposalQuotaCharger ProposalQuotaCharger
 }
 
 // logRangeAndNodeEventsEnabled is used to enable or disable logging range events
@@ -1410,6 +1426,9 @@ func (sc *StoreConfig) SetDefaults(numStores int) {
 	if sc.RangeFeedSchedulerConcurrencyPriority == 0 {
 		sc.RangeFeedSchedulerConcurrencyPriority = defaultRangefeedSchedulerPriorityShardSize
 	}
+	if sc.ProposalQuotaCharger == nil {
+		sc.ProposalQuotaCharger = DefaultProposalQuotaCharger
+	}
 }
 
 // GetStoreConfig exposes the config used for this store.
@@ -1597,6 +1616,8 @@ func NewStore(
 		s.snapshotSendQueue.UpdateConcurrencyLimit(int(SnapshotSendLimit.Get(&cfg.Settings.SV)))
 	})
 
+	s.proposalQuotaExemptions.watch(ctx, &cfg.Settings.SV)
+
 	s.consistencyLimiter = quotapool.NewRateLimiter(
 		"ConsistencyQueue",
 		quotapool.Limit(consistencyCheckRate.Get(&cfg.Settings.SV)),
@@ -3075,6 +3096,8 @@ func (s *Store) Capacity(ctx context.Context, useCached bool) (roachpb.StoreCapa
 	var totalQueriesPerSecond float64
 	var totalWritesPerSecond float64
 	var totalStoreCPUTimePerSecond float64
+	var quotaStalledLeaders int32
+	physicalNow := s.cfg.Clock.PhysicalTime()
 	replicaCount := s.metrics.ReplicaCount.Value()
 	bytesPerReplica := make([]float64, 0, replicaCount)
 	writesPerReplica := make([]float64, 0, replicaCount)
@@ -3091,6 +3114,9 @@ func (s *Store) Capacity(ctx context.Context, useCached bool) (roachpb.StoreCapa
 		if r.OwnsValidLease(ctx, now) {
 			leaseCount++
 		}
+		if r.QuotaStalled(physicalNow) {
+			quotaStalledLeaders++
+		}
 		usage := r.RangeUsageInfo()
 		logicalBytes += usage.LogicalBytes
 		bytesPerReplica = append(bytesPerReplica, float64(usage.LogicalBytes))
@@ -3130,6 +3156,7 @@ func (s *Store) Capacity(ctx context.Context, useCached bool) (roachpb.StoreCapa
 	capacity.CPUPerSecond = totalStoreCPUTimePerSecond
 	capacity.QueriesPerSecond = totalQueriesPerSecond
 	capacity.WritesPerSecond = totalWritesPerSecond
+	capacity.QuotaStalledLeaders = quotaStalledLeaders
 	goNow := now.ToTimestamp().GoTime()
 	{
 		s.ioThreshold.Lock()
@@ -3269,6 +3296,10 @@ func (s *Store) updateReplicationGauges(ctx context.Context) error {
 		ioOverload                float64
 		pendingRaftProposalCount  int64
 		slowRaftProposalCount     int64
+		proposalQuotaExemptCount  int64
+		proposalQuotaForcedCount  int64
+		proposalQuotaLowCount     int64
+		proposalQuotaByRange      []rangeProposalQuota
 
 		locks                          int64
 		totalLockHoldDurationNanos     int64
@@ -3285,6 +3316,7 @@ func (s *Store) updateReplicationGauges(ctx context.Context) error {
 	now := s.cfg.Clock.NowAsClockTimestamp()
 	goNow := now.ToTimestamp().GoTime()
 	clusterNodes := s.ClusterNodeCount()
+	quotaByRangeTopN := int(proposalQuotaAvailableByRangeTopN.Get(&s.cfg.Settings.SV))
 
 	s.mu.RLock()
 	uninitializedCount = int64(len(s.mu.uninitReplicas))
@@ -3310,8 +3342,22 @@ func (s *Store) updateReplicationGauges(ctx context.Context) error {
 				raftLeaderInvalidLeaseCount++
 			}
 		}
+		if metrics.QuotaPoolLow {
+			proposalQuotaLowCount++
+		}
 		if metrics.Leaseholder {
 			s.metrics.RaftQuotaPoolPercentUsed.RecordValue(metrics.QuotaPoolPercentUsed)
+			if s.proposalQuotaExemptions.exempt(rep.Desc(), goNow) {
+				proposalQuotaExemptCount++
+			}
+			if metrics.QuotaPoolForced {
+				proposalQuotaForcedCount++
+			}
+			if quotaByRangeTopN > 0 && metrics.QuotaPoolAvailable >= 0 {
+				proposalQuotaByRange = append(proposalQuotaByRange, rangeProposalQuota{
+					rangeID: rep.RangeID, available: metrics.QuotaPoolAvailable,
+				})
+			}
 			leaseHolderCount++
 			switch metrics.LeaseType {
 			case roachpb.LeaseNone:
@@ -3422,6 +3468,17 @@ func (s *Store) updateReplicationGauges(ctx context.Context) error {
 	s.metrics.IOOverload.Update(ioOverload)
 	s.metrics.RaftCommandsPending.Update(pendingRaftProposalCount)
 	s.metrics.SlowRaftRequests.Update(slowRaftProposalCount)
+	s.metrics.RaftProposalQuotaExemptRanges.Update(proposalQuotaExemptCount)
+	s.metrics.RaftProposalQuotaForcedRanges.Update(proposalQuotaForcedCount)
+	s.metrics.RaftProposalQuotaStoreQueueMemoryBytes.Update(s.proposalQuotaReleaseQueues.bytes())
+	s.metrics.RaftProposalQuotaRangesBelow10Pct.Update(proposalQuotaLowCount)
+	// The ranges in the top N change over time, so the vector is rebuilt from
+	// scratch to not keep exporting those which dropped out of it.
+	s.metrics.RaftProposalQuotaAvailableByRange.Clear()
+	for _, q := range lowestProposalQuota(proposalQuotaByRange, quotaByRangeTopN) {
+		s.metrics.RaftProposalQuotaAvailableByRange.Update(
+			map[string]string{"range_id": q.rangeID.String()}, q.available)
+	}
 
 	var averageLockHoldDurationNanos int64
 	var averageLockWaitDurationNanos int64
@@ -313,6 +313,12 @@ type StoreTestingKnobs struct {
 	// TraceAllRaftEvents enables raft event tracing even when the current
 	// vmodule would not have enabled it.
 	TraceAllRaftEvents bool
+	// QuotaAssertionAsError makes a proposal quota release queue which does not
+	// match the applied index, as asserted by updateProposalQuotaRaftMuLocked,
+	// be logged as an error and recorded on the replica rather than crash the
+	// node, so that tests can inject the inconsistency and verify that it is
+	// detected.
+	QuotaAssertionAsError bool
 	// EnableUnconditionalRefreshesInRaftReady will always set the refresh reason
 	// in handleRaftReady to refreshReasonNewLeaderOrConfigChange.
 	EnableUnconditionalRefreshesInRaftReady bool
@@ -18,10 +18,14 @@
 package rafttest
 
 import (
+	"bytes"
 	"fmt"
+	"log"
+	"regexp"
 	"strings"
 
 	"github.com/cockroachdb/cockroach/pkg/raft"
+	"github.com/cockroachdb/errors"
 )
 
 type logLevels [6]string
@@ -31,24 +35,67 @@ var lvlNames logLevels = [...]string{"DEBUG", "INFO", "WARN", "ERROR", "FATAL",
 type RedirectLogger struct {
 	*strings.Builder
 	Lvl int // 0 = DEBUG, 1 = INFO, 2 = WARNING, 3 = ERROR, 4 = FATAL, 5 = NONE
+
+	// Compact, if set, normalizes each logged message before writing it, so
+	// that golden files only change when the logged events do rather than
+	// with volatile details. Output written to the Builder directly is not
+	// affected.
+	Compact bool
+	// Normalizer normalizes messages in Compact mode. If nil, CompactMessage
+	// is used.
+	Normalizer func(string) string
+
+	// checkpoint is the length of the output as of the last Checkpoint, in
+	// checkpointed, the Builder at the time.
+	checkpoint   int
+	checkpointed *strings.Builder
 }
 
 var _ raft.Logger = (*RedirectLogger)(nil)
 
 func (l *RedirectLogger) printf(lvl int, format string, args ...interface{}) {
 	if l.Lvl <= lvl {
-		fmt.Fprint(l, lvlNames[lvl], " ")
-		fmt.Fprintf(l, format, args...)
+		msg := fmt.Sprintf(format, args...)
 		if n := len(format); n > 0 && format[n-1] != '\n' {
-			l.WriteByte('\n')
+			msg += "\n"
 		}
+		l.write(lvl, msg)
 	}
 }
 func (l *RedirectLogger) print(lvl int, args ...interface{}) {
 	if l.Lvl <= lvl {
-		fmt.Fprint(l, lvlNames[lvl], " ")
-		fmt.Fprintln(l, args...)
+		l.write(lvl, fmt.Sprintln(args...))
+	}
+}
+
+// write writes a message logged at the given level, normalizing it in Compact
+// mode.
+func (l *RedirectLogger) write(lvl int, msg string) {
+	if l.Compact {
+		normalize := l.Normalizer
+		if normalize == nil {
+			normalize = CompactMessage
+		}
+		// Normalize the message itself, keeping its terminating newline.
+		trimmed := strings.TrimSuffix(msg, "\n")
+		msg = normalize(trimmed) + msg[len(trimmed):]
 	}
+	fmt.Fprint(l, lvlNames[lvl], " ")
+	l.WriteString(msg)
+}
+
+// durationRE matches durations as formatted by time.Duration.String.
+var durationRE = regexp.MustCompile(`\b(\d+h)?(\d+m)?\d+(\.\d+)?(ns|µs|us|ms|s|m|h)\b`)
+
+// whitespaceRE matches runs of whitespace, including newlines.
+var whitespaceRE = regexp.MustCompile(`\s+`)
+
+// CompactMessage is the default Normalizer of RedirectLogger. It replaces
+// durations with "<duration>", and collapses whitespace so that a message
+// takes up a single line.
+func CompactMessage(msg string) string {
+	msg = durationRE.ReplaceAllString(msg, "<duration>")
+	return strings.TrimSpace(whitespaceRE.ReplaceAllString(msg, " "))
 }
 
 func (l *RedirectLogger) Debug(v ...interface{}) {
@@ -106,6 +153,70 @@ func (l *RedirectLogger) Panicf(format string, v ...interface{}) {
 	panic(fmt.Sprintf(format, v...))
 }
 
+// StdLogger returns a standard library logger which writes each message to l
+// at the given level, so that it is subject to the same level filtering and
+// quieting as the raft log output.
+func (l *RedirectLogger) StdLogger(lvl int) *log.Logger {
+	return log.New(redirectLoggerWriter{l: l, lvl: lvl}, "" /* prefix */, 0 /* flags */)
+}
+
+// redirectLoggerWriter is the io.Writer backing RedirectLogger.StdLogger.
+type redirectLoggerWriter struct {
+	l   *RedirectLogger
+	lvl int
+}
+
+func (w redirectLoggerWriter) Write(p []byte) (int, error) {
+	// log.Logger terminates every message with a newline; printf adds it back,
+	// the same as for messages logged directly.
+	w.l.printf(w.lvl, "%s", bytes.TrimSuffix(p, []byte("\n")))
+	return len(p), nil
+}
+
+// Checkpoint marks the current end of the output, so that SinceCheckpoint
+// returns only what is written after this call.
+func (l *RedirectLogger) Checkpoint() {
+	l.checkpoint, l.checkpointed = l.Len(), l.Builder
+}
+
+// SinceCheckpoint returns the output written since the last Checkpoint, or all
+// of the output if there was none or the Builder was replaced since. Output is
+// dropped while the logger is Quiet, so none of it is returned.
+func (l *RedirectLogger) SinceCheckpoint() string {
+	s := l.String()
+	if l.Builder != l.checkpointed {
+		return s
+	}
+	return s[l.checkpoint:]
+}
+
+// Reset resets the output, along with the checkpoint.
+func (l *RedirectLogger) Reset() {
+	l.Builder.Reset()
+	l.checkpoint = 0
+}
+
+// AssertClean returns an error listing the messages in the output which were
+// logged at maxLvl or above, if any, e.g. to assert that a scenario logs no
+// warnings or errors. Messages are identified by the level prefixes written
+// ahead of them, so only the first line of a multi-line message is listed.
+func (l *RedirectLogger) AssertClean(maxLvl int) error {
+	var lines []string
+	for _, line := range strings.Split(l.String(), "\n") {
+		for lvl := maxLvl; lvl < len(lvlNames)-1; lvl++ {
+			if strings.HasPrefix(line, lvlNames[lvl]+" ") {
+				lines = append(lines, line)
+				break
+			}
+		}
+	}
+	if len(lines) > 0 {
+		return errors.Newf("%d messages logged at %s or above:\n%s",
+			len(lines), lvlNames[maxLvl], strings.Join(lines, "\n"))
+	}
+	return nil
+}
+
 // Override StringBuilder write methods to silence them under NONE.
 
 func (l *RedirectLogger) Quiet() bool {
@@ -0,0 +1,114 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package rafttest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedirectLoggerCompact(t *testing.T) {
+	log := func(l *RedirectLogger) string {
+		l.Builder = &strings.Builder{}
+		l.Infof("1 became candidate  at term %d after %s", 2, "1m30.5s")
+		l.Debug("heartbeat timeout", "150ms")
+		l.Warningf("multi\nline")
+		return l.String()
+	}
+
+	// Output is preserved exactly by default.
+	require.Equal(t,
+		"INFO 1 became candidate  at term 2 after 1m30.5s\n"+
+			"DEBUG heartbeat timeout 150ms\n"+
+			"WARN multi\nline\n",
+		log(&RedirectLogger{}))
+
+	require.Equal(t,
+		"INFO 1 became candidate at term 2 after <duration>\n"+
+			"DEBUG heartbeat timeout <duration>\n"+
+			"WARN multi line\n",
+		log(&RedirectLogger{Compact: true}))
+
+	require.Equal(t,
+		"INFO 1 BECAME CANDIDATE  AT TERM 2 AFTER 1M30.5S\n"+
+			"DEBUG HEARTBEAT TIMEOUT 150MS\n"+
+			"WARN MULTI\nLINE\n",
+		log(&RedirectLogger{Compact: true, Normalizer: strings.ToUpper}))
+}
+
+func TestRedirectLoggerAssertClean(t *testing.T) {
+	l := &RedirectLogger{Builder: &strings.Builder{}}
+	l.Infof("1 became leader at term %d", 2)
+	l.Warningf("multi\nline")
+	require.NoError(t, l.AssertClean(3))
+
+	l.Errorf("1 failed to send message")
+	require.NoError(t, l.AssertClean(4))
+	require.EqualError(t, l.AssertClean(3),
+		"1 messages logged at ERROR or above:\nERROR 1 failed to send message")
+	require.EqualError(t, l.AssertClean(2),
+		"2 messages logged at WARN or above:\nWARN multi\nERROR 1 failed to send message")
+}
+
+func TestRedirectLoggerCheckpoint(t *testing.T) {
+	l := &RedirectLogger{Builder: &strings.Builder{}}
+	l.Infof("1 became follower at term %d", 1)
+	// Without a checkpoint, all of the output is returned.
+	require.Equal(t, "INFO 1 became follower at term 1\n", l.SinceCheckpoint())
+
+	l.Checkpoint()
+	require.Equal(t, "", l.SinceCheckpoint())
+	l.Infof("1 became candidate at term %d", 2)
+	require.Equal(t, "INFO 1 became candidate at term 2\n", l.SinceCheckpoint())
+	require.Equal(t, "INFO 1 became follower at term 1\n"+
+		"INFO 1 became candidate at term 2\n", l.String())
+
+	// Output dropped while quiet is not returned.
+	l.Checkpoint()
+	l.Lvl = len(lvlNames) - 1
+	l.Infof("1 became leader at term %d", 2)
+	l.Lvl = 0
+	require.Equal(t, "", l.SinceCheckpoint())
+
+	// Resetting the output resets the checkpoint.
+	l.Reset()
+	l.Infof("1 became leader at term %d", 2)
+	require.Equal(t, "INFO 1 became leader at term 2\n", l.SinceCheckpoint())
+
+	// Replacing the Builder returns all of its output.
+	l.Checkpoint()
+	l.Builder = &strings.Builder{}
+	l.Infof("1 became follower at term %d", 3)
+	require.Equal(t, "INFO 1 became follower at term 3\n", l.SinceCheckpoint())
+}
+
+func TestRedirectLoggerStdLogger(t *testing.T) {
+	l := &RedirectLogger{Builder: &strings.Builder{}, Lvl: 1}
+	info := l.StdLogger(1)
+
+	// Messages are terminated by a single newline, whether or not they had
+	// one.
+	info.Print("1 became leader at term 2\n")
+	info.Print("1 became follower at term 3")
+	info.Printf("1 became candidate at term %d", 4)
+	require.Equal(t, "INFO 1 became leader at term 2\n"+
+		"INFO 1 became follower at term 3\n"+
+		"INFO 1 became candidate at term 4\n", l.String())
+
+	// Messages below the level of the logger are dropped.
+	l.Reset()
+	l.StdLogger(0).Print("1 sent heartbeat")
+	require.Equal(t, "", l.String())
+	l.StdLogger(2).Print("1 dropped message")
+	require.Equal(t, "WARN 1 dropped message\n", l.String())
+}
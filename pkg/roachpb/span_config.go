@@ -103,6 +103,12 @@ func (s *SpanConfig) ValidateSystemTargetSpanConfig() error {
 	if s.ExcludeDataFromBackup {
 		return errors.AssertionFailedf("ExcludeDataFromBackup set on system span config")
 	}
+	if s.ForceProposalQuota {
+		return errors.AssertionFailedf("ForceProposalQuota set on system span config")
+	}
+	if len(s.ProposalQuotaExcludedFamilyIDs) != 0 {
+		return errors.AssertionFailedf("ProposalQuotaExcludedFamilyIDs set on system span config")
+	}
 	return nil
 }
 
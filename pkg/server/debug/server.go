@@ -220,6 +220,29 @@ func (ds *Server) RegisterWorkloadCollector(stores *kvserver.Stores) error {
 	return nil
 }
 
+// RegisterProposalQuotaCheck registers a web endpoint which verifies the
+// proposal quota invariants of all leader replicas on the node's stores and
+// reports any violations.
+func (ds *Server) RegisterProposalQuotaCheck(stores *kvserver.Stores) {
+	ds.mux.HandleFunc("/debug/proposal-quota-check",
+		func(w http.ResponseWriter, req *http.Request) {
+			var numViolations int
+			if err := stores.VisitStores(func(s *kvserver.Store) error {
+				for _, v := range s.CheckProposalQuotaInvariants() {
+					numViolations++
+					fmt.Fprintf(w, "s%d: %s\n", s.StoreID(), v)
+				}
+				return nil
+			}); err != nil {
+				fmt.Fprintf(w, "error checking proposal quota invariants: %v\n", err)
+				return
+			}
+			if numViolations == 0 {
+				fmt.Fprintln(w, "no proposal quota invariant violations found")
+			}
+		})
+}
+
 // GetLSMStats creates a mapping between store IDs and LSM stats for all of the
 // provided storage engines.
 func GetLSMStats(engines []storage.Engine) (map[roachpb.StoreID]string, error) {
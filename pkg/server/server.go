@@ -2162,6 +2162,9 @@ func (s *topLevelServer) PreStart(ctx context.Context) error {
 	// Register the ctc debug endpoints.
 	s.debug.RegisterClosedTimestampSideTransport(s.ctSender, s.node.storeCfg.ClosedTimestampReceiver)
 
+	// Register the proposal quota check debug endpoint.
+	s.debug.RegisterProposalQuotaCheck(s.node.stores)
+
 	// Start the closed timestamp loop.
 	s.ctSender.Run(workersCtx, state.nodeID)
 
@@ -750,9 +750,9 @@ func newSQLServer(ctx context.Context, cfg sqlServerArgs) (*SQLServer, error) {
 
 	distSQLMetrics := execinfra.MakeDistSQLMetrics(cfg.HistogramWindowInterval())
 	cfg.registry.AddMetricStruct(distSQLMetrics)
-	rowMetrics := sql.NewRowMetrics(false /* internal */)
+	rowMetrics := sql.NewRowMetrics(false /* internal */, cfg.HistogramWindowInterval())
 	cfg.registry.AddMetricStruct(rowMetrics)
-	internalRowMetrics := sql.NewRowMetrics(true /* internal */)
+	internalRowMetrics := sql.NewRowMetrics(true /* internal */, cfg.HistogramWindowInterval())
 	cfg.registry.AddMetricStruct(internalRowMetrics)
 
 	virtualSchemas, err := sql.NewVirtualSchemaHolder(ctx, cfg.Settings)
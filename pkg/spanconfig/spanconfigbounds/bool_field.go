@@ -41,6 +41,8 @@ func (f boolField) fieldValue(c *roachpb.SpanConfig) *bool {
 	switch f {
 	case globalReads:
 		return &c.GlobalReads
+	case forceProposalQuota:
+		return &c.ForceProposalQuota
 
 		// TODO(ajwerner): Decide what to do about these fields which do not exist
 		// zone configurations. For now, they can be set by the tenant.
@@ -53,6 +53,8 @@ var fields = [config.NumFields]Field{
 	constraints,
 	voterConstraints,
 	leasePreferences,
+	forceProposalQuota,
+	proposalQuotaExcludedFamilyIDs,
 }
 
 const (
@@ -65,4 +67,7 @@ const (
 	constraints      = constraintsConjunctionField(config.Constraints)
 	voterConstraints = constraintsConjunctionField(config.VoterConstraints)
 	leasePreferences = leasePreferencesField(config.LeasePreferences)
+
+	forceProposalQuota             = boolField(config.ForceProposalQuota)
+	proposalQuotaExcludedFamilyIDs = uint32sField(config.ProposalQuotaExcludedFamilyIDs)
 )
@@ -0,0 +1,50 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package spanconfigbounds
+
+import (
+	"github.com/cockroachdb/cockroach/pkg/config"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/redact"
+)
+
+type uint32sField int
+
+var _ field[[]uint32] = uint32sField(0)
+
+func (f uint32sField) SafeFormat(s redact.SafePrinter, verb rune) {
+	s.Printf("%s", config.Field(f))
+}
+
+func (f uint32sField) String() string {
+	return config.Field(f).String()
+}
+
+func (f uint32sField) FieldBound(b *Bounds) ValueBounds {
+	return unbounded{}
+}
+
+func (f uint32sField) FieldValue(c *roachpb.SpanConfig) Value {
+	return (*uint32sValue)(f.fieldValue(c))
+}
+
+func (f uint32sField) fieldValue(c *roachpb.SpanConfig) *[]uint32 {
+	switch f {
+	case proposalQuotaExcludedFamilyIDs:
+		return &c.ProposalQuotaExcludedFamilyIDs
+	default:
+		// This is safe because we test that all the fields in the proto have
+		// a corresponding field, and we call this for each of them, and the user
+		// never provides the input to this function.
+		panic(errors.AssertionFailedf("failed to look up field %s", f))
+	}
+}
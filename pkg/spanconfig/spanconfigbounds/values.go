@@ -61,6 +61,15 @@ func (l leasePreferencesValue) SafeFormat(s interfaces.SafePrinter, verb rune) {
 	s.Printf("%v", []roachpb.LeasePreference(l))
 }
 
+type uint32sValue []uint32
+
+func (u uint32sValue) String() string {
+	return fmt.Sprint([]uint32(u))
+}
+func (u uint32sValue) SafeFormat(s interfaces.SafePrinter, verb rune) {
+	s.Printf("%v", []uint32(u))
+}
+
 type boolValue bool
 
 func (b boolValue) String() string {
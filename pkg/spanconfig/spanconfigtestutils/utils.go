@@ -669,6 +669,12 @@ func PrintSpanConfigDiffedAgainstDefaults(conf roachpb.SpanConfig) string {
 	if conf.ExcludeDataFromBackup != defaultConf.ExcludeDataFromBackup {
 		diffs = append(diffs, fmt.Sprintf("exclude_data_from_backup=%v", conf.ExcludeDataFromBackup))
 	}
+	if conf.ForceProposalQuota != defaultConf.ForceProposalQuota {
+		diffs = append(diffs, fmt.Sprintf("force_proposal_quota=%v", conf.ForceProposalQuota))
+	}
+	if len(conf.ProposalQuotaExcludedFamilyIDs) != 0 {
+		diffs = append(diffs, fmt.Sprintf("proposal_quota_excluded_family_ids=%v", conf.ProposalQuotaExcludedFamilyIDs))
+	}
 
 	return strings.Join(diffs, " ")
 }
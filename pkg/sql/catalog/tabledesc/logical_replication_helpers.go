@@ -23,38 +23,55 @@ import (
 
 // CheckLogicalReplicationCompatibility verifies that the destination table
 // descriptor is a valid target for logical replication and is equivalent to the
-// source table.
+// source table. The error reports the first issue found by
+// LogicalReplicationCompatibilityIssues, with any others as details.
 func CheckLogicalReplicationCompatibility(
 	src, dst *descpb.TableDescriptor, skipTableEquivalenceCheck bool,
 ) error {
 	const cannotLDRMsg = "cannot create logical replication stream"
-	if !skipTableEquivalenceCheck {
-		if err := checkSrcDstColsMatch(src, dst); err != nil {
-			return pgerror.Wrapf(err, pgcode.InvalidTableDefinition, cannotLDRMsg)
-		}
+	issues := LogicalReplicationCompatibilityIssues(src, dst, skipTableEquivalenceCheck)
+	if len(issues) == 0 {
+		return nil
 	}
-	if err := checkColumnFamilies(dst); err != nil {
-		return pgerror.Wrapf(err, pgcode.InvalidTableDefinition, cannotLDRMsg)
+	err := pgerror.Wrapf(issues[0], pgcode.InvalidTableDefinition, cannotLDRMsg)
+	for _, issue := range issues[1:] {
+		err = errors.WithDetail(err, issue.Error())
 	}
+	return err
+}
 
-	if err := checkCompositeTypesInPrimaryKey(dst); err != nil {
-		return pgerror.Wrapf(err, pgcode.InvalidTableDefinition, cannotLDRMsg)
+// LogicalReplicationCompatibilityIssues returns every reason the destination
+// table descriptor is not a valid target for logical replication from the
+// source table, or nil if it is. It does not stop at the first issue, so that
+// all of the problems which would prevent a stream from being created or cause
+// its rows to be sent to the DLQ can be reported up front.
+func LogicalReplicationCompatibilityIssues(
+	src, dst *descpb.TableDescriptor, skipTableEquivalenceCheck bool,
+) []error {
+	var issues []error
+	if !skipTableEquivalenceCheck {
+		issues = append(issues, srcDstColMismatches(src, dst)...)
 	}
-
-	if err := checkExpressionEvaluation(dst); err != nil {
-		return pgerror.Wrapf(err, pgcode.InvalidTableDefinition, cannotLDRMsg)
+	for _, check := range []func(*descpb.TableDescriptor) error{
+		checkColumnFamilies,
+		checkCompositeTypesInPrimaryKey,
+		checkExpressionEvaluation,
+	} {
+		if err := check(dst); err != nil {
+			issues = append(issues, err)
+		}
 	}
 	if !skipTableEquivalenceCheck {
-		if err := checkUniqueIndexesMatch(src, dst); err != nil {
-			return pgerror.Wrapf(err, pgcode.InvalidTableDefinition, cannotLDRMsg)
-		}
-
-		if err := checkCheckConstraintsMatch(src, dst); err != nil {
-			return pgerror.Wrapf(err, pgcode.InvalidTableDefinition, cannotLDRMsg)
+		for _, check := range []func(src, dst *descpb.TableDescriptor) error{
+			checkUniqueIndexesMatch,
+			checkCheckConstraintsMatch,
+		} {
+			if err := check(src, dst); err != nil {
+				issues = append(issues, err)
+			}
 		}
 	}
-
-	return nil
+	return issues
 }
 
 // We disallow expression evaluation (e.g., virtual columns that appear in an
@@ -137,49 +154,82 @@ func checkColumnFamilies(dst *descpb.TableDescriptor) error {
 	return nil
 }
 
+// srcDstColMismatches returns the reasons the source and destination table's
+// columns do not match. At most one reason is returned for each column.
+//
 // All column names and types must match with the source table’s columns. The KV
 // and SQL write path ingestion side logic assumes that src and dst columns
 // match. If they don’t, the LDR job will DLQ these rows and move on.
-func checkSrcDstColsMatch(src *descpb.TableDescriptor, dst *descpb.TableDescriptor) error {
+func srcDstColMismatches(src *descpb.TableDescriptor, dst *descpb.TableDescriptor) []error {
 	if len(src.Columns) != len(dst.Columns) {
-		return errors.Newf(
+		mismatches := []error{errors.Newf(
 			"destination table %s has %d columns, but the source table %s has %d columns",
 			dst.Name, len(dst.Columns), src.Name, len(src.Columns),
-		)
+		)}
+		// Point out the columns responsible for the mismatch, if any.
+		srcNames := make(map[string]struct{}, len(src.Columns))
+		for i := range src.Columns {
+			srcNames[src.Columns[i].Name] = struct{}{}
+		}
+		dstNames := make(map[string]struct{}, len(dst.Columns))
+		for i := range dst.Columns {
+			dstNames[dst.Columns[i].Name] = struct{}{}
+		}
+		for i := range src.Columns {
+			if _, ok := dstNames[src.Columns[i].Name]; !ok {
+				mismatches = append(mismatches, errors.Newf(
+					"destination table %s is missing source table %s column %s",
+					dst.Name, src.Name, src.Columns[i].Name,
+				))
+			}
+		}
+		for i := range dst.Columns {
+			if _, ok := srcNames[dst.Columns[i].Name]; !ok {
+				mismatches = append(mismatches, errors.Newf(
+					"destination table %s column %s does not exist in the source table %s",
+					dst.Name, dst.Columns[i].Name, src.Name,
+				))
+			}
+		}
+		return mismatches
 	}
+	var mismatches []error
 	for i := range src.Columns {
 		srcCol := src.Columns[i]
 		dstCol := dst.Columns[i]
 
 		if srcCol.Name != dstCol.Name {
-			return errors.Newf(
+			mismatches = append(mismatches, errors.Newf(
 				"destination table %s column %s at position %d does not match source table %s column %s",
 				dst.Name, dstCol.Name, i, src.Name, srcCol.Name,
-			)
+			))
+			continue
 		}
 
 		if srcCol.Nullable != dstCol.Nullable {
-			return errors.Newf(
+			mismatches = append(mismatches, errors.Newf(
 				"destination table %s column %s has nullable=%t, but the source table %s has nullable=%t",
 				dst.Name, dstCol.Name, dstCol.Nullable, src.Name, srcCol.Nullable,
-			)
+			))
+			continue
 		}
 
 		if dstCol.Type.UserDefined() {
-			return errors.Newf(
+			mismatches = append(mismatches, errors.Newf(
 				"destination table %s column %s has user-defined type %s",
 				dst.Name, dstCol.Name, dstCol.Type.SQLStringForError(),
-			)
+			))
+			continue
 		}
 
 		if !srcCol.Type.Identical(dstCol.Type) {
-			return errors.Newf(
+			mismatches = append(mismatches, errors.Newf(
 				"destination table %s column %s has type %s, but the source table %s has type %s",
 				dst.Name, dstCol.Name, dstCol.Type.SQLStringForError(), src.Name, srcCol.Type.SQLStringForError(),
-			)
+			))
 		}
 	}
-	return nil
+	return mismatches
 }
 
 // The unique indexes on the source and destination tables must have the same
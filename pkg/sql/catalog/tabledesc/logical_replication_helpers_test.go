@@ -0,0 +1,94 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package tabledesc_test
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogicalReplicationCompatibilityIssues(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	makeTable := func(name string, cols ...descpb.ColumnDescriptor) *descpb.TableDescriptor {
+		for i := range cols {
+			cols[i].ID = descpb.ColumnID(i + 1)
+		}
+		return &descpb.TableDescriptor{
+			Name:    name,
+			Columns: cols,
+			Families: []descpb.ColumnFamilyDescriptor{
+				{Name: "primary", ID: 0},
+			},
+			PrimaryIndex: descpb.IndexDescriptor{
+				ID:           1,
+				KeyColumnIDs: []descpb.ColumnID{1},
+			},
+		}
+	}
+	col := func(name string, typ *types.T) descpb.ColumnDescriptor {
+		return descpb.ColumnDescriptor{Name: name, Type: typ}
+	}
+	messages := func(errs []error) []string {
+		var msgs []string
+		for _, err := range errs {
+			msgs = append(msgs, err.Error())
+		}
+		return msgs
+	}
+
+	src := makeTable("src", col("k", types.Int), col("a", types.String), col("b", types.Int))
+
+	t.Run("compatible", func(t *testing.T) {
+		dst := makeTable("dst", col("k", types.Int), col("a", types.String), col("b", types.Int))
+		require.Empty(t, tabledesc.LogicalReplicationCompatibilityIssues(src, dst, false /* skipTableEquivalenceCheck */))
+		require.NoError(t, tabledesc.CheckLogicalReplicationCompatibility(src, dst, false /* skipTableEquivalenceCheck */))
+	})
+
+	t.Run("every issue is reported", func(t *testing.T) {
+		dst := makeTable("dst", col("k", types.Int), col("a", types.Int), col("c", types.Int))
+		dst.Families = append(dst.Families, descpb.ColumnFamilyDescriptor{Name: "f1", ID: 1})
+		require.Equal(t, []string{
+			"destination table dst column a has type INT8, but the source table src has type STRING",
+			"destination table dst column c at position 2 does not match source table src column b",
+			"table dst has more than one column family",
+		}, messages(tabledesc.LogicalReplicationCompatibilityIssues(src, dst, false /* skipTableEquivalenceCheck */)))
+		// CREATE LOGICAL REPLICATION reports the first issue, and the others as
+		// details.
+		err := tabledesc.CheckLogicalReplicationCompatibility(src, dst, false /* skipTableEquivalenceCheck */)
+		require.ErrorContains(t, err, "column a has type INT8")
+		require.ElementsMatch(t, []string{
+			"destination table dst column c at position 2 does not match source table src column b",
+			"table dst has more than one column family",
+		}, errors.GetAllDetails(err))
+
+		// Skipping the equivalence check only reports the destination's issues.
+		require.Equal(t, []string{
+			"table dst has more than one column family",
+		}, messages(tabledesc.LogicalReplicationCompatibilityIssues(src, dst, true /* skipTableEquivalenceCheck */)))
+	})
+
+	t.Run("missing columns", func(t *testing.T) {
+		dst := makeTable("dst", col("k", types.Int), col("c", types.Int))
+		require.Equal(t, []string{
+			"destination table dst has 2 columns, but the source table src has 3 columns",
+			"destination table dst is missing source table src column a",
+			"destination table dst is missing source table src column b",
+			"destination table dst column c does not exist in the source table src",
+		}, messages(tabledesc.LogicalReplicationCompatibilityIssues(src, dst, false /* skipTableEquivalenceCheck */)))
+	})
+}
@@ -135,6 +135,23 @@ func init() {
 				c.InheritedLeasePreferences = false
 			},
 		},
+		{
+			Field:        config.ForceProposalQuota,
+			RequiredType: types.Bool,
+			Setter: func(c *zonepb.ZoneConfig, d tree.Datum) {
+				c.ForceProposalQuota = proto.Bool(bool(tree.MustBeDBool(d)))
+			},
+		},
+		{
+			Field:        config.ProposalQuotaExcludedFamilyIDs,
+			RequiredType: types.String,
+			Setter: func(c *zonepb.ZoneConfig, d tree.Datum) {
+				var familyIDs []uint32
+				loadYAML(&familyIDs, string(tree.MustBeDString(d)))
+				c.ProposalQuotaExcludedFamilyIDs = familyIDs
+				c.NullProposalQuotaExcludedFamilyIDsIsEmpty = true
+			},
+		},
 	}
 	SupportedZoneConfigOptions = make(map[tree.Name]ZoneConfigOption, len(opts))
 	ZoneOptionKeys = make([]string, len(opts))
@@ -82,6 +82,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/util/log/severity"
 	"github.com/cockroachdb/cockroach/pkg/util/metric"
 	"github.com/cockroachdb/cockroach/pkg/util/mon"
+	"github.com/cockroachdb/cockroach/pkg/util/quotapool"
 	"github.com/cockroachdb/cockroach/pkg/util/sentryutil"
 	"github.com/cockroachdb/cockroach/pkg/util/stop"
 	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
@@ -2236,10 +2237,13 @@ func (ex *connExecutor) run(
 	if !ex.activated {
 		ex.activate(ctx, parentMon, reserved)
 	}
+	sessionID := ex.planner.extendedEvalCtx.SessionID
+	// Attribute the session's writes to it for the purposes of fair proposal
+	// quota acquisition (see kv.raft.proposal_quota.session_fairness.enabled).
+	ctx = quotapool.ContextWithGroupKey(ctx, sessionID.String())
 	ex.ctxHolder.connCtx = ctx
 	ex.onCancelSession = onCancel
 
-	sessionID := ex.planner.extendedEvalCtx.SessionID
 	ex.server.cfg.SessionRegistry.register(sessionID, ex.queryCancelKey, ex)
 
 	defer func() {
@@ -58,7 +58,13 @@ WHERE job_type = 'LOGICAL REPLICATION'
 		payload)->'logicalReplicationDetails'->'defaultConflictResolution'->>'conflictResolutionType', 'LWW') AS conflict_resolution_type,
 	crdb_internal.pb_to_json(
 		'cockroach.sql.jobs.jobspb.Payload',
-		payload)->>'description' AS description`
+		payload)->>'description' AS description,
+	IFNULL(crdb_internal.pb_to_json(
+		'cockroach.sql.jobs.jobspb.Progress',
+		job_info.progress)->'LogicalReplication'->'stats'->>'eventsIngested', '0')::INT AS events_ingested,
+	IFNULL(crdb_internal.pb_to_json(
+		'cockroach.sql.jobs.jobspb.Progress',
+		job_info.progress)->'LogicalReplication'->'stats'->>'eventsDlqed', '0')::INT AS events_dlqed`
 )
 
 func (d *delegator) delegateShowLogicalReplicationJobs(
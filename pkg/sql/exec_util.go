@@ -3420,6 +3420,10 @@ func (m *sessionDataMutator) SetDeadlockTimeout(timeout time.Duration) {
 	m.data.DeadlockTimeout = timeout
 }
 
+func (m *sessionDataMutator) SetProposalQuotaMaxWaiters(val int64) {
+	m.data.ProposalQuotaMaxWaiters = val
+}
+
 func (m *sessionDataMutator) SetIdleInSessionTimeout(timeout time.Duration) {
 	m.data.IdleInSessionTimeout = timeout
 }
@@ -3877,6 +3881,10 @@ func (m *sessionDataMutator) SetOptimizerPushLimitIntoProjectFilteredScan(val bo
 	m.data.OptimizerPushLimitIntoProjectFilteredScan = val
 }
 
+func (m *sessionDataMutator) SetDecodeKVWrites(val bool) {
+	m.data.DecodeKVWrites = val
+}
+
 // Utility functions related to scrubbing sensitive information on SQL Stats.
 
 // quantizeCounts ensures that the Count field in the
@@ -4014,10 +4022,18 @@ func TestingDescsTxn(
 
 // NewRowMetrics creates a rowinfra.Metrics struct for either internal or user
 // queries.
-func NewRowMetrics(internal bool) rowinfra.Metrics {
+func NewRowMetrics(internal bool, histogramWindow time.Duration) rowinfra.Metrics {
 	return rowinfra.Metrics{
 		MaxRowSizeLogCount: metric.NewCounter(getMetricMeta(rowinfra.MetaMaxRowSizeLog, internal)),
 		MaxRowSizeErrCount: metric.NewCounter(getMetricMeta(rowinfra.MetaMaxRowSizeErr, internal)),
+		FamilyValueBytes: metric.NewHistogram(metric.HistogramOptions{
+			Metadata:     getMetricMeta(rowinfra.MetaFamilyValueBytes, internal),
+			Duration:     histogramWindow,
+			BucketConfig: metric.DataSize16MBBuckets,
+			Mode:         metric.HistogramModePrometheus,
+		}),
+		OriginTimestampCPuts: metric.NewCounter(getMetricMeta(rowinfra.MetaOriginTimestampCPuts, internal)),
+		Puts:                 metric.NewCounter(getMetricMeta(rowinfra.MetaPuts, internal)),
 	}
 }
 
@@ -309,10 +309,17 @@ func (ex *connExecutor) recordStatementSummary(
 func (ex *connExecutor) updateOptCounters(planFlags planFlags) {
 	m := &ex.metrics.EngineMetrics
 
+	stats := ex.sessionData().PlanCacheStats
 	if planFlags.IsSet(planFlagOptCacheHit) {
 		m.SQLOptPlanCacheHits.Inc(1)
+		if stats != nil {
+			stats.Hits.Add(1)
+		}
 	} else if planFlags.IsSet(planFlagOptCacheMiss) {
 		m.SQLOptPlanCacheMisses.Inc(1)
+		if stats != nil {
+			stats.Misses.Add(1)
+		}
 	}
 }
 
@@ -710,6 +710,32 @@ func TestInternalExecutorEncountersRetry(t *testing.T) {
 	// the retries.
 }
 
+// TestInternalExecutorPlanCacheStats verifies that the plan cache hits and
+// misses of internal executor statements are recorded into the PlanCacheStats
+// passed in the session data override.
+func TestInternalExecutorPlanCacheStats(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	s := serverutils.StartServerOnly(t, base.TestServerArgs{})
+	defer s.Stopper().Stop(ctx)
+
+	ie := s.InternalExecutor().(*sql.InternalExecutor)
+	var stats sessiondata.PlanCacheStats
+	override := sessiondata.InternalExecutorOverride{User: username.NodeUserName(), PlanCacheStats: &stats}
+	for i := 0; i < 2; i++ {
+		_, err := ie.QueryRowEx(
+			ctx, "plan-cache-stats", nil /* txn */, override,
+			"SELECT name FROM system.namespace WHERE id = $1", 1,
+		)
+		require.NoError(t, err)
+	}
+	// The second execution at least reuses the plan cached by the first.
+	require.Equal(t, int64(2), stats.Hits.Load()+stats.Misses.Load())
+	require.GreaterOrEqual(t, stats.Hits.Load(), int64(1))
+}
+
 // TestInternalExecutorSyntheticDesc injects a synthetic descriptor
 // into a new transaction and confirms that existing descriptors are
 // replaced for both new and old transactions
@@ -11,14 +11,23 @@
 package sql_test
 
 import (
+	"bytes"
 	"context"
 	gosql "database/sql"
+	"sync/atomic"
 	"testing"
 
+	"github.com/cockroachdb/cockroach/pkg/kv/kvpb"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/testutils"
 	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/sqlutils"
 	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/require"
 )
 
 // Regression tests for #22304.
@@ -92,3 +101,66 @@ INSERT INTO d.a(a) VALUES (1);
 		}
 	}
 }
+
+// TestAttributeIndexWrites verifies that mutation batches only identify the
+// primary index of the table they write to while
+// sql.mutations.attribute_index_writes.enabled is set.
+func TestAttributeIndexWrites(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	var tablePrefix atomic.Value
+	tablePrefix.Store(roachpb.Key(nil))
+	var mu struct {
+		syncutil.Mutex
+		primaryIndexIDs []map[uint32]uint32
+	}
+	params, _ := createTestServerParams()
+	params.Knobs.Store = &kvserver.StoreTestingKnobs{
+		TestingRequestFilter: func(_ context.Context, ba *kvpb.BatchRequest) *kvpb.Error {
+			prefix := tablePrefix.Load().(roachpb.Key)
+			if prefix == nil {
+				return nil
+			}
+			for _, ru := range ba.Requests {
+				if kvpb.IsIntentWrite(ru.GetInner()) && bytes.HasPrefix(ru.GetInner().Header().Key, prefix) {
+					mu.Lock()
+					defer mu.Unlock()
+					mu.primaryIndexIDs = append(mu.primaryIndexIDs, ba.PrimaryIndexIDs)
+					return nil
+				}
+			}
+			return nil
+		},
+	}
+	srv, db, _ := serverutils.StartServer(t, params)
+	defer srv.Stopper().Stop(context.Background())
+	s := srv.ApplicationLayer()
+	runner := sqlutils.MakeSQLRunner(db)
+
+	runner.Exec(t, `CREATE TABLE t (k INT PRIMARY KEY, v INT, INDEX (v))`)
+	var tableID uint32
+	runner.QueryRow(t, `SELECT 't'::regclass::oid`).Scan(&tableID)
+	tablePrefix.Store(s.Codec().TablePrefix(tableID))
+	lastPrimaryIndexIDs := func(t *testing.T) map[uint32]uint32 {
+		mu.Lock()
+		defer mu.Unlock()
+		require.NotEmpty(t, mu.primaryIndexIDs)
+		return mu.primaryIndexIDs[len(mu.primaryIndexIDs)-1]
+	}
+
+	runner.Exec(t, `INSERT INTO t VALUES (1, 1)`)
+	require.Empty(t, lastPrimaryIndexIDs(t))
+
+	runner.Exec(t, `SET CLUSTER SETTING sql.mutations.attribute_index_writes.enabled = true`)
+	k := 1
+	testutils.SucceedsSoon(t, func() error {
+		k++
+		runner.Exec(t, `INSERT INTO t VALUES ($1, $1)`, k)
+		if ids := lastPrimaryIndexIDs(t); len(ids) == 0 {
+			return errors.New("batch does not identify the primary index yet")
+		}
+		return nil
+	})
+	require.Equal(t, map[uint32]uint32{tableID: 1}, lastPrimaryIndexIDs(t))
+}
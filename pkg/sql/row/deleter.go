@@ -36,6 +36,7 @@ type Deleter struct {
 	// For allocation avoidance.
 	key         roachpb.Key
 	rawValueBuf []byte
+	putter      KVBatchAdapter
 }
 
 // MakeDeleter creates a Deleter for the given table.
@@ -116,6 +117,8 @@ func (rd *Deleter) DeleteRow(
 	oth *OriginTimestampCPutHelper,
 	traceKV bool,
 ) error {
+	rd.putter.Batch = b
+	putter := rd.Helper.wrapPutter(&rd.putter)
 
 	// Delete the row from any secondary indices.
 	for i := range rd.Helper.Indexes {
@@ -139,7 +142,7 @@ func (rd *Deleter) DeleteRow(
 			return err
 		}
 		for _, e := range entries {
-			if err := rd.Helper.deleteIndexEntry(ctx, b, rd.Helper.Indexes[i], rd.Helper.secIndexValDirs[i], &e, traceKV); err != nil {
+			if err := rd.Helper.deleteIndexEntry(ctx, putter, rd.Helper.Indexes[i], rd.Helper.secIndexValDirs[i], &e, traceKV); err != nil {
 				return err
 			}
 		}
@@ -173,12 +176,12 @@ func (rd *Deleter) DeleteRow(
 			if prevValue.IsPresent() {
 				expValue = prevValue.TagAndDataBytes()
 			}
-			oth.DelWithCPut(ctx, &KVBatchAdapter{b}, &rd.key, expValue, traceKV)
+			oth.DelWithCPut(ctx, putter, &rd.key, expValue, traceKV)
 		} else {
 			if traceKV {
 				log.VEventf(ctx, 2, "Del %s", keys.PrettyPrint(rd.Helper.primIndexValDirs, rd.key))
 			}
-			b.Del(&rd.key)
+			putter.Del(&rd.key)
 		}
 
 		rd.key = nil
@@ -194,7 +197,8 @@ func (rd *Deleter) DeleteRow(
 func (rd *Deleter) encodeValueForPrimaryIndexFamily(
 	family *descpb.ColumnFamilyDescriptor, values []tree.Datum,
 ) (roachpb.Value, error) {
-	if len(family.ColumnIDs) == 1 && family.ColumnIDs[0] == family.DefaultColumnID && family.ID != 0 {
+	valueCols := indexValueColumns{helper: &rd.Helper, primary: true}
+	if rd.Helper.ValueCodec.encodesSingleValue(&valueCols, family, values, rd.FetchColIDtoRowIndex) {
 		idx, ok := rd.FetchColIDtoRowIndex.Get(family.DefaultColumnID)
 		if !ok {
 			return roachpb.Value{}, nil
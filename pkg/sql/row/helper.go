@@ -36,6 +36,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/util/log/logpb"
 	"github.com/cockroachdb/cockroach/pkg/util/log/severity"
 	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/cockroachdb/errors"
 )
 
 const (
@@ -85,6 +86,22 @@ type RowHelper struct {
 	primaryIndexValueCols catalog.TableColSet
 	sortedColumnFamilies  map[descpb.FamilyID][]descpb.ColumnID
 
+	// ValueCodec is the format in which primary index values are written. It
+	// defaults to the current format.
+	ValueCodec ValueCodecVersion
+
+	// DecodeWrites, if set, is called with a human-readable rendering of each
+	// write made with the helper, for the decode_kv_writes session variable.
+	// The writes themselves are unaffected. See DebugDecodePutter.
+	DecodeWrites func(line string)
+	decoder      DebugDecodePutter
+
+	// TestingDisableSingleFamilyFastPath makes the rows of tables with a
+	// single column family be encoded by the general path of
+	// prepareInsertOrUpdateBatchForIndex rather than by
+	// prepareSingleFamilyBatch, e.g. to compare the two in tests.
+	TestingDisableSingleFamilyFastPath bool
+
 	// Used to check row size.
 	maxRowSizeLog, maxRowSizeErr uint32
 	internal                     bool
@@ -148,6 +165,24 @@ func (rh *RowHelper) encodeIndexes(
 	return primaryIndexKey, secondaryIndexEntries, nil
 }
 
+// wrapPutter returns b wrapped in a DebugDecodePutter if DecodeWrites is set.
+// The returned Putter is only valid until the next call to wrapPutter.
+func (rh *RowHelper) wrapPutter(b Putter) Putter {
+	if rh.DecodeWrites == nil {
+		return b
+	}
+	if _, ok := b.(*DebugDecodePutter); ok {
+		// The writes are already rendered, e.g. by the Updater whose Inserter
+		// this is.
+		return b
+	}
+	rh.decoder.Putter = b
+	rh.decoder.Codec = rh.Codec
+	rh.decoder.Table = rh.TableDesc
+	rh.decoder.Emit = rh.DecodeWrites
+	return &rh.decoder
+}
+
 func (rh *RowHelper) Init() {
 	rh.PrimaryIndexKeyPrefix = rowenc.MakeIndexKeyPrefix(
 		rh.Codec, rh.TableDesc.GetID(), rh.TableDesc.GetPrimaryIndexID(),
@@ -223,16 +258,24 @@ func (rh *RowHelper) SkipColumnNotInPrimaryIndexValue(
 		rh.primaryIndexKeyCols = rh.TableDesc.GetPrimaryIndex().CollectKeyColumnIDs()
 		rh.primaryIndexValueCols = rh.TableDesc.GetPrimaryIndex().CollectPrimaryStoredColumnIDs()
 	}
-	if !rh.primaryIndexKeyCols.Contains(colID) {
-		return !rh.primaryIndexValueCols.Contains(colID)
+	return skipColumnNotInIndexValue(rh.primaryIndexKeyCols, rh.primaryIndexValueCols, colID, value)
+}
+
+// skipColumnNotInIndexValue is SkipColumnNotInPrimaryIndexValue for an index
+// with the primary index encoding whose key consists of keyCols and whose
+// value stores valueCols.
+func skipColumnNotInIndexValue(
+	keyCols, valueCols catalog.TableColSet, colID descpb.ColumnID, value tree.Datum,
+) bool {
+	if !keyCols.Contains(colID) {
+		return !valueCols.Contains(colID)
 	}
 	if cdatum, ok := value.(tree.CompositeDatum); ok {
 		// Composite columns are encoded in both the key and the value.
 		return !cdatum.IsComposite()
 	}
-	// Skip primary key columns as their values are encoded in the key of
-	// each family. Family 0 is guaranteed to exist and acts as a
-	// sentinel.
+	// Skip key columns as their values are encoded in the key of each
+	// family. Family 0 is guaranteed to exist and acts as a sentinel.
 	return true
 }
 
@@ -294,6 +337,30 @@ func (rh *RowHelper) CheckRowSize(
 	return nil
 }
 
+// recordFamilyValueBytes records the size of a value written for a column
+// family of the primary index.
+func (rh *RowHelper) recordFamilyValueBytes(valueBytes []byte) {
+	if rh.metrics != nil && rh.metrics.FamilyValueBytes != nil {
+		rh.metrics.FamilyValueBytes.RecordValue(int64(len(valueBytes)))
+	}
+}
+
+// recordFamilyWrite records a write, or delete, of a column family of the
+// primary index, which is conditional on the origin timestamp of the existing
+// row for the writes made by logical replication.
+func (rh *RowHelper) recordFamilyWrite(originTimestampCPut bool) {
+	if rh.metrics == nil {
+		return
+	}
+	if originTimestampCPut {
+		if rh.metrics.OriginTimestampCPuts != nil {
+			rh.metrics.OriginTimestampCPuts.Inc(1)
+		}
+	} else if rh.metrics.Puts != nil {
+		rh.metrics.Puts.Inc(1)
+	}
+}
+
 var deleteEncoding protoutil.Message = &rowencpb.IndexValueWrapper{
 	Value:   nil,
 	Deleted: true,
@@ -301,7 +368,7 @@ var deleteEncoding protoutil.Message = &rowencpb.IndexValueWrapper{
 
 func (rh *RowHelper) deleteIndexEntry(
 	ctx context.Context,
-	batch *kv.Batch,
+	b Putter,
 	index catalog.Index,
 	valDirs []encoding.Direction,
 	entry *rowenc.IndexEntry,
@@ -312,7 +379,7 @@ func (rh *RowHelper) deleteIndexEntry(
 			log.VEventf(ctx, 2, "Put (delete) %s", entry.Key)
 		}
 
-		batch.Put(entry.Key, deleteEncoding)
+		b.Put(entry.Key, deleteEncoding)
 	} else {
 		if traceKV {
 			if valDirs != nil {
@@ -322,7 +389,7 @@ func (rh *RowHelper) deleteIndexEntry(
 			}
 		}
 
-		batch.Del(entry.Key)
+		b.Del(entry.Key)
 	}
 	return nil
 }
@@ -336,6 +403,39 @@ func (oh *OriginTimestampCPutHelper) IsSet() bool {
 	return oh != nil && oh.OriginTimestamp.IsSet()
 }
 
+// Validate returns an error if the helper is inconsistent with oldValues, the
+// values of the row it is expected to replace, from which the expected values
+// of its CPuts are encoded. A set helper must be given old values, as without
+// them its CPuts expect the row not to exist, and an unset helper must not be,
+// as they would be ignored and the write would skip conflict detection.
+//
+// Inserts which do not overwrite the row are exempt: they expect the row not
+// to exist, so a set helper legitimately has no old values, and the caller
+// does not validate them (see prepareInsertOrUpdateBatch). It is meant to be
+// called under test builds, as a mismatch otherwise only shows up as
+// conflicts being detected incorrectly.
+func (oh *OriginTimestampCPutHelper) Validate(oldValues []tree.Datum) error {
+	if oh != nil && oh.ShouldWinTie && !oh.OriginTimestamp.IsSet() {
+		return errors.AssertionFailedf("origin timestamp CPut helper set to win ties without an origin timestamp")
+	}
+	if !oh.IsSet() {
+		if len(oldValues) > 0 {
+			return errors.AssertionFailedf(
+				"got %d old values without an origin timestamp CPut helper", len(oldValues))
+		}
+		return nil
+	}
+	if len(oldValues) == 0 {
+		return errors.AssertionFailedf("origin timestamp CPut helper got no old values")
+	}
+	for i := range oldValues {
+		if oldValues[i] == nil {
+			return errors.AssertionFailedf("origin timestamp CPut helper got a nil old value at index %d", i)
+		}
+	}
+	return nil
+}
+
 func (oh *OriginTimestampCPutHelper) CPutFn(
 	ctx context.Context,
 	b Putter,
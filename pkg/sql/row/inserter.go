@@ -134,6 +134,7 @@ func (ri *Inserter) InsertRow(
 		return errors.Errorf("got %d values but expected %d", len(values), len(ri.InsertCols))
 	}
 
+	b = ri.Helper.wrapPutter(b)
 	putFn := insertCPutFn
 	if overwrite {
 		putFn = insertPutFn
@@ -163,7 +164,7 @@ func (ri *Inserter) InsertRow(
 		&ri.Helper, primaryIndexKey, ri.InsertCols,
 		values, ri.InsertColIDtoRowIndex,
 		ri.InsertColIDtoRowIndex,
-		&ri.key, &ri.value, ri.valueBuf, putFn, oth, nil, overwrite, traceKV)
+		&ri.key, &ri.value, ri.valueBuf, putFn, oth, nil, ri.Helper.ValueCodec, overwrite, traceKV)
 	if err != nil {
 		return err
 	}
@@ -188,5 +189,5 @@ func (ri *Inserter) InsertRow(
 		}
 	}
 
-	return nil
+	return putterErr(b)
 }
@@ -13,12 +13,22 @@ package row
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"sort"
+	"strings"
 
+	"github.com/cockroachdb/cockroach/pkg/keys"
 	"github.com/cockroachdb/cockroach/pkg/kv"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/catalogkeys"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/rowenc/valueside"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/util/encoding"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/errors"
 )
 
 // Putter is an interface for layering functionality on the path from SQL
@@ -38,13 +48,35 @@ type Putter interface {
 	InitPutTuples(kys []roachpb.Key, values [][]byte)
 }
 
+// ErrPutter is implemented by Putters which can fail. Since the Putter methods
+// do not return errors, such a Putter records the first error it encounters and
+// returns it from Err. The writers taking a Putter check Err after writing each
+// row, and return the error if there is one.
+type ErrPutter interface {
+	Putter
+	Err() error
+}
+
+// putterErr returns the error recorded by p if it is an ErrPutter.
+func putterErr(p Putter) error {
+	if ep, ok := p.(ErrPutter); ok {
+		return ep.Err()
+	}
+	return nil
+}
+
 // TracePutter logs all requests, ie implements kv trace.
 type TracePutter struct {
 	Putter Putter
 	Ctx    context.Context
 }
 
-var _ Putter = &TracePutter{}
+var _ ErrPutter = &TracePutter{}
+
+// Err implements the ErrPutter interface.
+func (t *TracePutter) Err() error {
+	return putterErr(t.Putter)
+}
 
 func (t *TracePutter) CPut(key, value interface{}, expValue []byte) {
 	log.VEventfDepth(t.Ctx, 1, 2, "CPut %v -> %v", key, value)
@@ -186,7 +218,12 @@ type SortingPutter struct {
 	Putter Putter
 }
 
-var _ Putter = &SortingPutter{}
+var _ ErrPutter = &SortingPutter{}
+
+// Err implements the ErrPutter interface.
+func (s *SortingPutter) Err() error {
+	return putterErr(s.Putter)
+}
 
 func (s *SortingPutter) CPut(key, value interface{}, expValue []byte) {
 	s.Putter.CPut(key, value, expValue)
@@ -245,6 +282,836 @@ func (s *SortingPutter) InitPutTuples(kys []roachpb.Key, values [][]byte) {
 	s.Putter.InitPutTuples(kvs.Keys, kvs.Values)
 }
 
+// WriteOncePutter is a Putter which verifies that no key is written more than
+// once. It is meant for verifying IMPORT, which must never write the same key
+// twice: doing so indicates a duplicate primary key which would otherwise
+// silently overwrite the earlier row. Different column families of a row are
+// different keys and therefore don't conflict.
+//
+// Since the Putter interface does not return errors, the first violation is
+// recorded and must be checked for with Err. Writes are still passed through to
+// the wrapped Putter. All keys written are retained in memory, so this should
+// only be used when verification is explicitly requested.
+type WriteOncePutter struct {
+	Putter Putter
+	// Table is the table being written to, used in the error.
+	Table catalog.TableDescriptor
+
+	written map[string]struct{}
+	err     error
+}
+
+var _ ErrPutter = &WriteOncePutter{}
+
+// Err returns an error describing the first key which was written twice, if
+// any, or else the error of the wrapped Putter.
+func (w *WriteOncePutter) Err() error {
+	if w.err != nil {
+		return w.err
+	}
+	return putterErr(w.Putter)
+}
+
+func (w *WriteOncePutter) record(key roachpb.Key) {
+	if len(key) == 0 {
+		// Sparse bulk methods skip empty keys.
+		return
+	}
+	if w.written == nil {
+		w.written = make(map[string]struct{})
+	}
+	if _, ok := w.written[string(key)]; ok {
+		if w.err == nil {
+			w.err = errors.AssertionFailedf("key %s of table %s (%d) written more than once",
+				key, w.Table.GetName(), w.Table.GetID())
+		}
+		return
+	}
+	w.written[string(key)] = struct{}{}
+}
+
+// recordKey records a key passed to one of the single-key methods, which
+// accept both roachpb.Key and *roachpb.Key.
+func (w *WriteOncePutter) recordKey(key interface{}) {
+	switch k := key.(type) {
+	case *roachpb.Key:
+		w.record(*k)
+	case roachpb.Key:
+		w.record(k)
+	default:
+		if w.err == nil {
+			w.err = errors.AssertionFailedf("unexpected key type %T", key)
+		}
+	}
+}
+
+func (w *WriteOncePutter) recordAll(kys []roachpb.Key) {
+	for _, k := range kys {
+		w.record(k)
+	}
+}
+
+func (w *WriteOncePutter) CPut(key, value interface{}, expValue []byte) {
+	w.recordKey(key)
+	w.Putter.CPut(key, value, expValue)
+}
+
+func (w *WriteOncePutter) CPutWithOriginTimestamp(
+	key, value interface{}, expValue []byte, ts hlc.Timestamp, shouldWinTie bool,
+) {
+	w.recordKey(key)
+	w.Putter.CPutWithOriginTimestamp(key, value, expValue, ts, shouldWinTie)
+}
+
+func (w *WriteOncePutter) Put(key, value interface{}) {
+	w.recordKey(key)
+	w.Putter.Put(key, value)
+}
+
+func (w *WriteOncePutter) InitPut(key, value interface{}, failOnTombstones bool) {
+	w.recordKey(key)
+	w.Putter.InitPut(key, value, failOnTombstones)
+}
+
+func (w *WriteOncePutter) Del(key ...interface{}) {
+	for _, k := range key {
+		w.recordKey(k)
+	}
+	w.Putter.Del(key...)
+}
+
+func (w *WriteOncePutter) CPutValuesEmpty(kys []roachpb.Key, values []roachpb.Value) {
+	w.recordAll(kys)
+	w.Putter.CPutValuesEmpty(kys, values)
+}
+
+func (w *WriteOncePutter) CPutTuplesEmpty(kys []roachpb.Key, values [][]byte) {
+	w.recordAll(kys)
+	w.Putter.CPutTuplesEmpty(kys, values)
+}
+
+func (w *WriteOncePutter) PutBytes(kys []roachpb.Key, values [][]byte) {
+	w.recordAll(kys)
+	w.Putter.PutBytes(kys, values)
+}
+
+func (w *WriteOncePutter) InitPutBytes(kys []roachpb.Key, values [][]byte) {
+	w.recordAll(kys)
+	w.Putter.InitPutBytes(kys, values)
+}
+
+func (w *WriteOncePutter) PutTuples(kys []roachpb.Key, values [][]byte) {
+	w.recordAll(kys)
+	w.Putter.PutTuples(kys, values)
+}
+
+func (w *WriteOncePutter) InitPutTuples(kys []roachpb.Key, values [][]byte) {
+	w.recordAll(kys)
+	w.Putter.InitPutTuples(kys, values)
+}
+
+// FamilyOrderPutter is a Putter which verifies that the column families of each
+// row of the table's primary index are written in the order in which they
+// appear in the table descriptor, as prepareInsertOrUpdateBatch writes them,
+// and passes the writes through to the wrapped Putter. The origin timestamp
+// conditional puts of logical replication rely on this order: family 0, which
+// is always written, is the sentinel of the row, so its conditional put must be
+// the first one of the row for the row to win or lose as a whole.
+//
+// Writes to other indexes and tables are not checked. As with WriteOncePutter,
+// the first violation is recorded and must be checked for with Err. The order
+// is tracked across all the writes made to the Putter, so a new one should be
+// used for each row written.
+type FamilyOrderPutter struct {
+	Putter Putter
+	Codec  keys.SQLCodec
+	Table  catalog.TableDescriptor
+
+	// positions are the positions of the families in the table descriptor, by
+	// family ID.
+	positions map[descpb.FamilyID]int
+	// last is the position of the last family written of each row, by row
+	// prefix.
+	last map[string]int
+	err  error
+}
+
+var _ ErrPutter = &FamilyOrderPutter{}
+
+// Err returns an error describing the first column family which was written
+// out of order, if any, or else the error of the wrapped Putter.
+func (f *FamilyOrderPutter) Err() error {
+	if f.err != nil {
+		return f.err
+	}
+	return putterErr(f.Putter)
+}
+
+func (f *FamilyOrderPutter) record(key roachpb.Key) {
+	if f.err != nil || len(key) == 0 {
+		return
+	}
+	_, tableID, indexID, err := f.Codec.DecodeIndexPrefix(key)
+	if err != nil || descpb.ID(tableID) != f.Table.GetID() ||
+		descpb.IndexID(indexID) != f.Table.GetPrimaryIndexID() {
+		return
+	}
+	n, err := keys.GetRowPrefixLength(key)
+	if err != nil {
+		return
+	}
+	familyID, err := keys.DecodeFamilyKey(key)
+	if err != nil {
+		return
+	}
+	families := f.Table.GetFamilies()
+	if f.positions == nil {
+		f.positions = make(map[descpb.FamilyID]int, len(families))
+		for i := range families {
+			f.positions[families[i].ID] = i
+		}
+		f.last = make(map[string]int)
+	}
+	pos, ok := f.positions[descpb.FamilyID(familyID)]
+	if !ok {
+		f.err = errors.AssertionFailedf("key %s of table %s (%d) is in unknown column family %d",
+			key, f.Table.GetName(), f.Table.GetID(), familyID)
+		return
+	}
+	row := string(key[:n])
+	if last, ok := f.last[row]; ok && pos <= last {
+		f.err = errors.AssertionFailedf(
+			"column family %s of key %s of table %s (%d) written after column family %s",
+			families[pos].Name, key, f.Table.GetName(), f.Table.GetID(), families[last].Name)
+		return
+	}
+	f.last[row] = pos
+}
+
+func (f *FamilyOrderPutter) recordAll(kys []roachpb.Key) {
+	for _, k := range kys {
+		f.record(k)
+	}
+}
+
+func (f *FamilyOrderPutter) CPut(key, value interface{}, expValue []byte) {
+	f.record(opKey(key))
+	f.Putter.CPut(key, value, expValue)
+}
+
+func (f *FamilyOrderPutter) CPutWithOriginTimestamp(
+	key, value interface{}, expValue []byte, ts hlc.Timestamp, shouldWinTie bool,
+) {
+	f.record(opKey(key))
+	f.Putter.CPutWithOriginTimestamp(key, value, expValue, ts, shouldWinTie)
+}
+
+func (f *FamilyOrderPutter) Put(key, value interface{}) {
+	f.record(opKey(key))
+	f.Putter.Put(key, value)
+}
+
+func (f *FamilyOrderPutter) InitPut(key, value interface{}, failOnTombstones bool) {
+	f.record(opKey(key))
+	f.Putter.InitPut(key, value, failOnTombstones)
+}
+
+func (f *FamilyOrderPutter) Del(key ...interface{}) {
+	for _, k := range key {
+		f.record(opKey(k))
+	}
+	f.Putter.Del(key...)
+}
+
+func (f *FamilyOrderPutter) CPutValuesEmpty(kys []roachpb.Key, values []roachpb.Value) {
+	f.recordAll(kys)
+	f.Putter.CPutValuesEmpty(kys, values)
+}
+
+func (f *FamilyOrderPutter) CPutTuplesEmpty(kys []roachpb.Key, values [][]byte) {
+	f.recordAll(kys)
+	f.Putter.CPutTuplesEmpty(kys, values)
+}
+
+func (f *FamilyOrderPutter) PutBytes(kys []roachpb.Key, values [][]byte) {
+	f.recordAll(kys)
+	f.Putter.PutBytes(kys, values)
+}
+
+func (f *FamilyOrderPutter) InitPutBytes(kys []roachpb.Key, values [][]byte) {
+	f.recordAll(kys)
+	f.Putter.InitPutBytes(kys, values)
+}
+
+func (f *FamilyOrderPutter) PutTuples(kys []roachpb.Key, values [][]byte) {
+	f.recordAll(kys)
+	f.Putter.PutTuples(kys, values)
+}
+
+func (f *FamilyOrderPutter) InitPutTuples(kys []roachpb.Key, values [][]byte) {
+	f.recordAll(kys)
+	f.Putter.InitPutTuples(kys, values)
+}
+
+// FaultyPutter is a Putter for testing the handling of failed conditional
+// puts. It passes writes through to the wrapped Putter, except for the
+// conditional put numbered FailCPut, counting from 1 across both the single-key
+// and bulk methods, which is dropped and recorded as failing with Error, as KV
+// would fail it if its condition were not met. Use Err to check for the
+// failure.
+type FaultyPutter struct {
+	Putter Putter
+	// FailCPut is the number of the conditional put to fail. Zero disables
+	// failures.
+	FailCPut int
+	// Error is the error the conditional put fails with, e.g. a
+	// *kvpb.ConditionFailedError.
+	Error error
+
+	cputs int
+	err   error
+}
+
+var _ ErrPutter = &FaultyPutter{}
+
+// Err implements the ErrPutter interface.
+func (f *FaultyPutter) Err() error {
+	if f.err != nil {
+		return f.err
+	}
+	return putterErr(f.Putter)
+}
+
+// CPuts returns the number of conditional puts seen so far, including the
+// failed one.
+func (f *FaultyPutter) CPuts() int {
+	return f.cputs
+}
+
+// fail counts a conditional put, and returns true if it is to fail.
+func (f *FaultyPutter) fail() bool {
+	f.cputs++
+	if f.FailCPut == 0 || f.cputs != f.FailCPut {
+		return false
+	}
+	if f.err == nil {
+		f.err = f.Error
+	}
+	return true
+}
+
+// failedIndex counts the conditional puts of a bulk method, skipping the empty
+// keys they ignore, and returns the index of the one which is to fail or -1.
+func (f *FaultyPutter) failedIndex(kys []roachpb.Key) int {
+	failed := -1
+	for i, k := range kys {
+		if len(k) != 0 && f.fail() {
+			failed = i
+		}
+	}
+	return failed
+}
+
+func (f *FaultyPutter) CPut(key, value interface{}, expValue []byte) {
+	if f.fail() {
+		return
+	}
+	f.Putter.CPut(key, value, expValue)
+}
+
+func (f *FaultyPutter) CPutWithOriginTimestamp(
+	key, value interface{}, expValue []byte, ts hlc.Timestamp, shouldWinTie bool,
+) {
+	if f.fail() {
+		return
+	}
+	f.Putter.CPutWithOriginTimestamp(key, value, expValue, ts, shouldWinTie)
+}
+
+func (f *FaultyPutter) Put(key, value interface{}) {
+	f.Putter.Put(key, value)
+}
+
+func (f *FaultyPutter) InitPut(key, value interface{}, failOnTombstones bool) {
+	f.Putter.InitPut(key, value, failOnTombstones)
+}
+
+func (f *FaultyPutter) Del(key ...interface{}) {
+	f.Putter.Del(key...)
+}
+
+func (f *FaultyPutter) CPutValuesEmpty(kys []roachpb.Key, values []roachpb.Value) {
+	if i := f.failedIndex(kys); i >= 0 {
+		// The bulk methods skip empty keys, so drop the failed one by copying
+		// the keys with it emptied.
+		kys = append([]roachpb.Key(nil), kys...)
+		kys[i] = nil
+	}
+	f.Putter.CPutValuesEmpty(kys, values)
+}
+
+func (f *FaultyPutter) CPutTuplesEmpty(kys []roachpb.Key, values [][]byte) {
+	if i := f.failedIndex(kys); i >= 0 {
+		// See CPutValuesEmpty.
+		kys = append([]roachpb.Key(nil), kys...)
+		kys[i] = nil
+	}
+	f.Putter.CPutTuplesEmpty(kys, values)
+}
+
+func (f *FaultyPutter) PutBytes(kys []roachpb.Key, values [][]byte) {
+	f.Putter.PutBytes(kys, values)
+}
+
+func (f *FaultyPutter) InitPutBytes(kys []roachpb.Key, values [][]byte) {
+	f.Putter.InitPutBytes(kys, values)
+}
+
+func (f *FaultyPutter) PutTuples(kys []roachpb.Key, values [][]byte) {
+	f.Putter.PutTuples(kys, values)
+}
+
+func (f *FaultyPutter) InitPutTuples(kys []roachpb.Key, values [][]byte) {
+	f.Putter.InitPutTuples(kys, values)
+}
+
+// SequencedOp is a write recorded by SequencingPutter.
+type SequencedOp struct {
+	// Seq is the position of the write among all those made to the
+	// SequencingPutter, starting at 1.
+	Seq int
+	// Op is the name of the request, e.g. "CPut" or "InitPut".
+	Op  string
+	Key roachpb.Key
+}
+
+func (o SequencedOp) String() string {
+	return fmt.Sprintf("%d: %s %s", o.Seq, o.Op, o.Key)
+}
+
+// SequencingPutter is a Putter which assigns consecutive sequence numbers to
+// the writes passed through it and records them in Ops, so that the writes
+// issued by two runs can be compared one by one, e.g. to verify that the order
+// in which a row's keys are written is deterministic. Unlike TracePutter, only
+// the request and key of each write are retained, not the values. The bulk
+// methods record one write per non-empty key. Writes are passed through to the
+// wrapped Putter unchanged.
+type SequencingPutter struct {
+	Putter Putter
+	Ops    []SequencedOp
+
+	err error
+}
+
+var _ ErrPutter = &SequencingPutter{}
+
+// Err returns an error describing the first key of an unexpected type, if
+// any, or else the error of the wrapped Putter.
+func (s *SequencingPutter) Err() error {
+	if s.err != nil {
+		return s.err
+	}
+	return putterErr(s.Putter)
+}
+
+func (s *SequencingPutter) record(op string, key roachpb.Key) {
+	s.Ops = append(s.Ops, SequencedOp{
+		Seq: len(s.Ops) + 1,
+		Op:  op,
+		Key: append(roachpb.Key(nil), key...),
+	})
+}
+
+// recordKey records a key passed to one of the single-key methods, which
+// accept both roachpb.Key and *roachpb.Key.
+func (s *SequencingPutter) recordKey(op string, key interface{}) {
+	switch k := key.(type) {
+	case *roachpb.Key:
+		s.record(op, *k)
+	case roachpb.Key:
+		s.record(op, k)
+	default:
+		if s.err == nil {
+			s.err = errors.AssertionFailedf("unexpected key type %T", key)
+		}
+	}
+}
+
+func (s *SequencingPutter) recordAll(op string, kys []roachpb.Key) {
+	for _, k := range kys {
+		if len(k) == 0 {
+			continue
+		}
+		s.record(op, k)
+	}
+}
+
+func (s *SequencingPutter) CPut(key, value interface{}, expValue []byte) {
+	s.recordKey("CPut", key)
+	s.Putter.CPut(key, value, expValue)
+}
+
+func (s *SequencingPutter) CPutWithOriginTimestamp(
+	key, value interface{}, expValue []byte, ts hlc.Timestamp, shouldWinTie bool,
+) {
+	s.recordKey("CPutWithOriginTimestamp", key)
+	s.Putter.CPutWithOriginTimestamp(key, value, expValue, ts, shouldWinTie)
+}
+
+func (s *SequencingPutter) Put(key, value interface{}) {
+	s.recordKey("Put", key)
+	s.Putter.Put(key, value)
+}
+
+func (s *SequencingPutter) InitPut(key, value interface{}, failOnTombstones bool) {
+	s.recordKey("InitPut", key)
+	s.Putter.InitPut(key, value, failOnTombstones)
+}
+
+func (s *SequencingPutter) Del(key ...interface{}) {
+	for _, k := range key {
+		s.recordKey("Del", k)
+	}
+	s.Putter.Del(key...)
+}
+
+func (s *SequencingPutter) CPutValuesEmpty(kys []roachpb.Key, values []roachpb.Value) {
+	s.recordAll("CPut", kys)
+	s.Putter.CPutValuesEmpty(kys, values)
+}
+
+func (s *SequencingPutter) CPutTuplesEmpty(kys []roachpb.Key, values [][]byte) {
+	s.recordAll("CPut", kys)
+	s.Putter.CPutTuplesEmpty(kys, values)
+}
+
+func (s *SequencingPutter) PutBytes(kys []roachpb.Key, values [][]byte) {
+	s.recordAll("Put", kys)
+	s.Putter.PutBytes(kys, values)
+}
+
+func (s *SequencingPutter) InitPutBytes(kys []roachpb.Key, values [][]byte) {
+	s.recordAll("InitPut", kys)
+	s.Putter.InitPutBytes(kys, values)
+}
+
+func (s *SequencingPutter) PutTuples(kys []roachpb.Key, values [][]byte) {
+	s.recordAll("Put", kys)
+	s.Putter.PutTuples(kys, values)
+}
+
+func (s *SequencingPutter) InitPutTuples(kys []roachpb.Key, values [][]byte) {
+	s.recordAll("InitPut", kys)
+	s.Putter.InitPutTuples(kys, values)
+}
+
+type bufferedOpType int
+
+const (
+	bufferedCPut bufferedOpType = iota
+	bufferedCPutWithOriginTimestamp
+	bufferedPut
+	bufferedInitPut
+	bufferedDel
+)
+
+// BufferedOp is a write buffered by BufferingPutter or recorded by
+// RecordingPutter.
+type BufferedOp struct {
+	typ   bufferedOpType
+	Key   roachpb.Key
+	value interface{}
+	// expValue is the expected value of conditional puts.
+	expValue         []byte
+	originTimestamp  hlc.Timestamp
+	shouldWinTie     bool
+	failOnTombstones bool
+}
+
+// Apply passes the write on to p.
+func (o *BufferedOp) Apply(p Putter) {
+	switch o.typ {
+	case bufferedCPut:
+		p.CPut(o.Key, o.value, o.expValue)
+	case bufferedCPutWithOriginTimestamp:
+		p.CPutWithOriginTimestamp(o.Key, o.value, o.expValue, o.originTimestamp, o.shouldWinTie)
+	case bufferedPut:
+		p.Put(o.Key, o.value)
+	case bufferedInitPut:
+		p.InitPut(o.Key, o.value, o.failOnTombstones)
+	case bufferedDel:
+		p.Del(o.Key)
+	default:
+		panic(errors.AssertionFailedf("unexpected buffered op type %d", o.typ))
+	}
+}
+
+// BufferingPutter is a Putter which buffers the writes passed to it and
+// flushes them sorted by key, so that writes to the same range, which a writer
+// such as a bulk UPSERT emits row by row and thus scattered across the ranges
+// of the table's indexes, are sent together. Each chunk of writes passed to
+// Flush is meant to be sent as one batch, which results in one raft proposal
+// per range it spans; if RangeEndKey is set, chunks don't span ranges.
+//
+// The sort is stable: multiple writes of the same key are flushed in the order
+// in which they were made, and always in the same chunk, so conditional puts
+// are evaluated against the same values as they would be without buffering.
+// The single-key methods are used to flush the writes of the bulk methods.
+//
+// As with a kv.Batch, the contents of the keys and values passed to the Putter
+// must not be modified until they have been flushed. Buffered writes must be flushed with
+// FlushBuffered once the last one has been made. The first error returned by
+// Flush is returned by Err, and any further writes are dropped.
+type BufferingPutter struct {
+	// BufferSize is the number of writes which are buffered before they are
+	// flushed.
+	BufferSize int
+	// Flush is called with each chunk of the buffered writes, sorted by key, to
+	// write them, e.g. by applying them to a kv.Batch which is then run.
+	Flush func(ops []BufferedOp) error
+	// RangeEndKey, if set, returns the end key of the range containing key,
+	// e.g. from the range descriptor cache, or nil if it is unknown.
+	RangeEndKey func(key roachpb.Key) roachpb.Key
+
+	ops []BufferedOp
+	err error
+}
+
+var _ ErrPutter = &BufferingPutter{}
+
+// Err implements the ErrPutter interface.
+func (b *BufferingPutter) Err() error {
+	return b.err
+}
+
+// Buffered returns the number of writes which have yet to be flushed.
+func (b *BufferingPutter) Buffered() int {
+	return len(b.ops)
+}
+
+// FlushBuffered flushes all the buffered writes, and returns the first error
+// returned by Flush, if any.
+func (b *BufferingPutter) FlushBuffered() error {
+	if b.err != nil || len(b.ops) == 0 {
+		b.ops = b.ops[:0]
+		return b.err
+	}
+	sort.SliceStable(b.ops, func(i, j int) bool {
+		return b.ops[i].Key.Compare(b.ops[j].Key) < 0
+	})
+	for ops := b.ops; len(ops) > 0 && b.err == nil; {
+		n := len(ops)
+		if b.RangeEndKey != nil {
+			if end := b.RangeEndKey(ops[0].Key); len(end) != 0 {
+				n = sort.Search(len(ops), func(i int) bool {
+					return ops[i].Key.Compare(end) >= 0
+				})
+			}
+			// A chunk must contain at least one key, and all of its writes.
+			for n == 0 || (n < len(ops) && ops[n].Key.Equal(ops[n-1].Key)) {
+				n++
+			}
+		}
+		b.err = b.Flush(ops[:n])
+		ops = ops[n:]
+	}
+	// Drop the references to the flushed keys and values.
+	for i := range b.ops {
+		b.ops[i] = BufferedOp{}
+	}
+	b.ops = b.ops[:0]
+	return b.err
+}
+
+func (b *BufferingPutter) add(op BufferedOp) {
+	if b.err != nil {
+		return
+	}
+	b.ops = append(b.ops, op)
+	if len(b.ops) >= b.BufferSize {
+		_ = b.FlushBuffered()
+	}
+}
+
+// opKey returns a key passed to one of the single-key methods, which accept
+// both roachpb.Key and *roachpb.Key.
+func opKey(key interface{}) roachpb.Key {
+	switch k := key.(type) {
+	case *roachpb.Key:
+		return *k
+	case roachpb.Key:
+		return k
+	default:
+		panic(errors.AssertionFailedf("unexpected key type %T", key))
+	}
+}
+
+// opValue returns a value passed to one of the single-key methods to be
+// buffered. A *roachpb.Value is copied, as the writers reuse it across calls.
+func opValue(value interface{}) interface{} {
+	if v, ok := value.(*roachpb.Value); ok {
+		c := *v
+		return &c
+	}
+	return value
+}
+
+// bytesOps calls add with the write of each non-empty key passed to one of the
+// bulk methods taking [][]byte values.
+func bytesOps(
+	typ bufferedOpType, kys []roachpb.Key, values [][]byte, tuples bool, add func(BufferedOp),
+) {
+	for i, k := range kys {
+		if len(k) == 0 {
+			continue
+		}
+		v := &roachpb.Value{}
+		if tuples {
+			v.SetTuple(values[i])
+		} else {
+			v.SetBytes(values[i])
+		}
+		add(BufferedOp{typ: typ, Key: k, value: v})
+	}
+}
+
+func (b *BufferingPutter) addBytes(
+	typ bufferedOpType, kys []roachpb.Key, values [][]byte, tuples bool,
+) {
+	bytesOps(typ, kys, values, tuples, b.add)
+}
+
+func (b *BufferingPutter) CPut(key, value interface{}, expValue []byte) {
+	b.add(BufferedOp{typ: bufferedCPut, Key: opKey(key), value: opValue(value), expValue: expValue})
+}
+
+func (b *BufferingPutter) CPutWithOriginTimestamp(
+	key, value interface{}, expValue []byte, ts hlc.Timestamp, shouldWinTie bool,
+) {
+	b.add(BufferedOp{
+		typ: bufferedCPutWithOriginTimestamp, Key: opKey(key), value: opValue(value), expValue: expValue,
+		originTimestamp: ts, shouldWinTie: shouldWinTie,
+	})
+}
+
+func (b *BufferingPutter) Put(key, value interface{}) {
+	b.add(BufferedOp{typ: bufferedPut, Key: opKey(key), value: opValue(value)})
+}
+
+func (b *BufferingPutter) InitPut(key, value interface{}, failOnTombstones bool) {
+	b.add(BufferedOp{
+		typ: bufferedInitPut, Key: opKey(key), value: opValue(value), failOnTombstones: failOnTombstones,
+	})
+}
+
+func (b *BufferingPutter) Del(key ...interface{}) {
+	for _, k := range key {
+		b.add(BufferedOp{typ: bufferedDel, Key: opKey(k)})
+	}
+}
+
+func (b *BufferingPutter) CPutValuesEmpty(kys []roachpb.Key, values []roachpb.Value) {
+	for i, k := range kys {
+		if len(k) == 0 {
+			continue
+		}
+		b.add(BufferedOp{typ: bufferedCPut, Key: k, value: opValue(&values[i])})
+	}
+}
+
+func (b *BufferingPutter) CPutTuplesEmpty(kys []roachpb.Key, values [][]byte) {
+	b.addBytes(bufferedCPut, kys, values, true /* tuples */)
+}
+
+func (b *BufferingPutter) PutBytes(kys []roachpb.Key, values [][]byte) {
+	b.addBytes(bufferedPut, kys, values, false /* tuples */)
+}
+
+func (b *BufferingPutter) InitPutBytes(kys []roachpb.Key, values [][]byte) {
+	b.addBytes(bufferedInitPut, kys, values, false /* tuples */)
+}
+
+func (b *BufferingPutter) PutTuples(kys []roachpb.Key, values [][]byte) {
+	b.addBytes(bufferedPut, kys, values, true /* tuples */)
+}
+
+func (b *BufferingPutter) InitPutTuples(kys []roachpb.Key, values [][]byte) {
+	b.addBytes(bufferedInitPut, kys, values, true /* tuples */)
+}
+
+// RecordingPutter is a Putter which records the writes made to it, in order,
+// instead of sending them anywhere. As with BufferingPutter, the writes of the
+// bulk methods are recorded as those of the corresponding single-key methods,
+// so that writers which use either can be compared with DiffOps; unlike
+// KVCollector, the request type and expected value of each write are kept.
+type RecordingPutter struct {
+	Ops []BufferedOp
+}
+
+var _ Putter = &RecordingPutter{}
+
+func (r *RecordingPutter) add(op BufferedOp) {
+	r.Ops = append(r.Ops, op)
+}
+
+func (r *RecordingPutter) CPut(key, value interface{}, expValue []byte) {
+	r.add(BufferedOp{typ: bufferedCPut, Key: opKey(key), value: opValue(value), expValue: expValue})
+}
+
+func (r *RecordingPutter) CPutWithOriginTimestamp(
+	key, value interface{}, expValue []byte, ts hlc.Timestamp, shouldWinTie bool,
+) {
+	r.add(BufferedOp{
+		typ: bufferedCPutWithOriginTimestamp, Key: opKey(key), value: opValue(value), expValue: expValue,
+		originTimestamp: ts, shouldWinTie: shouldWinTie,
+	})
+}
+
+func (r *RecordingPutter) Put(key, value interface{}) {
+	r.add(BufferedOp{typ: bufferedPut, Key: opKey(key), value: opValue(value)})
+}
+
+func (r *RecordingPutter) InitPut(key, value interface{}, failOnTombstones bool) {
+	r.add(BufferedOp{
+		typ: bufferedInitPut, Key: opKey(key), value: opValue(value), failOnTombstones: failOnTombstones,
+	})
+}
+
+func (r *RecordingPutter) Del(key ...interface{}) {
+	for _, k := range key {
+		r.add(BufferedOp{typ: bufferedDel, Key: opKey(k)})
+	}
+}
+
+func (r *RecordingPutter) CPutValuesEmpty(kys []roachpb.Key, values []roachpb.Value) {
+	for i, k := range kys {
+		if len(k) == 0 {
+			continue
+		}
+		r.add(BufferedOp{typ: bufferedCPut, Key: k, value: opValue(&values[i])})
+	}
+}
+
+func (r *RecordingPutter) CPutTuplesEmpty(kys []roachpb.Key, values [][]byte) {
+	bytesOps(bufferedCPut, kys, values, true /* tuples */, r.add)
+}
+
+func (r *RecordingPutter) PutBytes(kys []roachpb.Key, values [][]byte) {
+	bytesOps(bufferedPut, kys, values, false /* tuples */, r.add)
+}
+
+func (r *RecordingPutter) InitPutBytes(kys []roachpb.Key, values [][]byte) {
+	bytesOps(bufferedInitPut, kys, values, false /* tuples */, r.add)
+}
+
+func (r *RecordingPutter) PutTuples(kys []roachpb.Key, values [][]byte) {
+	bytesOps(bufferedPut, kys, values, true /* tuples */, r.add)
+}
+
+func (r *RecordingPutter) InitPutTuples(kys []roachpb.Key, values [][]byte) {
+	bytesOps(bufferedInitPut, kys, values, true /* tuples */, r.add)
+}
+
 type kvSparseSliceBulkSource[T kv.GValue] struct {
 	keys   []roachpb.Key
 	values []T
@@ -334,3 +1201,481 @@ func (k *KVBatchAdapter) PutTuples(kys []roachpb.Key, values [][]byte) {
 func (k *KVBatchAdapter) InitPutTuples(kys []roachpb.Key, values [][]byte) {
 	k.Batch.InitPutTuples(&kvSparseSliceBulkSource[[]byte]{kys, values})
 }
+
+// KVCollector is a Putter which collects the key/value pairs written to it
+// into KVs instead of sending them anywhere, e.g. for inspecting the exact
+// encoding of a row. Every write is recorded as a roachpb.KeyValue in the
+// order in which it was made, regardless of the method used; in particular,
+// the expected values of conditional writes are dropped. Deletions are
+// recorded with an empty Value.
+type KVCollector struct {
+	KVs []roachpb.KeyValue
+}
+
+var _ Putter = &KVCollector{}
+
+func (c *KVCollector) add(key, value interface{}) {
+	var kv roachpb.KeyValue
+	switch k := key.(type) {
+	case *roachpb.Key:
+		kv.Key = append(roachpb.Key(nil), *k...)
+	case roachpb.Key:
+		kv.Key = append(roachpb.Key(nil), k...)
+	default:
+		panic(errors.AssertionFailedf("unexpected key type %T", key))
+	}
+	switch v := value.(type) {
+	case nil:
+	case *roachpb.Value:
+		kv.Value = *v
+	case roachpb.Value:
+		kv.Value = v
+	default:
+		panic(errors.AssertionFailedf("unexpected value type %T", value))
+	}
+	c.KVs = append(c.KVs, kv)
+}
+
+func (c *KVCollector) addBytes(kys []roachpb.Key, values [][]byte, tuples bool) {
+	for i, k := range kys {
+		if len(k) == 0 {
+			continue
+		}
+		var v roachpb.Value
+		if tuples {
+			v.SetTuple(values[i])
+		} else {
+			v.SetBytes(values[i])
+		}
+		c.add(k, &v)
+	}
+}
+
+func (c *KVCollector) CPut(key, value interface{}, expValue []byte) {
+	c.add(key, value)
+}
+
+func (c *KVCollector) CPutWithOriginTimestamp(
+	key, value interface{}, expValue []byte, ts hlc.Timestamp, shouldWinTie bool,
+) {
+	c.add(key, value)
+}
+
+func (c *KVCollector) Put(key, value interface{}) {
+	c.add(key, value)
+}
+
+func (c *KVCollector) InitPut(key, value interface{}, failOnTombstones bool) {
+	c.add(key, value)
+}
+
+func (c *KVCollector) Del(key ...interface{}) {
+	for _, k := range key {
+		c.add(k, nil)
+	}
+}
+
+func (c *KVCollector) CPutValuesEmpty(kys []roachpb.Key, values []roachpb.Value) {
+	for i, k := range kys {
+		if len(k) == 0 {
+			continue
+		}
+		c.add(k, &values[i])
+	}
+}
+
+func (c *KVCollector) CPutTuplesEmpty(kys []roachpb.Key, values [][]byte) {
+	c.addBytes(kys, values, true /* tuples */)
+}
+
+func (c *KVCollector) PutBytes(kys []roachpb.Key, values [][]byte) {
+	c.addBytes(kys, values, false /* tuples */)
+}
+
+func (c *KVCollector) InitPutBytes(kys []roachpb.Key, values [][]byte) {
+	c.addBytes(kys, values, false /* tuples */)
+}
+
+func (c *KVCollector) PutTuples(kys []roachpb.Key, values [][]byte) {
+	c.addBytes(kys, values, true /* tuples */)
+}
+
+func (c *KVCollector) InitPutTuples(kys []roachpb.Key, values [][]byte) {
+	c.addBytes(kys, values, true /* tuples */)
+}
+
+// opRenderer renders writes as human-readable lines for DebugDecodePutter and
+// DiffOps, decoded using the descriptor of the table the writes are made to. As opposed to the kv
+// trace, keys are rendered with the name of their index and column family, and
+// the values of the primary index's column families with the names of their
+// columns, e.g.
+//
+//	CPut t@t_pkey/1/0 (f0) -> b=2 (if not exists)
+//
+// Keys outside the table's indexes, and values which can't be decoded, are
+// rendered as in the kv trace.
+type opRenderer struct {
+	codec keys.SQLCodec
+	table catalog.TableDescriptor
+	alloc tree.DatumAlloc
+}
+
+// decodeKey renders key, returning the index and column family it belongs to,
+// if known.
+func (r *opRenderer) decodeKey(
+	key roachpb.Key,
+) (string, catalog.Index, *descpb.ColumnFamilyDescriptor) {
+	_, tableID, indexID, err := r.codec.DecodeIndexPrefix(key)
+	if err != nil || descpb.ID(tableID) != r.table.GetID() {
+		return keys.PrettyPrint(nil /* valDirs */, key), nil, nil
+	}
+	index := catalog.FindIndexByID(r.table, descpb.IndexID(indexID))
+	if index == nil {
+		return keys.PrettyPrint(nil /* valDirs */, key), nil, nil
+	}
+	// Skip the table and index IDs, which are rendered as names.
+	s := fmt.Sprintf("%s@%s%s", r.table.GetName(), index.GetName(),
+		catalogkeys.PrettyKey(catalogkeys.IndexKeyValDirs(index), key[len(r.codec.TenantPrefix()):], 2 /* skip */))
+	familyID, err := keys.DecodeFamilyKey(key)
+	if err != nil {
+		return s, index, nil
+	}
+	family := catalog.FindFamilyByID(r.table, descpb.FamilyID(familyID))
+	if family == nil {
+		return s, index, nil
+	}
+	return fmt.Sprintf("%s (%s)", s, family.Name), index, family
+}
+
+// decodeValue renders the value of a key of the given index and column family.
+func (r *opRenderer) decodeValue(
+	index catalog.Index, family *descpb.ColumnFamilyDescriptor, value roachpb.Value,
+) string {
+	if index == nil || !index.Primary() || family == nil {
+		return value.PrettyPrint()
+	}
+	if value.GetTag() != roachpb.ValueType_TUPLE {
+		// A family with a single column may store it on its own.
+		col := catalog.FindColumnByID(r.table, family.DefaultColumnID)
+		if col == nil {
+			return value.PrettyPrint()
+		}
+		datum, err := valueside.UnmarshalLegacy(&r.alloc, col.GetType(), value)
+		if err != nil {
+			return value.PrettyPrint()
+		}
+		return fmt.Sprintf("%s=%s", col.GetName(), datum)
+	}
+	b, err := value.GetTuple()
+	if err != nil {
+		return value.PrettyPrint()
+	}
+	var buf strings.Builder
+	var lastColID descpb.ColumnID
+	for len(b) > 0 {
+		_, _, colIDDiff, _, err := encoding.DecodeValueTag(b)
+		if err != nil {
+			return value.PrettyPrint()
+		}
+		colID := lastColID + descpb.ColumnID(colIDDiff)
+		lastColID = colID
+		col := catalog.FindColumnByID(r.table, colID)
+		if col == nil {
+			return value.PrettyPrint()
+		}
+		var datum tree.Datum
+		if datum, b, err = valueside.Decode(&r.alloc, col.GetType(), b); err != nil {
+			return value.PrettyPrint()
+		}
+		if buf.Len() > 0 {
+			buf.WriteString(", ")
+		}
+		fmt.Fprintf(&buf, "%s=%s", col.GetName(), datum)
+	}
+	return buf.String()
+}
+
+// cputSuffix renders the expected value of a conditional put.
+func (r *opRenderer) cputSuffix(
+	index catalog.Index, family *descpb.ColumnFamilyDescriptor, expValue []byte,
+) string {
+	if expValue == nil {
+		return " (if not exists)"
+	}
+	var exp roachpb.Value
+	exp.SetTagAndData(expValue)
+	return fmt.Sprintf(" (replacing %s)", r.decodeValue(index, family, exp))
+}
+
+// render renders a write.
+func (r *opRenderer) render(o *BufferedOp) string {
+	k, index, family := r.decodeKey(o.Key)
+	var op, suffix string
+	switch o.typ {
+	case bufferedCPut:
+		op, suffix = "CPut", r.cputSuffix(index, family, o.expValue)
+	case bufferedCPutWithOriginTimestamp:
+		op = "CPutWithOriginTimestamp"
+		suffix = fmt.Sprintf("%s @ %s", r.cputSuffix(index, family, o.expValue), o.originTimestamp)
+	case bufferedPut:
+		op = "Put"
+	case bufferedInitPut:
+		op = "InitPut"
+	case bufferedDel:
+		return fmt.Sprintf("Del %s", k)
+	default:
+		panic(errors.AssertionFailedf("unexpected buffered op type %d", o.typ))
+	}
+	return fmt.Sprintf("%s %s -> %s%s", op, k, r.renderValue(index, family, o.value), suffix)
+}
+
+// renderValue renders a value passed to one of the single-key methods.
+func (r *opRenderer) renderValue(
+	index catalog.Index, family *descpb.ColumnFamilyDescriptor, value interface{},
+) string {
+	switch v := value.(type) {
+	case *roachpb.Value:
+		return r.decodeValue(index, family, *v)
+	case roachpb.Value:
+		return r.decodeValue(index, family, v)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// DebugDecodePutter is a Putter which renders each write passed through it as
+// a human-readable line, as DiffOps does, and passes it on to the wrapped
+// Putter unchanged. It is used for the decode_kv_writes session variable, see
+// RowHelper.DecodeWrites, and is meant for seeing exactly what a statement
+// writes: as opposed to the kv trace, keys are decoded down to the table,
+// index, column family and primary key, e.g.
+//
+//	CPut t@t_pkey/1/0 (f0) -> b=2 (if not exists)
+//
+// The bulk methods render one line per non-empty key.
+type DebugDecodePutter struct {
+	Putter Putter
+	Codec  keys.SQLCodec
+	Table  catalog.TableDescriptor
+	// Emit is called with each rendered line.
+	Emit func(line string)
+
+	r opRenderer
+}
+
+var _ ErrPutter = &DebugDecodePutter{}
+
+// Err implements the ErrPutter interface.
+func (d *DebugDecodePutter) Err() error {
+	return putterErr(d.Putter)
+}
+
+func (d *DebugDecodePutter) renderer() *opRenderer {
+	if d.r.table != d.Table {
+		d.r = opRenderer{codec: d.Codec, table: d.Table}
+	}
+	return &d.r
+}
+
+func (d *DebugDecodePutter) emit(op BufferedOp) {
+	d.Emit(d.renderer().render(&op))
+}
+
+// emitCPutAllowingIfNotExists renders a kv.Batch.CPutAllowingIfNotExists,
+// which the writers make to the batch directly as it has no Putter
+// equivalent.
+func (d *DebugDecodePutter) emitCPutAllowingIfNotExists(
+	key roachpb.Key, value *roachpb.Value, expValue []byte,
+) {
+	r := d.renderer()
+	k, index, family := r.decodeKey(key)
+	suffix := " (if not exists)"
+	if expValue != nil {
+		var exp roachpb.Value
+		exp.SetTagAndData(expValue)
+		suffix = fmt.Sprintf(" (replacing %s, if exists)", r.decodeValue(index, family, exp))
+	}
+	d.Emit(fmt.Sprintf("CPut %s -> %s%s", k, r.decodeValue(index, family, *value), suffix))
+}
+
+// decodeCPutAllowingIfNotExists renders a CPutAllowingIfNotExists made to the
+// batch underlying b if b is a DebugDecodePutter.
+func decodeCPutAllowingIfNotExists(
+	b Putter, key roachpb.Key, value *roachpb.Value, expValue []byte,
+) {
+	if d, ok := b.(*DebugDecodePutter); ok {
+		d.emitCPutAllowingIfNotExists(key, value, expValue)
+	}
+}
+
+func (d *DebugDecodePutter) CPut(key, value interface{}, expValue []byte) {
+	d.emit(BufferedOp{typ: bufferedCPut, Key: opKey(key), value: value, expValue: expValue})
+	d.Putter.CPut(key, value, expValue)
+}
+
+func (d *DebugDecodePutter) CPutWithOriginTimestamp(
+	key, value interface{}, expValue []byte, ts hlc.Timestamp, shouldWinTie bool,
+) {
+	d.emit(BufferedOp{
+		typ: bufferedCPutWithOriginTimestamp, Key: opKey(key), value: value, expValue: expValue,
+		originTimestamp: ts, shouldWinTie: shouldWinTie,
+	})
+	d.Putter.CPutWithOriginTimestamp(key, value, expValue, ts, shouldWinTie)
+}
+
+func (d *DebugDecodePutter) Put(key, value interface{}) {
+	d.emit(BufferedOp{typ: bufferedPut, Key: opKey(key), value: value})
+	d.Putter.Put(key, value)
+}
+
+func (d *DebugDecodePutter) InitPut(key, value interface{}, failOnTombstones bool) {
+	d.emit(BufferedOp{typ: bufferedInitPut, Key: opKey(key), value: value, failOnTombstones: failOnTombstones})
+	d.Putter.InitPut(key, value, failOnTombstones)
+}
+
+func (d *DebugDecodePutter) Del(key ...interface{}) {
+	for _, k := range key {
+		d.emit(BufferedOp{typ: bufferedDel, Key: opKey(k)})
+	}
+	d.Putter.Del(key...)
+}
+
+func (d *DebugDecodePutter) CPutValuesEmpty(kys []roachpb.Key, values []roachpb.Value) {
+	for i, k := range kys {
+		if len(k) == 0 {
+			continue
+		}
+		d.emit(BufferedOp{typ: bufferedCPut, Key: k, value: &values[i]})
+	}
+	d.Putter.CPutValuesEmpty(kys, values)
+}
+
+func (d *DebugDecodePutter) CPutTuplesEmpty(kys []roachpb.Key, values [][]byte) {
+	bytesOps(bufferedCPut, kys, values, true /* tuples */, d.emit)
+	d.Putter.CPutTuplesEmpty(kys, values)
+}
+
+func (d *DebugDecodePutter) PutBytes(kys []roachpb.Key, values [][]byte) {
+	bytesOps(bufferedPut, kys, values, false /* tuples */, d.emit)
+	d.Putter.PutBytes(kys, values)
+}
+
+func (d *DebugDecodePutter) InitPutBytes(kys []roachpb.Key, values [][]byte) {
+	bytesOps(bufferedInitPut, kys, values, false /* tuples */, d.emit)
+	d.Putter.InitPutBytes(kys, values)
+}
+
+func (d *DebugDecodePutter) PutTuples(kys []roachpb.Key, values [][]byte) {
+	bytesOps(bufferedPut, kys, values, true /* tuples */, d.emit)
+	d.Putter.PutTuples(kys, values)
+}
+
+func (d *DebugDecodePutter) InitPutTuples(kys []roachpb.Key, values [][]byte) {
+	bytesOps(bufferedInitPut, kys, values, true /* tuples */, d.emit)
+	d.Putter.InitPutTuples(kys, values)
+}
+
+// valueBytes returns the encoded value of a write, or nil for deletions.
+func (o *BufferedOp) valueBytes() []byte {
+	switch v := o.value.(type) {
+	case nil:
+		return nil
+	case *roachpb.Value:
+		return v.TagAndDataBytes()
+	case roachpb.Value:
+		return v.TagAndDataBytes()
+	default:
+		return []byte(fmt.Sprint(v))
+	}
+}
+
+// equal returns whether o and other are the same write.
+func (o *BufferedOp) equal(other *BufferedOp) bool {
+	return o.typ == other.typ &&
+		o.Key.Equal(other.Key) &&
+		bytes.Equal(o.valueBytes(), other.valueBytes()) &&
+		bytes.Equal(o.expValue, other.expValue) &&
+		o.originTimestamp == other.originTimestamp &&
+		o.shouldWinTie == other.shouldWinTie &&
+		o.failOnTombstones == other.failOnTombstones
+}
+
+// FormatOps renders writes, e.g. those recorded by a RecordingPutter, one line
+// per write, as DiffOps does.
+func FormatOps(codec keys.SQLCodec, table catalog.TableDescriptor, ops []BufferedOp) []string {
+	r := &opRenderer{codec: codec, table: table}
+	lines := make([]string, len(ops))
+	for i := range ops {
+		lines[i] = r.render(&ops[i])
+	}
+	return lines
+}
+
+// DiffOps returns a description of the differences between two sequences of
+// writes, e.g. those recorded by a RecordingPutter for the same rows with two
+// versions of a writer, one line per write, or nil if they are identical.
+// Writes are matched up by key, the i-th write of a key in before with the
+// i-th write of that key in after, and rendered with their decoded keys and
+// values, e.g.
+//
+//	[]string{
+//		"- CPut t@t_pkey/1/0 (f0) -> b=2 (if not exists)",
+//		"+ Put t@t_pkey/1/0 (f0) -> b=2",
+//		"+ Del t@t_pkey/1/1 (f1)",
+//	}
+//
+// A write which is only made in before, or is made differently in after, is
+// listed with "-", and one which is only made in after, or is the different
+// version of a write in before, with "+". Writes which are identical but are
+// made in a different order relative to the others are listed with "~" and
+// their positions. If the two versions of a write render the same, e.g.
+// because they only differ in their encoding, their raw bytes are included.
+func DiffOps(
+	codec keys.SQLCodec, table catalog.TableDescriptor, before, after []BufferedOp,
+) []string {
+	r := &opRenderer{codec: codec, table: table}
+	render := r.render
+	renderBoth := func(b, a *BufferedOp) (string, string) {
+		bl, al := render(b), render(a)
+		if bl == al {
+			bl += fmt.Sprintf(" [value=%x expected=%x]", b.valueBytes(), b.expValue)
+			al += fmt.Sprintf(" [value=%x expected=%x]", a.valueBytes(), a.expValue)
+		}
+		return bl, al
+	}
+
+	// afterByKey holds the positions in after of the writes to each key which
+	// have yet to be matched.
+	afterByKey := make(map[string][]int)
+	for i := range after {
+		k := string(after[i].Key)
+		afterByKey[k] = append(afterByKey[k], i)
+	}
+	matched := make([]bool, len(after))
+	var diff []string
+	lastMatch := -1
+	for i := range before {
+		k := string(before[i].Key)
+		pos := afterByKey[k]
+		if len(pos) == 0 {
+			diff = append(diff, "- "+render(&before[i]))
+			continue
+		}
+		j := pos[0]
+		afterByKey[k] = pos[1:]
+		matched[j] = true
+		if !before[i].equal(&after[j]) {
+			bl, al := renderBoth(&before[i], &after[j])
+			diff = append(diff, "- "+bl, "+ "+al)
+		} else if j < lastMatch {
+			diff = append(diff, fmt.Sprintf("~ %s (write %d, was write %d)", render(&before[i]), j+1, i+1))
+		}
+		lastMatch = max(lastMatch, j)
+	}
+	for j := range after {
+		if !matched[j] {
+			diff = append(diff, "+ "+render(&after[j]))
+		}
+	}
+	return diff
+}
@@ -0,0 +1,461 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package row_test
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/kv"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvpb"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/row"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/util/encoding"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteOncePutter(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	desc := tabledesc.NewBuilder(&descpb.TableDescriptor{ID: 104, Name: "t"}).BuildImmutableTable()
+	var written []roachpb.KeyValue
+	p := &row.WriteOncePutter{
+		Putter: row.KVInserter(func(kv roachpb.KeyValue) {
+			written = append(written, kv)
+		}),
+		Table: desc,
+	}
+
+	rowKey := keys.SystemSQLCodec.IndexPrefix(104, 1)
+	fam0 := keys.MakeFamilyKey(rowKey, 0)
+	fam1 := keys.MakeFamilyKey(rowKey, 1)
+	value := roachpb.MakeValueFromString("v")
+
+	// Different column families of the same row are distinct keys.
+	p.Put(&fam0, &value)
+	p.InitPut(&fam1, &value, false /* failOnTombstones */)
+	require.NoError(t, p.Err())
+
+	// Writing either family again is flagged, but the write is still passed
+	// through.
+	p.Put(&fam1, &value)
+	require.ErrorContains(t, p.Err(), "of table t (104) written more than once")
+	require.Len(t, written, 3)
+
+	// Only the first violation is reported.
+	p.Del(fam0)
+	require.ErrorContains(t, p.Err(), fam1.String())
+
+	// Without a violation, the error of the wrapped Putter is reported.
+	p = &row.WriteOncePutter{
+		Putter: &row.FaultyPutter{Putter: &row.KVCollector{}, FailCPut: 1, Error: &kvpb.ConditionFailedError{}},
+		Table:  desc,
+	}
+	p.CPut(&fam0, &value, nil /* expValue */)
+	require.True(t, errors.HasType(p.Err(), (*kvpb.ConditionFailedError)(nil)), "%v", p.Err())
+}
+
+func TestFamilyOrderPutter(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	st := cluster.MakeTestingClusterSettings()
+	// Drop the index being added, leaving the two families and t_b_idx.
+	mut := tabledesc.NewBuilder(makeEncodeRowTestTable().TableDesc()).BuildExistingMutableTable()
+	mut.Mutations = nil
+	table := tabledesc.NewBuilder(mut.TableDesc()).BuildImmutableTable()
+	ri, err := row.MakeInserter(ctx, nil /* txn */, keys.SystemSQLCodec, table, table.PublicColumns(),
+		&tree.DatumAlloc{}, &st.SV, false /* internal */, nil /* metrics */)
+	require.NoError(t, err)
+
+	var written row.KVCollector
+	p := &row.FamilyOrderPutter{Putter: &written, Codec: keys.SystemSQLCodec, Table: table}
+	// The writer writes the families of each row in order, and the rows are
+	// tracked separately.
+	for i := 1; i <= 2; i++ {
+		values := tree.Datums{tree.NewDInt(tree.DInt(i)), tree.NewDInt(2), tree.NewDString("foo")}
+		require.NoError(t, ri.InsertRow(ctx, p, values, row.PartialIndexUpdateHelper{},
+			nil /* oth */, false /* overwrite */, false /* traceKV */))
+	}
+	require.NotEmpty(t, written.KVs)
+
+	rowKey := encoding.EncodeVarintAscending(keys.SystemSQLCodec.IndexPrefix(104, 1), 3)
+	fam0 := keys.MakeFamilyKey(rowKey[:len(rowKey):len(rowKey)], 0)
+	fam1 := keys.MakeFamilyKey(rowKey[:len(rowKey):len(rowKey)], 1)
+	value := roachpb.MakeValueFromString("v")
+
+	// Keys of other indexes and tables aren't checked.
+	p.Put(keys.SystemSQLCodec.IndexPrefix(104, 2), &value)
+	p.Put(keys.SystemSQLCodec.IndexPrefix(105, 1), &value)
+	require.NoError(t, p.Err())
+
+	// Writing family 0 after family 1 is flagged, but the write is still
+	// passed through.
+	n := len(written.KVs)
+	p.Put(&fam1, &value)
+	p.CPut(&fam0, &value, nil /* expValue */)
+	require.ErrorContains(t, p.Err(), "column family f0 of key /Table/104/1/3/0 of table t (104) written after column family f1")
+	require.Len(t, written.KVs, n+2)
+}
+
+func TestFaultyPutter(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	st := cluster.MakeTestingClusterSettings()
+	// Drop the index being added, leaving the two families and t_b_idx.
+	mut := tabledesc.NewBuilder(makeEncodeRowTestTable().TableDesc()).BuildExistingMutableTable()
+	mut.Mutations = nil
+	table := tabledesc.NewBuilder(mut.TableDesc()).BuildImmutableTable()
+	ri, err := row.MakeInserter(ctx, nil /* txn */, keys.SystemSQLCodec, table, table.PublicColumns(),
+		&tree.DatumAlloc{}, &st.SV, false /* internal */, nil /* metrics */)
+	require.NoError(t, err)
+	values := tree.Datums{tree.NewDInt(1), tree.NewDInt(2), tree.NewDString("foo")}
+
+	insert := func(failCPut int) ([]roachpb.KeyValue, *row.FaultyPutter, error) {
+		var written []roachpb.KeyValue
+		p := &row.FaultyPutter{
+			Putter: row.KVInserter(func(kv roachpb.KeyValue) {
+				written = append(written, kv)
+			}),
+			FailCPut: failCPut,
+			Error:    &kvpb.ConditionFailedError{},
+		}
+		err := ri.InsertRow(ctx, p, values, row.PartialIndexUpdateHelper{}, nil, /* oth */
+			false /* overwrite */, false /* traceKV */)
+		return written, p, err
+	}
+
+	// Each of the two families is written with a CPut, and the secondary index
+	// with an InitPut.
+	written, p, err := insert(0)
+	require.NoError(t, err)
+	require.Len(t, written, 3)
+	require.Equal(t, 2, p.CPuts())
+
+	// The failed CPut is dropped, and the writer returns its error.
+	written, _, err = insert(2)
+	require.True(t, errors.HasType(err, (*kvpb.ConditionFailedError)(nil)), "%v", err)
+	require.Len(t, written, 2)
+
+	// Failing a CPut which is never issued has no effect.
+	_, _, err = insert(3)
+	require.NoError(t, err)
+}
+
+func TestSequencingPutter(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	st := cluster.MakeTestingClusterSettings()
+	mut := tabledesc.NewBuilder(makeEncodeRowTestTable().TableDesc()).BuildExistingMutableTable()
+	mut.Mutations = nil
+	table := tabledesc.NewBuilder(mut.TableDesc()).BuildImmutableTable()
+	ri, err := row.MakeInserter(ctx, nil /* txn */, keys.SystemSQLCodec, table, table.PublicColumns(),
+		&tree.DatumAlloc{}, &st.SV, false /* internal */, nil /* metrics */)
+	require.NoError(t, err)
+	values := tree.Datums{tree.NewDInt(1), tree.NewDInt(2), tree.NewDString("foo")}
+
+	insert := func() ([]roachpb.KeyValue, []row.SequencedOp) {
+		var unwrapped, wrapped row.KVCollector
+		require.NoError(t, ri.InsertRow(ctx, &unwrapped, values, row.PartialIndexUpdateHelper{},
+			nil /* oth */, false /* overwrite */, false /* traceKV */))
+		p := &row.SequencingPutter{Putter: &wrapped}
+		require.NoError(t, ri.InsertRow(ctx, p, values, row.PartialIndexUpdateHelper{},
+			nil /* oth */, false /* overwrite */, false /* traceKV */))
+		// The writes are passed through unchanged.
+		require.Equal(t, unwrapped.KVs, wrapped.KVs)
+		return wrapped.KVs, p.Ops
+	}
+
+	kvs, ops := insert()
+	require.Len(t, ops, 3)
+	for i, op := range ops {
+		require.Equal(t, i+1, op.Seq)
+		require.Equal(t, kvs[i].Key, op.Key)
+	}
+	require.Equal(t, []string{"CPut", "CPut", "InitPut"},
+		[]string{ops[0].Op, ops[1].Op, ops[2].Op})
+
+	// Encoding the same row again produces the same sequence.
+	_, again := insert()
+	require.Equal(t, ops, again)
+
+	// A key of an unexpected type is not recorded, but reported through Err.
+	p := &row.SequencingPutter{Putter: discardPutter{}}
+	p.Put("a", nil /* value */)
+	require.Empty(t, p.Ops)
+	require.ErrorContains(t, p.Err(), "unexpected key type string")
+}
+
+// discardPutter is a Putter which drops Puts of any key.
+type discardPutter struct {
+	row.Putter
+}
+
+func (discardPutter) Put(key, value interface{}) {}
+
+// bufferingPutterTestTable returns the table of makeEncodeRowTestTable without
+// the index being added, leaving the two families and t_b_idx, and a function
+// returning the end key of the range containing a key for a split of the
+// table's index spans into ranges of ten rows.
+func bufferingPutterTestTable() (catalog.TableDescriptor, func(roachpb.Key) roachpb.Key) {
+	mut := tabledesc.NewBuilder(makeEncodeRowTestTable().TableDesc()).BuildExistingMutableTable()
+	mut.Mutations = nil
+	table := tabledesc.NewBuilder(mut.TableDesc()).BuildImmutableTable()
+	var splits []roachpb.Key
+	for _, indexID := range []uint32{1, 2} {
+		for i := int64(0); i <= 1000; i += 10 {
+			splits = append(splits, encoding.EncodeVarintAscending(keys.SystemSQLCodec.IndexPrefix(104, indexID), i))
+		}
+	}
+	rangeEnd := func(key roachpb.Key) roachpb.Key {
+		i := sort.Search(len(splits), func(i int) bool { return splits[i].Compare(key) > 0 })
+		if i == len(splits) {
+			return nil
+		}
+		return splits[i]
+	}
+	return table, rangeEnd
+}
+
+func TestDebugDecodePutter(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	st := cluster.MakeTestingClusterSettings()
+	mut := tabledesc.NewBuilder(makeEncodeRowTestTable().TableDesc()).BuildExistingMutableTable()
+	mut.Mutations = nil
+	table := tabledesc.NewBuilder(mut.TableDesc()).BuildImmutableTable()
+	ri, err := row.MakeInserter(ctx, nil /* txn */, keys.SystemSQLCodec, table, table.PublicColumns(),
+		&tree.DatumAlloc{}, &st.SV, false /* internal */, nil /* metrics */)
+	require.NoError(t, err)
+	values := tree.Datums{tree.NewDInt(1), tree.NewDInt(2), tree.NewDString("foo")}
+
+	var unwrapped, wrapped row.KVCollector
+	require.NoError(t, ri.InsertRow(ctx, &unwrapped, values, row.PartialIndexUpdateHelper{},
+		nil /* oth */, false /* overwrite */, false /* traceKV */))
+	var lines []string
+	p := &row.DebugDecodePutter{
+		Putter: &wrapped,
+		Codec:  keys.SystemSQLCodec,
+		Table:  table,
+		Emit:   func(line string) { lines = append(lines, line) },
+	}
+	require.NoError(t, ri.InsertRow(ctx, p, values, row.PartialIndexUpdateHelper{},
+		nil /* oth */, false /* overwrite */, false /* traceKV */))
+	// The writes are passed through unchanged.
+	require.Equal(t, unwrapped.KVs, wrapped.KVs)
+	require.Len(t, lines, len(wrapped.KVs))
+	// Each of the row's column families is decoded by column.
+	require.Equal(t, []string{
+		"CPut t@t_pkey/1/0 (f0) -> b=2 (if not exists)",
+		"CPut t@t_pkey/1/1 (f1) -> c='foo' (if not exists)",
+	}, lines[:2])
+	for _, l := range lines[2:] {
+		require.Contains(t, l, "InitPut t@t_b_idx/2/1/")
+	}
+
+	// Keys outside of the table are rendered as in the kv trace.
+	explicit := lines
+	lines = nil
+	other := keys.SystemSQLCodec.IndexPrefix(105, 1)
+	p.Del(other)
+	require.Equal(t, []string{"Del " + keys.PrettyPrint(nil /* valDirs */, other)}, lines)
+
+	// The writers render their writes the same way once DecodeWrites is set,
+	// without changing them.
+	lines = nil
+	var decoded row.KVCollector
+	ri.Helper.DecodeWrites = func(line string) { lines = append(lines, line) }
+	require.NoError(t, ri.InsertRow(ctx, &decoded, values, row.PartialIndexUpdateHelper{},
+		nil /* oth */, false /* overwrite */, false /* traceKV */))
+	require.Equal(t, unwrapped.KVs, decoded.KVs)
+	require.Equal(t, explicit, lines)
+
+	lines = nil
+	rd := row.MakeDeleter(keys.SystemSQLCodec, table, nil /* requestedCols */, &st.SV,
+		false /* internal */, nil /* metrics */)
+	rd.Helper.DecodeWrites = func(line string) { lines = append(lines, line) }
+	b := &kv.Batch{}
+	require.NoError(t, rd.DeleteRow(ctx, b, tree.Datums{tree.NewDInt(1), tree.NewDInt(2)},
+		row.PartialIndexUpdateHelper{}, nil /* oth */, false /* traceKV */))
+	require.Len(t, lines, len(b.Requests()))
+	require.Equal(t, []string{
+		"Del t@t_pkey/1/0 (f0)",
+		"Del t@t_pkey/1/1 (f1)",
+	}, lines[len(lines)-2:])
+	for _, l := range lines[:len(lines)-2] {
+		require.Contains(t, l, "Del t@t_b_idx/2/1/")
+	}
+}
+
+func TestBufferingPutter(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	st := cluster.MakeTestingClusterSettings()
+	table, rangeEnd := bufferingPutterTestTable()
+	ri, err := row.MakeInserter(ctx, nil /* txn */, keys.SystemSQLCodec, table, table.PublicColumns(),
+		&tree.DatumAlloc{}, &st.SV, false /* internal */, nil /* metrics */)
+	require.NoError(t, err)
+	// The rows are inserted in the opposite order of their secondary index
+	// keys, and span two ranges of each index.
+	insert := func(p row.Putter) {
+		for a := 5; a < 15; a++ {
+			values := tree.Datums{tree.NewDInt(tree.DInt(a)), tree.NewDInt(tree.DInt(20 - a)), tree.NewDString("foo")}
+			require.NoError(t, ri.InsertRow(ctx, p, values, row.PartialIndexUpdateHelper{},
+				nil /* oth */, false /* overwrite */, false /* traceKV */))
+		}
+	}
+
+	var unbuffered row.KVCollector
+	insert(&unbuffered)
+	expected := append([]roachpb.KeyValue(nil), unbuffered.KVs...)
+	sort.SliceStable(expected, func(i, j int) bool { return expected[i].Key.Compare(expected[j].Key) < 0 })
+
+	var flushed row.KVCollector
+	var chunks []int
+	p := &row.BufferingPutter{
+		BufferSize: 100,
+		Flush: func(ops []row.BufferedOp) error {
+			for i := range ops {
+				require.Equal(t, rangeEnd(ops[0].Key), rangeEnd(ops[i].Key))
+				ops[i].Apply(&flushed)
+			}
+			chunks = append(chunks, len(ops))
+			return nil
+		},
+		RangeEndKey: rangeEnd,
+	}
+	insert(p)
+	require.Empty(t, flushed.KVs)
+	require.Equal(t, len(expected), p.Buffered())
+	require.NoError(t, p.FlushBuffered())
+	require.Zero(t, p.Buffered())
+	// The writes are flushed sorted by key, one chunk per range.
+	require.Equal(t, expected, flushed.KVs)
+	require.Len(t, chunks, 4)
+
+	// Writes to the same key are flushed in order, in the same chunk.
+	key := keys.SystemSQLCodec.IndexPrefix(104, 1)
+	v1, v2 := roachpb.MakeValueFromString("1"), roachpb.MakeValueFromString("2")
+	flushed, chunks = row.KVCollector{}, nil
+	p.Put(key, &v1)
+	p.Del(key.Next())
+	p.CPut(key, &v2, v1.TagAndDataBytes())
+	require.NoError(t, p.FlushBuffered())
+	require.Equal(t, []roachpb.KeyValue{{Key: key, Value: v1}, {Key: key, Value: v2}, {Key: key.Next()}}, flushed.KVs)
+	require.Equal(t, []int{3}, chunks)
+
+	// Writes are flushed once the buffer is full, and the first error of a
+	// flush is returned.
+	flushed, chunks = row.KVCollector{}, nil
+	p.BufferSize = 2
+	p.Flush = func(ops []row.BufferedOp) error { return errors.New("boom") }
+	p.Put(key, &v1)
+	require.NoError(t, p.Err())
+	p.Put(key, &v2)
+	require.ErrorContains(t, p.Err(), "boom")
+	p.Put(key, &v1)
+	require.Zero(t, p.Buffered())
+}
+
+// BenchmarkBufferingPutter compares the number of batches, and thus of raft
+// proposals, needed to insert rows whose primary and secondary index keys are
+// spread across ranges when each row is written in its own batch, with the
+// number needed when the writes are buffered and flushed one batch per range.
+func BenchmarkBufferingPutter(b *testing.B) {
+	defer log.Scope(b).Close(b)
+
+	ctx := context.Background()
+	st := cluster.MakeTestingClusterSettings()
+	table, rangeEnd := bufferingPutterTestTable()
+	ri, err := row.MakeInserter(ctx, nil /* txn */, keys.SystemSQLCodec, table, table.PublicColumns(),
+		&tree.DatumAlloc{}, &st.SV, false /* internal */, nil /* metrics */)
+	require.NoError(b, err)
+	const numRows = 1000
+	rows := make([]tree.Datums, numRows)
+	for i := range rows {
+		// Scatter the rows across the index ranges.
+		a := (i * 7919) % numRows
+		rows[i] = tree.Datums{tree.NewDInt(tree.DInt(a)), tree.NewDInt(tree.DInt(numRows - a)), tree.NewDString("foo")}
+	}
+	// countProposals returns the number of proposals needed to apply a batch
+	// of writes, one per range they span.
+	countProposals := func(kvs []roachpb.KeyValue) int {
+		ranges := make(map[string]struct{})
+		for _, kv := range kvs {
+			ranges[string(rangeEnd(kv.Key))] = struct{}{}
+		}
+		return len(ranges)
+	}
+
+	for _, bufferSize := range []int{0, 100, 1000, 10000} {
+		b.Run(fmt.Sprintf("buffer=%d", bufferSize), func(b *testing.B) {
+			var proposals int
+			var collector row.KVCollector
+			p := &row.BufferingPutter{
+				BufferSize: bufferSize,
+				Flush: func(ops []row.BufferedOp) error {
+					collector.KVs = collector.KVs[:0]
+					for i := range ops {
+						ops[i].Apply(&collector)
+					}
+					proposals += countProposals(collector.KVs)
+					return nil
+				},
+				RangeEndKey: rangeEnd,
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for _, values := range rows {
+					if bufferSize == 0 {
+						// Each row is written in its own batch.
+						collector.KVs = collector.KVs[:0]
+						if err := ri.InsertRow(ctx, &collector, values, row.PartialIndexUpdateHelper{},
+							nil /* oth */, false /* overwrite */, false /* traceKV */); err != nil {
+							b.Fatal(err)
+						}
+						proposals += countProposals(collector.KVs)
+						continue
+					}
+					if err := ri.InsertRow(ctx, p, values, row.PartialIndexUpdateHelper{},
+						nil /* oth */, false /* overwrite */, false /* traceKV */); err != nil {
+						b.Fatal(err)
+					}
+				}
+				if err := p.FlushBuffered(); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(proposals)/float64(b.N*numRows), "proposals/row")
+		})
+	}
+}
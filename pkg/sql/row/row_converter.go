@@ -15,6 +15,7 @@ import (
 
 	"github.com/cockroachdb/cockroach/pkg/kv"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/catsessiondata"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/colinfo"
@@ -235,9 +236,21 @@ type DatumRowConverter struct {
 	CompletedRowFn func() int64
 	FractionFn     func() float32
 
+	// writeOnce, if set, verifies that no key is produced twice.
+	writeOnce *WriteOncePutter
+
 	db *kv.DB
 }
 
+var importVerifyWriteOnce = settings.RegisterBoolSetting(
+	settings.ApplicationLevel,
+	"bulkio.import.verify_write_once.enabled",
+	"if enabled, IMPORT verifies that each of its workers writes every key at most "+
+		"once, failing on the first duplicate; this retains all keys in memory and "+
+		"should only be used for debugging",
+	false,
+)
+
 var kvDatumRowConverterBatchSize = metamorphic.ConstantWithTestValue(
 	"datum-row-converter-batch-size",
 	5000, /* defaultValue */
@@ -382,6 +395,9 @@ func NewDatumRowConverter(
 
 	c.ri = ri
 	c.cols = cols
+	if importVerifyWriteOnce.Get(&evalCtx.Settings.SV) {
+		c.writeOnce = &WriteOncePutter{Table: tableDesc}
+	}
 
 	c.VisibleCols = targetCols
 	c.VisibleColTypes = make([]*types.T, len(c.VisibleCols))
@@ -559,19 +575,25 @@ func (c *DatumRowConverter) Row(ctx context.Context, sourceID int32, rowIndex in
 		c.EvalCtx.PopIVarContainer()
 	}
 
+	var putter Putter = KVInserter(func(kv roachpb.KeyValue) {
+		kv.Value.InitChecksum(kv.Key)
+		c.KvBatch.KVs = append(c.KvBatch.KVs, kv)
+		c.KvBatch.MemSize += int64(cap(kv.Key) + cap(kv.Value.RawBytes))
+	})
+	if c.writeOnce != nil {
+		c.writeOnce.Putter = putter
+		putter = c.writeOnce
+	}
 	if err := c.ri.InsertRow(
 		ctx,
-		KVInserter(func(kv roachpb.KeyValue) {
-			kv.Value.InitChecksum(kv.Key)
-			c.KvBatch.KVs = append(c.KvBatch.KVs, kv)
-			c.KvBatch.MemSize += int64(cap(kv.Key) + cap(kv.Value.RawBytes))
-		}),
+		putter,
 		insertRow,
 		pm,
 		nil,   /* OriginTimestampCPutHelper */
 		true,  /* ignoreConflicts */
 		false, /* traceKV */
 	); err != nil {
+		// This includes any key written twice, as recorded by writeOnce.
 		return errors.Wrap(err, "insert row")
 	}
 	// If our batch is full, flush it and start a new one.
@@ -40,6 +40,12 @@ type Updater struct {
 	UpdateColIDtoRowIndex catalog.TableColMap
 	primaryKeyColChange   bool
 
+	// WrapPutter, if set, is called with the Putter that each UpdateRow writes
+	// the new values of the row to, and returns the Putter to write them to
+	// instead, e.g. to verify them. Errors recorded by a returned ErrPutter are
+	// returned by UpdateRow.
+	WrapPutter func(Putter) Putter
+
 	// rd and ri are used when the update this Updater is created for modifies
 	// the primary key of the table. In that case, rows must be deleted and
 	// re-added instead of merely updated, since the keys are changing.
@@ -353,7 +359,13 @@ func (ru *Updater) UpdateRow(
 		}
 	}
 
-	putter := &KVBatchAdapter{Batch: batch}
+	// Deletions and the CPuts made to batch directly are rendered through
+	// decoded, rather than putter, as they are not meant to be wrapped.
+	decoded := ru.Helper.wrapPutter(&KVBatchAdapter{Batch: batch})
+	putter := decoded
+	if ru.WrapPutter != nil {
+		putter = ru.WrapPutter(putter)
+	}
 	if rowPrimaryKeyChanged {
 		if err := ru.rd.DeleteRow(ctx, batch, oldValues, pm, oth, traceKV); err != nil {
 			return nil, err
@@ -367,12 +379,18 @@ func (ru *Updater) UpdateRow(
 		return ru.newValues, nil
 	}
 
-	// Add the new values.
+	// Add the new values. The old values are only used to encode the expected
+	// values of the CPuts of oth.
+	var othOldValues []tree.Datum
+	if oth.IsSet() {
+		othOldValues = oldValues
+	}
 	ru.valueBuf, err = prepareInsertOrUpdateBatch(ctx, putter,
 		&ru.Helper, primaryIndexKey, ru.FetchCols,
 		ru.newValues, ru.FetchColIDtoRowIndex,
 		ru.UpdateColIDtoRowIndex,
-		&ru.key, &ru.value, ru.valueBuf, insertPutFn, oth, oldValues, true /* overwrite */, traceKV)
+		&ru.key, &ru.value, ru.valueBuf, insertPutFn, oth, othOldValues, ru.Helper.ValueCodec,
+		true /* overwrite */, traceKV)
 	if err != nil {
 		return nil, err
 	}
@@ -407,7 +425,7 @@ func (ru *Updater) UpdateRow(
 					newIdx++
 					var expValue []byte
 					if !bytes.Equal(oldEntry.Key, newEntry.Key) {
-						if err := ru.Helper.deleteIndexEntry(ctx, batch, index, ru.Helper.secIndexValDirs[i], oldEntry, traceKV); err != nil {
+						if err := ru.Helper.deleteIndexEntry(ctx, decoded, index, ru.Helper.secIndexValDirs[i], oldEntry, traceKV); err != nil {
 							return nil, err
 						}
 					} else if !newEntry.Value.EqualTagAndData(oldEntry.Value) {
@@ -436,6 +454,7 @@ func (ru *Updater) UpdateRow(
 								log.VEventf(ctx, 2, "CPut %s -> %v (expecting does not exist)", k, v)
 							}
 						}
+						decodeCPutAllowingIfNotExists(decoded, newEntry.Key, &newEntry.Value, expValue)
 						batch.CPutAllowingIfNotExists(newEntry.Key, &newEntry.Value, expValue)
 					}
 				} else if oldEntry.Family < newEntry.Family {
@@ -447,7 +466,7 @@ func (ru *Updater) UpdateRow(
 					}
 					// In this case, the index has a k/v for a family that does not exist in
 					// the new set of k/v's for the row. So, we need to delete the old k/v.
-					if err := ru.Helper.deleteIndexEntry(ctx, batch, index, ru.Helper.secIndexValDirs[i], oldEntry, traceKV); err != nil {
+					if err := ru.Helper.deleteIndexEntry(ctx, decoded, index, ru.Helper.secIndexValDirs[i], oldEntry, traceKV); err != nil {
 						return nil, err
 					}
 					oldIdx++
@@ -471,7 +490,7 @@ func (ru *Updater) UpdateRow(
 							v := newEntry.Value.PrettyPrint()
 							log.VEventf(ctx, 2, "CPut %s -> %v (expecting does not exist)", k, v)
 						}
-						batch.CPut(newEntry.Key, &newEntry.Value, nil)
+						decoded.CPut(newEntry.Key, &newEntry.Value, nil)
 					}
 					newIdx++
 				}
@@ -482,7 +501,7 @@ func (ru *Updater) UpdateRow(
 				// the new set of k/v's or 2) the index is a partial index and
 				// the new row values do not match the partial index predicate.
 				oldEntry := &oldEntries[oldIdx]
-				if err := ru.Helper.deleteIndexEntry(ctx, batch, index, ru.Helper.secIndexValDirs[i], oldEntry, traceKV); err != nil {
+				if err := ru.Helper.deleteIndexEntry(ctx, decoded, index, ru.Helper.secIndexValDirs[i], oldEntry, traceKV); err != nil {
 					return nil, err
 				}
 				oldIdx++
@@ -504,14 +523,14 @@ func (ru *Updater) UpdateRow(
 						v := newEntry.Value.PrettyPrint()
 						log.VEventf(ctx, 2, "CPut %s -> %v (expecting does not exist)", k, v)
 					}
-					batch.CPut(newEntry.Key, &newEntry.Value, nil)
+					decoded.CPut(newEntry.Key, &newEntry.Value, nil)
 				}
 				newIdx++
 			}
 		} else {
 			// Remove all inverted index entries, and re-add them.
 			for j := range ru.oldIndexEntries[i] {
-				if err := ru.Helper.deleteIndexEntry(ctx, batch, index, nil /*valDir*/, &ru.oldIndexEntries[i][j], traceKV); err != nil {
+				if err := ru.Helper.deleteIndexEntry(ctx, decoded, index, nil /*valDir*/, &ru.oldIndexEntries[i][j], traceKV); err != nil {
 					return nil, err
 				}
 			}
@@ -539,7 +558,7 @@ func (ru *Updater) UpdateRow(
 
 			if ok {
 				for _, deletedSecondaryIndexEntry := range deletedSecondaryIndexEntries {
-					if err := ru.DeleteHelper.deleteIndexEntry(ctx, batch, index, nil /*valDir*/, &deletedSecondaryIndexEntry, traceKV); err != nil {
+					if err := ru.DeleteHelper.deleteIndexEntry(ctx, decoded, index, nil /*valDir*/, &deletedSecondaryIndexEntry, traceKV); err != nil {
 						return nil, err
 					}
 				}
@@ -547,6 +566,9 @@ func (ru *Updater) UpdateRow(
 		}
 	}
 
+	if err := putterErr(putter); err != nil {
+		return nil, err
+	}
 	return ru.newValues, nil
 }
 
@@ -570,3 +592,20 @@ func (ru *Updater) IsColumnOnlyUpdate() bool {
 	// operations) and these should be split.
 	return !ru.primaryKeyColChange && ru.DeleteHelper == nil && len(ru.Helper.Indexes) == 0
 }
+
+// SetDecodeWrites sets RowHelper.DecodeWrites on the helpers of the Updater,
+// including those used to delete and re-insert rows whose primary key changes.
+func (ru *Updater) SetDecodeWrites(emit func(line string)) {
+	ru.Helper.DecodeWrites = emit
+	// The writes of ri are made through the Putter of UpdateRow, which already
+	// renders them.
+	ru.rd.Helper.DecodeWrites = emit
+}
+
+// SetValueCodec sets RowHelper.ValueCodec on the helpers of the Updater,
+// including those used to delete and re-insert rows whose primary key changes.
+func (ru *Updater) SetValueCodec(codec ValueCodecVersion) {
+	ru.Helper.ValueCodec = codec
+	ru.rd.Helper.ValueCodec = codec
+	ru.ri.Helper.ValueCodec = codec
+}
@@ -11,15 +11,19 @@
 package row
 
 import (
+	"bytes"
 	"context"
 
 	"github.com/cockroachdb/cockroach/pkg/keys"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/catenumpb"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/rowenc"
 	"github.com/cockroachdb/cockroach/pkg/sql/rowenc/valueside"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/util"
+	"github.com/cockroachdb/cockroach/pkg/util/buildutil"
 	"github.com/cockroachdb/errors"
 )
 
@@ -65,6 +69,117 @@ func ColMapping(fromCols, toCols []catalog.Column) []int {
 	return result
 }
 
+// ValueCodecVersion selects the format in which prepareInsertOrUpdateBatch
+// encodes the primary index value of each column family. Every version
+// produces values which can be decoded by all versions of the row fetcher,
+// which determines the format of a family's value from its tag, so the choice
+// only affects the bytes which are written.
+//
+// The zero value is ValueCodecCurrent.
+type ValueCodecVersion int8
+
+const (
+	// ValueCodecCurrent is the encoding used by default. A family which
+	// consists of exactly one column, its DefaultColumnID, is stored as a single
+	// legacy-encoded value (see valueside.MarshalLegacy) without a column ID,
+	// unless it is family 0. All other families are stored as a TUPLE of
+	// column-ID-tagged values (see valueside.Encode).
+	ValueCodecCurrent ValueCodecVersion = iota
+	// ValueCodecLegacyUntagged stores every family other than family 0 as a
+	// single legacy-encoded value wherever the family's contents allow it, i.e.
+	// whenever the family has a DefaultColumnID and none of its other columns
+	// need to be stored in the value, such as primary key columns with
+	// non-composite values (see RowHelper.SkipColumnNotInPrimaryIndexValue).
+	// This matches the
+	// format written for such families by older versions and is meant for
+	// producing values which must be byte-identical to those of a downlevel
+	// writer, e.g. during mixed-version replication. Note that when used with
+	// an OriginTimestampCPutHelper, the expected values are encoded in the
+	// same format, so the existing values must have been written with the same
+	// codec version.
+	ValueCodecLegacyUntagged
+)
+
+// encodesSingleValue returns whether the family's value is to be stored as a
+// single legacy-encoded value rather than a tuple.
+func (v ValueCodecVersion) encodesSingleValue(
+	valueCols *indexValueColumns,
+	family *descpb.ColumnFamilyDescriptor,
+	values []tree.Datum,
+	valColIDMapping catalog.TableColMap,
+) bool {
+	// Decoders expect that column family 0 is encoded with a TUPLE value tag.
+	if family.ID == 0 || family.DefaultColumnID == 0 {
+		return false
+	}
+	if len(family.ColumnIDs) == 1 && family.ColumnIDs[0] == family.DefaultColumnID {
+		return true
+	}
+	if v != ValueCodecLegacyUntagged {
+		return false
+	}
+	for _, colID := range family.ColumnIDs {
+		if colID == family.DefaultColumnID {
+			continue
+		}
+		idx, ok := valColIDMapping.Get(colID)
+		if !ok || !valueCols.skip(colID, values[idx]) {
+			return false
+		}
+	}
+	return true
+}
+
+// indexValueColumns determines which columns are stored in the value of an
+// index with the primary index encoding.
+type indexValueColumns struct {
+	helper *RowHelper
+	// keyCols and storedCols are only populated for indexes other than the
+	// table's primary index; the helper caches the sets of the latter.
+	keyCols, storedCols catalog.TableColSet
+	primary             bool
+}
+
+func makeIndexValueColumns(
+	helper *RowHelper, index catalog.Index, valColIDMapping catalog.TableColMap,
+) (indexValueColumns, error) {
+	if index.GetID() == helper.TableDesc.GetPrimaryIndexID() {
+		return indexValueColumns{helper: helper, primary: true}, nil
+	}
+	if index.GetEncodingType() != catenumpb.PrimaryIndexEncoding {
+		return indexValueColumns{}, errors.AssertionFailedf(
+			"index %q does not use the primary index encoding", index.GetName())
+	}
+	if index.UseDeletePreservingEncoding() {
+		return indexValueColumns{}, errors.AssertionFailedf(
+			"index %q uses the delete-preserving encoding", index.GetName())
+	}
+	c := indexValueColumns{helper: helper, keyCols: index.CollectKeyColumnIDs()}
+	if index.GetVersion() < descpb.PrimaryIndexWithStoredColumnsVersion {
+		// The stored columns are not populated at older versions, in which case
+		// all columns which are not part of the key are stored; see
+		// rowenc.EncodePrimaryIndex.
+		valColIDMapping.ForEach(func(colID descpb.ColumnID, _ int) {
+			c.storedCols.Add(colID)
+		})
+		c.storedCols = c.storedCols.Difference(c.keyCols)
+	} else if index.Primary() {
+		c.storedCols = index.CollectPrimaryStoredColumnIDs()
+	} else {
+		c.storedCols = index.CollectSecondaryStoredColumnIDs()
+	}
+	return c, nil
+}
+
+// skip is like RowHelper.SkipColumnNotInPrimaryIndexValue, but for the index
+// the columns were made for.
+func (c *indexValueColumns) skip(colID descpb.ColumnID, value tree.Datum) bool {
+	if c.primary {
+		return c.helper.SkipColumnNotInPrimaryIndexValue(colID, value)
+	}
+	return skipColumnNotInIndexValue(c.keyCols, c.storedCols, colID, value)
+}
+
 // prepareInsertOrUpdateBatch constructs a KV batch that inserts or
 // updates a row in KV.
 //   - batch is the KV batch where commands should be appended.
@@ -84,8 +199,12 @@ func ColMapping(fromCols, toCols []catalog.Column) []int {
 //   - rawValueBuf must be a scratch byte array. This must be reinitialized
 //     to an empty slice on each call but can be preserved at its current
 //     capacity to avoid allocations. The function returns the slice.
+//   - codec selects the format of the values written; see ValueCodecVersion.
 //   - overwrite must be set to true for UPDATE and UPSERT.
 //   - traceKV is to be set to log the KV operations added to the batch.
+//
+// It is the primary index specialization of
+// prepareInsertOrUpdateBatchForIndex.
 func prepareInsertOrUpdateBatch(
 	ctx context.Context,
 	batch Putter,
@@ -101,39 +220,88 @@ func prepareInsertOrUpdateBatch(
 	putFn func(ctx context.Context, b Putter, key *roachpb.Key, value *roachpb.Value, traceKV bool),
 	oth *OriginTimestampCPutHelper,
 	oldValues []tree.Datum,
+	codec ValueCodecVersion,
+	overwrite, traceKV bool,
+) ([]byte, error) {
+	return prepareInsertOrUpdateBatchForIndex(ctx, batch, helper, helper.TableDesc.GetPrimaryIndex(),
+		primaryIndexKey, fetchedCols, values, valColIDMapping, updatedColIDMapping,
+		kvKey, kvValue, rawValueBuf, putFn, oth, oldValues, codec, overwrite, traceKV)
+}
+
+// prepareInsertOrUpdateBatchForIndex is like prepareInsertOrUpdateBatch, but
+// writes the row in the layout of the given index, whose key prefix for the
+// row is indexKey, rather than the table's primary index. Each column family
+// is encoded as in the primary index, but only contains the index's stored
+// columns and composite key columns; families without any such columns are
+// only written for family 0. This is the layout of the primary index and of
+// any other index with the primary index encoding, e.g. a new primary index
+// being backfilled during a primary key change. Indexes with the secondary
+// index encoding store their values in a different format, and are written
+// by prepareSecondaryEncodedBatch.
+func prepareInsertOrUpdateBatchForIndex(
+	ctx context.Context,
+	batch Putter,
+	helper *RowHelper,
+	index catalog.Index,
+	indexKey []byte,
+	fetchedCols []catalog.Column,
+	values []tree.Datum,
+	valColIDMapping catalog.TableColMap,
+	updatedColIDMapping catalog.TableColMap,
+	kvKey *roachpb.Key,
+	kvValue *roachpb.Value,
+	rawValueBuf []byte,
+	putFn func(ctx context.Context, b Putter, key *roachpb.Key, value *roachpb.Value, traceKV bool),
+	oth *OriginTimestampCPutHelper,
+	oldValues []tree.Datum,
+	codec ValueCodecVersion,
 	overwrite, traceKV bool,
 ) ([]byte, error) {
+	if buildutil.CrdbTestBuild {
+		// An insert which does not overwrite the row expects it not to exist, so
+		// it has no old values to validate.
+		if overwrite || len(oldValues) > 0 {
+			if err := oth.Validate(oldValues); err != nil {
+				return nil, err
+			}
+		}
+		if len(oldValues) > 0 && len(oldValues) != len(values) {
+			return nil, errors.AssertionFailedf(
+				"got %d old values for a row of %d values", len(oldValues), len(values))
+		}
+	}
+	if index.GetEncodingType() == catenumpb.SecondaryIndexEncoding {
+		return rawValueBuf, prepareSecondaryEncodedBatch(ctx, batch, helper, index, indexKey,
+			values, valColIDMapping, kvKey, kvValue, putFn, oth, overwrite, traceKV)
+	}
+	valueCols, err := makeIndexValueColumns(helper, index, valColIDMapping)
+	if err != nil {
+		return nil, err
+	}
 	families := helper.TableDesc.GetFamilies()
+	if len(families) == 1 && !helper.TestingDisableSingleFamilyFastPath {
+		return prepareSingleFamilyBatch(ctx, batch, helper, &valueCols, indexKey, fetchedCols,
+			values, valColIDMapping, updatedColIDMapping, kvKey, kvValue, rawValueBuf, putFn, oth,
+			oldValues, traceKV)
+	}
 	for i := range families {
 		family := &families[i]
-		update := false
-		for _, colID := range family.ColumnIDs {
-			if _, ok := updatedColIDMapping.Get(colID); ok {
-				update = true
-				break
-			}
-		}
-		// We can have an empty family.ColumnIDs in the following case:
-		// * A table is created with the primary key not in family 0, and another column in family 0.
-		// * The column in family 0 is dropped, leaving the 0'th family empty.
-		// In this case, we must keep the empty 0'th column family in order to ensure that column family 0
-		// is always encoded as the sentinel k/v for a row.
-		if !update && len(family.ColumnIDs) != 0 {
+		if !mustWriteFamily(family, updatedColIDMapping) {
 			continue
 		}
 
 		if i > 0 {
 			// HACK: MakeFamilyKey appends to its argument, so on every loop iteration
-			// after the first, trim primaryIndexKey so nothing gets overwritten.
+			// after the first, trim indexKey so nothing gets overwritten.
 			// TODO(dan): Instead of this, use something like engine.ChunkAllocator.
-			primaryIndexKey = primaryIndexKey[:len(primaryIndexKey):len(primaryIndexKey)]
+			indexKey = indexKey[:len(indexKey):len(indexKey)]
 		}
 
-		*kvKey = keys.MakeFamilyKey(primaryIndexKey, uint32(family.ID))
+		*kvKey = keys.MakeFamilyKey(indexKey, uint32(family.ID))
 		// We need to ensure that column family 0 contains extra metadata, like composite primary key values.
 		// Additionally, the decoders expect that column family 0 is encoded with a TUPLE value tag, so we
 		// don't want to use the untagged value encoding.
-		if len(family.ColumnIDs) == 1 && family.ColumnIDs[0] == family.DefaultColumnID && family.ID != 0 {
+		if codec.encodesSingleValue(&valueCols, family, values, valColIDMapping) {
 			// Storage optimization to store DefaultColumnID directly as a value. Also
 			// backwards compatible with the original BaseFormatVersion.
 
@@ -141,9 +309,9 @@ func prepareInsertOrUpdateBatch(
 			if !ok {
 				continue
 			}
-			// Skip any values with a default ID not stored in the primary index,
-			// which can happen if we are adding new columns.
-			if skip := helper.SkipColumnNotInPrimaryIndexValue(family.DefaultColumnID, values[idx]); skip {
+			// Skip any values with a default ID not stored in the index, which can
+			// happen if we are adding new columns.
+			if skip := valueCols.skip(family.DefaultColumnID, values[idx]); skip {
 				continue
 			}
 			typ := fetchedCols[idx].GetType()
@@ -168,6 +336,7 @@ func prepareInsertOrUpdateBatch(
 				if overwrite {
 					// If the new family contains a NULL value, then we must
 					// delete any pre-existing row.
+					helper.recordFamilyWrite(oth.IsSet())
 					if oth.IsSet() {
 						oth.DelWithCPut(ctx, batch, kvKey, oldVal, traceKV)
 					} else {
@@ -181,7 +350,9 @@ func prepareInsertOrUpdateBatch(
 				if err := helper.CheckRowSize(ctx, kvKey, marshaled.RawBytes, family.ID); err != nil {
 					return nil, err
 				}
+				helper.recordFamilyValueBytes(marshaled.RawBytes)
 
+				helper.recordFamilyWrite(oth.IsSet())
 				if oth.IsSet() {
 					oth.CPutFn(ctx, batch, kvKey, &marshaled, oldVal, traceKV)
 				} else {
@@ -192,57 +363,18 @@ func prepareInsertOrUpdateBatch(
 			continue
 		}
 
-		rawValueBuf = rawValueBuf[:0]
-
-		var lastColID descpb.ColumnID
-		var oldBytes []byte
-
-		familySortedColumnIDs, ok := helper.SortedColumnFamily(family.ID)
-		if !ok {
-			return nil, errors.AssertionFailedf("invalid family sorted column id map")
-		}
-		for _, colID := range familySortedColumnIDs {
-			idx, ok := valColIDMapping.Get(colID)
-			if !ok || values[idx] == tree.DNull {
-				// Column not being updated or inserted.
-				continue
-			}
-
-			if skip := helper.SkipColumnNotInPrimaryIndexValue(colID, values[idx]); skip {
-				continue
-			}
-
-			col := fetchedCols[idx]
-			if lastColID > col.GetID() {
-				return nil, errors.AssertionFailedf("cannot write column id %d after %d", col.GetID(), lastColID)
-			}
-			colIDDelta := valueside.MakeColumnIDDelta(lastColID, col.GetID())
-			lastColID = col.GetID()
-			var err error
-			rawValueBuf, err = valueside.Encode(rawValueBuf, colIDDelta, values[idx], nil)
-			if err != nil {
-				return nil, err
-			}
-			if oth.IsSet() && len(oldValues) > 0 {
-				var err error
-				oldBytes, err = valueside.Encode(oldBytes, colIDDelta, oldValues[idx], nil)
-				if err != nil {
-					return nil, err
-				}
-			}
-		}
-
 		var expBytes []byte
-		if oth.IsSet() && len(oldBytes) > 0 {
-			old := &roachpb.Value{}
-			old.SetTuple(oldBytes)
-			expBytes = old.TagAndDataBytes()
+		rawValueBuf, expBytes, err = encodeFamilyTuple(
+			helper, &valueCols, family, fetchedCols, values, valColIDMapping, rawValueBuf, oth, oldValues)
+		if err != nil {
+			return nil, err
 		}
 
 		if family.ID != 0 && len(rawValueBuf) == 0 {
 			if overwrite {
 				// The family might have already existed but every column in it is being
 				// set to NULL, so delete it.
+				helper.recordFamilyWrite(oth.IsSet())
 				if oth.IsSet() {
 					oth.DelWithCPut(ctx, batch, kvKey, expBytes, traceKV)
 				} else {
@@ -257,6 +389,8 @@ func prepareInsertOrUpdateBatch(
 			if err := helper.CheckRowSize(ctx, kvKey, kvValue.RawBytes, family.ID); err != nil {
 				return nil, err
 			}
+			helper.recordFamilyValueBytes(kvValue.RawBytes)
+			helper.recordFamilyWrite(oth.IsSet())
 			if oth.IsSet() {
 				oth.CPutFn(ctx, batch, kvKey, kvValue, expBytes, traceKV)
 			} else {
@@ -274,3 +408,366 @@ func prepareInsertOrUpdateBatch(
 
 	return rawValueBuf, nil
 }
+
+// prepareSecondaryEncodedBatch is the body of
+// prepareInsertOrUpdateBatchForIndex for an index with the secondary index
+// encoding, as used by the secondary indexes of a table. The keys of such an
+// index depend on the row's values, as the key suffix is appended to the key
+// of a unique index if it contains NULLs, so they are encoded from the values
+// by rowenc.EncodeSecondaryIndex, and must lie within indexKey. Families whose
+// stored columns are all NULL are deleted if overwrite is set. As an update
+// which changes the index's key columns must delete the row's old keys, it is
+// up to the caller to do so. Inverted indexes, whose rows may have any number
+// of keys, and origin timestamp CPuts are not supported. The writes are not
+// recorded in the helper's metrics, which only cover the primary index.
+func prepareSecondaryEncodedBatch(
+	ctx context.Context,
+	batch Putter,
+	helper *RowHelper,
+	index catalog.Index,
+	indexKey []byte,
+	values []tree.Datum,
+	valColIDMapping catalog.TableColMap,
+	kvKey *roachpb.Key,
+	kvValue *roachpb.Value,
+	putFn func(ctx context.Context, b Putter, key *roachpb.Key, value *roachpb.Value, traceKV bool),
+	oth *OriginTimestampCPutHelper,
+	overwrite, traceKV bool,
+) error {
+	if index.GetType() == descpb.IndexDescriptor_INVERTED {
+		return errors.AssertionFailedf("index %q is an inverted index", index.GetName())
+	}
+	if index.UseDeletePreservingEncoding() {
+		return errors.AssertionFailedf(
+			"index %q uses the delete-preserving encoding", index.GetName())
+	}
+	if oth.IsSet() {
+		return errors.AssertionFailedf(
+			"origin timestamp CPuts are not supported for index %q", index.GetName())
+	}
+	entries, err := rowenc.EncodeSecondaryIndex(ctx, helper.Codec, helper.TableDesc, index,
+		valColIDMapping, values, overwrite /* includeEmpty */)
+	if err != nil {
+		return err
+	}
+	for i := range entries {
+		entry := &entries[i]
+		if !bytes.HasPrefix(entry.Key, indexKey) {
+			return errors.AssertionFailedf(
+				"key %s of index %q is not within %s", entry.Key, index.GetName(), roachpb.Key(indexKey))
+		}
+		*kvKey = entry.Key
+		if entry.Family != 0 {
+			tuple, err := entry.Value.GetTuple()
+			if err != nil {
+				return err
+			}
+			if len(tuple) == 0 {
+				// Empty families are only included when overwriting, in which case
+				// the family might have already existed, so delete it.
+				insertDelFn(ctx, batch, kvKey, traceKV)
+				*kvKey = nil
+				continue
+			}
+		}
+		if err := helper.CheckRowSize(ctx, kvKey, entry.Value.RawBytes, entry.Family); err != nil {
+			return err
+		}
+		*kvValue = entry.Value
+		putFn(ctx, batch, kvKey, kvValue, traceKV)
+		*kvKey = nil
+		*kvValue = roachpb.Value{}
+	}
+	return nil
+}
+
+// prepareSingleFamilyBatch is the body of prepareInsertOrUpdateBatchForIndex
+// for a table with a single column family, the common case. That family is
+// family 0, which holds the row sentinel and so is never deleted or encoded as
+// a single value, so the per-family checks for these cases are skipped along
+// with the iteration over the families.
+func prepareSingleFamilyBatch(
+	ctx context.Context,
+	batch Putter,
+	helper *RowHelper,
+	valueCols *indexValueColumns,
+	indexKey []byte,
+	fetchedCols []catalog.Column,
+	values []tree.Datum,
+	valColIDMapping catalog.TableColMap,
+	updatedColIDMapping catalog.TableColMap,
+	kvKey *roachpb.Key,
+	kvValue *roachpb.Value,
+	rawValueBuf []byte,
+	putFn func(ctx context.Context, b Putter, key *roachpb.Key, value *roachpb.Value, traceKV bool),
+	oth *OriginTimestampCPutHelper,
+	oldValues []tree.Datum,
+	traceKV bool,
+) ([]byte, error) {
+	family := &helper.TableDesc.GetFamilies()[0]
+	if family.ID != 0 {
+		return nil, errors.AssertionFailedf("single column family has ID %d", family.ID)
+	}
+	if !mustWriteFamily(family, updatedColIDMapping) {
+		return rawValueBuf, nil
+	}
+
+	*kvKey = keys.MakeFamilyKey(indexKey, 0)
+	var expBytes []byte
+	var err error
+	rawValueBuf, expBytes, err = encodeFamilyTuple(
+		helper, valueCols, family, fetchedCols, values, valColIDMapping, rawValueBuf, oth, oldValues)
+	if err != nil {
+		return nil, err
+	}
+	kvValue.SetTuple(rawValueBuf)
+	if err := helper.CheckRowSize(ctx, kvKey, kvValue.RawBytes, family.ID); err != nil {
+		return nil, err
+	}
+	helper.recordFamilyValueBytes(kvValue.RawBytes)
+	helper.recordFamilyWrite(oth.IsSet())
+	if oth.IsSet() {
+		oth.CPutFn(ctx, batch, kvKey, kvValue, expBytes, traceKV)
+	} else {
+		putFn(ctx, batch, kvKey, kvValue, traceKV)
+	}
+
+	// See the end of the loop in prepareInsertOrUpdateBatchForIndex.
+	*kvKey = nil
+	*kvValue = roachpb.Value{}
+	return rawValueBuf, nil
+}
+
+// mustWriteFamily returns whether the family is to be written, i.e. whether
+// any of its columns are updated.
+func mustWriteFamily(
+	family *descpb.ColumnFamilyDescriptor, updatedColIDMapping catalog.TableColMap,
+) bool {
+	// We can have an empty family.ColumnIDs in the following case:
+	// * A table is created with the primary key not in family 0, and another column in family 0.
+	// * The column in family 0 is dropped, leaving the 0'th family empty.
+	// In this case, we must keep the empty 0'th column family in order to ensure that column family 0
+	// is always encoded as the sentinel k/v for a row.
+	if len(family.ColumnIDs) == 0 {
+		return true
+	}
+	for _, colID := range family.ColumnIDs {
+		if _, ok := updatedColIDMapping.Get(colID); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeFamilyTuple encodes the non-NULL values of the family's columns as a
+// tuple into rawValueBuf, which it resets and returns. If oth is set, it also
+// returns the tag and data bytes of the family's old value, as encoded from
+// oldValues, to be used as the expected value of a CPut.
+func encodeFamilyTuple(
+	helper *RowHelper,
+	valueCols *indexValueColumns,
+	family *descpb.ColumnFamilyDescriptor,
+	fetchedCols []catalog.Column,
+	values []tree.Datum,
+	valColIDMapping catalog.TableColMap,
+	rawValueBuf []byte,
+	oth *OriginTimestampCPutHelper,
+	oldValues []tree.Datum,
+) (_ []byte, expBytes []byte, _ error) {
+	rawValueBuf = rawValueBuf[:0]
+
+	var lastColID descpb.ColumnID
+	var oldBytes []byte
+
+	familySortedColumnIDs, ok := helper.SortedColumnFamily(family.ID)
+	if !ok {
+		return nil, nil, errors.AssertionFailedf("invalid family sorted column id map")
+	}
+	for _, colID := range familySortedColumnIDs {
+		idx, ok := valColIDMapping.Get(colID)
+		if !ok || values[idx] == tree.DNull {
+			// Column not being updated or inserted.
+			continue
+		}
+
+		if skip := valueCols.skip(colID, values[idx]); skip {
+			continue
+		}
+
+		col := fetchedCols[idx]
+		if lastColID > col.GetID() {
+			return nil, nil, errors.AssertionFailedf("cannot write column id %d after %d", col.GetID(), lastColID)
+		}
+		colIDDelta := valueside.MakeColumnIDDelta(lastColID, col.GetID())
+		lastColID = col.GetID()
+		var err error
+		rawValueBuf, err = valueside.Encode(rawValueBuf, colIDDelta, values[idx], nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		if oth.IsSet() && len(oldValues) > 0 {
+			var err error
+			oldBytes, err = valueside.Encode(oldBytes, colIDDelta, oldValues[idx], nil)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	if oth.IsSet() && len(oldBytes) > 0 {
+		old := &roachpb.Value{}
+		old.SetTuple(oldBytes)
+		expBytes = old.TagAndDataBytes()
+	}
+	return rawValueBuf, expBytes, nil
+}
+
+// EncodeRowOptions configures EncodeRowKVs.
+type EncodeRowOptions struct {
+	// Overwrite encodes the row as it would be written by an UPDATE or
+	// UPSERT rather than by an INSERT, i.e. column families which only
+	// contain NULL values are deleted.
+	Overwrite bool
+	// Index, if set, is the index whose layout the row is encoded in instead
+	// of the table's primary index. It must not be an inverted index; see
+	// prepareInsertOrUpdateBatchForIndex.
+	Index catalog.Index
+}
+
+// ErrInvalidEncodeRowInput marks the errors returned by EncodeRowKVs when the
+// row it is given cannot be encoded, e.g. because a value does not match the
+// type of its column. Any other error returned for a row which passes these
+// checks, in particular an assertion failure, is a bug in the encoder.
+var ErrInvalidEncodeRowInput = errors.New("invalid row encoding input")
+
+func invalidEncodeRowInputf(format string, args ...interface{}) error {
+	return errors.Mark(errors.Newf(format, args...), ErrInvalidEncodeRowInput)
+}
+
+// validateEncodeRowInputs checks the inputs of EncodeRowKVs up front, so that
+// callers constructing rows from arbitrary input get an error marked with
+// ErrInvalidEncodeRowInput rather than an assertion failure, a panic or a
+// corrupt encoding from deep within prepareInsertOrUpdateBatchForIndex.
+func validateEncodeRowInputs(
+	helper *RowHelper,
+	index catalog.Index,
+	indexKey roachpb.Key,
+	cols []catalog.Column,
+	values []tree.Datum,
+) error {
+	if len(values) != len(cols) {
+		return invalidEncodeRowInputf("got %d values but expected %d", len(values), len(cols))
+	}
+	if index.GetType() == descpb.IndexDescriptor_INVERTED {
+		return invalidEncodeRowInputf("index %q is an inverted index", index.GetName())
+	}
+	if index.UseDeletePreservingEncoding() {
+		return invalidEncodeRowInputf("index %q uses the delete-preserving encoding", index.GetName())
+	}
+	prefix := helper.Codec.IndexPrefix(uint32(helper.TableDesc.GetID()), uint32(index.GetID()))
+	if !bytes.HasPrefix(indexKey, prefix) {
+		return invalidEncodeRowInputf("key %s is not in index %q", indexKey, index.GetName())
+	}
+	var seen catalog.TableColSet
+	for i, col := range cols {
+		if seen.Contains(col.GetID()) {
+			return invalidEncodeRowInputf("column %q is given more than once", col.GetName())
+		}
+		seen.Add(col.GetID())
+		if catalog.FindColumnByID(helper.TableDesc, col.GetID()) == nil {
+			return invalidEncodeRowInputf("column %q is not a column of table %q",
+				col.GetName(), helper.TableDesc.GetName())
+		}
+		if values[i] == nil {
+			return invalidEncodeRowInputf("missing value for column %q", col.GetName())
+		}
+		if values[i] != tree.DNull && !values[i].ResolvedType().Equivalent(col.GetType()) {
+			return invalidEncodeRowInputf("value of type %s does not match column %q of type %s",
+				values[i].ResolvedType().SQLString(), col.GetName(), col.GetType().SQLString())
+		}
+	}
+	return nil
+}
+
+// EncodeRowKVs returns the primary index key/value pairs which inserting or
+// updating a row would write, one per column family, in the order in which
+// they would be written. It runs the same encoding as the Inserter and
+// Updater, using helper.ValueCodec, but collects the result instead of adding
+// it to a batch, which makes it convenient for testing the encoding and for
+// tools which need to precompute a row's KV representation. Deleted families
+// are returned with an empty Value. If opts.Index is set, the row is encoded
+// in the layout of that index instead. Inputs which cannot be encoded result
+// in an error marked with ErrInvalidEncodeRowInput.
+//
+// indexKey is the key prefix of the row in the index being encoded, and values
+// holds the value of each of cols. The keys of an index with the secondary
+// index encoding are encoded from values, in which case indexKey may be any
+// prefix of them, such as the index's prefix.
+func EncodeRowKVs(
+	ctx context.Context,
+	helper *RowHelper,
+	indexKey roachpb.Key,
+	cols []catalog.Column,
+	values []tree.Datum,
+	opts EncodeRowOptions,
+) ([]roachpb.KeyValue, error) {
+	var collector KVCollector
+	if err := encodeRow(ctx, &collector, helper, indexKey, cols, values, opts); err != nil {
+		return nil, err
+	}
+	return collector.KVs, nil
+}
+
+// RecordRowWrites is like EncodeRowKVs, but returns the writes made to encode
+// the row as recorded by a RecordingPutter, i.e. including their request types
+// and expected values. It is meant for checking that a change to the writer
+// preserves its behavior, by comparing the writes it makes for a corpus of
+// rows with those made by the previous version or with different options
+// using DiffOps.
+func RecordRowWrites(
+	ctx context.Context,
+	helper *RowHelper,
+	indexKey roachpb.Key,
+	cols []catalog.Column,
+	values []tree.Datum,
+	opts EncodeRowOptions,
+) ([]BufferedOp, error) {
+	var recorder RecordingPutter
+	if err := encodeRow(ctx, &recorder, helper, indexKey, cols, values, opts); err != nil {
+		return nil, err
+	}
+	return recorder.Ops, nil
+}
+
+// encodeRow writes the KVs of a row to p for EncodeRowKVs and RecordRowWrites.
+func encodeRow(
+	ctx context.Context,
+	p Putter,
+	helper *RowHelper,
+	indexKey roachpb.Key,
+	cols []catalog.Column,
+	values []tree.Datum,
+	opts EncodeRowOptions,
+) error {
+	index := opts.Index
+	if index == nil {
+		index = helper.TableDesc.GetPrimaryIndex()
+	}
+	if err := validateEncodeRowInputs(helper, index, indexKey, cols, values); err != nil {
+		return err
+	}
+	colIDtoRowIndex := ColIDtoRowIndexFromCols(cols)
+	putFn := insertCPutFn
+	if opts.Overwrite {
+		putFn = insertPutFn
+	}
+	var key roachpb.Key
+	var value roachpb.Value
+	_, err := prepareInsertOrUpdateBatchForIndex(ctx, p,
+		helper, index, indexKey, cols,
+		values, colIDtoRowIndex,
+		colIDtoRowIndex,
+		&key, &value, nil /* rawValueBuf */, putFn, nil /* oth */, nil /* oldValues */, helper.ValueCodec,
+		opts.Overwrite, false /* traceKV */)
+	return err
+}
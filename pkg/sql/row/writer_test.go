@@ -0,0 +1,772 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package row_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/catenumpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/desctestutils"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/fetchpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/randgen"
+	"github.com/cockroachdb/cockroach/pkg/sql/row"
+	"github.com/cockroachdb/cockroach/pkg/sql/rowenc"
+	"github.com/cockroachdb/cockroach/pkg/sql/rowenc/valueside"
+	"github.com/cockroachdb/cockroach/pkg/sql/rowinfra"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/testutils/datapathutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/pkg/util/encoding"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/metric"
+	"github.com/cockroachdb/datadriven"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// makeEncodeRowTestTable returns the descriptor of:
+//
+//	CREATE TABLE t (
+//	  a INT PRIMARY KEY, b INT, c STRING,
+//	  FAMILY (a, b), FAMILY (c),
+//	  INDEX t_b_idx (b) STORING (c)
+//	)
+//
+// along with t_b_pkey, an index which is being added with the primary index
+// encoding as it would be during ALTER PRIMARY KEY USING COLUMNS (b).
+func makeEncodeRowTestTable() catalog.TableDescriptor {
+	return tabledesc.NewBuilder(&descpb.TableDescriptor{
+		ID:   104,
+		Name: "t",
+		Columns: []descpb.ColumnDescriptor{
+			{ID: 1, Name: "a", Type: types.Int},
+			{ID: 2, Name: "b", Type: types.Int, Nullable: true},
+			{ID: 3, Name: "c", Type: types.String, Nullable: true},
+		},
+		Families: []descpb.ColumnFamilyDescriptor{
+			{ID: 0, Name: "f0", ColumnIDs: []descpb.ColumnID{1, 2}, ColumnNames: []string{"a", "b"}},
+			{ID: 1, Name: "f1", ColumnIDs: []descpb.ColumnID{3}, ColumnNames: []string{"c"}, DefaultColumnID: 3},
+		},
+		PrimaryIndex: descpb.IndexDescriptor{
+			ID:                  1,
+			Name:                "t_pkey",
+			Unique:              true,
+			KeyColumnIDs:        []descpb.ColumnID{1},
+			KeyColumnNames:      []string{"a"},
+			KeyColumnDirections: []catenumpb.IndexColumn_Direction{catenumpb.IndexColumn_ASC},
+			StoreColumnIDs:      []descpb.ColumnID{2, 3},
+			StoreColumnNames:    []string{"b", "c"},
+			EncodingType:        catenumpb.PrimaryIndexEncoding,
+			Version:             descpb.LatestIndexDescriptorVersion,
+		},
+		Indexes: []descpb.IndexDescriptor{{
+			ID:                  2,
+			Name:                "t_b_idx",
+			KeyColumnIDs:        []descpb.ColumnID{2},
+			KeyColumnNames:      []string{"b"},
+			KeyColumnDirections: []catenumpb.IndexColumn_Direction{catenumpb.IndexColumn_ASC},
+			KeySuffixColumnIDs:  []descpb.ColumnID{1},
+			StoreColumnIDs:      []descpb.ColumnID{3},
+			StoreColumnNames:    []string{"c"},
+			EncodingType:        catenumpb.SecondaryIndexEncoding,
+			Version:             descpb.LatestIndexDescriptorVersion,
+		}},
+		Mutations: []descpb.DescriptorMutation{{
+			Descriptor_: &descpb.DescriptorMutation_Index{Index: &descpb.IndexDescriptor{
+				ID:                  3,
+				Name:                "t_b_pkey",
+				Unique:              true,
+				KeyColumnIDs:        []descpb.ColumnID{2},
+				KeyColumnNames:      []string{"b"},
+				KeyColumnDirections: []catenumpb.IndexColumn_Direction{catenumpb.IndexColumn_ASC},
+				StoreColumnIDs:      []descpb.ColumnID{1, 3},
+				StoreColumnNames:    []string{"a", "c"},
+				EncodingType:        catenumpb.PrimaryIndexEncoding,
+				Version:             descpb.LatestIndexDescriptorVersion,
+			}},
+			State:     descpb.DescriptorMutation_WRITE_ONLY,
+			Direction: descpb.DescriptorMutation_ADD,
+		}},
+		NextColumnID: 4,
+		NextFamilyID: 2,
+		NextIndexID:  4,
+	}).BuildImmutableTable()
+}
+
+func TestEncodeRowKVs(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	st := cluster.MakeTestingClusterSettings()
+
+	desc := makeEncodeRowTestTable()
+	helper := row.NewRowHelper(keys.SystemSQLCodec, desc, nil /* indexes */, &st.SV, false /* internal */, nil /* metrics */)
+	pk := roachpb.Key(encoding.EncodeVarintAscending(keys.SystemSQLCodec.IndexPrefix(104, 1), 1))
+	cols := desc.PublicColumns()
+
+	kvs, err := row.EncodeRowKVs(ctx, &helper, pk, cols,
+		tree.Datums{tree.NewDInt(1), tree.NewDInt(2), tree.NewDString("foo")}, row.EncodeRowOptions{})
+	require.NoError(t, err)
+	require.Len(t, kvs, 2)
+	require.Equal(t, roachpb.Key(keys.MakeFamilyKey(pk, 0)), kvs[0].Key)
+	require.Equal(t, roachpb.ValueType_TUPLE, kvs[0].Value.GetTag())
+	require.Equal(t, roachpb.Key(keys.MakeFamilyKey(pk, 1)), kvs[1].Key)
+	c, err := kvs[1].Value.GetBytes()
+	require.NoError(t, err)
+	require.Equal(t, "foo", string(c))
+
+	// A family which is entirely NULL is not written by an insert, but is
+	// deleted when overwriting.
+	nullRow := tree.Datums{tree.NewDInt(1), tree.NewDInt(2), tree.DNull}
+	kvs, err = row.EncodeRowKVs(ctx, &helper, pk, cols, nullRow, row.EncodeRowOptions{})
+	require.NoError(t, err)
+	require.Len(t, kvs, 1)
+
+	kvs, err = row.EncodeRowKVs(ctx, &helper, pk, cols, nullRow, row.EncodeRowOptions{Overwrite: true})
+	require.NoError(t, err)
+	require.Len(t, kvs, 2)
+	require.Equal(t, roachpb.Key(keys.MakeFamilyKey(pk, 1)), kvs[1].Key)
+	require.False(t, kvs[1].Value.IsPresent())
+
+	_, err = row.EncodeRowKVs(ctx, &helper, pk, cols, nullRow[:2], row.EncodeRowOptions{})
+	require.Error(t, err)
+}
+
+// TestEncodeRowKVsLegacyUntagged tests that ValueCodecLegacyUntagged encodes a
+// family other than family 0 which has a DefaultColumnID as a single legacy
+// value, even if it also holds primary key columns, and that the row fetcher
+// decodes the row to the same datums regardless of the codec version.
+func TestEncodeRowKVsLegacyUntagged(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	st := cluster.MakeTestingClusterSettings()
+
+	// CREATE TABLE t (
+	//   a INT PRIMARY KEY, b INT, c STRING, d INT,
+	//   FAMILY f0 (b), FAMILY f1 (a, c), FAMILY f2 (d)
+	// )
+	desc := tabledesc.NewBuilder(&descpb.TableDescriptor{
+		ID:   104,
+		Name: "t",
+		Columns: []descpb.ColumnDescriptor{
+			{ID: 1, Name: "a", Type: types.Int},
+			{ID: 2, Name: "b", Type: types.Int, Nullable: true},
+			{ID: 3, Name: "c", Type: types.String, Nullable: true},
+			{ID: 4, Name: "d", Type: types.Int, Nullable: true},
+		},
+		Families: []descpb.ColumnFamilyDescriptor{
+			{ID: 0, Name: "f0", ColumnIDs: []descpb.ColumnID{2}, ColumnNames: []string{"b"}, DefaultColumnID: 2},
+			{ID: 1, Name: "f1", ColumnIDs: []descpb.ColumnID{1, 3}, ColumnNames: []string{"a", "c"}, DefaultColumnID: 3},
+			{ID: 2, Name: "f2", ColumnIDs: []descpb.ColumnID{4}, ColumnNames: []string{"d"}, DefaultColumnID: 4},
+		},
+		PrimaryIndex: descpb.IndexDescriptor{
+			ID:                  1,
+			Name:                "t_pkey",
+			Unique:              true,
+			KeyColumnIDs:        []descpb.ColumnID{1},
+			KeyColumnNames:      []string{"a"},
+			KeyColumnDirections: []catenumpb.IndexColumn_Direction{catenumpb.IndexColumn_ASC},
+			StoreColumnIDs:      []descpb.ColumnID{2, 3, 4},
+			StoreColumnNames:    []string{"b", "c", "d"},
+			EncodingType:        catenumpb.PrimaryIndexEncoding,
+			Version:             descpb.LatestIndexDescriptorVersion,
+		},
+		NextColumnID: 5,
+		NextFamilyID: 3,
+		NextIndexID:  2,
+	}).BuildImmutableTable()
+	pk := roachpb.Key(encoding.EncodeVarintAscending(keys.SystemSQLCodec.IndexPrefix(104, 1), 1))
+	cols := desc.PublicColumns()
+	values := tree.Datums{tree.NewDInt(1), tree.NewDInt(2), tree.NewDString("foo"), tree.NewDInt(3)}
+
+	var spec fetchpb.IndexFetchSpec
+	require.NoError(t, rowenc.InitIndexFetchSpec(
+		&spec, keys.SystemSQLCodec, desc, desc.GetPrimaryIndex(), desc.PublicColumnIDs(),
+	))
+	var rf row.Fetcher
+	require.NoError(t, rf.Init(ctx, row.FetcherInitArgs{
+		WillUseKVProvider: true,
+		Alloc:             &tree.DatumAlloc{},
+		Spec:              &spec,
+	}))
+	decode := func(kvs []roachpb.KeyValue) tree.Datums {
+		require.NoError(t, rf.ConsumeKVProvider(ctx, &row.KVProvider{KVs: kvs}))
+		datums, err := rf.NextRowDecoded(ctx)
+		require.NoError(t, err)
+		return append(tree.Datums(nil), datums...)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		codec row.ValueCodecVersion
+		// f1Tag is the tag of the value of family f1.
+		f1Tag roachpb.ValueType
+	}{
+		{name: "current", codec: row.ValueCodecCurrent, f1Tag: roachpb.ValueType_TUPLE},
+		{name: "legacy-untagged", codec: row.ValueCodecLegacyUntagged, f1Tag: roachpb.ValueType_BYTES},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			helper := row.NewRowHelper(keys.SystemSQLCodec, desc, nil /* indexes */, &st.SV, false /* internal */, nil /* metrics */)
+			helper.ValueCodec = tc.codec
+			kvs, err := row.EncodeRowKVs(ctx, &helper, pk, cols, values, row.EncodeRowOptions{})
+			require.NoError(t, err)
+			require.Len(t, kvs, 3)
+			// Family 0 is always a tuple, and a family consisting of only its
+			// DefaultColumnID is always a single value.
+			require.Equal(t, roachpb.ValueType_TUPLE, kvs[0].Value.GetTag())
+			require.Equal(t, tc.f1Tag, kvs[1].Value.GetTag())
+			require.Equal(t, roachpb.ValueType_INT, kvs[2].Value.GetTag())
+			if tc.codec == row.ValueCodecLegacyUntagged {
+				legacy, err := valueside.MarshalLegacy(types.String, values[2])
+				require.NoError(t, err)
+				require.Equal(t, legacy.RawBytes, kvs[1].Value.RawBytes)
+			}
+
+			decoded := decode(kvs)
+			require.Equal(t, tree.AsString(&values), tree.AsString(&decoded))
+		})
+	}
+}
+
+func TestEncodeRowKVsForIndex(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	st := cluster.MakeTestingClusterSettings()
+	desc := makeEncodeRowTestTable()
+	helper := row.NewRowHelper(keys.SystemSQLCodec, desc, nil /* indexes */, &st.SV, false /* internal */, nil /* metrics */)
+	cols := desc.PublicColumns()
+	colMap := row.ColIDtoRowIndexFromCols(cols)
+
+	newPK, err := catalog.MustFindIndexByName(desc, "t_b_pkey")
+	require.NoError(t, err)
+	for _, values := range []tree.Datums{
+		{tree.NewDInt(1), tree.NewDInt(2), tree.NewDString("foo")},
+		{tree.NewDInt(1), tree.NewDInt(2), tree.DNull},
+	} {
+		// The index stores a and c, but not b which is in its key, so the row
+		// must be encoded exactly as it would be by the index backfiller.
+		expected, err := rowenc.EncodePrimaryIndex(keys.SystemSQLCodec, desc, newPK, colMap, values, false /* includeEmpty */)
+		require.NoError(t, err)
+		indexKey, _, err := rowenc.EncodeIndexKey(desc, newPK, colMap, values,
+			rowenc.MakeIndexKeyPrefix(keys.SystemSQLCodec, desc.GetID(), newPK.GetID()))
+		require.NoError(t, err)
+
+		kvs, err := row.EncodeRowKVs(ctx, &helper, indexKey, cols, values, row.EncodeRowOptions{Index: newPK})
+		require.NoError(t, err)
+		require.Len(t, kvs, len(expected))
+		for i := range expected {
+			require.Equal(t, roachpb.Key(expected[i].Key), kvs[i].Key)
+			require.Equal(t, expected[i].Value.RawBytes, kvs[i].Value.RawBytes)
+		}
+	}
+
+	// Rows written to an index with the secondary index encoding are encoded
+	// exactly as they would be by the index backfiller, whether the index key
+	// passed is the row's key in the index or only the index's prefix.
+	secondary, err := catalog.MustFindIndexByName(desc, "t_b_idx")
+	require.NoError(t, err)
+	for _, values := range []tree.Datums{
+		{tree.NewDInt(1), tree.NewDInt(2), tree.NewDString("foo")},
+		{tree.NewDInt(1), tree.NewDInt(2), tree.DNull},
+	} {
+		expected, err := rowenc.EncodeSecondaryIndex(
+			ctx, keys.SystemSQLCodec, desc, secondary, colMap, values, false /* includeEmpty */)
+		require.NoError(t, err)
+		indexKey, _, err := rowenc.EncodeIndexKey(desc, secondary, colMap, values,
+			rowenc.MakeIndexKeyPrefix(keys.SystemSQLCodec, desc.GetID(), secondary.GetID()))
+		require.NoError(t, err)
+
+		for _, key := range []roachpb.Key{indexKey, keys.SystemSQLCodec.IndexPrefix(104, 2)} {
+			kvs, err := row.EncodeRowKVs(ctx, &helper, key, cols, values, row.EncodeRowOptions{Index: secondary})
+			require.NoError(t, err)
+			require.Len(t, kvs, len(expected))
+			for i := range expected {
+				require.Equal(t, roachpb.Key(expected[i].Key), kvs[i].Key)
+				require.Equal(t, expected[i].Value.RawBytes, kvs[i].Value.RawBytes)
+			}
+		}
+	}
+
+	// When overwriting, the family of the stored column is deleted if it is
+	// NULL.
+	nullRow := tree.Datums{tree.NewDInt(1), tree.NewDInt(2), tree.DNull}
+	kvs, err := row.EncodeRowKVs(ctx, &helper, keys.SystemSQLCodec.IndexPrefix(104, 2), cols, nullRow,
+		row.EncodeRowOptions{Index: secondary, Overwrite: true})
+	require.NoError(t, err)
+	require.Len(t, kvs, 2)
+	rowKey, err := keys.EnsureSafeSplitKey(kvs[0].Key)
+	require.NoError(t, err)
+	require.Equal(t, roachpb.Key(keys.MakeFamilyKey(rowKey, 1)), kvs[1].Key)
+	require.False(t, kvs[1].Value.IsPresent())
+
+	// The row's keys must be within the index key which is passed.
+	otherKey, _, err := rowenc.EncodeIndexKey(desc, secondary, colMap,
+		tree.Datums{tree.NewDInt(1), tree.NewDInt(3), tree.DNull},
+		rowenc.MakeIndexKeyPrefix(keys.SystemSQLCodec, desc.GetID(), secondary.GetID()))
+	require.NoError(t, err)
+	_, err = row.EncodeRowKVs(ctx, &helper, otherKey, cols, nullRow, row.EncodeRowOptions{Index: secondary})
+	require.ErrorContains(t, err, "is not within")
+}
+
+func TestFamilyValueBytesMetric(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	st := cluster.MakeTestingClusterSettings()
+
+	metrics := &rowinfra.Metrics{
+		FamilyValueBytes: metric.NewHistogram(metric.HistogramOptions{
+			Metadata:     rowinfra.MetaFamilyValueBytes,
+			Duration:     time.Minute,
+			BucketConfig: metric.DataSize16MBBuckets,
+			Mode:         metric.HistogramModePrometheus,
+		}),
+	}
+	desc := makeEncodeRowTestTable()
+	helper := row.NewRowHelper(keys.SystemSQLCodec, desc, nil /* indexes */, &st.SV, false /* internal */, metrics)
+	pk := roachpb.Key(encoding.EncodeVarintAscending(keys.SystemSQLCodec.IndexPrefix(104, 1), 1))
+	cols := desc.PublicColumns()
+
+	// Each family written is recorded once, with the size of its value.
+	kvs, err := row.EncodeRowKVs(ctx, &helper, pk, cols,
+		tree.Datums{tree.NewDInt(1), tree.NewDInt(2), tree.NewDString("foo")}, row.EncodeRowOptions{})
+	require.NoError(t, err)
+	count, sum := metrics.FamilyValueBytes.CumulativeSnapshot().Total()
+	require.Equal(t, int64(2), count)
+	require.Equal(t, float64(len(kvs[0].Value.RawBytes)+len(kvs[1].Value.RawBytes)), sum)
+
+	// Deleted families are not recorded.
+	_, err = row.EncodeRowKVs(ctx, &helper, pk, cols,
+		tree.Datums{tree.NewDInt(1), tree.NewDInt(2), tree.DNull}, row.EncodeRowOptions{Overwrite: true})
+	require.NoError(t, err)
+	count, _ = metrics.FamilyValueBytes.CumulativeSnapshot().Total()
+	require.Equal(t, int64(3), count)
+}
+
+func TestPutMetrics(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	st := cluster.MakeTestingClusterSettings()
+
+	metrics := &rowinfra.Metrics{
+		OriginTimestampCPuts: metric.NewCounter(rowinfra.MetaOriginTimestampCPuts),
+		Puts:                 metric.NewCounter(rowinfra.MetaPuts),
+	}
+	desc := makeEncodeRowTestTable()
+	helper := row.NewRowHelper(keys.SystemSQLCodec, desc, nil /* indexes */, &st.SV, false /* internal */, metrics)
+	pk := roachpb.Key(encoding.EncodeVarintAscending(keys.SystemSQLCodec.IndexPrefix(104, 1), 1))
+	cols := desc.PublicColumns()
+
+	// Each family written is counted once.
+	_, err := row.EncodeRowKVs(ctx, &helper, pk, cols,
+		tree.Datums{tree.NewDInt(1), tree.NewDInt(2), tree.NewDString("foo")}, row.EncodeRowOptions{})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), metrics.Puts.Count())
+
+	// So is each family deleted.
+	_, err = row.EncodeRowKVs(ctx, &helper, pk, cols,
+		tree.Datums{tree.NewDInt(1), tree.NewDInt(2), tree.DNull}, row.EncodeRowOptions{Overwrite: true})
+	require.NoError(t, err)
+	require.Equal(t, int64(4), metrics.Puts.Count())
+	require.Zero(t, metrics.OriginTimestampCPuts.Count())
+}
+
+func TestRecordRowWritesDiff(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	st := cluster.MakeTestingClusterSettings()
+	desc := makeEncodeRowTestTable()
+	helper := row.NewRowHelper(keys.SystemSQLCodec, desc, nil /* indexes */, &st.SV, false /* internal */, nil /* metrics */)
+	pk := roachpb.Key(encoding.EncodeVarintAscending(keys.SystemSQLCodec.IndexPrefix(104, 1), 1))
+	cols := desc.PublicColumns()
+	record := func(values tree.Datums, opts row.EncodeRowOptions) []row.BufferedOp {
+		ops, err := row.RecordRowWrites(ctx, &helper, pk, cols, values, opts)
+		require.NoError(t, err)
+		return ops
+	}
+	diff := func(before, after []row.BufferedOp) []string {
+		return row.DiffOps(keys.SystemSQLCodec, desc, before, after)
+	}
+
+	values := tree.Datums{tree.NewDInt(1), tree.NewDInt(2), tree.NewDString("foo")}
+	inserted := record(values, row.EncodeRowOptions{})
+	require.Len(t, inserted, 2)
+	require.Nil(t, diff(inserted, record(values, row.EncodeRowOptions{})))
+
+	// Writes which are only made by one version, or are made differently, are
+	// listed with their request types.
+	nullRow := tree.Datums{tree.NewDInt(1), tree.NewDInt(2), tree.DNull}
+	require.Equal(t, []string{
+		"- CPut t@t_pkey/1/0 (f0) -> b=2 (if not exists)",
+		"+ Put t@t_pkey/1/0 (f0) -> b=2",
+		"+ Del t@t_pkey/1/1 (f1)",
+	}, diff(record(nullRow, row.EncodeRowOptions{}), record(nullRow, row.EncodeRowOptions{Overwrite: true})))
+
+	// Writes made in a different order are listed with their positions.
+	require.Equal(t, []string{
+		"~ CPut t@t_pkey/1/1 (f1) -> c='foo' (if not exists) (write 1, was write 2)",
+	}, diff(inserted, []row.BufferedOp{inserted[1], inserted[0]}))
+}
+
+// TestRecordRowWritesGolden runs RecordRowWrites over the corpus of schemas and
+// rows in testdata/record_row_writes, and checks the writes made against those
+// recorded there, so that a change to the writer which changes the writes it
+// makes for any of them is reported with the writes which differ. Run with
+// --rewrite to record the writes made once such a change is intended.
+//
+// The commands are:
+//
+//   - exec: runs the SQL statements in the input, e.g. to create tables.
+//   - write table=<name> [index=<name>] [overwrite] values=(<datum>,...): lists
+//     the writes made to encode the row in the given index, the primary index
+//     by default. Datums are parsed as the type of their column, and NULL is
+//     NULL.
+//   - diff table=<name> [index=<name>] values=(<datum>,...) before=<mode>
+//     after=<mode>: lists the differences, as reported by DiffOps, between the
+//     writes made to encode the row in the two modes, insert or overwrite.
+func TestRecordRowWritesGolden(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	srv, db, kvDB := serverutils.StartServer(t, base.TestServerArgs{})
+	defer srv.Stopper().Stop(ctx)
+	s := srv.ApplicationLayer()
+	codec := s.Codec()
+
+	datadriven.RunTest(t, datapathutils.TestDataPath(t, "record_row_writes"),
+		func(t *testing.T, d *datadriven.TestData) string {
+			if d.Cmd == "exec" {
+				if _, err := db.Exec(d.Input); err != nil {
+					d.Fatalf(t, "%+v", err)
+				}
+				return ""
+			}
+
+			var tableName, indexName string
+			d.ScanArgs(t, "table", &tableName)
+			d.MaybeScanArgs(t, "index", &indexName)
+			desc := desctestutils.TestingGetPublicTableDescriptor(kvDB, codec, "defaultdb", tableName)
+			index := desc.GetPrimaryIndex()
+			if indexName != "" {
+				var err error
+				if index, err = catalog.MustFindIndexByName(desc, indexName); err != nil {
+					d.Fatalf(t, "%+v", err)
+				}
+			}
+			cols := desc.PublicColumns()
+			arg, ok := d.Arg("values")
+			if !ok || len(arg.Vals) != len(cols) {
+				d.Fatalf(t, "expected values=(...) with a datum for each of the %d columns", len(cols))
+			}
+			values := make(tree.Datums, len(cols))
+			for i, v := range arg.Vals {
+				if v == "NULL" {
+					values[i] = tree.DNull
+					continue
+				}
+				var err error
+				if values[i], _, err = tree.ParseAndRequireString(cols[i].GetType(), v, nil /* ctx */); err != nil {
+					d.Fatalf(t, "%+v", err)
+				}
+			}
+			indexKey, _, err := rowenc.EncodeIndexKey(desc, index, row.ColIDtoRowIndexFromCols(cols), values,
+				rowenc.MakeIndexKeyPrefix(codec, desc.GetID(), index.GetID()))
+			if err != nil {
+				d.Fatalf(t, "%+v", err)
+			}
+			helper := row.NewRowHelper(codec, desc, nil /* indexes */, &s.ClusterSettings().SV, false /* internal */, nil /* metrics */)
+			record := func(mode string) []row.BufferedOp {
+				if mode != "insert" && mode != "overwrite" {
+					d.Fatalf(t, "unknown mode %q, expected insert or overwrite", mode)
+				}
+				ops, err := row.RecordRowWrites(ctx, &helper, indexKey, cols, values,
+					row.EncodeRowOptions{Index: index, Overwrite: mode == "overwrite"})
+				if err != nil {
+					d.Fatalf(t, "%+v", err)
+				}
+				return ops
+			}
+
+			var lines []string
+			switch d.Cmd {
+			case "write":
+				mode := "insert"
+				if d.HasArg("overwrite") {
+					mode = "overwrite"
+				}
+				lines = row.FormatOps(codec, desc, record(mode))
+			case "diff":
+				var before, after string
+				d.ScanArgs(t, "before", &before)
+				d.ScanArgs(t, "after", &after)
+				lines = row.DiffOps(codec, desc, record(before), record(after))
+			default:
+				d.Fatalf(t, "unknown command %q", d.Cmd)
+			}
+			return strings.Join(lines, "\n")
+		})
+}
+
+// BenchmarkEncodeRowKVs compares encoding a row of a table with a single
+// column family by prepareSingleFamilyBatch with encoding it by the general
+// path of prepareInsertOrUpdateBatchForIndex.
+func BenchmarkEncodeRowKVs(b *testing.B) {
+	defer log.Scope(b).Close(b)
+
+	ctx := context.Background()
+	st := cluster.MakeTestingClusterSettings()
+	pk := roachpb.Key(encoding.EncodeVarintAscending(keys.SystemSQLCodec.IndexPrefix(104, 1), 1))
+	values := tree.Datums{tree.NewDInt(1), tree.NewDInt(2), tree.NewDString("foo")}
+
+	singleFamily := tabledesc.NewBuilder(makeEncodeRowTestTable().TableDesc()).BuildExistingMutableTable()
+	singleFamily.Families = []descpb.ColumnFamilyDescriptor{{
+		ID: 0, Name: "primary", ColumnIDs: []descpb.ColumnID{1, 2, 3}, ColumnNames: []string{"a", "b", "c"},
+	}}
+	singleFamily.NextFamilyID = 1
+	desc := singleFamily.ImmutableCopy().(catalog.TableDescriptor)
+	helper := row.NewRowHelper(keys.SystemSQLCodec, desc, nil /* indexes */, &st.SV, false /* internal */, nil /* metrics */)
+	cols := desc.PublicColumns()
+
+	for _, fastPath := range []bool{true, false} {
+		b.Run(fmt.Sprintf("fast-path=%t", fastPath), func(b *testing.B) {
+			helper.TestingDisableSingleFamilyFastPath = !fastPath
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := row.EncodeRowKVs(ctx, &helper, pk, cols, values, row.EncodeRowOptions{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// FuzzPrepareInsertOrUpdateBatch encodes random rows of random tables through
+// EncodeRowKVs, which runs prepareInsertOrUpdateBatch. Rows are occasionally
+// made invalid, in which case the encoder must reject them with an error
+// marked with ErrInvalidEncodeRowInput; any other failure is an encoder bug.
+// The rows of tables with a single column family are also encoded by the
+// general path, bypassing prepareSingleFamilyBatch, which must encode them
+// identically.
+func FuzzPrepareInsertOrUpdateBatch(f *testing.F) {
+	f.Add(int64(0), uint8(3), []byte{0, 1, 1}, false, false)
+	f.Add(int64(1), uint8(8), []byte{0, 1, 2, 0, 3, 3, 1, 2}, true, false)
+	f.Add(int64(2), uint8(5), []byte{4, 3, 2, 1, 0}, false, true)
+	f.Add(int64(3), uint8(6), []byte{0, 0, 0, 0, 0, 0}, true, false)
+
+	ctx := context.Background()
+	st := cluster.MakeTestingClusterSettings()
+	f.Fuzz(func(t *testing.T, seed int64, numCols uint8, famAssignment []byte, overwrite, invalid bool) {
+		rng := rand.New(rand.NewSource(seed))
+		desc := makeRandomEncodeRowTestTable(rng, 1+int(numCols)%16, famAssignment)
+		helper := row.NewRowHelper(keys.SystemSQLCodec, desc, nil /* indexes */, &st.SV, false /* internal */, nil /* metrics */)
+		cols := desc.PublicColumns()
+		values := make(tree.Datums, len(cols))
+		for i, col := range cols {
+			values[i] = randgen.RandDatum(rng, col.GetType(), col.IsNullable())
+		}
+		if invalid && len(cols) > 1 {
+			// Replace a value column with a datum of a different type.
+			i := 1 + rng.Intn(len(cols)-1)
+			for {
+				typ := randgen.RandColumnType(rng)
+				if !typ.Equivalent(cols[i].GetType()) {
+					values[i] = randgen.RandDatum(rng, typ, false /* nullOk */)
+					break
+				}
+			}
+		}
+		pk, _, err := rowenc.EncodeIndexKey(desc, desc.GetPrimaryIndex(), row.ColIDtoRowIndexFromCols(cols), values,
+			rowenc.MakeIndexKeyPrefix(keys.SystemSQLCodec, desc.GetID(), desc.GetPrimaryIndexID()))
+		require.NoError(t, err)
+
+		kvs, err := row.EncodeRowKVs(ctx, &helper, pk, cols, values, row.EncodeRowOptions{Overwrite: overwrite})
+		if invalid && len(cols) > 1 {
+			require.Truef(t, errors.Is(err, row.ErrInvalidEncodeRowInput), "expected invalid input error, got %v", err)
+			return
+		}
+		require.NoError(t, err)
+		require.NotEmpty(t, kvs)
+		for i := range kvs {
+			require.True(t, bytes.HasPrefix(kvs[i].Key, pk))
+			if i > 0 {
+				require.Less(t, kvs[i-1].Key.Compare(kvs[i].Key), 0)
+			}
+		}
+		if len(desc.GetFamilies()) == 1 {
+			// The single-family fast path must encode the row exactly as the
+			// general path does.
+			helper.TestingDisableSingleFamilyFastPath = true
+			general, err := row.EncodeRowKVs(ctx, &helper, pk, cols, values, row.EncodeRowOptions{Overwrite: overwrite})
+			require.NoError(t, err)
+			require.Equal(t, general, kvs)
+		}
+	})
+}
+
+// makeRandomEncodeRowTestTable returns the descriptor of a table with an INT
+// primary key followed by numCols-1 columns of random types, with the i-th
+// column in the family given by famAssignment[i].
+func makeRandomEncodeRowTestTable(
+	rng *rand.Rand, numCols int, famAssignment []byte,
+) catalog.TableDescriptor {
+	var cols []descpb.ColumnDescriptor
+	var storeIDs []descpb.ColumnID
+	var storeNames []string
+	famCols := make(map[descpb.FamilyID][]descpb.ColumnID)
+	for i := 0; i < numCols; i++ {
+		col := descpb.ColumnDescriptor{
+			ID:       descpb.ColumnID(i + 1),
+			Name:     fmt.Sprintf("c%d", i),
+			Type:     types.Int,
+			Nullable: i > 0,
+		}
+		if i > 0 {
+			col.Type = randgen.RandColumnType(rng)
+			storeIDs = append(storeIDs, col.ID)
+			storeNames = append(storeNames, col.Name)
+		}
+		var famID descpb.FamilyID
+		if i < len(famAssignment) {
+			famID = descpb.FamilyID(famAssignment[i] % 4)
+		}
+		famCols[famID] = append(famCols[famID], col.ID)
+		cols = append(cols, col)
+	}
+	// Family 0 must always exist.
+	if _, ok := famCols[0]; !ok {
+		famCols[0] = nil
+	}
+	var families []descpb.ColumnFamilyDescriptor
+	for famID := descpb.FamilyID(0); famID < 4; famID++ {
+		ids, ok := famCols[famID]
+		if !ok {
+			continue
+		}
+		fam := descpb.ColumnFamilyDescriptor{ID: famID, Name: fmt.Sprintf("f%d", famID), ColumnIDs: ids}
+		for _, id := range ids {
+			fam.ColumnNames = append(fam.ColumnNames, cols[id-1].Name)
+		}
+		if len(ids) == 1 && ids[0] != 1 {
+			fam.DefaultColumnID = ids[0]
+		}
+		families = append(families, fam)
+	}
+	return tabledesc.NewBuilder(&descpb.TableDescriptor{
+		ID:       104,
+		Name:     "t",
+		Columns:  cols,
+		Families: families,
+		PrimaryIndex: descpb.IndexDescriptor{
+			ID:                  1,
+			Name:                "t_pkey",
+			Unique:              true,
+			KeyColumnIDs:        []descpb.ColumnID{1},
+			KeyColumnNames:      []string{"c0"},
+			KeyColumnDirections: []catenumpb.IndexColumn_Direction{catenumpb.IndexColumn_ASC},
+			StoreColumnIDs:      storeIDs,
+			StoreColumnNames:    storeNames,
+			EncodingType:        catenumpb.PrimaryIndexEncoding,
+			Version:             descpb.LatestIndexDescriptorVersion,
+		},
+		NextColumnID: descpb.ColumnID(numCols + 1),
+		NextFamilyID: 4,
+		NextIndexID:  2,
+	}).BuildImmutableTable()
+}
+
+func TestOriginTimestampCPutHelperValidate(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	values := []tree.Datum{tree.NewDInt(1), tree.NewDInt(2), tree.DNull}
+	ts := hlc.Timestamp{WallTime: 1}
+
+	for _, tc := range []struct {
+		name      string
+		oth       *row.OriginTimestampCPutHelper
+		oldValues []tree.Datum
+		expErr    string
+	}{
+		{name: "unset", oth: nil},
+		{name: "unset timestamp", oth: &row.OriginTimestampCPutHelper{}},
+		{name: "update", oth: &row.OriginTimestampCPutHelper{OriginTimestamp: ts, ShouldWinTie: true}, oldValues: values},
+		{
+			name:   "tie without timestamp",
+			oth:    &row.OriginTimestampCPutHelper{ShouldWinTie: true},
+			expErr: "win ties without an origin timestamp",
+		},
+		{
+			name:   "set without old values",
+			oth:    &row.OriginTimestampCPutHelper{OriginTimestamp: ts},
+			expErr: "got no old values",
+		},
+		{
+			name:      "set with empty old values",
+			oth:       &row.OriginTimestampCPutHelper{OriginTimestamp: ts},
+			oldValues: []tree.Datum{},
+			expErr:    "got no old values",
+		},
+		{
+			name:      "unset with old values",
+			oth:       nil,
+			oldValues: values,
+			expErr:    "got 3 old values without an origin timestamp CPut helper",
+		},
+		{
+			name:      "unset timestamp with old values",
+			oth:       &row.OriginTimestampCPutHelper{},
+			oldValues: values,
+			expErr:    "got 3 old values without an origin timestamp CPut helper",
+		},
+		{
+			name:      "nil old value",
+			oth:       &row.OriginTimestampCPutHelper{OriginTimestamp: ts},
+			oldValues: []tree.Datum{tree.NewDInt(1), nil, tree.DNull},
+			expErr:    "nil old value at index 1",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.oth.Validate(tc.oldValues)
+			if tc.expErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, tc.expErr)
+			}
+		})
+	}
+}
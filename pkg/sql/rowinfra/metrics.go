@@ -29,6 +29,29 @@ var (
 		Measurement: "Rows",
 		Unit:        metric.Unit_COUNT,
 	}
+	// MetaFamilyValueBytes is metadata for the
+	// sql.mutation.family_value_bytes{.internal} metrics.
+	MetaFamilyValueBytes = metric.Metadata{
+		Name:        "sql.mutation.family_value_bytes",
+		Help:        "Encoded size of the values written for the column families of primary index rows",
+		Measurement: "Bytes",
+		Unit:        metric.Unit_BYTES,
+	}
+	// MetaOriginTimestampCPuts is metadata for the
+	// sql.mutation.origin_timestamp_cput.count{.internal} metrics.
+	MetaOriginTimestampCPuts = metric.Metadata{
+		Name:        "sql.mutation.origin_timestamp_cput.count",
+		Help:        "Number of writes and deletes of primary index column families conditional on the origin timestamp of the existing row",
+		Measurement: "Writes",
+		Unit:        metric.Unit_COUNT,
+	}
+	// MetaPuts is metadata for the sql.mutation.put.count{.internal} metrics.
+	MetaPuts = metric.Metadata{
+		Name:        "sql.mutation.put.count",
+		Help:        "Number of writes and deletes of primary index column families not conditional on the origin timestamp of the existing row",
+		Measurement: "Writes",
+		Unit:        metric.Unit_COUNT,
+	}
 )
 
 // Metrics holds metrics measuring calls into the KV layer by various parts of
@@ -36,6 +59,17 @@ var (
 type Metrics struct {
 	MaxRowSizeLogCount *metric.Counter
 	MaxRowSizeErrCount *metric.Counter
+	// FamilyValueBytes records the size of the value of each column family
+	// written by inserts and updates, which shows which families are large
+	// enough to be worth splitting. It is not labeled by table, to bound its
+	// cardinality.
+	FamilyValueBytes metric.IHistogram
+	// OriginTimestampCPuts and Puts count the writes and deletes of primary
+	// index column families which are conditional on the origin timestamp of
+	// the existing row, as made by logical replication when it applies through
+	// SQL, and those which are not, respectively.
+	OriginTimestampCPuts *metric.Counter
+	Puts                 *metric.Counter
 }
 
 var _ metric.Struct = Metrics{}
@@ -82,6 +82,9 @@ type InternalExecutorOverride struct {
 	GrowStackSize bool
 	// DisablePlanGists, if true, overrides the disable_plan_gists session var.
 	DisablePlanGists bool
+	// PlanCacheStats, if set, counts the query cache hits and misses of the
+	// statements executed with this override.
+	PlanCacheStats *PlanCacheStats
 }
 
 // NoSessionDataOverride is the empty InternalExecutorOverride which does not
@@ -14,6 +14,7 @@ import (
 	"net"
 	"reflect"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/cockroachdb/cockroach/pkg/security/username"
@@ -174,6 +175,11 @@ type LocalUnmigratableSessionData struct {
 	// IsSSL indicates whether the session is using SSL/TLS.
 	IsSSL bool
 
+	// PlanCacheStats, if set, counts the query cache hits and misses of the
+	// statements planned by the session. It is only set by users of the
+	// internal executor, via InternalExecutorOverride.
+	PlanCacheStats *PlanCacheStats
+
 	// ////////////////////////////////////////////////////////////////////////
 	// WARNING: consider whether a session parameter you're adding needs to  //
 	// be propagated to the remote nodes or needs to persist amongst session //
@@ -182,6 +188,13 @@ type LocalUnmigratableSessionData struct {
 	// ////////////////////////////////////////////////////////////////////////
 }
 
+// PlanCacheStats counts the query cache hits and misses of the statements
+// planned by a session; see sql.optimizer.plan_cache.hits and
+// sql.optimizer.plan_cache.misses.
+type PlanCacheStats struct {
+	Hits, Misses atomic.Int64
+}
+
 // IsTemporarySchemaID returns true if the given ID refers to any of the temp
 // schemas created by the session.
 func (s *SessionData) IsTemporarySchemaID(schemaID uint32) bool {
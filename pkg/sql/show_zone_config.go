@@ -233,6 +233,18 @@ func zoneConfigToSQL(zs *tree.ZoneSpecifier, zone *zonepb.ZoneConfig) (string, e
 		maybeWriteComma(f)
 		f.Printf("\tlease_preferences = %s", lexbase.EscapeSQLString(prefs))
 	}
+	if zone.ForceProposalQuota != nil {
+		maybeWriteComma(f)
+		f.Printf("\tforce_proposal_quota = %t", *zone.ForceProposalQuota)
+	}
+	if !zone.InheritedProposalQuotaExcludedFamilyIDs() {
+		maybeWriteComma(f)
+		ids, err := yamlMarshalFlow(zone.ProposalQuotaExcludedFamilyIDs)
+		if err != nil {
+			return "", err
+		}
+		f.Printf("\tproposal_quota_excluded_family_ids = %s", lexbase.EscapeSQLString(ids))
+	}
 	return f.String(), nil
 }
 
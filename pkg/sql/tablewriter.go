@@ -20,6 +20,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/settings"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
 	"github.com/cockroachdb/cockroach/pkg/sql/mutations"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgnotice"
 	"github.com/cockroachdb/cockroach/pkg/sql/row"
 	"github.com/cockroachdb/cockroach/pkg/sql/rowcontainer"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/eval"
@@ -121,6 +122,9 @@ type tableWriterBase struct {
 	// deadlockTimeout specifies the amount of time that the writer will wait
 	// on a lock before checking if there is a race condition.
 	deadlockTimeout time.Duration
+	// proposalQuotaMaxWaiters is the maximum number of proposals the writer's
+	// batches will wait behind for proposal quota.
+	proposalQuotaMaxWaiters int64
 	// maxBatchSize determines the maximum number of entries in the KV batch
 	// for a mutation operation. By default, it will be set to 10k but can be
 	// a different value in tests.
@@ -153,6 +157,15 @@ type tableWriterBase struct {
 	// originID is an identifier for the cluster that originally wrote the data
 	// being written by the table writer during Logical Data Replication.
 	originID uint32
+	// primaryIndexIDs, if attributeIndexWrites is enabled, identifies the
+	// primary index of the table in each batch, so that KV can attribute the
+	// writes of a batch to the primary or secondary indexes. It is shared by
+	// all batches and must not be modified.
+	primaryIndexIDs map[uint32]uint32
+	// decodeWrites, if the decode_kv_writes session variable is set, sends a
+	// human-readable rendering of each of the writer's KV writes to the client
+	// as a notice, see row.RowHelper.DecodeWrites.
+	decodeWrites func(line string)
 }
 
 var maxBatchBytes = settings.RegisterByteSizeSetting(
@@ -162,20 +175,48 @@ var maxBatchBytes = settings.RegisterByteSizeSetting(
 	4<<20,
 )
 
+// attributeIndexWrites controls whether mutation batches identify the primary
+// index of the table they write to, see kvpb.Header.PrimaryIndexIDs. It is off
+// by default as it adds to the size of every batch.
+var attributeIndexWrites = settings.RegisterBoolSetting(
+	settings.ApplicationLevel,
+	"sql.mutations.attribute_index_writes.enabled",
+	"if enabled, mutation batches identify the primary index of the table they write "+
+		"to, so that KV can attribute the proposal quota charged for them to secondary "+
+		"index writes, and exclude the column families listed in the "+
+		"proposal_quota_excluded_family_ids zone config field from the charge",
+	false,
+)
+
 func (tb *tableWriterBase) init(
-	txn *kv.Txn, tableDesc catalog.TableDescriptor, evalCtx *eval.Context,
+	ctx context.Context, txn *kv.Txn, tableDesc catalog.TableDescriptor, evalCtx *eval.Context,
 ) error {
 	if txn.Type() != kv.RootTxn {
 		return errors.AssertionFailedf("unexpectedly non-root txn is used by the table writer")
 	}
 	tb.txn = txn
 	tb.desc = tableDesc
+	tb.primaryIndexIDs = nil
+	if evalCtx != nil && attributeIndexWrites.Get(&evalCtx.Settings.SV) {
+		tb.primaryIndexIDs = map[uint32]uint32{
+			uint32(tableDesc.GetID()): uint32(tableDesc.GetPrimaryIndexID()),
+		}
+	}
 	tb.lockTimeout = 0
 	tb.deadlockTimeout = 0
+	tb.proposalQuotaMaxWaiters = 0
 	tb.originID = 0
+	tb.decodeWrites = nil
+	if evalCtx != nil && evalCtx.SessionData().DecodeKVWrites && evalCtx.ClientNoticeSender != nil {
+		sender := evalCtx.ClientNoticeSender
+		tb.decodeWrites = func(line string) {
+			sender.BufferClientNotice(ctx, pgnotice.Newf("%s", line))
+		}
+	}
 	if evalCtx != nil {
 		tb.lockTimeout = evalCtx.SessionData().LockTimeout
 		tb.deadlockTimeout = evalCtx.SessionData().DeadlockTimeout
+		tb.proposalQuotaMaxWaiters = evalCtx.SessionData().ProposalQuotaMaxWaiters
 		tb.originID = evalCtx.SessionData().OriginIDForLogicalDataReplication
 	}
 	tb.forceProductionBatchSizes = evalCtx != nil && evalCtx.TestingKnobs.ForceProductionValues
@@ -276,6 +317,8 @@ func (tb *tableWriterBase) initNewBatch() {
 	tb.putter.Batch = tb.b
 	tb.b.Header.LockTimeout = tb.lockTimeout
 	tb.b.Header.DeadlockTimeout = tb.deadlockTimeout
+	tb.b.Header.ProposalQuotaMaxWaiters = tb.proposalQuotaMaxWaiters
+	tb.b.Header.PrimaryIndexIDs = tb.primaryIndexIDs
 	if tb.originID != 0 {
 		tb.b.Header.WriteOptions = &kvpb.WriteOptions{OriginID: tb.originID}
 	}
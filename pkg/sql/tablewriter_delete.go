@@ -40,8 +40,12 @@ func (*tableDeleter) desc() string { return "deleter" }
 func (td *tableDeleter) walkExprs(_ func(desc string, index int, expr tree.TypedExpr)) {}
 
 // init is part of the tableWriter interface.
-func (td *tableDeleter) init(_ context.Context, txn *kv.Txn, evalCtx *eval.Context) error {
-	return td.tableWriterBase.init(txn, td.tableDesc(), evalCtx)
+func (td *tableDeleter) init(ctx context.Context, txn *kv.Txn, evalCtx *eval.Context) error {
+	if err := td.tableWriterBase.init(ctx, txn, td.tableDesc(), evalCtx); err != nil {
+		return err
+	}
+	td.rd.Helper.DecodeWrites = td.decodeWrites
+	return nil
 }
 
 // row is part of the tableWriter interface.
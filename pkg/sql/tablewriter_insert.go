@@ -32,8 +32,12 @@ var _ tableWriter = &tableInserter{}
 func (*tableInserter) desc() string { return "inserter" }
 
 // init is part of the tableWriter interface.
-func (ti *tableInserter) init(_ context.Context, txn *kv.Txn, evalCtx *eval.Context) error {
-	return ti.tableWriterBase.init(txn, ti.tableDesc(), evalCtx)
+func (ti *tableInserter) init(ctx context.Context, txn *kv.Txn, evalCtx *eval.Context) error {
+	if err := ti.tableWriterBase.init(ctx, txn, ti.tableDesc(), evalCtx); err != nil {
+		return err
+	}
+	ti.ri.Helper.DecodeWrites = ti.decodeWrites
+	return nil
 }
 
 // row is part of the tableWriter interface.
@@ -32,8 +32,12 @@ var _ tableWriter = &tableUpdater{}
 func (*tableUpdater) desc() string { return "updater" }
 
 // init is part of the tableWriter interface.
-func (tu *tableUpdater) init(_ context.Context, txn *kv.Txn, evalCtx *eval.Context) error {
-	return tu.tableWriterBase.init(txn, tu.tableDesc(), evalCtx)
+func (tu *tableUpdater) init(ctx context.Context, txn *kv.Txn, evalCtx *eval.Context) error {
+	if err := tu.tableWriterBase.init(ctx, txn, tu.tableDesc(), evalCtx); err != nil {
+		return err
+	}
+	tu.ru.SetDecodeWrites(tu.decodeWrites)
+	return nil
 }
 
 // row is part of the tableWriter interface.
@@ -98,9 +98,11 @@ var _ tableWriter = &optTableUpserter{}
 
 // init is part of the tableWriter interface.
 func (tu *optTableUpserter) init(ctx context.Context, txn *kv.Txn, evalCtx *eval.Context) error {
-	if err := tu.tableWriterBase.init(txn, tu.ri.Helper.TableDesc, evalCtx); err != nil {
+	if err := tu.tableWriterBase.init(ctx, txn, tu.ri.Helper.TableDesc, evalCtx); err != nil {
 		return err
 	}
+	tu.ri.Helper.DecodeWrites = tu.decodeWrites
+	tu.ru.SetDecodeWrites(tu.decodeWrites)
 
 	// rowsNeeded, set upon initialization, indicates whether or not we want
 	// rows returned from the operation.
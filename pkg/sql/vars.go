@@ -1989,6 +1989,27 @@ var varGen = map[string]sessionVar{
 		},
 	},
 
+	// CockroachDB extension.
+	`proposal_quota_max_waiters`: {
+		GetStringVal: makeIntGetStringValFn(`proposal_quota_max_waiters`),
+		Set: func(_ context.Context, m sessionDataMutator, s string) error {
+			b, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return err
+			}
+			if b < 0 {
+				return pgerror.Newf(pgcode.InvalidParameterValue,
+					"cannot set proposal_quota_max_waiters to a negative value: %d", b)
+			}
+			m.SetProposalQuotaMaxWaiters(b)
+			return nil
+		},
+		Get: func(evalCtx *extendedEvalContext, _ *kv.Txn) (string, error) {
+			return strconv.FormatInt(evalCtx.SessionData().ProposalQuotaMaxWaiters, 10), nil
+		},
+		GlobalDefault: func(sv *settings.Values) string { return "0" },
+	},
+
 	// CockroachDB extension.
 	`transaction_rows_written_err`: {
 		GetStringVal: makeIntGetStringValFn(`transaction_rows_written_err`),
@@ -3487,6 +3508,23 @@ var varGen = map[string]sessionVar{
 		},
 		GlobalDefault: globalTrue,
 	},
+
+	// CockroachDB extension.
+	`decode_kv_writes`: {
+		GetStringVal: makePostgresBoolGetStringValFn(`decode_kv_writes`),
+		Set: func(_ context.Context, m sessionDataMutator, s string) error {
+			b, err := paramparse.ParseBoolVar("decode_kv_writes", s)
+			if err != nil {
+				return err
+			}
+			m.SetDecodeKVWrites(b)
+			return nil
+		},
+		Get: func(evalCtx *extendedEvalContext, _ *kv.Txn) (string, error) {
+			return formatBoolAsPostgresSetting(evalCtx.SessionData().DecodeKVWrites), nil
+		},
+		GlobalDefault: globalFalse,
+	},
 }
 
 func ReplicationModeFromString(s string) (sessiondatapb.ReplicationMode, error) {
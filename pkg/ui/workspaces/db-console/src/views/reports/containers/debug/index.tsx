@@ -414,6 +414,15 @@ export default function Debug() {
             url="debug/closedts-receiver"
           />
         </DebugTableRow>
+        <DebugTableRow
+          title="Proposal quota"
+          disabled={disable_kv_level_advanced_debug}
+        >
+          <DebugTableLink
+            name="Check invariants on this node"
+            url="debug/proposal-quota-check"
+          />
+        </DebugTableRow>
       </DebugTable>
       <DebugTable
         heading={
@@ -200,6 +200,7 @@ var _ PrometheusExportable = &CounterFloat64{}
 
 var _ PrometheusVector = &GaugeVec{}
 var _ PrometheusVector = &CounterVec{}
+var _ PrometheusVector = &HistogramVec{}
 
 var now = timeutil.Now
 
@@ -445,6 +446,24 @@ func (h *Histogram) RecordValue(n int64) {
 	h.windowed.cur.Observe(v)
 }
 
+// RecordValueWithExemplar adds the given value to the histogram, attaching
+// the exemplar labels (such as a trace ID) to the bucket of the cumulative
+// histogram it falls into. Each bucket retains only its most recent exemplar.
+// Exemplars are only exposed by exposition formats which support them, i.e.
+// protobuf and OpenMetrics.
+func (h *Histogram) RecordValueWithExemplar(n int64, exemplar prometheus.Labels) {
+	v := float64(n)
+	if eo, ok := h.cum.(prometheus.ExemplarObserver); ok {
+		eo.ObserveWithExemplar(v, exemplar)
+	} else {
+		h.cum.Observe(v)
+	}
+
+	h.windowed.RLock()
+	defer h.windowed.RUnlock()
+	h.windowed.cur.Observe(v)
+}
+
 // GetType returns the prometheus type enum for this metric.
 func (h *Histogram) GetType() *prometheusgo.MetricType {
 	return prometheusgo.MetricType_HISTOGRAM.Enum()
@@ -1118,6 +1137,14 @@ func (v *vector) recordLabels(labelValues []string) {
 	v.encounteredLabelValues = append(v.encounteredLabelValues, labelValues)
 }
 
+// clear forgets all the label values recorded so far.
+func (v *vector) clear() {
+	v.Lock()
+	defer v.Unlock()
+	v.encounteredLabelsLookup = make(map[string]struct{})
+	v.encounteredLabelValues = [][]string{}
+}
+
 // GaugeVec is a collector for gauges that have a variable set of labels.
 // This uses the prometheus.GaugeVec under the hood. The contained gauges are
 // not persisted by the internal TSDB, nor are they aggregated; see aggmetric
@@ -1168,6 +1195,13 @@ func (gv *GaugeVec) Dec(labels map[string]string, v int64) {
 	gv.promVec.WithLabelValues(labelValues...).Sub(float64(v))
 }
 
+// Clear removes the gauges for all combinations of labels, e.g. to bound the
+// cardinality of a vector whose label values come and go.
+func (gv *GaugeVec) Clear() {
+	gv.clear()
+	gv.promVec.Reset()
+}
+
 // GetMetadata implements Iterable.
 func (gv *GaugeVec) GetMetadata() Metadata {
 	return gv.Metadata
@@ -1188,6 +1222,8 @@ func (gv *GaugeVec) GetType() *prometheusgo.MetricType {
 
 // ToPrometheusMetrics implements PrometheusExportable.
 func (gv *GaugeVec) ToPrometheusMetrics() []*prometheusgo.Metric {
+	gv.RLock()
+	defer gv.RUnlock()
 	metrics := make([]*prometheusgo.Metric, 0, len(gv.encounteredLabelValues))
 
 	for _, labels := range gv.encounteredLabelValues {
@@ -1300,3 +1336,82 @@ func (cv *CounterVec) ToPrometheusMetrics() []*prometheusgo.Metric {
 
 	return metrics
 }
+
+// HistogramVec wraps a prometheus.HistogramVec; it is not aggregated or
+// persisted. Unlike Histogram, it only keeps the cumulative histograms, so it
+// has no windowed view.
+type HistogramVec struct {
+	Metadata
+	vector
+	promVec *prometheus.HistogramVec
+}
+
+// NewExportedHistogramVec creates a new HistogramVec containing labeled
+// histograms with the given buckets to be exported to an external collector;
+// the contained histograms are not aggregated or persisted to the tsdb (see
+// aggmetric.Histogram for a histogram that persists the aggregation of n
+// labeled child metrics).
+func NewExportedHistogramVec(
+	metadata Metadata, buckets []float64, labelNames []string,
+) *HistogramVec {
+	metadata.MetricType = prometheusgo.MetricType_HISTOGRAM
+	vec := newVector(labelNames)
+
+	promVec := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    metadata.Name,
+		Help:    metadata.Help,
+		Buckets: buckets,
+	}, vec.orderedLabelNames)
+
+	return &HistogramVec{
+		Metadata: metadata,
+		vector:   vec,
+		promVec:  promVec,
+	}
+}
+
+// RecordValue adds the given value to the histogram for the given combination
+// of labels.
+func (hv *HistogramVec) RecordValue(labels map[string]string, v int64) {
+	labelValues := hv.getOrderedValues(labels)
+	hv.recordLabels(labelValues)
+	hv.promVec.WithLabelValues(labelValues...).Observe(float64(v))
+}
+
+// GetMetadata implements Iterable.
+func (hv *HistogramVec) GetMetadata() Metadata {
+	return hv.Metadata
+}
+
+// Inspect implements Iterable.
+func (hv *HistogramVec) Inspect(f func(interface{})) { f(hv) }
+
+// MarshalJSON implements JSONMarshaler.
+func (hv *HistogramVec) MarshalJSON() ([]byte, error) {
+	return json.Marshal(hv)
+}
+
+// GetType implements PrometheusExportable.
+func (hv *HistogramVec) GetType() *prometheusgo.MetricType {
+	return prometheusgo.MetricType_HISTOGRAM.Enum()
+}
+
+// ToPrometheusMetrics implements PrometheusExportable.
+func (hv *HistogramVec) ToPrometheusMetrics() []*prometheusgo.Metric {
+	hv.RLock()
+	defer hv.RUnlock()
+	metrics := make([]*prometheusgo.Metric, 0, len(hv.encounteredLabelValues))
+
+	for _, labels := range hv.encounteredLabelValues {
+		m := &prometheusgo.Metric{}
+		h := hv.promVec.WithLabelValues(labels...).(prometheus.Histogram)
+
+		if err := h.Write(m); err != nil {
+			panic(err)
+		}
+
+		metrics = append(metrics, m)
+	}
+
+	return metrics
+}
@@ -87,6 +87,16 @@ func TestGaugeVector(t *testing.T) {
 	require.Equal(t, "value3", *metrics[1].GetLabel()[0].Value)
 	require.Equal(t, "label2", *metrics[1].GetLabel()[1].Name)
 	require.Equal(t, "value4", *metrics[1].GetLabel()[1].Value)
+
+	// Clearing the vector removes all the gauges, and later updates start
+	// from scratch.
+	g.Clear()
+	require.Empty(t, g.ToPrometheusMetrics())
+	g.Update(ls2, 5)
+	metrics = g.ToPrometheusMetrics()
+	require.Len(t, metrics, 1)
+	require.Equal(t, 5.0, *metrics[0].Gauge.Value)
+	require.Equal(t, "value3", *metrics[0].GetLabel()[0].Value)
 }
 
 func TestFunctionalGauge(t *testing.T) {
@@ -230,6 +240,32 @@ func TestHistogram(t *testing.T) {
 	require.Nil(t, h.ToPrometheusMetric().Histogram.Schema)
 }
 
+func TestHistogramRecordValueWithExemplar(t *testing.T) {
+	h := NewHistogram(HistogramOptions{
+		Mode:     HistogramModePrometheus,
+		Metadata: Metadata{},
+		Duration: time.Hour,
+		Buckets:  []float64{1.0, 10.0},
+	}).(*Histogram)
+
+	h.RecordValue(1)
+	h.RecordValueWithExemplar(5, prometheus.Labels{"trace_id": "123"})
+
+	act := h.ToPrometheusMetric().Histogram
+	require.Equal(t, uint64(2), act.GetSampleCount())
+	require.Len(t, act.Bucket, 2)
+	require.Nil(t, act.Bucket[0].Exemplar)
+	ex := act.Bucket[1].Exemplar
+	require.NotNil(t, ex)
+	require.Equal(t, 5.0, ex.GetValue())
+	require.Len(t, ex.Label, 1)
+	require.Equal(t, "trace_id", ex.Label[0].GetName())
+	require.Equal(t, "123", ex.Label[0].GetValue())
+
+	// The value is also recorded in the windowed histogram.
+	require.Equal(t, 10.0, h.WindowedSnapshot().ValueAtQuantile(99))
+}
+
 func TestNativeHistogram(t *testing.T) {
 	defer func(enabled bool) {
 		nativeHistogramsEnabled = enabled
@@ -635,6 +671,28 @@ func (cv *CounterVec) assertPrometheusMetrics(t *testing.T, tc []toPromMetricsTC
 
 }
 
+func TestHistogramVec(t *testing.T) {
+	h := NewExportedHistogramVec(emptyMetadata, []float64{10, 100}, []string{"label1", "label2"})
+	ls1 := map[string]string{"label1": "value1", "label2": "value2"}
+	ls2 := map[string]string{"label1": "value3", "label2": "value4"}
+	require.Equal(t, prometheusgo.MetricType_HISTOGRAM, h.GetMetadata().MetricType)
+
+	h.RecordValue(ls1, 5)
+	h.RecordValue(ls1, 50)
+	h.RecordValue(ls2, 500)
+
+	metrics := h.ToPrometheusMetrics()
+	require.Len(t, metrics, 2)
+	require.Equal(t, uint64(2), metrics[0].Histogram.GetSampleCount())
+	require.Equal(t, 55.0, metrics[0].Histogram.GetSampleSum())
+	require.Equal(t, uint64(1), metrics[0].Histogram.Bucket[0].GetCumulativeCount())
+	require.Equal(t, uint64(2), metrics[0].Histogram.Bucket[1].GetCumulativeCount())
+	require.Equal(t, uint64(1), metrics[1].Histogram.GetSampleCount())
+	require.Equal(t, uint64(0), metrics[1].Histogram.Bucket[1].GetCumulativeCount())
+	require.Equal(t, "value1", *metrics[0].GetLabel()[0].Value)
+	require.Equal(t, "value4", *metrics[1].GetLabel()[1].Value)
+}
+
 func TestCounterVec(t *testing.T) {
 	t.Run("labels provided match what is declared", func(t *testing.T) {
 		c := NewExportedCounterVec(emptyMetadata, []string{"label1", "label2"})
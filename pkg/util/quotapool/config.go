@@ -118,6 +118,32 @@ func WithMinimumWait(duration time.Duration) Option {
 	})
 }
 
+// WithGrouping is used to configure a quotapool to order waiting acquisitions
+// fairly across groups of clients rather than in plain FIFO order. Each group
+// may have at most one waiter in the queue at a time; further waiters from the
+// same group are parked behind it and join the back of the queue once the
+// group's current waiter has been fulfilled or canceled. The effect is that
+// waiting groups are served round-robin, so a single group issuing many
+// concurrent acquisitions cannot starve the others.
+//
+// Acquisitions for which f returns the empty string are not grouped and are
+// queued in FIFO order. Acquisitions which can be fulfilled immediately are
+// unaffected.
+func WithGrouping(f GroupingFunc) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.groupingFunc = f
+	})
+}
+
+// WithAllocTracking is used to configure an IntPool to track the allocs which
+// have been acquired from it and not yet released, see IntPool.HeldAllocs. It
+// has no effect on other quotapools.
+func WithAllocTracking() Option {
+	return optionFunc(func(cfg *config) {
+		cfg.trackAllocs = true
+	})
+}
+
 type config struct {
 	onAcquisition            AcquisitionFunc
 	onSlowAcquisition        SlowAcquisitionFunc
@@ -127,6 +153,8 @@ type config struct {
 	timeSource               timeutil.TimeSource
 	closer                   <-chan struct{}
 	minimumWait              time.Duration
+	groupingFunc             GroupingFunc
+	trackAllocs              bool
 }
 
 var defaultConfig = config{
@@ -0,0 +1,125 @@
+// Copyright 2024 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package quotapool
+
+import "context"
+
+// GroupingFunc returns the group to which an acquisition belongs. See
+// WithGrouping.
+type GroupingFunc func(ctx context.Context) string
+
+type groupKeyCtxKey struct{}
+
+// ContextWithGroupKey returns a context which associates acquisitions made
+// under it with the provided group. See GroupKeyFromContext.
+func ContextWithGroupKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, groupKeyCtxKey{}, key)
+}
+
+// GroupKeyFromContext returns the group key stored in ctx by
+// ContextWithGroupKey, or the empty string if there is none. It can be used
+// as a GroupingFunc.
+func GroupKeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(groupKeyCtxKey{}).(string)
+	return key
+}
+
+// waitGroup tracks the waiters of a single group. A waitGroup exists in the
+// pool's groups map for as long as one of its members is in the notifyQueue.
+type waitGroup struct {
+	key string
+	// parked are the waiters of this group which are not yet in the
+	// notifyQueue, in the order in which they arrived.
+	parked []*groupWaiter
+}
+
+// groupWaiter is a waiter which belongs to a waitGroup.
+type groupWaiter struct {
+	group *waitGroup
+	c     chan struct{}
+	// n is nil while the waiter is parked.
+	n *notifyee
+}
+
+// waiter is the handle held by an acquisition which is waiting for quota.
+type waiter struct {
+	// c is the channel on which the waiter is notified.
+	c chan struct{}
+	// n is the waiter's entry in the notifyQueue if it is not grouped.
+	n *notifyee
+	// gw is set if the waiter is grouped.
+	gw *groupWaiter
+}
+
+// notifyee returns the waiter's entry in the notifyQueue. It must only be
+// called once the waiter has been notified or with qp.mu held.
+func (w *waiter) notifyee() *notifyee {
+	if w.gw != nil {
+		return w.gw.n
+	}
+	return w.n
+}
+
+// parked returns true if the waiter is not yet in the notifyQueue. It must
+// be called with qp.mu held.
+func (w *waiter) parked() bool {
+	return w.gw != nil && w.gw.n == nil
+}
+
+// enqueueGroupedLocked adds c to the notifyQueue on behalf of the group key,
+// or parks it if the group already has a member in the queue.
+func (qp *AbstractPool) enqueueGroupedLocked(key string, c chan struct{}) waiter {
+	if qp.mu.groups == nil {
+		qp.mu.groups = make(map[string]*waitGroup)
+	}
+	gw := &groupWaiter{c: c}
+	if g, ok := qp.mu.groups[key]; ok {
+		gw.group = g
+		g.parked = append(g.parked, gw)
+		qp.mu.numParked++
+	} else {
+		gw.group = &waitGroup{key: key}
+		qp.mu.groups[key] = gw.group
+		gw.n = qp.mu.q.enqueue(c)
+	}
+	return waiter{c: c, gw: gw}
+}
+
+// advanceGroupLocked is called when gw leaves the notifyQueue. It moves the
+// next parked waiter of gw's group, if any, to the back of the queue. It is a
+// no-op for ungrouped waiters.
+func (qp *AbstractPool) advanceGroupLocked(gw *groupWaiter) {
+	if gw == nil {
+		return
+	}
+	g := gw.group
+	if len(g.parked) == 0 {
+		delete(qp.mu.groups, g.key)
+		return
+	}
+	next := g.parked[0]
+	g.parked[0] = nil
+	g.parked = g.parked[1:]
+	qp.mu.numParked--
+	next.n = qp.mu.q.enqueue(next.c)
+}
+
+// unparkLocked removes a canceled parked waiter from its group.
+func (qp *AbstractPool) unparkLocked(gw *groupWaiter) {
+	g := gw.group
+	for i := range g.parked {
+		if g.parked[i] == gw {
+			g.parked = append(g.parked[:i], g.parked[i+1:]...)
+			qp.mu.numParked--
+			return
+		}
+	}
+}
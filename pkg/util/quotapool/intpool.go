@@ -14,6 +14,7 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"sort"
 	"strconv"
 	"sync"
 	"sync/atomic"
@@ -39,6 +40,16 @@ type IntPool struct {
 
 	// updateCapacityMu synchronizes accesses to the capacity.
 	updateCapacityMu syncutil.Mutex
+
+	// held is non-nil if the pool was created WithAllocTracking.
+	held *heldIntAllocs
+}
+
+// heldIntAllocs tracks the sizes of the allocs acquired from an IntPool which
+// have not yet been released, see IntPool.HeldAllocs.
+type heldIntAllocs struct {
+	syncutil.Mutex
+	sizes map[*IntAlloc]uint64
 }
 
 // IntAlloc is an allocated quantity which should be released.
@@ -73,6 +84,7 @@ func (ia *IntAlloc) Merge(other *IntAlloc) {
 		panic("cannot merge IntAllocs from two different pools")
 	}
 	ia.alloc = min(int64(ia.p.Capacity()), ia.alloc+other.alloc)
+	ia.p.untrack(other, ia)
 	ia.p.putIntAlloc(other)
 }
 
@@ -92,6 +104,7 @@ func (ia *IntAlloc) Merge(other *IntAlloc) {
 // AcquireFunc() requests will be woken up with an updated Capacity, and Alloc()
 // requests will be trimmed accordingly.
 func (ia *IntAlloc) Freeze() {
+	ia.p.untrack(ia, nil)
 	ia.p.decCapacity(uint64(ia.alloc))
 	ia.p = nil // ensure that future uses of this alloc will panic
 }
@@ -124,6 +137,9 @@ func NewIntPool(name string, capacity uint64, options ...Option) *IntPool {
 		capacity: capacity,
 	}
 	p.qp = New(name, (*intAlloc)(p.newIntAlloc(int64(capacity))), options...)
+	if p.qp.trackAllocs {
+		p.held = &heldIntAllocs{sizes: make(map[*IntAlloc]uint64)}
+	}
 	return &p
 }
 
@@ -178,7 +194,7 @@ func (p *IntPool) acquireMaybeWait(ctx context.Context, v uint64, wait bool) (*I
 	if err := p.qp.Acquire(ctx, req); err != nil {
 		return nil, err
 	}
-	return p.newIntAlloc(int64(r.want)), nil
+	return p.newHeldIntAlloc(int64(r.want)), nil
 }
 
 // Release will release allocs back to their pool. Allocs which are from p are
@@ -279,7 +295,25 @@ func (p *IntPool) acquireFuncMaybeWait(
 	}
 	// NB: We know that r.took must be less than math.MaxInt64 because capacity
 	// cannot exceed that value and took cannot exceed capacity.
-	return p.newIntAlloc(int64(r.took)), nil
+	return p.newHeldIntAlloc(int64(r.took)), nil
+}
+
+// HeldAllocs returns the sizes of the allocs acquired from the pool which have
+// not yet been released or frozen, largest first. Allocs which were merged are
+// reported as one. It returns nil unless the pool was created
+// WithAllocTracking.
+func (p *IntPool) HeldAllocs() []uint64 {
+	if p.held == nil {
+		return nil
+	}
+	p.held.Lock()
+	sizes := make([]uint64, 0, len(p.held.sizes))
+	for _, size := range p.held.sizes {
+		sizes = append(sizes, size)
+	}
+	p.held.Unlock()
+	sort.Slice(sizes, func(i, j int) bool { return sizes[i] > sizes[j] })
+	return sizes
 }
 
 // Len returns the current length of the queue for this IntPool.
@@ -341,6 +375,37 @@ func (p *IntPool) newIntAlloc(v int64) *IntAlloc {
 	return ia
 }
 
+// newHeldIntAlloc is like newIntAlloc, for allocs acquired by clients, which
+// are tracked until they are released if the pool was created
+// WithAllocTracking.
+func (p *IntPool) newHeldIntAlloc(v int64) *IntAlloc {
+	ia := p.newIntAlloc(v)
+	if p.held != nil && v > 0 {
+		p.held.Lock()
+		p.held.sizes[ia] = uint64(v)
+		p.held.Unlock()
+	}
+	return ia
+}
+
+// untrack stops tracking ia, which is no longer held by a client, adding its
+// size to that of into if into is also tracked, as ia was merged into it.
+func (p *IntPool) untrack(ia, into *IntAlloc) {
+	if p.held == nil {
+		return
+	}
+	p.held.Lock()
+	defer p.held.Unlock()
+	size, ok := p.held.sizes[ia]
+	if !ok {
+		return
+	}
+	delete(p.held.sizes, ia)
+	if intoSize, ok := p.held.sizes[into]; ok {
+		p.held.sizes[into] = intoSize + size
+	}
+}
+
 func (p *IntPool) putIntAlloc(ia *IntAlloc) {
 	*ia = IntAlloc{}
 	intAllocSyncPool.Put(ia)
@@ -334,6 +334,39 @@ func TestQuotaPoolZeroCapacity(t *testing.T) {
 	acq2.Release()
 }
 
+// TestQuotaPoolHeldAllocs tests that a pool created WithAllocTracking reports
+// the allocs acquired from it until they are released.
+func TestQuotaPoolHeldAllocs(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	require.Nil(t, quotapool.NewIntPool("untracked", 10).HeldAllocs())
+
+	qp := quotapool.NewIntPool("tracked", 10, quotapool.WithAllocTracking())
+	require.Empty(t, qp.HeldAllocs())
+
+	acquire := func(v uint64) *quotapool.IntAlloc {
+		alloc, err := qp.Acquire(ctx, v)
+		require.NoError(t, err)
+		return alloc
+	}
+	a, b, c := acquire(1), acquire(3), acquire(2)
+	require.Equal(t, []uint64{3, 2, 1}, qp.HeldAllocs())
+
+	b.Release()
+	require.Equal(t, []uint64{2, 1}, qp.HeldAllocs())
+
+	// Merged allocs are held as one.
+	a.Merge(c)
+	require.Equal(t, []uint64{3}, qp.HeldAllocs())
+
+	qp.Release(a, acquire(4))
+	require.Empty(t, qp.HeldAllocs())
+
+	acquire(5).Freeze()
+	require.Empty(t, qp.HeldAllocs())
+}
+
 func TestOnAcquisition(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 
@@ -903,3 +936,135 @@ func TestCloser(t *testing.T) {
 	close(closer)
 	require.True(t, quotapool.HasErrClosed(<-errCh))
 }
+
+// TestQuotaPoolGrouping tests that a pool configured WithGrouping serves
+// waiters of different groups round-robin rather than in FIFO order, and that
+// canceled waiters, parked or not, do not break the ordering.
+func TestQuotaPoolGrouping(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	qp := quotapool.NewIntPool("test", 1, quotapool.WithGrouping(quotapool.GroupKeyFromContext))
+	held, err := qp.Acquire(ctx, 1)
+	require.NoError(t, err)
+
+	var mu struct {
+		sync.Mutex
+		order []string
+	}
+	var wg sync.WaitGroup
+	acquire := func(ctx context.Context, name string) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			alloc, err := qp.Acquire(ctx, 1)
+			if err != nil {
+				assert.ErrorIs(t, err, context.Canceled)
+				return
+			}
+			mu.Lock()
+			mu.order = append(mu.order, name)
+			mu.Unlock()
+			alloc.Release()
+		}()
+	}
+	// enqueue starts an acquisition and waits for it to be queued, such that
+	// waiters are queued in a deterministic order.
+	enqueue := func(ctx context.Context, name string) {
+		l := qp.Len()
+		acquire(ctx, name)
+		testutils.SucceedsSoon(t, func() error {
+			if qp.Len() != l+1 {
+				return errors.Errorf("expected %d waiters, got %d", l+1, qp.Len())
+			}
+			return nil
+		})
+	}
+
+	// A single session issues a burst of acquisitions, followed by a single
+	// acquisition from each of two other sessions and an ungrouped one.
+	a := quotapool.ContextWithGroupKey(ctx, "a")
+	aCanceled, cancelA := context.WithCancel(a)
+	for i := 1; i <= 4; i++ {
+		if i == 3 {
+			enqueue(aCanceled, "a-canceled")
+		}
+		enqueue(a, fmt.Sprintf("a%d", i))
+	}
+	bCanceled, cancelB := context.WithCancel(quotapool.ContextWithGroupKey(ctx, "b"))
+	enqueue(bCanceled, "b-canceled")
+	enqueue(quotapool.ContextWithGroupKey(ctx, "b"), "b1")
+	enqueue(quotapool.ContextWithGroupKey(ctx, "c"), "c1")
+	enqueue(ctx, "ungrouped")
+
+	// Cancel a parked waiter of session a and the queued waiter of session b.
+	cancelA()
+	cancelB()
+	testutils.SucceedsSoon(t, func() error {
+		if l := qp.Len(); l != 7 {
+			return errors.Errorf("expected 7 waiters, got %d", l)
+		}
+		return nil
+	})
+
+	held.Release()
+	wg.Wait()
+	// Session b lost its place in line when its queued waiter was canceled, so
+	// its next waiter joined the back of the queue.
+	require.Equal(t, []string{"a1", "c1", "ungrouped", "b1", "a2", "a3", "a4"}, mu.order)
+	require.Equal(t, uint64(1), qp.ApproximateQuota())
+	require.Equal(t, 0, qp.Len())
+}
+
+func TestQuotaPoolQueuePosition(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	qp := quotapool.NewIntPool("test", 1)
+	held, err := qp.Acquire(ctx, 1)
+	require.NoError(t, err)
+
+	errTooDeep := errors.New("too deep")
+	var positions []int
+	withPosition := func(ctx context.Context, maxAhead int) context.Context {
+		return quotapool.ContextWithQueuePositionFunc(ctx, func(ahead int) error {
+			positions = append(positions, ahead)
+			if ahead >= maxAhead {
+				return errTooDeep
+			}
+			return nil
+		})
+	}
+
+	// Acquisitions which do not have to wait are not told their position.
+	held.Release()
+	held, err = qp.Acquire(withPosition(ctx, 0), 1)
+	require.NoError(t, err)
+	require.Empty(t, positions)
+
+	// The first waiter has nobody ahead of it.
+	errCh := make(chan error, 1)
+	go func() {
+		alloc, err := qp.Acquire(withPosition(ctx, 1), 1)
+		if err == nil {
+			alloc.Release()
+		}
+		errCh <- err
+	}()
+	testutils.SucceedsSoon(t, func() error {
+		if qp.Len() != 1 {
+			return errors.Errorf("expected 1 waiter, got %d", qp.Len())
+		}
+		return nil
+	})
+	require.Equal(t, []int{0}, positions)
+
+	// The second one fails fast instead of waiting behind the first.
+	_, err = qp.Acquire(withPosition(ctx, 1), 1)
+	require.ErrorIs(t, err, errTooDeep)
+	require.Equal(t, []int{0, 1}, positions)
+	require.Equal(t, 1, qp.Len())
+
+	held.Release()
+	require.NoError(t, <-errCh)
+}
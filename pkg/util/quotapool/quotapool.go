@@ -108,6 +108,13 @@ type AbstractPool struct {
 		// which is q.len() less this value.
 		numCanceled int
 
+		// groups tracks the waiters of each group which currently has a member
+		// in q when the pool is configured WithGrouping.
+		groups map[string]*waitGroup
+
+		// numParked is the number of grouped waiters which are not yet in q.
+		numParked int
+
 		// closed is set to true when the quota pool is closed (see
 		// AbstractPool.Close).
 		closed bool
@@ -137,7 +144,25 @@ func (qp *AbstractPool) TimeSource() timeutil.TimeSource {
 func (qp *AbstractPool) Len() int {
 	qp.mu.Lock()
 	defer qp.mu.Unlock()
-	return int(qp.mu.q.len) - qp.mu.numCanceled
+	return qp.lenLocked()
+}
+
+func (qp *AbstractPool) lenLocked() int {
+	return int(qp.mu.q.len) - qp.mu.numCanceled + qp.mu.numParked
+}
+
+// QueuePositionFunc is called when an acquisition is about to wait for quota
+// with the number of acquisitions already waiting ahead of it. It is called
+// with the pool's mutex held and so must not call into the pool. If it returns
+// an error, the acquisition does not wait and Acquire returns the error.
+type QueuePositionFunc func(ahead int) error
+
+type queuePositionFuncCtxKey struct{}
+
+// ContextWithQueuePositionFunc returns a context under which acquisitions
+// which have to wait for quota call f with their position in the queue.
+func ContextWithQueuePositionFunc(ctx context.Context, f QueuePositionFunc) context.Context {
+	return context.WithValue(ctx, queuePositionFuncCtxKey{}, f)
 }
 
 // Close signals to all ongoing and subsequent acquisitions that they are
@@ -210,8 +235,13 @@ func (qp *AbstractPool) Acquire(ctx context.Context, r Request) (err error) {
 		}()
 	}
 
+	var groupKey string
+	if qp.config.groupingFunc != nil {
+		groupKey = qp.config.groupingFunc(ctx)
+	}
+
 	// Attempt to acquire quota on the fast path.
-	fulfilled, n, tryAgainAfter, err := qp.acquireFastPath(ctx, r)
+	fulfilled, w, tryAgainAfter, err := qp.acquireFastPath(ctx, r, groupKey)
 	if fulfilled || err != nil {
 		return err
 	}
@@ -261,7 +291,7 @@ func (qp *AbstractPool) Acquire(ctx context.Context, r Request) (err error) {
 	}
 	tryAcquire := func() (fulfilled bool) {
 		tryAgainTimerC = nil
-		fulfilled, tryAgainAfter = qp.tryAcquireOnNotify(ctx, r, n)
+		fulfilled, tryAgainAfter = qp.tryAcquireOnNotify(ctx, r, &w)
 		if fulfilled {
 			return true
 		}
@@ -284,14 +314,14 @@ func (qp *AbstractPool) Acquire(ctx context.Context, r Request) (err error) {
 			slowTimerC = nil
 			defer qp.onSlowAcquisition(ctx, qp.name, r, start)()
 			continue
-		case <-n.c:
+		case <-w.c:
 			if fulfilled := tryAcquire(); fulfilled {
 				return nil
 			}
 		case <-qp.closer:
 			qp.Close("closer")
 		case <-ctx.Done():
-			qp.cleanupOnCancel(n)
+			qp.cleanupOnCancel(&w)
 			return ctx.Err()
 		case <-qp.done:
 			// We don't need to 'unregister' ourselves as in the case when the
@@ -308,33 +338,43 @@ func (qp *AbstractPool) Acquire(ctx context.Context, r Request) (err error) {
 }
 
 // acquireFastPath attempts to acquire quota if nobody is waiting and returns a
-// notifyee if the request is not immediately fulfilled. The returned
-// tryAgainAfter will only be non-zero if the notifyee is at the front of the
+// waiter if the request is not immediately fulfilled. The returned
+// tryAgainAfter will only be non-zero if the waiter is at the front of the
 // queue. This property ensures that only one tryAgainTimer in acquire exists
 // at a time.
 func (qp *AbstractPool) acquireFastPath(
-	ctx context.Context, r Request,
-) (fulfilled bool, _ *notifyee, tryAgainAfter time.Duration, _ error) {
+	ctx context.Context, r Request, groupKey string,
+) (fulfilled bool, _ waiter, tryAgainAfter time.Duration, _ error) {
 
 	qp.mu.Lock()
 	defer qp.mu.Unlock()
 	if qp.mu.closed {
-		return false, nil, 0, qp.closeErr
+		return false, waiter{}, 0, qp.closeErr
 	}
 	if qp.mu.q.len == 0 {
 		if fulfilled, tryAgainAfter = r.Acquire(ctx, qp.mu.quota); fulfilled {
-			return true, nil, tryAgainAfter, nil
+			return true, waiter{}, tryAgainAfter, nil
 		}
 	}
 	if !r.ShouldWait() {
-		return false, nil, 0, ErrNotEnoughQuota
+		return false, waiter{}, 0, ErrNotEnoughQuota
+	}
+	if f, ok := ctx.Value(queuePositionFuncCtxKey{}).(QueuePositionFunc); ok {
+		if err := f(qp.lenLocked()); err != nil {
+			return false, waiter{}, 0, err
+		}
 	}
 	c := chanSyncPool.Get().(chan struct{})
-	return false, qp.mu.q.enqueue(c), tryAgainAfter, nil
+	if groupKey != "" {
+		// NB: if the queue was empty above then so is the group, so a waiter
+		// with a non-zero tryAgainAfter is never parked.
+		return false, qp.enqueueGroupedLocked(groupKey, c), tryAgainAfter, nil
+	}
+	return false, waiter{c: c, n: qp.mu.q.enqueue(c)}, tryAgainAfter, nil
 }
 
 func (qp *AbstractPool) tryAcquireOnNotify(
-	ctx context.Context, r Request, n *notifyee,
+	ctx context.Context, r Request, w *waiter,
 ) (fulfilled bool, tryAgainAfter time.Duration) {
 	// Release the notify channel back into the sync pool if we're fulfilled.
 	// Capture nc's value because it's not safe to avoid a race accessing n after
@@ -343,36 +383,48 @@ func (qp *AbstractPool) tryAcquireOnNotify(
 		if fulfilled {
 			chanSyncPool.Put(nc)
 		}
-	}(n.c)
+	}(w.c)
 
 	qp.mu.Lock()
 	defer qp.mu.Unlock()
 	// Make sure nobody already notified us again between the last receive and grabbing
 	// the mutex.
-	if len(n.c) > 0 {
-		<-n.c
+	if len(w.c) > 0 {
+		<-w.c
 	}
 	if fulfilled, tryAgainAfter = r.Acquire(ctx, qp.mu.quota); fulfilled {
-		n.c = nil
+		w.notifyee().c = nil
+		// Move the next waiter of our group, if any, into the queue before we
+		// dequeue ourselves so that it is notified if it is next in line.
+		qp.advanceGroupLocked(w.gw)
 		qp.notifyNextLocked()
 	}
 	return fulfilled, tryAgainAfter
 }
 
-func (qp *AbstractPool) cleanupOnCancel(n *notifyee) {
+func (qp *AbstractPool) cleanupOnCancel(w *waiter) {
 	// No matter what, we're going to want to put our notify channel back in to
-	// the sync pool. Note that this defer call evaluates n.c here and is not
+	// the sync pool. Note that this defer call evaluates w.c here and is not
 	// affected by later code that sets n.c to nil.
-	defer chanSyncPool.Put(n.c)
+	defer chanSyncPool.Put(w.c)
 
 	qp.mu.Lock()
 	defer qp.mu.Unlock()
 
+	// If we're parked behind another member of our group we're not in the
+	// queue at all, so we only need to leave the group.
+	if w.parked() {
+		qp.unparkLocked(w.gw)
+		return
+	}
+
 	// It we're not the head, prevent ourselves from being notified and move
 	// along.
+	n := w.notifyee()
 	if n != qp.mu.q.peek() {
 		n.c = nil
 		qp.mu.numCanceled++
+		qp.advanceGroupLocked(w.gw)
 		return
 	}
 
@@ -381,6 +433,7 @@ func (qp *AbstractPool) cleanupOnCancel(n *notifyee) {
 	if len(n.c) > 0 {
 		<-n.c
 	}
+	qp.advanceGroupLocked(w.gw)
 	qp.notifyNextLocked()
 }
 